@@ -19,8 +19,10 @@ export enum FilterType {
     count = 'count',
     file = 'file',
     fork = 'fork',
+    fuzzy = 'fuzzy',
     lang = 'lang',
     message = 'message',
+    order = 'order',
     patterntype = 'patterntype',
     repo = 'repo',
     repogroup = 'repogroup',
@@ -231,6 +233,12 @@ export const FILTERS: Record<NegatableFilter, NegatableFilterDefinition> &
         description: 'Include results from forked repositories.',
         singular: true,
     },
+    [FilterType.fuzzy]: {
+        description: 'Match symbol names fuzzily, for type:symbol searches.',
+        discreteValues: () => ['yes', 'no'].map(value => ({ label: value })),
+        default: 'no',
+        singular: true,
+    },
     [FilterType.lang]: {
         alias: 'l',
         discreteValues: value => languageCompletion(value).map(toCompletionItem),
@@ -243,6 +251,11 @@ export const FILTERS: Record<NegatableFilter, NegatableFilterDefinition> &
         description: negated =>
             `${negated ? 'Exclude' : 'Include only'} Commits with messages matching a certain string`,
     },
+    [FilterType.order]: {
+        discreteValues: () => ['relevance', 'path', 'recency'].map(value => ({ label: value })),
+        description: 'Order results by relevance, path, or recency of the last change.',
+        singular: true,
+    },
     [FilterType.patterntype]: {
         discreteValues: () => ['regexp', 'literal', 'structural'].map(value => ({ label: value })),
         description: 'The pattern type (regexp, literal, structural) in use',
@@ -27,6 +27,7 @@ export interface PathMatch {
     repoLastFetched?: string
     branches?: string[]
     version?: string
+    owners?: string[]
 }
 
 export interface ContentMatch {
@@ -166,6 +167,7 @@ export interface Skipped {
      * - excluded-fork :: we did not search a repository because it is a fork.
      * - excluded-archive :: we did not search a repository because it is archived.
      * - display :: we hit the display limit, so we stopped sending results from the backend.
+     * - queued :: we did not start searching yet, because the search waits for other expensive searches of the user to finish.
      */
     reason:
         | 'document-match-limit'
@@ -177,6 +179,7 @@ export interface Skipped {
         | 'excluded-fork'
         | 'excluded-archive'
         | 'display'
+        | 'queued'
         | 'error'
     /**
      * A short message. eg 1,200 timed out.
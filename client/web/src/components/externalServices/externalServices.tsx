@@ -5,7 +5,10 @@ import BitbucketIcon from 'mdi-react/BitbucketIcon'
 import GithubIcon from 'mdi-react/GithubIcon'
 import GitIcon from 'mdi-react/GitIcon'
 import GitLabIcon from 'mdi-react/GitlabIcon'
+import LanguageGoIcon from 'mdi-react/LanguageGoIcon'
 import LanguageJavaIcon from 'mdi-react/LanguageJavaIcon'
+import LanguagePythonIcon from 'mdi-react/LanguagePythonIcon'
+import NpmIcon from 'mdi-react/NpmIcon'
 import React from 'react'
 
 import { PhabricatorIcon } from '@sourcegraph/shared/src/components/icons'
@@ -16,10 +19,13 @@ import bitbucketServerSchemaJSON from '../../../../../schema/bitbucket_server.sc
 import githubSchemaJSON from '../../../../../schema/github.schema.json'
 import gitlabSchemaJSON from '../../../../../schema/gitlab.schema.json'
 import gitoliteSchemaJSON from '../../../../../schema/gitolite.schema.json'
+import goModulesSchemaJSON from '../../../../../schema/go-modules.schema.json'
 import jvmPackagesSchemaJSON from '../../../../../schema/jvm-packages.schema.json'
+import npmPackagesSchemaJSON from '../../../../../schema/npm-packages.schema.json'
 import otherExternalServiceSchemaJSON from '../../../../../schema/other_external_service.schema.json'
 import perforceSchemaJSON from '../../../../../schema/perforce.schema.json'
 import phabricatorSchemaJSON from '../../../../../schema/phabricator.schema.json'
+import pythonPackagesSchemaJSON from '../../../../../schema/python-packages.schema.json'
 import { ExternalServiceKind } from '../../graphql-operations'
 import { EditorAction } from '../../site-admin/configHelpers'
 import { PerforceIcon } from '../PerforceIcon'
@@ -1208,6 +1214,95 @@ const JVM_PACKAGES: AddExternalServiceOptions = {
     ),
     editorActions: [],
 }
+const NPM_PACKAGES: AddExternalServiceOptions = {
+    kind: ExternalServiceKind.NPMPACKAGES,
+    title: 'npm Dependencies',
+    icon: NpmIcon,
+    jsonSchema: npmPackagesSchemaJSON,
+    defaultDisplayName: 'npm Dependencies',
+    defaultConfig: `{
+  "registry": "https://registry.npmjs.org",
+  "dependencies": []
+}`,
+    instructions: (
+        <div>
+            <ol>
+                <li>
+                    In the configuration below, set <Field>registry</Field> to the URL of the npm registry. For example,
+                    <code>"https://registry.npmjs.org"</code> or <code>"http://localhost:4873"</code> for a local
+                    Verdaccio.
+                </li>
+                <li>
+                    If the registry requires authentication, set <Field>credentials</Field> to an access token.
+                </li>
+                <li>
+                    In the configuration below, set <Field>dependencies</Field> to the list of package versions that
+                    you want to manually add. For example,
+                    <code>"react@17.0.2"</code> or
+                    <code>"@types/node@16.11.7"</code>.
+                </li>
+            </ol>
+        </div>
+    ),
+    editorActions: [],
+}
+const GO_MODULES: AddExternalServiceOptions = {
+    kind: ExternalServiceKind.GOMODULES,
+    title: 'Go Dependencies',
+    icon: LanguageGoIcon,
+    jsonSchema: goModulesSchemaJSON,
+    defaultDisplayName: 'Go Dependencies',
+    defaultConfig: `{
+  "urls": ["https://proxy.golang.org"],
+  "dependencies": []
+}`,
+    instructions: (
+        <div>
+            <ol>
+                <li>
+                    In the configuration below, set <Field>urls</Field> to the list of Go module proxies to fetch
+                    modules from. For example, <code>["https://proxy.golang.org"]</code> or{' '}
+                    <code>["https://athens.mycompany.com", "https://proxy.golang.org"]</code>.
+                </li>
+                <li>
+                    In the configuration below, set <Field>dependencies</Field> to the list of module versions that you
+                    want to manually add. For example,
+                    <code>"golang.org/x/mod@v0.4.2"</code>, or <code>"github.com/pkg/errors"</code> to add all
+                    versions of a module.
+                </li>
+            </ol>
+        </div>
+    ),
+    editorActions: [],
+}
+const PYTHON_PACKAGES: AddExternalServiceOptions = {
+    kind: ExternalServiceKind.PYTHONPACKAGES,
+    title: 'Python Dependencies',
+    icon: LanguagePythonIcon,
+    jsonSchema: pythonPackagesSchemaJSON,
+    defaultDisplayName: 'Python Dependencies',
+    defaultConfig: `{
+  "urls": ["https://pypi.org/simple"],
+  "dependencies": []
+}`,
+    instructions: (
+        <div>
+            <ol>
+                <li>
+                    In the configuration below, set <Field>urls</Field> to the list of Python package indexes to fetch
+                    packages from. For example, <code>["https://pypi.org/simple"]</code>.
+                </li>
+                <li>
+                    In the configuration below, set <Field>dependencies</Field> to the list of package versions that
+                    you want to manually add. For example,
+                    <code>"numpy==1.22.0"</code> or
+                    <code>"pandas==1.3.5"</code>.
+                </li>
+            </ol>
+        </div>
+    ),
+    editorActions: [],
+}
 
 export const codeHostExternalServices: Record<string, AddExternalServiceOptions> = {
     github: GITHUB_DOTCOM,
@@ -1222,6 +1317,11 @@ export const codeHostExternalServices: Record<string, AddExternalServiceOptions>
     git: GENERIC_GIT,
     ...(window.context?.experimentalFeatures?.perforce === 'enabled' ? { perforce: PERFORCE } : {}),
     ...(window.context?.experimentalFeatures?.jvmPackages === 'enabled' ? { jvmPackages: JVM_PACKAGES } : {}),
+    ...(window.context?.experimentalFeatures?.npmPackages === 'enabled' ? { npmPackages: NPM_PACKAGES } : {}),
+    ...(window.context?.experimentalFeatures?.goModules === 'enabled' ? { goModules: GO_MODULES } : {}),
+    ...(window.context?.experimentalFeatures?.pythonPackages === 'enabled'
+        ? { pythonPackages: PYTHON_PACKAGES }
+        : {}),
 }
 
 export const nonCodeHostExternalServices: Record<string, AddExternalServiceOptions> = {
@@ -1244,4 +1344,7 @@ export const defaultExternalServices: Record<ExternalServiceKind, AddExternalSer
     [ExternalServiceKind.AWSCODECOMMIT]: AWS_CODE_COMMIT,
     [ExternalServiceKind.PERFORCE]: PERFORCE,
     [ExternalServiceKind.JVMPACKAGES]: JVM_PACKAGES,
+    [ExternalServiceKind.NPMPACKAGES]: NPM_PACKAGES,
+    [ExternalServiceKind.GOMODULES]: GO_MODULES,
+    [ExternalServiceKind.PYTHONPACKAGES]: PYTHON_PACKAGES,
 }
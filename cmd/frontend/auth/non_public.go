@@ -130,6 +130,11 @@ func AllowAnonymousRequest(req *http.Request) bool {
 		}
 	}
 
+	// Permission is checked by the signature of the download URL
+	if strings.HasPrefix(req.URL.Path, "/.api/search/export/download/") {
+		return true
+	}
+
 	// Permission is checked by a shared token
 	if strings.HasPrefix(req.URL.Path, "/.executors") {
 		return true
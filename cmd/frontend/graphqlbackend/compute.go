@@ -2,13 +2,12 @@ package graphqlbackend
 
 import (
 	"context"
-	"regexp"
+	"encoding/csv"
+	"strings"
 
-	"github.com/cockroachdb/errors"
 	"github.com/sourcegraph/go-langserver/pkg/lsp"
 	"github.com/sourcegraph/sourcegraph/internal/compute"
 	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
-	"github.com/sourcegraph/sourcegraph/internal/search/query"
 	"github.com/sourcegraph/sourcegraph/internal/search/result"
 	"github.com/sourcegraph/sourcegraph/internal/types"
 )
@@ -109,18 +108,25 @@ func toLspRange(r compute.Range) lsp.Range {
 // ComputeText GQL result resolver definitions.
 
 type computeTextResolver struct {
-	repository *RepositoryResolver //nolint
-	commit     string              //nolint
-	path       string              //nolint
+	repository *RepositoryResolver
+	commit     string
+	path       string
 	t          *compute.Text
 }
 
-func (c *computeTextResolver) Repository() *RepositoryResolver { return nil }
-func (r *computeTextResolver) Commit() *string                 { return nil }
-func (r *computeTextResolver) Path() *string                   { return nil }
-func (r *computeTextResolver) Kind() *string                   { return nil }
+func (c *computeTextResolver) Repository() *RepositoryResolver { return c.repository }
+func (r *computeTextResolver) Commit() *string                 { return nonEmptyString(r.commit) }
+func (r *computeTextResolver) Path() *string                   { return nonEmptyString(r.path) }
+func (r *computeTextResolver) Kind() *string                   { return nonEmptyString(r.t.Kind) }
 func (r *computeTextResolver) Value() string                   { return r.t.Value }
 
+func nonEmptyString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
 // Definitions required by https://github.com/graph-gophers/graphql-go to resolve
 // a union type in GraphQL.
 
@@ -167,55 +173,57 @@ func toComputeResultResolver(r *computeMatchContextResolver) *computeResultResol
 	return &computeResultResolver{result: r}
 }
 
-func regexpFromQuery(q string) (*regexp.Regexp, error) {
-	plan, err := query.Pipeline(query.Init(q, query.SearchTypeRegex))
-	if err != nil {
-		return nil, err
-	}
-	if len(plan) != 1 {
-		return nil, errors.New("compute endpoint only supports one search pattern currently ('and' or 'or' operators are not supported yet)")
-	}
-	switch node := plan[0].Pattern.(type) {
-	case query.Operator:
-		if len(node.Operands) == 1 {
-			if pattern, ok := node.Operands[0].(query.Pattern); ok && !pattern.Negated {
-				rp, err := regexp.Compile(pattern.Value)
-				if err != nil {
-					return nil, errors.Wrap(err, "regular expression is not valid for compute endpoint")
-				}
-				return rp, nil
-			}
-		}
-		return nil, errors.New("compute endpoint only supports one search pattern currently ('and' or 'or' operators are not supported yet)")
-	case query.Pattern:
-		if !node.Negated {
-			return regexp.Compile(node.Value)
-		}
-	}
-	// unreachable
-	return nil, nil
+func toComputeTextResolver(fm *result.FileMatch, t *compute.Text, db dbutil.DB) *computeResultResolver {
+	return &computeResultResolver{result: &computeTextResolver{
+		repository: NewRepositoryResolver(db, fm.Repo.ToRepo()),
+		commit:     string(fm.CommitID),
+		path:       fm.Path,
+		t:          t,
+	}}
 }
 
-func toResultResolverList(pattern *regexp.Regexp, matches []result.Match, db dbutil.DB) []*computeResultResolver {
+func toResultResolverList(ctx context.Context, cmd compute.Command, matches []result.Match, db dbutil.DB) ([]*computeResultResolver, error) {
 	var computeResult []*computeResultResolver
 	for _, m := range matches {
-		if fm, ok := m.(*result.FileMatch); ok {
-			matchContext := compute.FromFileMatch(fm, pattern)
-			computeResult = append(computeResult, toComputeResultResolver(toComputeMatchContextResolver(fm, matchContext, db)))
+		fm, ok := m.(*result.FileMatch)
+		if !ok {
+			continue
+		}
+		r, err := cmd.Run(ctx, fm)
+		if err != nil {
+			return nil, err
+		}
+		switch r := r.(type) {
+		case *compute.MatchContext:
+			computeResult = append(computeResult, toComputeResultResolver(toComputeMatchContextResolver(fm, r, db)))
+		case *compute.Text:
+			computeResult = append(computeResult, toComputeTextResolver(fm, r, db))
+		case *compute.Table:
+			// Render each row of an output as CSV.
+			for _, row := range r.Rows {
+				var b strings.Builder
+				w := csv.NewWriter(&b)
+				if err := w.Write(row); err != nil {
+					return nil, err
+				}
+				w.Flush()
+				text := &compute.Text{Value: strings.TrimSuffix(b.String(), "\n"), Kind: "output"}
+				computeResult = append(computeResult, toComputeTextResolver(fm, text, db))
+			}
 		}
 	}
-	return computeResult
+	return computeResult, nil
 }
 
 // NewComputeImplementer is a function that abstracts away the need to have a
 // handle on (*schemaResolver) Compute.
 func NewComputeImplementer(ctx context.Context, db dbutil.DB, args *ComputeArgs) ([]*computeResultResolver, error) {
-	pattern, err := regexpFromQuery(args.Query)
+	computeQuery, err := compute.Parse(args.Query)
 	if err != nil {
 		return nil, err
 	}
 	patternType := "regexp"
-	job, err := NewSearchImplementer(ctx, db, &SearchArgs{Query: args.Query, PatternType: &patternType})
+	job, err := NewSearchImplementer(ctx, db, &SearchArgs{Query: computeQuery.ToSearchQuery(), PatternType: &patternType})
 	if err != nil {
 		return nil, err
 	}
@@ -224,7 +232,7 @@ func NewComputeImplementer(ctx context.Context, db dbutil.DB, args *ComputeArgs)
 	if err != nil {
 		return nil, err
 	}
-	return toResultResolverList(pattern, results.Matches, db), nil
+	return toResultResolverList(ctx, computeQuery.Command, results.Matches, db)
 }
 
 func (r *schemaResolver) Compute(ctx context.Context, args *ComputeArgs) ([]*computeResultResolver, error) {
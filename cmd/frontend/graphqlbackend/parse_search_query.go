@@ -37,6 +37,16 @@ func toJSON(node query.Node) interface{} {
 			}{
 				Concat: jsons,
 			}
+		case query.Near, query.Before:
+			return struct {
+				Near     []interface{} `json:"near"`
+				Distance int           `json:"distance"`
+				Ordered  bool          `json:"ordered"`
+			}{
+				Near:     jsons,
+				Distance: n.Distance,
+				Ordered:  n.Kind.Ordered(),
+			}
 		}
 	case query.Parameter:
 		return struct {
@@ -93,7 +103,7 @@ func (r *schemaResolver) ParseSearchQuery(ctx context.Context, args *struct {
 	globbing := getBoolPtr(settings.SearchGlobbing, false)
 
 	plan, err := query.Pipeline(
-		query.Init(args.Query, searchType),
+		query.InitWithMacros(args.Query, searchType, settings.SearchMacros),
 		query.With(globbing, query.Globbing),
 	)
 	if err != nil {
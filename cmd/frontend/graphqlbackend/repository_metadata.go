@@ -0,0 +1,110 @@
+package graphqlbackend
+
+import (
+	"context"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/graph-gophers/graphql-go"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/internal/database"
+)
+
+type KeyValuePair struct {
+	key   string
+	value *string
+}
+
+func (k KeyValuePair) Key() string {
+	return k.key
+}
+
+func (k KeyValuePair) Value() *string {
+	return k.value
+}
+
+func (r *RepositoryResolver) KeyValuePairs(ctx context.Context) ([]KeyValuePair, error) {
+	kvps, err := database.RepoKVPs(r.db).List(ctx, r.IDInt32())
+	if err != nil {
+		return nil, err
+	}
+	res := make([]KeyValuePair, 0, len(kvps))
+	for _, kvp := range kvps {
+		res = append(res, KeyValuePair{key: kvp.Key, value: kvp.Value})
+	}
+	return res, nil
+}
+
+type repoKeyValuePairArgs struct {
+	Repo  graphql.ID
+	Key   string
+	Value *string
+}
+
+func (r *schemaResolver) AddRepoKeyValuePair(ctx context.Context, args *repoKeyValuePairArgs) (*EmptyResponse, error) {
+	// 🚨 SECURITY: Only site admins may change repository metadata, since it
+	// affects which repositories searches of all users match.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx, r.db); err != nil {
+		return nil, err
+	}
+	if err := validateRepoMetadataKey(args.Key); err != nil {
+		return nil, err
+	}
+
+	repo, err := r.repositoryByID(ctx, args.Repo)
+	if err != nil {
+		return nil, err
+	}
+
+	kvp := database.KeyValuePair{Key: args.Key, Value: args.Value}
+	return &EmptyResponse{}, database.RepoKVPs(r.db).Create(ctx, repo.IDInt32(), kvp)
+}
+
+func (r *schemaResolver) UpdateRepoKeyValuePair(ctx context.Context, args *repoKeyValuePairArgs) (*EmptyResponse, error) {
+	// 🚨 SECURITY: Only site admins may change repository metadata.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx, r.db); err != nil {
+		return nil, err
+	}
+	if err := validateRepoMetadataKey(args.Key); err != nil {
+		return nil, err
+	}
+
+	repo, err := r.repositoryByID(ctx, args.Repo)
+	if err != nil {
+		return nil, err
+	}
+
+	kvp := database.KeyValuePair{Key: args.Key, Value: args.Value}
+	_, err = database.RepoKVPs(r.db).Update(ctx, repo.IDInt32(), kvp)
+	return &EmptyResponse{}, err
+}
+
+func (r *schemaResolver) DeleteRepoKeyValuePair(ctx context.Context, args *struct {
+	Repo graphql.ID
+	Key  string
+}) (*EmptyResponse, error) {
+	// 🚨 SECURITY: Only site admins may change repository metadata.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx, r.db); err != nil {
+		return nil, err
+	}
+
+	repo, err := r.repositoryByID(ctx, args.Repo)
+	if err != nil {
+		return nil, err
+	}
+
+	return &EmptyResponse{}, database.RepoKVPs(r.db).Delete(ctx, repo.IDInt32(), args.Key)
+}
+
+// validateRepoMetadataKey returns an error for keys which cannot be searched
+// with repo:has(key:value) or repo:has(key).
+func validateRepoMetadataKey(key string) error {
+	if strings.TrimSpace(key) != key || key == "" {
+		return errors.Errorf("invalid key %q: keys must be non-empty and may not start or end with whitespace", key)
+	}
+	if strings.ContainsAny(key, ":()") {
+		return errors.Errorf("invalid key %q: keys may not contain ':', '(' or ')'", key)
+	}
+	return nil
+}
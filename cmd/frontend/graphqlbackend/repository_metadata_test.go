@@ -0,0 +1,99 @@
+package graphqlbackend
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/internal/actor"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/database"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbtesting"
+	"github.com/sourcegraph/sourcegraph/internal/types"
+)
+
+// 🚨 SECURITY: This tests that only site admins can change repository metadata.
+func TestMutation_AddRepoKeyValuePair(t *testing.T) {
+	db := new(dbtesting.MockDB)
+	ctx := actor.WithActor(context.Background(), &actor.Actor{UID: 1})
+	value := "payments"
+	args := &repoKeyValuePairArgs{Repo: MarshalRepositoryID(5), Key: "team", Value: &value}
+
+	t.Run("non site admin", func(t *testing.T) {
+		resetMocks()
+		database.Mocks.Users.GetByCurrentAuthUser = func(ctx context.Context) (*types.User, error) {
+			return &types.User{ID: 1, SiteAdmin: false}, nil
+		}
+		database.Mocks.RepoKVPs.Create = func(context.Context, api.RepoID, database.KeyValuePair) error {
+			t.Fatal("unexpected call to Create")
+			return nil
+		}
+
+		_, err := (&schemaResolver{db: db}).AddRepoKeyValuePair(ctx, args)
+		if want := backend.ErrMustBeSiteAdmin; err != want {
+			t.Errorf("got err %v, want %v", err, want)
+		}
+	})
+
+	t.Run("site admin", func(t *testing.T) {
+		resetMocks()
+		database.Mocks.Users.GetByCurrentAuthUser = func(ctx context.Context) (*types.User, error) {
+			return &types.User{ID: 1, SiteAdmin: true}, nil
+		}
+		database.Mocks.Repos.Get = func(ctx context.Context, id api.RepoID) (*types.Repo, error) {
+			return &types.Repo{ID: id, Name: "github.com/a/b"}, nil
+		}
+		var gotRepo api.RepoID
+		var gotKVP database.KeyValuePair
+		database.Mocks.RepoKVPs.Create = func(_ context.Context, repoID api.RepoID, kvp database.KeyValuePair) error {
+			gotRepo, gotKVP = repoID, kvp
+			return nil
+		}
+
+		if _, err := (&schemaResolver{db: db}).AddRepoKeyValuePair(ctx, args); err != nil {
+			t.Fatal(err)
+		}
+		if gotRepo != 5 {
+			t.Errorf("got repo %d, want 5", gotRepo)
+		}
+		if diff := cmp.Diff(database.KeyValuePair{Key: "team", Value: &value}, gotKVP); diff != "" {
+			t.Errorf("unexpected key/value pair (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("invalid key", func(t *testing.T) {
+		resetMocks()
+		database.Mocks.Users.GetByCurrentAuthUser = func(ctx context.Context) (*types.User, error) {
+			return &types.User{ID: 1, SiteAdmin: true}, nil
+		}
+
+		args := &repoKeyValuePairArgs{Repo: args.Repo, Key: "team:a", Value: args.Value}
+		if _, err := (&schemaResolver{db: db}).AddRepoKeyValuePair(ctx, args); err == nil {
+			t.Error("expected error for key with ':'")
+		}
+	})
+}
+
+func TestMutation_UpdateRepoKeyValuePair(t *testing.T) {
+	db := new(dbtesting.MockDB)
+	ctx := actor.WithActor(context.Background(), &actor.Actor{UID: 1})
+	value := "payments"
+
+	t.Run("invalid key", func(t *testing.T) {
+		resetMocks()
+		database.Mocks.Users.GetByCurrentAuthUser = func(ctx context.Context) (*types.User, error) {
+			return &types.User{ID: 1, SiteAdmin: true}, nil
+		}
+		database.Mocks.RepoKVPs.Update = func(context.Context, api.RepoID, database.KeyValuePair) (database.KeyValuePair, error) {
+			t.Fatal("unexpected call to Update")
+			return database.KeyValuePair{}, nil
+		}
+
+		args := &repoKeyValuePairArgs{Repo: MarshalRepositoryID(5), Key: "team:a", Value: &value}
+		if _, err := (&schemaResolver{db: db}).UpdateRepoKeyValuePair(ctx, args); err == nil {
+			t.Error("expected error for key with ':'")
+		}
+	})
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/internal/endpoint"
 	"github.com/sourcegraph/sourcegraph/internal/search"
 	searchbackend "github.com/sourcegraph/sourcegraph/internal/search/backend"
+	"github.com/sourcegraph/sourcegraph/internal/search/codeowners"
 	"github.com/sourcegraph/sourcegraph/internal/search/query"
 	searchrepos "github.com/sourcegraph/sourcegraph/internal/search/repos"
 	"github.com/sourcegraph/sourcegraph/internal/search/run"
@@ -56,6 +57,9 @@ type SearchImplementer interface {
 	Stats(context.Context) (*searchResultsStats, error)
 
 	Inputs() run.SearchInputs
+
+	// CountRepos returns the number of repositories b searches.
+	CountRepos(ctx context.Context, b query.Basic) (int, error)
 }
 
 // NewSearchImplementer returns a SearchImplementer that provides search results and suggestions.
@@ -89,7 +93,7 @@ func NewSearchImplementer(ctx context.Context, db dbutil.DB, args *SearchArgs) (
 	globbing := getBoolPtr(settings.SearchGlobbing, false)
 	tr.LogFields(otlog.Bool("globbing", globbing))
 	plan, err = query.Pipeline(
-		query.Init(args.Query, searchType),
+		query.InitWithMacros(args.Query, searchType, settings.SearchMacros),
 		query.With(globbing, query.Globbing),
 	)
 	if err != nil {
@@ -119,6 +123,7 @@ func NewSearchImplementer(ctx context.Context, db dbutil.DB, args *SearchArgs) (
 		},
 
 		stream: args.Stream,
+		owners: codeowners.NewResolver(),
 
 		zoekt:        search.Indexed(),
 		searcherURLs: search.SearcherURLs(),
@@ -208,6 +213,11 @@ type searchResolver struct {
 
 	zoekt        *searchbackend.Zoekt
 	searcherURLs *endpoint.Map
+
+	// owners resolves the owners of files for file:has.owner() and
+	// select:file.owners. It is shared by all evaluations of the search so
+	// that each CODEOWNERS file is read once.
+	owners *codeowners.Resolver
 }
 
 func (r *searchResolver) Inputs() run.SearchInputs {
@@ -307,6 +317,29 @@ func (r *searchResolver) resolveRepositories(ctx context.Context, options search
 	return repositoryResolver.Resolve(ctx, options)
 }
 
+// CountRepos returns the number of repositories b searches. Repository
+// predicates are ignored, so it may overestimate. If b is the only query of
+// the plan, the resolved repositories are cached for the search.
+func (r *searchResolver) CountRepos(ctx context.Context, b query.Basic) (int, error) {
+	hasPredicates := false
+	parameters := make([]query.Parameter, 0, len(b.Parameters))
+	for _, p := range b.Parameters {
+		if p.Annotation.Labels.IsSet(query.IsPredicate) {
+			hasPredicates = true
+			continue
+		}
+		parameters = append(parameters, p)
+	}
+
+	options := r.toRepoOptions(b.MapParameters(parameters).ToParseTree(), resolveRepositoriesOpts{})
+	options.CacheLookup = !hasPredicates && len(r.Plan) == 1 && !shouldInvalidateRepoCache(r.Plan)
+	resolved, err := r.resolveRepositories(ctx, options)
+	if err != nil {
+		return 0, err
+	}
+	return len(resolved.RepoRevs), nil
+}
+
 func (r *searchResolver) suggestFilePaths(ctx context.Context, limit int) ([]SearchSuggestionResolver, error) {
 	q, err := query.ToBasicQuery(r.Query)
 	if err != nil {
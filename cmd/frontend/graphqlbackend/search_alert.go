@@ -66,6 +66,14 @@ func alertForCappedAndExpression() *searchAlert {
 
 // alertForQuery converts errors in the query to search alerts.
 func alertForQuery(queryString string, err error) *searchAlert {
+	var macroErr *query.MacroError
+	if errors.As(err, &macroErr) {
+		return &searchAlert{
+			prometheusType: "invalid_macro",
+			title:          "Unable To Expand Macro",
+			description:    capFirst(macroErr.Error()) + `. Macros are defined in the "search.macros" setting.`,
+		}
+	}
 	if errors.HasType(err, &query.UnsupportedError{}) || errors.HasType(err, &query.ExpectedOperand{}) {
 		return &searchAlert{
 			prometheusType: "unsupported_and_or_query",
@@ -482,6 +490,7 @@ func (alertSearchImplementer) Stats(context.Context) (*searchResultsStats, error
 func (alertSearchImplementer) Inputs() run.SearchInputs {
 	return run.SearchInputs{}
 }
+func (alertSearchImplementer) CountRepos(context.Context, query.Basic) (int, error) { return 0, nil }
 
 // capFirst capitalizes the first rune in the given string. It can be safely
 // used with UTF-8 strings.
@@ -616,6 +625,14 @@ func alertForStalePermissions() *searchAlert {
 	}
 }
 
+func alertForOwnersNotResolved(err error) *searchAlert {
+	return &searchAlert{
+		prometheusType: "codeowners_not_resolved",
+		title:          "Some file owners could not be resolved",
+		description:    fmt.Sprintf("Results in files whose owners could not be resolved from their CODEOWNERS file are missing: %s", err),
+	}
+}
+
 func alertForInvalidRevision(revision string) *searchAlert {
 	revision = strings.TrimSuffix(revision, "^0")
 	return &searchAlert{
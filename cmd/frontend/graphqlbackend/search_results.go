@@ -33,8 +33,10 @@ import (
 	"github.com/sourcegraph/sourcegraph/internal/honey"
 	"github.com/sourcegraph/sourcegraph/internal/rcache"
 	"github.com/sourcegraph/sourcegraph/internal/search"
+	"github.com/sourcegraph/sourcegraph/internal/search/codeowners"
 	"github.com/sourcegraph/sourcegraph/internal/search/filter"
 	"github.com/sourcegraph/sourcegraph/internal/search/query"
+	"github.com/sourcegraph/sourcegraph/internal/search/ranking"
 	searchrepos "github.com/sourcegraph/sourcegraph/internal/search/repos"
 	"github.com/sourcegraph/sourcegraph/internal/search/result"
 	"github.com/sourcegraph/sourcegraph/internal/search/run"
@@ -499,6 +501,19 @@ func (r *searchResolver) toRepoOptions(q query.Q, opts resolveRepositoriesOpts)
 	visibility := query.ParseVisibility(visibilityStr)
 
 	commitAfter, _ := q.StringValue(query.FieldRepoHasCommitAfter)
+	revAtTime, _ := q.StringValue(query.FieldRevAtTime)
+	kvpValues, _ := q.StringValues(query.FieldRepoHasKVP)
+	var hasKVPs []search.RepoKVPFilter
+	for _, value := range kvpValues {
+		var kvp query.RepoHasKVPPredicate
+		if err := kvp.ParseParams(value); err == nil {
+			filter := search.RepoKVPFilter{Key: kvp.Key}
+			if kvp.Value != "" {
+				filter.Value = &kvp.Value
+			}
+			hasKVPs = append(hasKVPs, filter)
+		}
+	}
 	searchContextSpec, _ := q.StringValue(query.FieldContext)
 
 	var versionContextName string
@@ -525,6 +540,8 @@ func (r *searchResolver) toRepoOptions(q query.Q, opts resolveRepositoriesOpts)
 		NoArchived:         archived == query.No,
 		Visibility:         visibility,
 		CommitAfter:        commitAfter,
+		RevAtTime:          revAtTime,
+		HasKVPs:            hasKVPs,
 		Query:              q,
 		Ranked:             true,
 		Limit:              opts.limit,
@@ -844,6 +861,15 @@ func (r *searchResolver) evaluatePatternExpression(ctx context.Context, q query.
 				return &SearchResults{}, err
 			}
 			return r.evaluateLeaf(ctx, args, jobs)
+		case query.Near, query.Before:
+			// Proximity expressions are evaluated by the backends as a
+			// single pattern.
+			r.invalidateCache()
+			args, jobs, err := r.toSearchInputs(q.ToParseTree())
+			if err != nil {
+				return &SearchResults{}, err
+			}
+			return r.evaluateLeaf(ctx, args, jobs)
 		}
 	case query.Pattern:
 		r.invalidateCache()
@@ -958,6 +984,7 @@ func (r *searchResolver) resultsBatch(ctx context.Context) (*SearchResultsResolv
 	start := time.Now()
 	sr, err := r.resultsRecursive(ctx, r.Plan)
 	srr := r.resultsToResolver(sr)
+	r.alertForOwners(srr)
 	r.logBatch(ctx, srr, start, err)
 	return srr, err
 }
@@ -981,10 +1008,14 @@ func (r *searchResolver) resultsStreaming(ctx context.Context) (*SearchResultsRe
 	if sp, _ := r.Plan.ToParseTree().StringValue(query.FieldSelect); sp != "" {
 		// Ensure downstream events sent on the stream are processed by `select:`.
 		selectPath, _ := filter.SelectPathFromString(sp) // Invariant: error already checked
+		if isSelectOwners(selectPath) {
+			r.stream = codeowners.WithAnnotation(ctx, r.stream, r.owners)
+		}
 		r.stream = streaming.WithSelect(r.stream, selectPath)
 	}
 	sr, err := r.resultsRecursive(ctx, r.Plan)
 	srr := r.resultsToResolver(sr)
+	r.alertForOwners(srr)
 	return srr, err
 }
 
@@ -1076,7 +1107,15 @@ func (r *searchResolver) resultsRecursive(ctx context.Context, plan query.Plan)
 		}
 
 		if newResult != nil {
+			// Ownership must be checked before `select:` discards file
+			// information. Only the subplans of file:has.owner() filter
+			// by owner, and they are never streamed.
+			newResult.Matches = r.filterByOwner(ctx, q, newResult.Matches)
 			newResult.Matches = result.Select(newResult.Matches, q)
+			if r.stream == nil {
+				// When streaming, owners are annotated on the stream.
+				r.annotateOwners(ctx, q, newResult.Matches)
+			}
 			sr = union(sr, newResult)
 			if len(sr.Matches) > wantCount {
 				sr.Matches = sr.Matches[:wantCount]
@@ -1086,24 +1125,36 @@ func (r *searchResolver) resultsRecursive(ctx context.Context, plan query.Plan)
 	}
 
 	if sr != nil {
-		r.sortResults(sr.Matches)
+		r.sortResults(ctx, sr.Matches)
 	}
 	return sr, err
 }
 
 // searchResultsToRepoNodes converts a set of search results into repository nodes
-// such that they can be used to replace a repository predicate
+// such that they can be used to replace a repository predicate. File matches
+// (e.g. from symbol predicates) are converted to the repositories containing them.
 func searchResultsToRepoNodes(matches []result.Match) ([]query.Node, error) {
 	nodes := make([]query.Node, 0, len(matches))
+	seen := make(map[api.RepoName]struct{}, len(matches))
 	for _, match := range matches {
-		repoMatch, ok := match.(*result.RepoMatch)
-		if !ok {
+		var name api.RepoName
+		switch v := match.(type) {
+		case *result.RepoMatch:
+			name = v.Name
+		case *result.FileMatch:
+			name = v.Repo.Name
+		default:
 			return nil, errors.Errorf("expected type %T, but got %T", &result.RepoMatch{}, match)
 		}
 
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
+
 		nodes = append(nodes, query.Parameter{
 			Field: query.FieldRepo,
-			Value: "^" + regexp.QuoteMeta(string(repoMatch.Name)) + "$",
+			Value: "^" + regexp.QuoteMeta(string(name)) + "$",
 		})
 	}
 
@@ -1248,6 +1299,45 @@ func substitutePredicates(q query.Basic, evaluate func(query.Predicate) (*Search
 
 var ErrPredicateNoResults = errors.New("no results returned for predicate")
 
+// filterByOwner drops matches in files not owned by the owner specified in a
+// `file:has.owner()` predicate, as resolved from the repository's CODEOWNERS
+// file at the searched revision.
+func (r *searchResolver) filterByOwner(ctx context.Context, q query.Basic, matches []result.Match) []result.Match {
+	owner, _ := q.ToParseTree().StringValue(query.FieldFileHasOwner)
+	if owner == "" {
+		return matches
+	}
+	return r.owners.Filter(ctx, matches, owner)
+}
+
+// annotateOwners populates the owners of file matches for `select:file.owners`.
+func (r *searchResolver) annotateOwners(ctx context.Context, q query.Basic, matches []result.Match) {
+	sp, _ := q.ToParseTree().StringValue(query.FieldSelect)
+	if sp == "" {
+		return
+	}
+	selectPath, _ := filter.SelectPathFromString(sp) // Invariant: error already checked
+	if !isSelectOwners(selectPath) {
+		return
+	}
+	r.owners.Annotate(ctx, matches)
+}
+
+// alertForOwners adds an alert to srr if the owners of some files could not be
+// resolved, since matches in those files are missing from the results.
+func (r *searchResolver) alertForOwners(srr *SearchResultsResolver) {
+	if r.owners == nil {
+		return
+	}
+	if err := r.owners.Err(); err != nil {
+		srr.SearchResults.Alert = maxAlertByPriority(srr.SearchResults.Alert, alertForOwnersNotResolved(err))
+	}
+}
+
+func isSelectOwners(sp filter.SelectPath) bool {
+	return len(sp) > 1 && sp.Root() == filter.File && sp[1] == "owners"
+}
+
 // longer returns a suggested longer time to wait if the given duration wasn't long enough.
 func longer(n int, dt time.Duration) time.Duration {
 	dt2 := func() time.Duration {
@@ -1653,7 +1743,7 @@ func (r *searchResolver) toSearchResults(ctx context.Context, agg *run.Aggregato
 	}
 	alert, err := ao.Done(&common)
 
-	r.sortResults(matches)
+	r.sortResults(ctx, matches)
 
 	return &SearchResults{
 		Matches: matches,
@@ -1750,7 +1840,12 @@ func compareSearchResults(left, right result.Match, exactFilePatterns map[string
 	return arepo < brepo
 }
 
-func (r *searchResolver) sortResults(results []result.Match) {
+func (r *searchResolver) sortResults(ctx context.Context, results []result.Match) {
+	if order := r.Query.Order(); order != query.OrderPath {
+		ranking.NewRanker(ranking.NewSource(r.db)).Rank(ctx, results, order)
+		return
+	}
+
 	var exactPatterns map[string]struct{}
 	if getBoolPtr(r.UserSettings.SearchGlobbing, false) {
 		exactPatterns = r.getExactFilePatterns()
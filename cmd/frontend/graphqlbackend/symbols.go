@@ -124,6 +124,14 @@ func (r symbolResolver) Location() *locationResolver {
 	}
 }
 
+func (r symbolResolver) DefinitionRange() *rangeResolver {
+	dr, ok := r.Symbol.DefinitionRange()
+	if !ok {
+		return nil
+	}
+	return &rangeResolver{lspRange: dr}
+}
+
 func (r symbolResolver) URL(ctx context.Context) (string, error) { return r.Location().URL(ctx) }
 
 func (r symbolResolver) CanonicalURL() string { return r.Location().CanonicalURL() }
@@ -28,6 +28,7 @@ func newTest() *httptestutil.Client {
 		enterpriseServices.GitHubWebhook,
 		enterpriseServices.GitLabWebhook,
 		enterpriseServices.BitbucketServerWebhook,
+		enterpriseServices.SearchExportHandler,
 		enterpriseServices.NewCodeIntelUploadHandler,
 		rateLimiter,
 	))
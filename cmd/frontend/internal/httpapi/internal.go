@@ -212,15 +212,28 @@ func serveSearchConfiguration(w http.ResponseWriter, r *http.Request) error {
 			return string(commitID), err
 		}
 
+		listBranches := func() ([]string, error) {
+			branches, err := git.ListBranches(ctx, repo.Name, git.BranchesOptions{})
+			if err != nil {
+				return nil, err
+			}
+			names := make([]string, 0, len(branches))
+			for _, b := range branches {
+				names = append(names, b.Name)
+			}
+			return names, nil
+		}
+
 		priority := float64(repo.Stars) + repoRankFromConfig(siteConfig, repoName)
 
 		return &searchbackend.RepoIndexOptions{
-			RepoID:     int32(repo.ID),
-			Public:     !repo.Private,
-			Priority:   priority,
-			Fork:       repo.Fork,
-			Archived:   repo.Archived,
-			GetVersion: getVersion,
+			RepoID:       int32(repo.ID),
+			Public:       !repo.Private,
+			Priority:     priority,
+			Fork:         repo.Fork,
+			Archived:     repo.Archived,
+			GetVersion:   getVersion,
+			ListBranches: listBranches,
 		}, nil
 	}
 
@@ -0,0 +1,302 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/gomodule/redigo/redis"
+	"github.com/google/uuid"
+	"github.com/inconshreveable/log15"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/internal/actor"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
+	"github.com/sourcegraph/sourcegraph/internal/redispool"
+	searchshared "github.com/sourcegraph/sourcegraph/internal/search"
+	"github.com/sourcegraph/sourcegraph/internal/search/query"
+	streamhttp "github.com/sourcegraph/sourcegraph/internal/search/streaming/http"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+// maxQueueWait is how long an expensive search waits for the other
+// expensive searches of the user to finish before we give up.
+const maxQueueWait = time.Minute
+
+// queryCostError is returned for searches that don't run because of their
+// estimated cost.
+type queryCostError struct {
+	Cost  searchshared.QueryCost
+	Limit int
+
+	// Confirm is true if the search runs once the user confirms it.
+	Confirm bool
+}
+
+func (e *queryCostError) Error() string {
+	if e.Confirm {
+		return fmt.Sprintf("search requires confirmation: estimated cost %d exceeds %d", e.Cost, e.Limit)
+	}
+	return fmt.Sprintf("search rejected: estimated cost %d exceeds %d", e.Cost, e.Limit)
+}
+
+func (e *queryCostError) alert() streamhttp.EventAlert {
+	if e.Confirm {
+		return streamhttp.EventAlert{
+			Title:       "Confirm expensive search",
+			Description: fmt.Sprintf("This search is estimated to cost %d, which is more than the %d you can run without confirming. Narrow it down with `repo:`, `file:` or `after:` filters, or run it again with the `confirm=true` parameter to confirm it.", e.Cost, e.Limit),
+		}
+	}
+	return streamhttp.EventAlert{
+		Title:       "Search too expensive",
+		Description: fmt.Sprintf("This search is estimated to cost %d, which is more than the limit of %d. Narrow it down with `repo:`, `file:` or `after:` filters.", e.Cost, e.Limit),
+	}
+}
+
+var metricAdmission = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "src_search_admission_total",
+	Help: "Total number of streaming searches by admission decision based on their estimated cost.",
+}, []string{"decision"})
+
+// admissionController decides whether searches run based on their estimated
+// cost and the search.limits.queryCost site configuration. It queues the
+// expensive searches of each user, so that one user can't starve the search
+// backends.
+type admissionController struct {
+	// isSiteAdmin returns true if the current user is a site admin.
+	isSiteAdmin func(ctx context.Context) bool
+
+	// running counts the running expensive searches of each user across all
+	// frontends.
+	running expensiveSearches
+
+	// pollInterval is how often a queued search checks whether it can run.
+	pollInterval time.Duration
+}
+
+func newAdmissionController(db dbutil.DB) *admissionController {
+	return &admissionController{
+		isSiteAdmin: func(ctx context.Context) bool {
+			return backend.CheckCurrentUserIsSiteAdmin(ctx, db) == nil
+		},
+		running:      &redisExpensiveSearches{pool: redispool.Store},
+		pollInterval: time.Second,
+	}
+}
+
+// admit returns an error if search may not run. Expensive searches block
+// until user has fewer than the maximum number of expensive searches
+// running, and call queued once if they have to wait. The returned release
+// func must be called once the search is done.
+func (a *admissionController) admit(ctx context.Context, search searchResolver, user string, confirmed bool, queued func()) (release func(), err error) {
+	release = func() {}
+	if a == nil {
+		return release, nil
+	}
+	limits := searchshared.SearchLimits(conf.Get()).QueryCost
+	if limits == nil || limits.Queue <= 0 && limits.Confirm <= 0 && limits.Reject <= 0 {
+		return release, nil
+	}
+
+	inputs := search.Inputs()
+	cost, err := estimate(ctx, search, inputs.Plan)
+	if err != nil {
+		// Don't fail searches because we can't estimate their cost.
+		log15.Warn("search: failed to estimate query cost", "query", inputs.OriginalQuery, "error", err)
+		metricAdmission.WithLabelValues("unknown").Inc()
+		return release, nil
+	}
+
+	isSiteAdmin := func() bool { return a.isSiteAdmin(ctx) }
+	if err := checkQueryCost(limits, cost, confirmed, isSiteAdmin); err != nil {
+		metricAdmission.WithLabelValues("rejected").Inc()
+		return nil, err
+	}
+
+	if limits.Queue <= 0 || int(cost) <= limits.Queue {
+		metricAdmission.WithLabelValues("admitted").Inc()
+		return release, nil
+	}
+	metricAdmission.WithLabelValues("queued").Inc()
+	return a.acquire(ctx, user, limits.MaxConcurrentExpensive, queued)
+}
+
+// checkQueryCost returns a *queryCostError if a search of the given cost
+// must be confirmed or may not run at all.
+func checkQueryCost(limits *schema.QueryCostLimits, cost searchshared.QueryCost, confirmed bool, isSiteAdmin func() bool) error {
+	if limits.Reject > 0 && int(cost) > limits.Reject {
+		// Site admins know best, but we still ask them to confirm.
+		if !isSiteAdmin() {
+			return &queryCostError{Cost: cost, Limit: limits.Reject}
+		}
+		if !confirmed {
+			return &queryCostError{Cost: cost, Limit: limits.Reject, Confirm: true}
+		}
+		return nil
+	}
+	if limits.Confirm > 0 && int(cost) > limits.Confirm && !confirmed {
+		return &queryCostError{Cost: cost, Limit: limits.Confirm, Confirm: true}
+	}
+	return nil
+}
+
+// estimate returns the cost of all queries in plan. It counts the
+// repositories of each query like search resolves them, so that filters
+// like context:, fork: and archived: are taken into account.
+func estimate(ctx context.Context, search searchResolver, plan query.Plan) (searchshared.QueryCost, error) {
+	var cost searchshared.QueryCost
+	for _, b := range plan {
+		repoCount, err := search.CountRepos(ctx, b)
+		if err != nil {
+			return 0, err
+		}
+		cost += searchshared.EstimateQueryCost(b, repoCount)
+	}
+	return cost, nil
+}
+
+// acquire waits until user runs fewer than max expensive searches, and then
+// counts ctx's search as one of them until release is called.
+func (a *admissionController) acquire(ctx context.Context, user string, max int, queued func()) (release func(), err error) {
+	ctx, cancel := context.WithTimeout(ctx, maxQueueWait)
+	defer cancel()
+
+	ticker := time.NewTicker(a.pollInterval)
+	defer ticker.Stop()
+
+	for first := true; ; first = false {
+		release, ok, err := a.running.tryAcquire(user, max)
+		if err != nil {
+			// Don't fail searches because we can't count expensive searches.
+			log15.Warn("search: failed to count expensive searches", "user", user, "error", err)
+			return func() {}, nil
+		}
+		if ok {
+			return release, nil
+		}
+		if first && queued != nil {
+			queued()
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return nil, errors.Errorf("search rejected: you are running %d expensive searches already, try again once they finish", max)
+			}
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// expensiveSearches counts the running expensive searches of each user.
+type expensiveSearches interface {
+	// tryAcquire counts a search of user as running if user runs fewer than
+	// max searches. It returns false if user runs max searches already.
+	tryAcquire(user string, max int) (release func(), ok bool, err error)
+}
+
+// expensiveSearchLease is how long an expensive search counts as running
+// unless its frontend renews the lease. It makes sure the searches of
+// frontends which went away stop counting.
+const expensiveSearchLease = time.Minute
+
+// acquireScript adds ARGV[4] to the sorted set KEYS[1] of running searches
+// with its lease expiring at ARGV[3], unless the set has ARGV[2] searches
+// with leases expiring after ARGV[1] already. The set expires after ARGV[5]
+// seconds.
+var acquireScript = redis.NewScript(1, `
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", ARGV[1])
+if redis.call("ZCARD", KEYS[1]) >= tonumber(ARGV[2]) then
+	return 0
+end
+redis.call("ZADD", KEYS[1], ARGV[3], ARGV[4])
+redis.call("EXPIRE", KEYS[1], ARGV[5])
+return 1
+`)
+
+// redisExpensiveSearches counts expensive searches in redis, so that the
+// limits apply across all frontend replicas. The searches of a user are a
+// sorted set scored by the time their lease expires.
+type redisExpensiveSearches struct {
+	pool *redis.Pool
+}
+
+func (s *redisExpensiveSearches) tryAcquire(user string, max int) (release func(), ok bool, err error) {
+	key := "search:admission:" + user
+	id := uuid.New().String()
+
+	c := s.pool.Get()
+	defer c.Close()
+
+	now := time.Now()
+	ok, err = redis.Bool(acquireScript.Do(c,
+		key,
+		now.UnixNano()/int64(time.Millisecond),
+		max,
+		now.Add(expensiveSearchLease).UnixNano()/int64(time.Millisecond),
+		id,
+		int(expensiveSearchLease.Seconds()),
+	))
+	if err != nil || !ok {
+		return nil, false, err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(expensiveSearchLease / 2)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				s.do("ZADD", key, "XX", now.Add(expensiveSearchLease).UnixNano()/int64(time.Millisecond), id)
+				s.do("EXPIRE", key, int(expensiveSearchLease.Seconds()))
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			s.do("ZREM", key, id)
+		})
+	}, true, nil
+}
+
+// do runs a redis command. The leases of running searches expire, so we only
+// log errors.
+func (s *redisExpensiveSearches) do(cmd string, args ...interface{}) {
+	c := s.pool.Get()
+	defer c.Close()
+
+	if _, err := c.Do(cmd, args...); err != nil {
+		log15.Warn("search: failed to execute redis command", "cmd", cmd, "error", err)
+	}
+}
+
+// admissionUser returns the key the admission controller counts the
+// expensive searches of the user of r by. Anonymous users are counted by IP
+// address rather than all together, and not by their anonymous user ID
+// cookie, since they can drop it. For the same reason the address is taken
+// from the connection and not from the X-Forwarded-For header, which clients
+// can set to anything.
+func admissionUser(r *http.Request) string {
+	if a := actor.FromContext(r.Context()); a.IsAuthenticated() {
+		return "user:" + a.UIDString()
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return "ip:" + host
+	}
+	return "ip:" + r.RemoteAddr
+}
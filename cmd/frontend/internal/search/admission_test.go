@@ -0,0 +1,184 @@
+package search
+
+import (
+	"context"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/sourcegraph/sourcegraph/internal/actor"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	searchshared "github.com/sourcegraph/sourcegraph/internal/search"
+	"github.com/sourcegraph/sourcegraph/internal/search/query"
+	"github.com/sourcegraph/sourcegraph/internal/search/run"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+func TestCheckQueryCost(t *testing.T) {
+	limits := &schema.QueryCostLimits{Confirm: 100, Reject: 1000}
+
+	cases := []struct {
+		name        string
+		cost        int
+		confirmed   bool
+		siteAdmin   bool
+		wantErr     bool
+		wantConfirm bool
+	}{
+		{name: "cheap", cost: 100},
+		{name: "expensive", cost: 101, wantErr: true, wantConfirm: true},
+		{name: "expensive confirmed", cost: 101, confirmed: true},
+		{name: "too expensive", cost: 1001, confirmed: true, wantErr: true},
+		{name: "too expensive site admin", cost: 1001, siteAdmin: true, wantErr: true, wantConfirm: true},
+		{name: "too expensive site admin confirmed", cost: 1001, siteAdmin: true, confirmed: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkQueryCost(limits, searchshared.QueryCost(tc.cost), tc.confirmed, func() bool { return tc.siteAdmin })
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tc.wantErr)
+			}
+			var costErr *queryCostError
+			if err != nil && (!errors.As(err, &costErr) || costErr.Confirm != tc.wantConfirm) {
+				t.Fatalf("got error %v, want confirm %v", err, tc.wantConfirm)
+			}
+		})
+	}
+}
+
+func TestAdmissionController(t *testing.T) {
+	conf.Mock(&conf.Unified{SiteConfiguration: schema.SiteConfiguration{
+		SearchLimits: &schema.SearchLimits{
+			QueryCost: &schema.QueryCostLimits{Queue: 100, Reject: 1000},
+		},
+	}})
+	defer conf.Mock(nil)
+
+	a := &admissionController{
+		isSiteAdmin:  func(context.Context) bool { return false },
+		running:      &memoryExpensiveSearches{running: map[string]int{}},
+		pollInterval: time.Millisecond,
+	}
+
+	search := func(q string) searchResolver {
+		t.Helper()
+		plan, err := query.Pipeline(query.Init(q, query.SearchTypeLiteral))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &mockSearchResolver{
+			inputs: &run.SearchInputs{Plan: plan, OriginalQuery: q},
+			repoCount: func(b query.Basic) int {
+				if b.FindValue(query.FieldRepo) == "all" {
+					return 10000
+				}
+				return 50
+			},
+		}
+	}
+
+	ctx := context.Background()
+	notQueued := func() { t.Error("expected search not to be queued") }
+
+	// Rejected searches don't run at all.
+	if _, err := a.admit(ctx, search("repo:all foobar"), "alice", false, notQueued); err == nil {
+		t.Fatal("expected search to be rejected")
+	}
+
+	// Cheap searches don't count towards the limit of expensive searches.
+	release, err := a.admit(ctx, search("repo:some foobar count:10"), "alice", false, notQueued)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+
+	// Alice can only run one expensive search at a time, but that doesn't
+	// affect Bob.
+	releaseAlice, err := a.admit(ctx, search("repo:some foobar count:5000"), "alice", false, notQueued)
+	if err != nil {
+		t.Fatal(err)
+	}
+	releaseBob, err := a.admit(ctx, search("repo:some foobar count:5000"), "bob", false, notQueued)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer releaseBob()
+
+	queued := make(chan struct{})
+	admitted := make(chan func())
+	go func() {
+		release, err := a.admit(ctx, search("repo:some foobar count:5000"), "alice", false, func() { close(queued) })
+		if err != nil {
+			t.Error(err)
+		}
+		admitted <- release
+	}()
+
+	select {
+	case <-queued:
+	case <-admitted:
+		t.Fatal("expected second expensive search of alice to be queued")
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected second expensive search of alice to report it is queued")
+	}
+
+	releaseAlice()
+	select {
+	case release := <-admitted:
+		if release != nil {
+			release()
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected queued search to be admitted")
+	}
+}
+
+func TestAdmissionUser(t *testing.T) {
+	cases := []struct {
+		name  string
+		actor *actor.Actor
+		xff   string
+		want  string
+	}{
+		{name: "user", actor: actor.FromUser(1), xff: "10.0.0.1", want: "user:1"},
+		{name: "anonymous", actor: &actor.Actor{}, want: "ip:192.0.2.1"},
+		{name: "anonymous with spoofed header", actor: &actor.Actor{}, xff: "10.0.0.1", want: "ip:192.0.2.1"},
+		{name: "anonymous with spoofed proxy chain", actor: &actor.Actor{}, xff: "10.0.0.1, 10.0.0.2", want: "ip:192.0.2.1"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/search/stream", nil)
+			r = r.WithContext(actor.WithActor(r.Context(), tc.actor))
+			if tc.xff != "" {
+				r.Header.Set("X-Forwarded-For", tc.xff)
+			}
+			if got := admissionUser(r); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+// memoryExpensiveSearches counts expensive searches in memory.
+type memoryExpensiveSearches struct {
+	mu      sync.Mutex
+	running map[string]int
+}
+
+func (s *memoryExpensiveSearches) tryAcquire(user string, max int) (func(), bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running[user] >= max {
+		return nil, false, nil
+	}
+	s.running[user]++
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.running[user]--
+	}, true, nil
+}
@@ -0,0 +1,213 @@
+package search
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+
+	"github.com/inconshreveable/log15"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend"
+	"github.com/sourcegraph/sourcegraph/internal/compute"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
+	"github.com/sourcegraph/sourcegraph/internal/search/result"
+	"github.com/sourcegraph/sourcegraph/internal/trace"
+)
+
+// ComputeStreamHandler is an http handler which streams back the results of a
+// compute query as CSV or JSON lines, for exporting them to other tools.
+func ComputeStreamHandler(db dbutil.DB) http.Handler {
+	return &computeStreamHandler{
+		db:                db,
+		newSearchResolver: defaultNewSearchResolver,
+	}
+}
+
+type computeStreamHandler struct {
+	db                dbutil.DB
+	newSearchResolver func(context.Context, dbutil.DB, *graphqlbackend.SearchArgs) (searchResolver, error)
+}
+
+func (h *computeStreamHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	q := r.URL.Query().Get("q")
+	if q == "" {
+		http.Error(w, "no query found", http.StatusBadRequest)
+		return
+	}
+	computeQuery, err := compute.Parse(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var out computeWriter
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "jsonl":
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		out = &jsonLinesWriter{enc: json.NewEncoder(w)}
+	case "csv":
+		output, ok := computeQuery.Command.(*compute.Output)
+		if !ok {
+			http.Error(w, "CSV format requires a compute:output(...) parameter", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "text/csv")
+		out = &csvWriter{w: csv.NewWriter(w), header: output.Fields}
+	default:
+		http.Error(w, "format must be csv or jsonl, got "+format, http.StatusBadRequest)
+		return
+	}
+
+	tr, ctx := trace.New(ctx, "search.ServeComputeStream", q)
+	defer func() {
+		tr.SetError(err)
+		tr.Finish()
+	}()
+
+	flush := func() {
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+	}
+
+	// Exports are over all results, unless the query limits them.
+	searchQuery := computeQuery.ToSearchQuery()
+	if !countFieldRegexp.MatchString(searchQuery) {
+		searchQuery += " count:all"
+	}
+
+	events, _, results := (&streamHandler{db: h.db, newSearchResolver: h.newSearchResolver}).startSearch(ctx, &args{
+		Query:       searchQuery,
+		Version:     "V2",
+		PatternType: "regexp",
+	}, "", nil)
+
+	// Send headers right away, since the first result may take a while.
+	w.WriteHeader(http.StatusOK)
+	flush()
+
+	var writeErr error
+	for event := range events {
+		if writeErr != nil {
+			// The client went away. Drain events until the search stops.
+			continue
+		}
+		repoMetadata, err := getEventRepoMetadata(ctx, h.db, event)
+		if err != nil {
+			log15.Error("failed to get repo metadata", "error", err)
+			continue
+		}
+		for _, match := range event.Results {
+			fm, ok := match.(*result.FileMatch)
+			if !ok {
+				continue
+			}
+			// Like streaming search, skip matches in repos the actor
+			// cannot access.
+			if md, ok := repoMetadata[fm.Repo.ID]; !ok || md.Name != fm.Repo.Name {
+				continue
+			}
+			res, err := computeQuery.Command.Run(ctx, fm)
+			if err != nil {
+				log15.Warn("compute: skipping file", "repo", fm.Repo.Name, "path", fm.Path, "error", err)
+				continue
+			}
+			if res == nil {
+				continue
+			}
+			if writeErr = out.Write(fm, res); writeErr != nil {
+				cancel()
+				break
+			}
+		}
+		flush()
+	}
+
+	if writeErr != nil {
+		return
+	}
+	if _, err = results(); err != nil {
+		log15.Error("compute: search failed", "error", err)
+	}
+	_ = out.Close()
+	flush()
+}
+
+// computeWriter writes the results of a compute query.
+type computeWriter interface {
+	Write(*result.FileMatch, compute.Result) error
+	Close() error
+}
+
+// jsonLinesWriter writes a JSON object for each row of a *compute.Table, and
+// a computeEvent for any other result.
+type jsonLinesWriter struct {
+	enc *json.Encoder
+}
+
+// computeEvent is a compute result with the file it was computed from.
+type computeEvent struct {
+	Repository string         `json:"repository"`
+	Commit     string         `json:"commit"`
+	Path       string         `json:"path"`
+	Result     compute.Result `json:"result"`
+}
+
+func (w *jsonLinesWriter) Write(fm *result.FileMatch, res compute.Result) error {
+	table, ok := res.(*compute.Table)
+	if !ok {
+		return w.enc.Encode(computeEvent{
+			Repository: string(fm.Repo.Name),
+			Commit:     string(fm.CommitID),
+			Path:       fm.Path,
+			Result:     res,
+		})
+	}
+	for _, row := range table.Rows {
+		object := make(map[string]string, len(row))
+		for i, column := range table.Columns {
+			object[column] = row[i]
+		}
+		if err := w.enc.Encode(object); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *jsonLinesWriter) Close() error { return nil }
+
+// csvWriter writes the fields of a compute:output(...) parameter as a header,
+// followed by the rows of each *compute.Table.
+type csvWriter struct {
+	w      *csv.Writer
+	header []string
+}
+
+func (w *csvWriter) Write(_ *result.FileMatch, res compute.Result) error {
+	table, ok := res.(*compute.Table)
+	if !ok {
+		return nil
+	}
+	if w.header != nil {
+		if err := w.w.Write(w.header); err != nil {
+			return err
+		}
+		w.header = nil
+	}
+	return w.w.WriteAll(table.Rows)
+}
+
+func (w *csvWriter) Close() error {
+	if w.header != nil {
+		if err := w.w.Write(w.header); err != nil {
+			return err
+		}
+	}
+	w.w.Flush()
+	return w.w.Error()
+}
@@ -0,0 +1,114 @@
+package search
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend"
+	api2 "github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/database"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
+	"github.com/sourcegraph/sourcegraph/internal/search/result"
+	"github.com/sourcegraph/sourcegraph/internal/search/streaming"
+	"github.com/sourcegraph/sourcegraph/internal/types"
+)
+
+func TestServeComputeStream(t *testing.T) {
+	database.Mocks.Repos.Metadata = func(ctx context.Context, ids ...api2.RepoID) ([]*types.SearchedRepo, error) {
+		res := make([]*types.SearchedRepo, 0, len(ids))
+		for _, id := range ids {
+			res = append(res, &types.SearchedRepo{ID: id, Name: "github.com/foo/bar"})
+		}
+		return res, nil
+	}
+	defer func() { database.Mocks.Repos.Metadata = nil }()
+
+	fileMatch := &result.FileMatch{
+		File: result.File{
+			Repo: types.RepoName{ID: 1, Name: "github.com/foo/bar"},
+			Path: "go.mod",
+		},
+		LineMatches: []*result.LineMatch{
+			{Preview: "\tgithub.com/a/b v1.0.0", LineNumber: 2},
+			{Preview: "\tgithub.com/c/d v0.2.0", LineNumber: 3},
+		},
+	}
+
+	cases := []struct {
+		name   string
+		format string
+		want   string
+	}{{
+		name:   "csv",
+		format: "csv",
+		want:   "repo,$1,line\ngithub.com/foo/bar,github.com/a/b,3\ngithub.com/foo/bar,github.com/c/d,4\n",
+	}, {
+		name:   "json lines",
+		format: "jsonl",
+		want: `{"$1":"github.com/a/b","line":"3","repo":"github.com/foo/bar"}
+{"$1":"github.com/c/d","line":"4","repo":"github.com/foo/bar"}
+`,
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mock := &mockSearchResolver{done: make(chan struct{})}
+			var gotQuery string
+			ts := httptest.NewServer(&computeStreamHandler{
+				newSearchResolver: func(_ context.Context, _ dbutil.DB, args *graphqlbackend.SearchArgs) (searchResolver, error) {
+					gotQuery = args.Query
+					mock.c = args.Stream
+					return mock, nil
+				},
+			})
+			defer ts.Close()
+
+			q := url.Values{
+				"q":      []string{`compute:output(repo, $1, line) file:go.mod (\S+) v`},
+				"format": []string{c.format},
+			}
+			res, err := http.Get(ts.URL + "?" + q.Encode())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+
+			mock.c.Send(streaming.SearchEvent{Results: []result.Match{fileMatch}})
+			mock.Close()
+
+			b, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.StatusCode != http.StatusOK {
+				t.Fatalf("expected status 200, got %d: %s", res.StatusCode, b)
+			}
+			if diff := cmp.Diff(c.want, string(b)); diff != "" {
+				t.Errorf("unexpected output (-want +got):\n%s", diff)
+			}
+			if want := `file:go.mod (\S+) v count:all`; gotQuery != want {
+				t.Errorf("got search query %q, want %q", gotQuery, want)
+			}
+		})
+	}
+}
+
+func TestServeComputeStream_csvRequiresOutput(t *testing.T) {
+	ts := httptest.NewServer(ComputeStreamHandler(nil))
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "?" + url.Values{"q": []string{"foo"}, "format": []string{"csv"}}.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", res.StatusCode)
+	}
+}
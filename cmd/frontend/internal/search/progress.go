@@ -58,6 +58,12 @@ func (p *progressAggregator) currentStats() api.ProgressStats {
 	}
 }
 
+// Complete returns true if no results are missing, because no limit was hit
+// and no repository timed out.
+func (p *progressAggregator) Complete() bool {
+	return !p.Stats.IsLimitHit && !p.Stats.Status.Any(searchshared.RepoStatusTimedout)
+}
+
 // Current returns the current progress event.
 func (p *progressAggregator) Current() api.Progress {
 	p.Dirty = false
@@ -65,6 +71,12 @@ func (p *progressAggregator) Current() api.Progress {
 	return api.BuildProgressEvent(p.currentStats())
 }
 
+// queuedProgress returns the progress event of a search which waits for
+// other expensive searches to finish before it starts.
+func queuedProgress() api.Progress {
+	return api.BuildProgressEvent(api.ProgressStats{Queued: true})
+}
+
 // Final returns the current progress event, but with final fields set to
 // indicate it is the last progress event.
 func (p *progressAggregator) Final() api.Progress {
@@ -25,6 +25,8 @@ import (
 	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
 	"github.com/sourcegraph/sourcegraph/internal/honey"
 	"github.com/sourcegraph/sourcegraph/internal/lazyregexp"
+	"github.com/sourcegraph/sourcegraph/internal/search/query"
+	"github.com/sourcegraph/sourcegraph/internal/search/ranking"
 	"github.com/sourcegraph/sourcegraph/internal/search/result"
 	"github.com/sourcegraph/sourcegraph/internal/search/run"
 	"github.com/sourcegraph/sourcegraph/internal/search/streaming"
@@ -38,6 +40,7 @@ func StreamHandler(db dbutil.DB) http.Handler {
 	return &streamHandler{
 		db:                  db,
 		newSearchResolver:   defaultNewSearchResolver,
+		admission:           newAdmissionController(db),
 		flushTickerInternal: 100 * time.Millisecond,
 		pingTickerInterval:  5 * time.Second,
 	}
@@ -46,6 +49,7 @@ func StreamHandler(db dbutil.DB) http.Handler {
 type streamHandler struct {
 	db                  dbutil.DB
 	newSearchResolver   func(context.Context, dbutil.DB, *graphqlbackend.SearchArgs) (searchResolver, error)
+	admission           *admissionController
 	flushTickerInternal time.Duration
 	pingTickerInterval  time.Duration
 }
@@ -60,6 +64,18 @@ func (h *streamHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var aggregate *streaming.Aggregate
+	if args.Aggregate != "" {
+		mode, err := streaming.ParseAggregateMode(args.Aggregate)
+		if err == nil {
+			aggregate, err = streaming.NewAggregate(mode, args.AggregatePattern)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
 	tr, ctx := trace.New(ctx, "search.ServeStream", args.Query,
 		trace.Tag{Key: "version", Value: args.Version},
 		trace.Tag{Key: "pattern_type", Value: args.PatternType},
@@ -83,7 +99,16 @@ func (h *streamHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// Log events to trace
 	eventWriter.StatHook = eventStreamOTHook(tr.LogFields)
 
-	events, inputs, results := h.startSearch(ctx, args)
+	// Tell the client if the search has to wait for other expensive searches
+	// to finish. The progress events sent once it runs clear the notice.
+	queued := func() {
+		_ = eventWriter.Event("progress", queuedProgress())
+	}
+
+	events, inputs, results := h.startSearch(ctx, args, admissionUser(r), queued)
+	if order := inputs.Query.Order(); order != query.OrderPath {
+		events = rankEvents(ctx, events, ranking.NewRanker(ranking.NewSource(h.db)), order)
+	}
 	events = batchEvents(events, 50*time.Millisecond)
 
 	// Display is the number of results we send down. If display is < 0 we
@@ -142,6 +167,9 @@ func (h *streamHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	handleEvent := func(event streaming.SearchEvent) {
 		progress.Update(event)
 		filters.Update(event)
+		if aggregate != nil {
+			aggregate.Update(event)
+		}
 
 		// Truncate the event to the match limit before fetching repo metadata
 		for i, match := range event.Results {
@@ -219,10 +247,35 @@ LOOP:
 
 	resultsResolver, err := results()
 	if err != nil {
+		var costErr *queryCostError
+		if errors.As(err, &costErr) {
+			_ = eventWriter.Event("alert", costErr.alert())
+			return
+		}
 		_ = eventWriter.Event("error", streamhttp.EventError{Message: err.Error()})
 		return
 	}
 
+	// Send the aggregation once, since it is only exact over the full result
+	// set. If some results are missing because a limit was hit or repositories
+	// timed out, the counts would be wrong so we don't send it. The final
+	// progress event tells the client why.
+	if aggregate != nil && progress.Complete() {
+		groups := aggregate.Compute()
+		buf := make([]streamhttp.EventAggregate, 0, len(groups))
+		for _, g := range groups {
+			buf = append(buf, streamhttp.EventAggregate{
+				Label: g.Label,
+				Count: g.Count,
+			})
+		}
+
+		if err := eventWriter.Event("aggregate", buf); err != nil {
+			// EOF
+			return
+		}
+	}
+
 	alert := resultsResolver.Alert()
 	if alert != nil {
 		var pqs []streamhttp.ProposedQuery
@@ -274,8 +327,9 @@ LOOP:
 
 // startSearch will start a search. It returns the events channel which
 // streams out search events. Once events is closed you can call results which
-// will return the results resolver and error.
-func (h *streamHandler) startSearch(ctx context.Context, a *args) (events <-chan streaming.SearchEvent, inputs run.SearchInputs, results func() (*graphqlbackend.SearchResultsResolver, error)) {
+// will return the results resolver and error. If the search of user is
+// expensive and has to wait for others to finish, queued is called.
+func (h *streamHandler) startSearch(ctx context.Context, a *args, user string, queued func()) (events <-chan streaming.SearchEvent, inputs run.SearchInputs, results func() (*graphqlbackend.SearchResultsResolver, error)) {
 	eventsC := make(chan streaming.SearchEvent)
 
 	search, err := h.newSearchResolver(ctx, h.db, &graphqlbackend.SearchArgs{
@@ -295,12 +349,23 @@ func (h *streamHandler) startSearch(ctx context.Context, a *args) (events <-chan
 		}
 	}
 
+	// Expensive searches may have to wait for others to finish, or not run
+	// at all.
+	release, err := h.admission.admit(ctx, search, user, a.Confirmed, queued)
+	if err != nil {
+		close(eventsC)
+		return eventsC, search.Inputs(), func() (*graphqlbackend.SearchResultsResolver, error) {
+			return nil, err
+		}
+	}
+
 	type finalResult struct {
 		resultsResolver *graphqlbackend.SearchResultsResolver
 		err             error
 	}
 	final := make(chan finalResult, 1)
 	go func() {
+		defer release()
 		defer close(final)
 		defer close(eventsC)
 
@@ -317,6 +382,7 @@ func (h *streamHandler) startSearch(ctx context.Context, a *args) (events <-chan
 type searchResolver interface {
 	Results(context.Context) (*graphqlbackend.SearchResultsResolver, error)
 	Inputs() run.SearchInputs
+	CountRepos(context.Context, query.Basic) (int, error)
 }
 
 func defaultNewSearchResolver(ctx context.Context, db dbutil.DB, args *graphqlbackend.SearchArgs) (searchResolver, error) {
@@ -329,8 +395,18 @@ type args struct {
 	PatternType    string
 	VersionContext string
 	Display        int
+
+	// Confirmed is true if the user confirmed running an expensive search.
+	Confirmed bool
+
+	// Aggregate if non-empty is the AggregateMode to group results by.
+	Aggregate        string
+	AggregatePattern string
 }
 
+// countFieldRegexp matches a count: field in a query.
+var countFieldRegexp = lazyregexp.New(`(?i)(^|[\s(])count:`)
+
 func parseURLQuery(q url.Values) (*args, error) {
 	get := func(k, def string) string {
 		v := q.Get(k)
@@ -357,6 +433,24 @@ func parseURLQuery(q url.Values) (*args, error) {
 		return nil, errors.Errorf("display must be an integer, got %q: %w", display, err)
 	}
 
+	confirm := get("confirm", "false")
+	if a.Confirmed, err = strconv.ParseBool(confirm); err != nil {
+		return nil, errors.Errorf("confirm must be a boolean, got %q: %w", confirm, err)
+	}
+
+	a.Aggregate = get("aggregate", "")
+	a.AggregatePattern = get("aggregate-pattern", "")
+	if a.Aggregate != "" {
+		// An aggregation is computed over the full result set and replaces
+		// individual matches, unless the caller explicitly asks for them.
+		if q.Get("display") == "" {
+			a.Display = 0
+		}
+		if !countFieldRegexp.MatchString(a.Query) {
+			a.Query += " count:all"
+		}
+	}
+
 	return &a, nil
 }
 
@@ -403,6 +497,7 @@ func fromPathMatch(fm *result.FileMatch, repoCache map[api.RepoID]*types.Searche
 		Repository:   string(fm.Repo.Name),
 		RepositoryID: int32(fm.Repo.ID),
 		Version:      string(fm.CommitID),
+		Owners:       fm.Owners,
 	}
 
 	if r, ok := repoCache[fm.Repo.ID]; ok {
@@ -636,6 +731,49 @@ func batchEvents(source <-chan streaming.SearchEvent, delay time.Duration) <-cha
 	return results
 }
 
+// rankWindowSize is the number of results rankEvents ranks together. It bounds
+// the results we hold back before sending them to the client.
+const rankWindowSize = 500
+
+// rankEvents buffers the results of events from source and sends them ranked by
+// order in windows of rankWindowSize results. Results are only ordered within a
+// window. Events are still forwarded without their results as they come in, so
+// that clients receive progress updates.
+func rankEvents(ctx context.Context, source <-chan streaming.SearchEvent, ranker *ranking.Ranker, order query.ResultOrder) <-chan streaming.SearchEvent {
+	events := make(chan streaming.SearchEvent)
+	go func() {
+		defer close(events)
+
+		send := func(event streaming.SearchEvent) {
+			select {
+			case events <- event:
+			case <-ctx.Done():
+			}
+		}
+
+		var window []result.Match
+		flush := func() {
+			if len(window) > 0 && ctx.Err() == nil {
+				ranker.Rank(ctx, window, order)
+				send(streaming.SearchEvent{Results: window})
+			}
+			window = nil
+		}
+
+		// We keep reading from source once ctx is done, so that the search
+		// sending to it does not block.
+		for event := range source {
+			window = append(window, event.Results...)
+			if len(window) >= rankWindowSize {
+				flush()
+			}
+			send(streaming.SearchEvent{Stats: event.Stats})
+		}
+		flush()
+	}()
+	return events
+}
+
 func repoIDs(results []result.Match) []api.RepoID {
 	ids := make(map[api.RepoID]struct{}, 5)
 	for _, result := range results {
@@ -10,6 +10,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/cockroachdb/errors"
 	"golang.org/x/sync/errgroup"
 
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend"
@@ -18,6 +19,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/internal/database/dbtesting"
 	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
 	"github.com/sourcegraph/sourcegraph/internal/search/query"
+	"github.com/sourcegraph/sourcegraph/internal/search/ranking"
 	"github.com/sourcegraph/sourcegraph/internal/search/result"
 	"github.com/sourcegraph/sourcegraph/internal/search/run"
 	"github.com/sourcegraph/sourcegraph/internal/search/streaming"
@@ -212,6 +214,108 @@ func TestDisplayLimit(t *testing.T) {
 	}
 }
 
+func TestServeStream_aggregate(t *testing.T) {
+	cases := []struct {
+		name          string
+		queryString   string
+		err           error
+		wantAggregate bool
+	}{
+		{
+			name:          "complete",
+			queryString:   "foo",
+			wantAggregate: true,
+		},
+		{
+			name:          "count limit hit",
+			queryString:   "foo count:1",
+			wantAggregate: false,
+		},
+		{
+			name:          "search failed",
+			queryString:   "foo",
+			err:           errors.New("boom"),
+			wantAggregate: false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mock := &mockSearchResolver{
+				done: make(chan struct{}),
+				err:  c.err,
+			}
+
+			database.Mocks.Repos.Metadata = func(ctx context.Context, ids ...api2.RepoID) (_ []*types.SearchedRepo, err error) {
+				res := make([]*types.SearchedRepo, 0, len(ids))
+				for _, id := range ids {
+					res = append(res, &types.SearchedRepo{
+						ID: id,
+					})
+				}
+				return res, nil
+			}
+
+			ts := httptest.NewServer(&streamHandler{
+				flushTickerInternal: 1 * time.Millisecond,
+				pingTickerInterval:  1 * time.Millisecond,
+				newSearchResolver: func(_ context.Context, _ dbutil.DB, args *graphqlbackend.SearchArgs) (searchResolver, error) {
+					mock.c = args.Stream
+					q, err := query.Parse(args.Query, query.Literal)
+					if err != nil {
+						t.Fatal(err)
+					}
+					mock.inputs = &run.SearchInputs{
+						Query: q,
+					}
+					return mock, nil
+				}})
+			defer ts.Close()
+
+			req, _ := streamhttp.NewRequest(ts.URL, c.queryString)
+			q := req.URL.Query()
+			q.Add("aggregate", "repo")
+			req.URL.RawQuery = q.Encode()
+
+			var aggregate []*streamhttp.EventAggregate
+			decoder := streamhttp.FrontendStreamDecoder{
+				OnAggregate: func(groups []*streamhttp.EventAggregate) {
+					aggregate = groups
+				},
+				OnError: func(*streamhttp.EventError) {},
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			// Consume events.
+			g := errgroup.Group{}
+			g.Go(func() error {
+				return decoder.ReadAll(resp.Body)
+			})
+
+			// Send 2 repository matches.
+			mock.c.Send(streaming.SearchEvent{
+				Results: []result.Match{mkRepoMatch(1), mkRepoMatch(2)},
+			})
+			mock.Close()
+			if err := g.Wait(); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := aggregate != nil; got != c.wantAggregate {
+				t.Fatalf("got aggregate %v, want aggregate: %t", aggregate, c.wantAggregate)
+			}
+			if c.wantAggregate && len(aggregate) != 2 {
+				t.Fatalf("got %d groups, want 2", len(aggregate))
+			}
+		})
+	}
+}
+
 func mkRepoMatch(id int) *result.RepoMatch {
 	return &result.RepoMatch{
 		ID:   api2.RepoID(id),
@@ -220,9 +324,11 @@ func mkRepoMatch(id int) *result.RepoMatch {
 }
 
 type mockSearchResolver struct {
-	done   chan struct{}
-	c      streaming.Sender
-	inputs *run.SearchInputs
+	done      chan struct{}
+	c         streaming.Sender
+	inputs    *run.SearchInputs
+	err       error
+	repoCount func(query.Basic) int
 }
 
 func (h *mockSearchResolver) Results(ctx context.Context) (*graphqlbackend.SearchResultsResolver, error) {
@@ -230,6 +336,9 @@ func (h *mockSearchResolver) Results(ctx context.Context) (*graphqlbackend.Searc
 	case <-ctx.Done():
 		return nil, ctx.Err()
 	case <-h.done:
+		if h.err != nil {
+			return nil, h.err
+		}
 		return &graphqlbackend.SearchResultsResolver{
 			UserSettings:  &schema.Settings{},
 			SearchResults: &graphqlbackend.SearchResults{},
@@ -247,3 +356,40 @@ func (h *mockSearchResolver) Inputs() run.SearchInputs {
 	}
 	return *h.inputs
 }
+
+func (h *mockSearchResolver) CountRepos(_ context.Context, b query.Basic) (int, error) {
+	if h.repoCount == nil {
+		return 0, nil
+	}
+	return h.repoCount(b), nil
+}
+
+func TestRankEvents(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	source := make(chan streaming.SearchEvent)
+	events := rankEvents(ctx, source, ranking.NewRanker(nil), query.OrderRelevance)
+
+	var window []result.Match
+	for i := 0; i < rankWindowSize; i++ {
+		window = append(window, &result.FileMatch{File: result.File{Path: strconv.Itoa(i)}})
+	}
+	source <- streaming.SearchEvent{Results: window}
+
+	// A full window is sent before the search completes.
+	if event := <-events; len(event.Results) != rankWindowSize {
+		t.Fatalf("got %d results, want %d", len(event.Results), rankWindowSize)
+	}
+	<-events // stats
+
+	// Once ctx is done, source is drained without blocking on events.
+	cancel()
+	source <- streaming.SearchEvent{Results: window[:1]}
+	close(source)
+	for event := range events {
+		if len(event.Results) > 0 {
+			t.Fatalf("got %d results after cancellation", len(event.Results))
+		}
+	}
+}
@@ -120,6 +120,12 @@ func (s *Server) cleanupRepos() {
 	}
 
 	scrubRemoteURL := func(dir GitDir) (done bool, err error) {
+		if isPartialClone(dir) {
+			// Removing origin would also remove its promisor configuration,
+			// without which git can't fetch the objects missing from partial
+			// clones. We never store the remote URL of partial clones.
+			return false, nil
+		}
 		cmd := exec.Command("git", "remote", "remove", "origin")
 		dir.Set(cmd)
 		// ignore error since we fail if the remote has already been scrubbed.
@@ -504,6 +510,8 @@ func (s *Server) removeRepoDirectory(gitDir GitDir) error {
 	// Set as not_cloned in the database
 	s.setCloneStatusNonFatal(ctx, s.name(gitDir), types.CloneStatusNotCloned)
 
+	s.partialClones.forget(gitDir, s.name(gitDir))
+
 	// Cleanup empty parent directories. We just attempt to remove and if we
 	// have a failure we assume it's due to the directory having other
 	// children. If we checked first we could race with someone else adding a
@@ -697,6 +705,12 @@ func checkMaybeCorruptRepo(repo api.RepoName, dir GitDir, stderr string) {
 	if !maybeCorruptStderrRe.MatchString(stderr) {
 		return
 	}
+	// Objects missing from partial clones are fetched on demand. Failing to
+	// fetch them, for example because the code host is unreachable, doesn't
+	// mean the repository is corrupt.
+	if strings.Contains(stderr, "promisor remote") {
+		return
+	}
 
 	log15.Warn("marking repo for re-cloning due to stderr output indicating repo corruption", "repo", repo, "stderr", stderr)
 
@@ -712,6 +726,22 @@ func checkMaybeCorruptRepo(repo api.RepoName, dir GitDir, stderr string) {
 // operate synchronously and be aggressive with its internal heurisitcs when
 // deciding to act (meaning it will act now at lower thresholds).
 func gitGC(dir GitDir) error {
+	// Git only expects objects to be missing from a repository with promisor
+	// packs if its promisor remote is configured. Make sure it is before git gc
+	// repacks and prunes objects, in case the configuration was removed along
+	// with the remote.
+	if isPartialClone(dir) {
+		promisor, err := gitConfigGet(dir, "remote.origin.promisor")
+		if err != nil {
+			return err
+		}
+		if promisor == "" {
+			if err := setPartialCloneConfig(dir, ""); err != nil {
+				return errors.Wrap(err, "failed to restore partial clone config")
+			}
+		}
+	}
+
 	cmd := exec.Command("git", "-c", "gc.auto=1", "-c", "gc.autoDetach=false", "gc", "--auto")
 	dir.Set(cmd)
 	err := cmd.Run()
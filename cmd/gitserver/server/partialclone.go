@@ -0,0 +1,180 @@
+package server
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/vcs"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+var partialCloneFilters = conf.Cached(func() interface{} {
+	return buildPartialCloneFilters(conf.Get().ExperimentalFeatures.PartialClone)
+})
+
+func buildPartialCloneFilters(c []*schema.PartialCloneMapping) map[string]string {
+	filters := map[string]string{}
+	for _, mapping := range c {
+		if mapping.BlobLimit == "" {
+			continue
+		}
+		filters[mapping.DomainPath] = "blob:limit=" + mapping.BlobLimit
+	}
+	return filters
+}
+
+// partialCloneFilter returns the object filter with which the repository at
+// remoteURL is cloned and fetched, or "" if it is cloned in full. Repositories
+// with a custom fetch command are always cloned in full.
+func partialCloneFilter(remoteURL *vcs.URL) string {
+	dp := path.Join(remoteURL.Host, remoteURL.Path)
+	if cgm := customGitFetch().(map[string][]string); len(cgm[dp]) > 0 {
+		return ""
+	}
+	return partialCloneFilters().(map[string]string)[dp]
+}
+
+// partialCloneFetchCmd returns the command to fetch refspecs from remoteURL
+// into a partial clone, leaving out the objects matching filter.
+//
+// The objects left out are fetched on demand from the "origin" promisor
+// remote. We don't store the remote URL on disk, so that it is passed to each
+// command which may need to fetch objects.
+func partialCloneFetchCmd(ctx context.Context, remoteURL *vcs.URL, filter string, refspecs []string) *exec.Cmd {
+	args := []string{"-c", "remote.origin.url=" + remoteURL.String(), "fetch", "--progress", "--prune", "--filter=" + filter, "origin"}
+	return exec.CommandContext(ctx, "git", append(args, refspecs...)...)
+}
+
+// setPartialCloneConfig configures "origin" as the promisor remote of the
+// repository in dir, from which git fetches missing objects. If filter is not
+// empty, it is stored as the filter of later fetches.
+func setPartialCloneConfig(dir GitDir, filter string) error {
+	config := [][2]string{
+		// Extensions are only read in repositories of format version 1.
+		{"core.repositoryformatversion", "1"},
+		{"extensions.partialClone", "origin"},
+		{"remote.origin.promisor", "true"},
+	}
+	if filter != "" {
+		config = append(config, [2]string{"remote.origin.partialclonefilter", filter})
+	}
+	for _, kv := range config {
+		if err := gitConfigSet(dir, kv[0], kv[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isPartialClone reports whether objects may be missing from the repository in
+// dir. Git marks the packs fetched with a filter with a ".promisor" file.
+func isPartialClone(dir GitDir) bool {
+	matches, _ := filepath.Glob(dir.Path("objects", "pack", "*.promisor"))
+	return len(matches) > 0
+}
+
+// configureLazyFetch configures cmd, a git command run in the partial clone of
+// repo, to fetch missing objects on demand. It returns the remote URL, which
+// must be redacted from the output of cmd.
+func (s *Server) configureLazyFetch(ctx context.Context, repo api.RepoName, cmd *exec.Cmd) (*vcs.URL, error) {
+	remoteURL, err := s.partialClones.remoteURL(ctx, repo, s.getRemoteURL)
+	if err != nil {
+		return nil, err
+	}
+	cmd.Args = append([]string{cmd.Args[0], "-c", "remote.origin.url=" + remoteURL.String()}, cmd.Args[1:]...)
+	configureRemoteGitCommand(cmd, tlsExternal().(*tlsConfig))
+	return remoteURL, nil
+}
+
+// partialCloneRemoteURLTTL is how long the remote URL of a partial clone is
+// cached for.
+const partialCloneRemoteURLTTL = time.Minute
+
+// partialCloneCache caches which repositories are partial clones and their
+// remote URLs, so that exec doesn't list the packs of a repository and ask the
+// frontend for its remote URL on every request. The zero value is ready to
+// use.
+type partialCloneCache struct {
+	mu         sync.Mutex
+	packDirs   map[GitDir]partialClonePackDir
+	remoteURLs map[api.RepoName]partialCloneRemoteURL
+}
+
+type partialClonePackDir struct {
+	// info is the pack directory when we looked for promisor packs. Fetches
+	// and repacks change its modification time, and clones replace it.
+	info      os.FileInfo
+	isPartial bool
+}
+
+type partialCloneRemoteURL struct {
+	url     *vcs.URL
+	expires time.Time
+}
+
+// isPartialClone is like the isPartialClone function, but only looks for
+// promisor packs again once the pack directory of dir changed.
+func (c *partialCloneCache) isPartialClone(dir GitDir) bool {
+	info, err := os.Stat(dir.Path("objects", "pack"))
+	if err != nil {
+		return false
+	}
+
+	c.mu.Lock()
+	cached, ok := c.packDirs[dir]
+	c.mu.Unlock()
+	if ok && os.SameFile(cached.info, info) && cached.info.ModTime().Equal(info.ModTime()) {
+		return cached.isPartial
+	}
+
+	// We stat the pack directory before listing it, so that packs added in
+	// between are found the next time.
+	isPartial := isPartialClone(dir)
+	c.mu.Lock()
+	if c.packDirs == nil {
+		c.packDirs = map[GitDir]partialClonePackDir{}
+	}
+	c.packDirs[dir] = partialClonePackDir{info: info, isPartial: isPartial}
+	c.mu.Unlock()
+	return isPartial
+}
+
+// remoteURL returns the remote URL of repo, calling getRemoteURL at most once
+// per partialCloneRemoteURLTTL.
+func (c *partialCloneCache) remoteURL(ctx context.Context, repo api.RepoName, getRemoteURL func(context.Context, api.RepoName) (*vcs.URL, error)) (*vcs.URL, error) {
+	now := time.Now()
+	c.mu.Lock()
+	cached, ok := c.remoteURLs[repo]
+	c.mu.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.url, nil
+	}
+
+	remoteURL, err := getRemoteURL(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	if c.remoteURLs == nil {
+		c.remoteURLs = map[api.RepoName]partialCloneRemoteURL{}
+	}
+	c.remoteURLs[repo] = partialCloneRemoteURL{url: remoteURL, expires: now.Add(partialCloneRemoteURLTTL)}
+	c.mu.Unlock()
+	return remoteURL, nil
+}
+
+// forget removes what is cached about the repository repo in dir, once it is
+// removed.
+func (c *partialCloneCache) forget(dir GitDir, repo api.RepoName) {
+	c.mu.Lock()
+	delete(c.packDirs, dir)
+	delete(c.remoteURLs, repo)
+	c.mu.Unlock()
+}
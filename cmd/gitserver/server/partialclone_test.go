@@ -0,0 +1,197 @@
+package server
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/vcs"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+func TestPartialCloneFilter(t *testing.T) {
+	customGitFetch = func() interface{} {
+		return buildCustomFetchMappings([]*schema.CustomGitFetchMapping{
+			{DomainPath: "github.com/foo/custom", Fetch: "echo custom"},
+		})
+	}
+	partialCloneFilters = func() interface{} {
+		return buildPartialCloneFilters([]*schema.PartialCloneMapping{
+			{DomainPath: "github.com/foo/monorepo", BlobLimit: "1m"},
+			{DomainPath: "github.com/foo/custom", BlobLimit: "1m"},
+			{DomainPath: "github.com/foo/faulty", BlobLimit: ""},
+		})
+	}
+	defer func() {
+		customGitFetch = func() interface{} { return buildCustomFetchMappings(nil) }
+		partialCloneFilters = func() interface{} { return buildPartialCloneFilters(nil) }
+	}()
+
+	tests := map[string]string{
+		"https://8cd1419f4d5c1e0527f2893c9422f1a2a435116d@github.com/foo/monorepo": "blob:limit=1m",
+		"git@github.com:foo/monorepo": "blob:limit=1m",
+		// The custom fetch command takes precedence.
+		"https://github.com/foo/custom":   "",
+		"https://github.com/foo/faulty":   "",
+		"https://github.com/bar/notthere": "",
+	}
+	for rawURL, want := range tests {
+		remoteURL, err := vcs.ParseURL(rawURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := partialCloneFilter(remoteURL); got != want {
+			t.Errorf("URL %q: got filter %q, want %q", rawURL, got, want)
+		}
+	}
+}
+
+func TestPartialClone(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+
+	remote := filepath.Join(root, "remote")
+	runCmd(t, root, "git", "init", remote)
+	runCmd(t, remote, "git", "config", "uploadpack.allowFilter", "true")
+	if err := os.WriteFile(filepath.Join(remote, "small"), []byte("small\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	large := strings.Repeat("large\n", 1000)
+	if err := os.WriteFile(filepath.Join(remote, "large"), []byte(large), 0600); err != nil {
+		t.Fatal(err)
+	}
+	runCmd(t, remote, "git", "add", ".")
+	runCmd(t, remote, "git", "commit", "-m", "initial")
+
+	customGitFetch = func() interface{} { return buildCustomFetchMappings(nil) }
+	partialCloneFilters = func() interface{} {
+		return buildPartialCloneFilters([]*schema.PartialCloneMapping{{DomainPath: remote, BlobLimit: "1k"}})
+	}
+	defer func() {
+		partialCloneFilters = func() interface{} { return buildPartialCloneFilters(nil) }
+	}()
+
+	remoteURL, err := vcs.ParseURL("file://" + remote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := GitDir(filepath.Join(root, "repo", ".git"))
+	cmd, err := (&GitRepoSyncer{}).CloneCommand(ctx, remoteURL, string(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out, err := runWith(ctx, cmd, true, nil); err != nil {
+		t.Fatalf("clone failed: %s\nOutput: %s", err, out)
+	}
+	if !isPartialClone(dir) {
+		t.Fatal("expected a partial clone")
+	}
+
+	// The large blob is left out.
+	missing := func() string {
+		t.Helper()
+		cmd := exec.Command("git", "rev-list", "--objects", "--missing=print", "HEAD")
+		dir.Set(cmd)
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var missing []string
+		for _, line := range strings.Split(string(out), "\n") {
+			if strings.HasPrefix(line, "?") {
+				missing = append(missing, line)
+			}
+		}
+		return strings.Join(missing, "\n")
+	}
+	if missing() == "" {
+		t.Fatal("expected the large blob to be missing")
+	}
+
+	// Removing the remote also removes the promisor configuration, which
+	// git gc restores.
+	runCmd(t, string(dir), "git", "--git-dir=.", "remote", "remove", "origin")
+	if err := gitGC(dir); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := gitConfigGet(dir, "remote.origin.promisor"); err != nil || got != "true\n" {
+		t.Fatalf("got remote.origin.promisor %q, %v, want true", got, err)
+	}
+	if !isPartialClone(dir) {
+		t.Fatal("expected git gc to keep the promisor packs")
+	}
+
+	// The missing blob is fetched on demand.
+	s := &Server{GetRemoteURLFunc: staticGetRemoteURL("file://" + remote)}
+	cmd = exec.Command("git", "show", "HEAD:large")
+	dir.Set(cmd)
+	if _, err := s.configureLazyFetch(ctx, "repo", cmd); err != nil {
+		t.Fatal(err)
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != large {
+		t.Fatalf("got %d bytes of the large blob, want %d", len(out), len(large))
+	}
+	if got := missing(); got != "" {
+		t.Fatalf("expected no missing objects, got %s", got)
+	}
+}
+
+func TestPartialCloneCache(t *testing.T) {
+	ctx := context.Background()
+	dir := GitDir(filepath.Join(t.TempDir(), ".git"))
+	var c partialCloneCache
+
+	if c.isPartialClone(dir) {
+		t.Fatal("expected a missing repository not to be a partial clone")
+	}
+	packDir := dir.Path("objects", "pack")
+	if err := os.MkdirAll(packDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if c.isPartialClone(dir) {
+		t.Fatal("expected no partial clone without promisor packs")
+	}
+
+	// Adding a pack changes the pack directory, so the cached status is
+	// checked again.
+	if err := os.WriteFile(filepath.Join(packDir, "pack-1.promisor"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if !c.isPartialClone(dir) {
+		t.Fatal("expected a partial clone after adding a promisor pack")
+	}
+
+	calls := 0
+	getRemoteURL := func(ctx context.Context, repo api.RepoName) (*vcs.URL, error) {
+		calls++
+		return vcs.ParseURL("https://github.com/foo/" + string(repo))
+	}
+	for i := 0; i < 2; i++ {
+		remoteURL, err := c.remoteURL(ctx, "bar", getRemoteURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := remoteURL.String(), "https://github.com/foo/bar"; got != want {
+			t.Fatalf("got remote URL %q, want %q", got, want)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("got %d calls to getRemoteURL, want 1", calls)
+	}
+
+	c.forget(dir, "bar")
+	if _, err := c.remoteURL(ctx, "bar", getRemoteURL); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Fatalf("got %d calls to getRemoteURL after forget, want 2", calls)
+	}
+}
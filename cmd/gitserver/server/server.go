@@ -167,6 +167,10 @@ type Server struct {
 
 	repoUpdateLocksMu sync.Mutex // protects the map below and also updates to locks.once
 	repoUpdateLocks   map[api.RepoName]*locks
+
+	// partialClones caches which repositories are partial clones and their
+	// remote URLs, which exec needs to fetch missing objects.
+	partialClones partialCloneCache
 }
 
 type locks struct {
@@ -308,19 +312,20 @@ func (s *Server) Janitor(interval time.Duration) {
 
 // SyncRepoState syncs state on disk to the database for all repos and is
 // expected to run in a background goroutine. We perform a full sync if the known
-// gitserver addresses has changed since the last run. Otherwise, we only sync
-// repos that have not yet been assigned a shard.
+// gitserver addresses or the replication factor has changed since the last run.
+// Otherwise, we only sync repos that have not yet been assigned a shard.
 func (s *Server) SyncRepoState(interval time.Duration, batchSize, perSecond int) {
 	var previousAddrs string
 	for {
 		addrs := conf.Get().ServiceConnections.GitServers
+		replicationFactor := conf.GitserverReplicationFactor()
 		// We turn addrs into a string here for easy comparison and storage of previous
 		// addresses since we'd need to take a copy of the slice anyway.
-		currentAddrs := strings.Join(addrs, ",")
+		currentAddrs := strings.Join(addrs, ",") + ";" + strconv.Itoa(replicationFactor)
 		fullSync := currentAddrs != previousAddrs
 		previousAddrs = currentAddrs
 
-		if err := s.syncRepoState(addrs, batchSize, perSecond, fullSync); err != nil {
+		if err := s.syncRepoState(addrs, replicationFactor, batchSize, perSecond, fullSync); err != nil {
 			log15.Error("Syncing repo state", "error ", err)
 		}
 
@@ -343,6 +348,43 @@ func (s *Server) hostnameMatch(addr string) bool {
 	return next == '.' || next == ':'
 }
 
+// replicaIndex returns the index of this gitserver in replicas, the addresses
+// of the gitservers a repo is cloned on, or -1 if the repo isn't cloned on this
+// gitserver. The repo lives on the gitserver at index 0, the others hold
+// replicas of it.
+func (s *Server) replicaIndex(replicas []string) int {
+	for i, addr := range replicas {
+		if s.hostnameMatch(addr) {
+			return i
+		}
+	}
+	return -1
+}
+
+// replicationConfig is the configuration isReplica needs for each update of
+// the state of a repo.
+type replicationConfig struct {
+	addrs             []string
+	replicationFactor int
+}
+
+var gitserverReplication = conf.Cached(func() interface{} {
+	return replicationConfig{
+		addrs:             conf.Get().ServiceConnections.GitServers,
+		replicationFactor: conf.GitserverReplicationFactor(),
+	}
+})
+
+// isReplica reports whether this gitserver holds a replica of the repo, rather
+// than being the gitserver the repo lives on.
+func (s *Server) isReplica(name api.RepoName) bool {
+	c := gitserverReplication().(replicationConfig)
+	if len(c.addrs) == 0 || c.replicationFactor == 1 {
+		return false
+	}
+	return s.replicaIndex(gitserver.AddrsForRepo(name, c.addrs, c.replicationFactor)) > 0
+}
+
 var (
 	repoSyncStateCounter = promauto.NewCounterVec(prometheus.CounterOpts{
 		Name: "src_repo_sync_state_counter",
@@ -358,7 +400,38 @@ var (
 	}, []string{"success"})
 )
 
-func (s *Server) syncRepoState(addrs []string, batchSize, perSecond int, fullSync bool) error {
+// deleteStaleReplicas forgets about the replicas this shard holds of repos
+// other than the ones in keep. The replicas are listed and deleted in batches
+// of batchSize.
+func (s *Server) deleteStaleReplicas(ctx context.Context, limiter *rate.Limiter, batchSize int, keep map[api.RepoID]struct{}) error {
+	store := database.GitserverRepos(s.DB)
+	var after api.RepoID
+	for {
+		ids, err := store.ListReplicaRepoIDs(ctx, s.Hostname, after, batchSize)
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+		after = ids[len(ids)-1]
+
+		var stale []api.RepoID
+		for _, id := range ids {
+			if _, ok := keep[id]; !ok {
+				stale = append(stale, id)
+			}
+		}
+		if len(stale) == 0 {
+			continue
+		}
+		if err := limiter.WaitN(ctx, len(stale)); err != nil {
+			return err
+		}
+		if err := store.DeleteReplicas(ctx, s.Hostname, stale...); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) syncRepoState(addrs []string, replicationFactor, batchSize, perSecond int, fullSync bool) error {
 	log15.Info("starting syncRepoState", "fullSync", fullSync)
 
 	// When fullSync is true we'll scan all repos in the database and ensure we set
@@ -367,6 +440,10 @@ func (s *Server) syncRepoState(addrs []string, batchSize, perSecond int, fullSyn
 	//
 	// When fullSync is false, we assume that we only need to check repos that have
 	// not yet had their shard_id allocated.
+	//
+	// The clone state of repos this shard holds a replica of is tracked
+	// separately, in gitserver_repo_replicas. After a full sync, we forget about
+	// the replicas of repos which are no longer replicated on this shard.
 
 	// Sanity check our host exists in addrs before starting any work
 	var found bool
@@ -420,6 +497,31 @@ func (s *Server) syncRepoState(addrs []string, batchSize, perSecond int, fullSyn
 		repoStateUpsertCounter.WithLabelValues("true").Add(float64(len(batch)))
 	}
 
+	replicaBatch := make([]*types.GitserverRepoReplica, 0)
+	replicaIDs := make(map[api.RepoID]struct{})
+
+	writeReplicaBatch := func() {
+		if len(replicaBatch) == 0 {
+			return
+		}
+		// We always clear the batch
+		defer func() {
+			replicaBatch = replicaBatch[0:0]
+		}()
+		err := limiter.WaitN(ctx, len(replicaBatch))
+		if err != nil {
+			log15.Error("Waiting for rate limiter", "error", err)
+			return
+		}
+
+		if err := store.UpsertReplicas(ctx, replicaBatch...); err != nil {
+			repoStateUpsertCounter.WithLabelValues("false").Add(float64(len(replicaBatch)))
+			log15.Error("Upserting GitserverRepoReplicas", "error", err)
+			return
+		}
+		repoStateUpsertCounter.WithLabelValues("true").Add(float64(len(replicaBatch)))
+	}
+
 	totalRepos, err := database.Repos(s.DB).Count(ctx, database.ReposListOptions{})
 	if err != nil {
 		return errors.Wrap(err, "counting repos")
@@ -436,16 +538,31 @@ func (s *Server) syncRepoState(addrs []string, batchSize, perSecond int, fullSyn
 
 		repoSyncStateCounter.WithLabelValues("check").Inc()
 		// Ensure we're only dealing with repos we are responsible for
-		if addr := gitserver.AddrForRepo(repo.Name, addrs); !s.hostnameMatch(addr) {
+		i := s.replicaIndex(gitserver.AddrsForRepo(repo.Name, addrs, replicationFactor))
+		if i < 0 {
 			repoSyncStateCounter.WithLabelValues("other_shard").Inc()
 			return nil
 		}
-		repoSyncStateCounter.WithLabelValues("this_shard").Inc()
 
 		dir := s.dir(repo.Name)
 		cloned := repoCloned(dir)
 		_, cloning := s.locker.Status(dir)
 
+		if i > 0 {
+			repoSyncStateCounter.WithLabelValues("replica").Inc()
+			replicaIDs[repo.ID] = struct{}{}
+			replicaBatch = append(replicaBatch, &types.GitserverRepoReplica{
+				RepoID:      repo.ID,
+				ShardID:     s.Hostname,
+				CloneStatus: cloneStatus(cloned, cloning),
+			})
+			if len(replicaBatch) >= batchSize {
+				writeReplicaBatch()
+			}
+			return nil
+		}
+		repoSyncStateCounter.WithLabelValues("this_shard").Inc()
+
 		var shouldUpdate bool
 		if repo.GitserverRepo == nil {
 			repo.GitserverRepo = &types.GitserverRepo{
@@ -478,6 +595,13 @@ func (s *Server) syncRepoState(addrs []string, batchSize, perSecond int, fullSyn
 
 	// Attempt final write
 	writeBatch()
+	writeReplicaBatch()
+
+	if err == nil && fullSync {
+		if err := s.deleteStaleReplicas(ctx, limiter, batchSize, replicaIDs); err != nil {
+			return errors.Wrap(err, "deleting stale replicas")
+		}
+	}
 
 	return err
 }
@@ -961,6 +1085,16 @@ func (s *Server) exec(w http.ResponseWriter, r *http.Request, req *protocol.Exec
 	cmd.Stdout = stdoutW
 	cmd.Stderr = stderrW
 
+	// Blobs missing from partial clones, for example for git archive or git
+	// show, are fetched on demand from the remote.
+	var remoteURL *vcs.URL
+	if s.partialClones.isPartialClone(dir) {
+		var err error
+		if remoteURL, err = s.configureLazyFetch(ctx, req.Repo, cmd); err != nil {
+			log15.Warn("failed to configure fetching missing objects of partial clone", "repo", req.Repo, "error", err)
+		}
+	}
+
 	exitStatus, execErr = runCommand(ctx, cmd)
 
 	status = strconv.Itoa(exitStatus)
@@ -968,6 +1102,9 @@ func (s *Server) exec(w http.ResponseWriter, r *http.Request, req *protocol.Exec
 	stderrN = stderrW.n
 
 	stderr := stderrBuf.String()
+	if remoteURL != nil {
+		stderr = newURLRedactor(remoteURL).redact(stderr)
+	}
 	checkMaybeCorruptRepo(req.Repo, dir, stderr)
 
 	// write trailer
@@ -1140,6 +1277,9 @@ func (s *Server) setLastError(ctx context.Context, name api.RepoName, error stri
 	if s.DB == nil {
 		return nil
 	}
+	if s.isReplica(name) {
+		return database.GitserverRepos(s.DB).SetReplicaLastError(ctx, name, error, s.Hostname)
+	}
 	return database.GitserverRepos(s.DB).SetLastError(ctx, name, error, s.Hostname)
 }
 
@@ -1147,6 +1287,9 @@ func (s *Server) setLastFetched(ctx context.Context, name api.RepoName, lastFetc
 	if s.DB == nil {
 		return nil
 	}
+	if s.isReplica(name) {
+		return database.GitserverRepos(s.DB).SetReplicaLastFetched(ctx, name, lastFetched, s.Hostname)
+	}
 	return database.GitserverRepos(s.DB).SetLastFetched(ctx, name, lastFetched, s.Hostname)
 }
 
@@ -1165,6 +1308,9 @@ func (s *Server) setCloneStatus(ctx context.Context, name api.RepoName, status t
 	if s.DB == nil {
 		return nil
 	}
+	if s.isReplica(name) {
+		return database.GitserverRepos(s.DB).SetReplicaCloneStatus(ctx, name, status, s.Hostname)
+	}
 	return database.GitserverRepos(s.DB).SetCloneStatus(ctx, name, status, s.Hostname)
 }
 
@@ -27,6 +27,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/internal/database"
 	"github.com/sourcegraph/sourcegraph/internal/database/dbtesting"
 	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
 	"github.com/sourcegraph/sourcegraph/internal/mutablelimiter"
 	"github.com/sourcegraph/sourcegraph/internal/repoupdater/protocol"
 	"github.com/sourcegraph/sourcegraph/internal/types"
@@ -933,7 +934,7 @@ func TestSyncRepoState(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	err = s.syncRepoState([]string{hostname}, 10, 10, true)
+	err = s.syncRepoState([]string{hostname}, 1, 10, 10, true)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -946,6 +947,39 @@ func TestSyncRepoState(t *testing.T) {
 	if gr.CloneStatus != types.CloneStatusCloned {
 		t.Fatalf("Want %v, got %v", types.CloneStatusCloned, gr.CloneStatus)
 	}
+
+	// When the repo lives on another gitserver, we track the state of our
+	// replica of it.
+	addrs := []string{hostname, "other"}
+	if gitserver.AddrsForRepo(repoName, addrs, 2)[0] == hostname {
+		addrs = []string{"other", hostname}
+	}
+	err = s.syncRepoState(addrs, 2, 10, 10, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replicas, err := database.GitserverRepos(db).GetReplicasByID(ctx, dbRepo.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replicas) != 1 || replicas[0].ShardID != hostname || replicas[0].CloneStatus != types.CloneStatusCloned {
+		t.Fatalf("Want a cloned replica on %q, got %+v", hostname, replicas)
+	}
+
+	// The replica is forgotten once the repo is no longer replicated.
+	err = s.syncRepoState(addrs, 1, 10, 10, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replicas, err = database.GitserverRepos(db).GetReplicasByID(ctx, dbRepo.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replicas) != 0 {
+		t.Fatalf("Want no replicas, got %+v", replicas)
+	}
 }
 
 func TestMain(m *testing.M) {
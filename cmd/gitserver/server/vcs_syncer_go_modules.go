@@ -0,0 +1,167 @@
+package server
+
+import (
+	"archive/zip"
+	"context"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/inconshreveable/log15"
+
+	"github.com/sourcegraph/sourcegraph/internal/conf/reposource"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/gomodules/proxy"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+// placeholderGoDependency is used to set GIT_AUTHOR_NAME for git commands
+// that don't create commits or tags.
+var placeholderGoDependency = reposource.GoDependency{
+	GoModule: reposource.GoModule{Path: "sourcegraph.com/sourcegraph"},
+	Version:  "v1.0.0",
+}
+
+// NewGoModulesSyncer returns a VCSSyncer which mirrors the module versions
+// configured in the given connection from the Go module proxies of client.
+func NewGoModulesSyncer(connection *schema.GoModulesConnection, client *proxy.Client) VCSSyncer {
+	return &packagesSyncer{
+		typ:         "go_modules",
+		source:      &goModulesSource{connection: connection, client: client},
+		placeholder: placeholderGoDependency,
+	}
+}
+
+type goModulesSource struct {
+	connection *schema.GoModulesConnection
+	client     *proxy.Client
+}
+
+// dependencies returns the configured versions of the Go module of the given
+// URL path which exist in the proxies. A configured module without a version
+// expands to all versions listed by the proxies.
+func (s *goModulesSource) dependencies(ctx context.Context, repoURLPath string) ([]reposource.PackageDependency, error) {
+	mod, err := reposource.ParseGoModuleFromRepoURL(repoURLPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var dependencies []reposource.GoDependency
+	isAdded := map[string]bool{}
+	add := func(dependency reposource.GoDependency) {
+		if !isAdded[dependency.Version] {
+			dependencies = append(dependencies, dependency)
+		}
+		isAdded[dependency.Version] = true
+	}
+
+	for _, dependency := range s.connection.Dependencies {
+		if !mod.MatchesDependencyString(dependency) {
+			continue
+		}
+		dependency, err := reposource.ParseGoDependencyConfig(dependency)
+		if err != nil {
+			return nil, err
+		}
+
+		if dependency.Version != "" {
+			exists, err := s.client.Exists(ctx, dependency)
+			if err != nil {
+				return nil, err
+			}
+			if !exists {
+				// Silently ignore non-existent dependencies because they are
+				// already logged in the `GetRepo` method in
+				// internal/repos/go_modules.go.
+				continue
+			}
+			add(dependency)
+			continue
+		}
+
+		versions, err := s.client.ListVersions(ctx, mod)
+		if err != nil && !proxy.IsNotFound(err) {
+			return nil, err
+		}
+		for _, version := range versions {
+			add(reposource.GoDependency{GoModule: mod, Version: version})
+		}
+	}
+
+	if len(dependencies) == 0 {
+		return nil, errors.Errorf("no Go dependencies for URL path %s", repoURLPath)
+	}
+
+	reposource.SortGoDependencies(dependencies)
+	packageDependencies := make([]reposource.PackageDependency, 0, len(dependencies))
+	for _, dependency := range dependencies {
+		packageDependencies = append(packageDependencies, dependency)
+	}
+	return packageDependencies, nil
+}
+
+// download extracts the zip of the given module version into dir.
+func (s *goModulesSource) download(ctx context.Context, dir string, dependency reposource.PackageDependency) error {
+	goDependency := dependency.(reposource.GoDependency)
+	body, err := s.client.FetchZip(ctx, goDependency)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	// Reading a zip requires random access, so that we first save it to a
+	// temporary file.
+	zipFile, err := os.CreateTemp("", "go-module-*.zip")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(zipFile.Name())
+	_, err = io.Copy(zipFile, body)
+	if err1 := zipFile.Close(); err == nil {
+		err = err1
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to save module zip")
+	}
+
+	return extractGoModuleZip(zipFile.Name(), goDependency, dir)
+}
+
+// extractGoModuleZip extracts the files of the module zip at zipPath into
+// dir. All files of a module zip are in the "module@version/" directory,
+// which is stripped. See https://golang.org/ref/mod#zip-files.
+func extractGoModuleZip(zipPath string, dependency reposource.GoDependency, dir string) error {
+	reader, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return errors.Wrap(err, "failed to read module zip")
+	}
+	defer reader.Close()
+
+	prefix := dependency.PackageManagerSyntax() + "/"
+	for _, entry := range reader.File {
+		if entry.FileInfo().IsDir() {
+			continue
+		}
+
+		// Cleaning the rooted name removes any "..", see "Zip Slip
+		// Vulnerability".
+		name := strings.TrimPrefix(path.Clean("/"+entry.Name), "/")
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		name = strings.TrimPrefix(name, prefix)
+		if isGitPath(name) {
+			// For security reasons, don't extract files under `.git/`
+			// directories. See https://github.com/sourcegraph/security-issues/issues/163
+			log15.Warn("Skipping .git file in Go module zip", "name", entry.Name)
+			continue
+		}
+
+		if err := copyZipFileEntry(reader, entry, filepath.Join(dir, filepath.FromSlash(name))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
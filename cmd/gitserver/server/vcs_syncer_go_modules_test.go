@@ -0,0 +1,119 @@
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/gomodules/proxy"
+	"github.com/sourcegraph/sourcegraph/internal/vcs"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+const exampleGoModuleURL = "go/example.com/example"
+
+// createZip returns a zip with the given files.
+func createZip(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zipWriter := zip.NewWriter(&buf)
+	for name, contents := range files {
+		w, err := zipWriter.Create(name)
+		assert.Nil(t, err)
+		_, err = w.Write([]byte(contents))
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, zipWriter.Close())
+	return buf.Bytes()
+}
+
+// goModuleProxy returns a Go module proxy serving the given zips of
+// example.com/example by version.
+func goModuleProxy(t *testing.T, zips map[string][]byte) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/example.com/example/@v/list" {
+			for version := range zips {
+				w.Write([]byte(version + "\n"))
+			}
+			return
+		}
+		for version, zip := range zips {
+			switch r.URL.Path {
+			case "/example.com/example/@v/" + version + ".info":
+				w.Write([]byte(`{"Version":"` + version + `"}`))
+				return
+			case "/example.com/example/@v/" + version + ".zip":
+				w.Write(zip)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	}))
+}
+
+func TestGoModulesCloneCommand(t *testing.T) {
+	dir, err := os.MkdirTemp("", "")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	srv := goModuleProxy(t, map[string][]byte{
+		"v1.0.0": createZip(t, map[string]string{
+			"example.com/example@v1.0.0/go.mod":     "module example.com/example\n",
+			"example.com/example@v1.0.0/example.go": "package example\n",
+		}),
+		"v1.1.0": createZip(t, map[string]string{
+			"example.com/example@v1.1.0/go.mod":        "module example.com/example\n",
+			"example.com/example@v1.1.0/sub/sub.go":    "package sub\n",
+			"example.com/example@v1.1.0/.git/config":   "[core]\n",
+			"example.com/example@v1.0.0/other.go":      "package other\n",
+			"example.com/example@v1.1.0/../../evil.go": "package evil\n",
+		}),
+	})
+	defer srv.Close()
+
+	connection := &schema.GoModulesConnection{Urls: []string{srv.URL}}
+	client, err := proxy.NewClient(connection, http.DefaultClient)
+	assert.Nil(t, err)
+	s := NewGoModulesSyncer(connection, client)
+
+	bareGitDirectory := path.Join(dir, "git")
+	runCloneCommand := func(dependencies ...string) {
+		t.Helper()
+		connection.Dependencies = dependencies
+		cmd, err := s.CloneCommand(context.Background(), &vcs.URL{URL: url.URL{Path: exampleGoModuleURL}}, bareGitDirectory)
+		assert.Nil(t, err)
+		assert.Nil(t, cmd.Run())
+	}
+
+	runCloneCommand("example.com/example@v1.0.0", "example.com/example@v0.0.1")
+	assertCommandOutput(t, exec.Command("git", "tag", "--list"), bareGitDirectory, "v1.0.0\n")
+	assertCommandOutput(t, exec.Command("git", "show", "v1.0.0:example.go"), bareGitDirectory, "package example\n")
+	assertCommandOutput(t, exec.Command("git", "log", "--format=%s", "latest"), bareGitDirectory, "example.com/example@v1.0.0\n")
+
+	// A module without a version mirrors all versions.
+	runCloneCommand("example.com/example")
+	assertCommandOutput(t, exec.Command("git", "tag", "--list"), bareGitDirectory, "v1.0.0\nv1.1.0\n")
+	assertCommandOutput(t, exec.Command("git", "log", "--format=%s", "latest"), bareGitDirectory, "example.com/example@v1.1.0\n")
+	// The .git directory and the files outside of the module directory are
+	// skipped.
+	assertCommandOutput(t, exec.Command("git", "ls-tree", "-r", "--name-only", "v1.1.0"), bareGitDirectory, "go.mod\nsub/sub.go\n")
+
+	runCloneCommand("example.com/example@v1.1.0")
+	assertCommandOutput(t, exec.Command("git", "tag", "--list"), bareGitDirectory, "v1.1.0\n")
+
+	connection.Dependencies = []string{"example.com/example@v0.0.1"}
+	err = s.IsCloneable(context.Background(), &vcs.URL{URL: url.URL{Path: exampleGoModuleURL}})
+	if err == nil || !strings.Contains(err.Error(), "no Go dependencies") {
+		t.Fatalf("expected no dependencies error, got %v", err)
+	}
+}
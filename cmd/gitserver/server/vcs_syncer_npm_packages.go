@@ -0,0 +1,103 @@
+package server
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/sourcegraph/sourcegraph/internal/conf/reposource"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/npmpackages/npm"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+// placeholderNpmDependency is used to set GIT_AUTHOR_NAME for git commands
+// that don't create commits or tags.
+var placeholderNpmDependency = reposource.NpmDependency{
+	NpmPackage: reposource.NpmPackage{
+		Scope: "sourcegraph",
+		Name:  "sourcegraph",
+	},
+	Version: "1.0.0",
+}
+
+// NewNpmPackagesSyncer returns a VCSSyncer which mirrors the package versions
+// configured in the given connection from the npm registry of client.
+func NewNpmPackagesSyncer(connection *schema.NpmPackagesConnection, client *npm.Client) VCSSyncer {
+	return &packagesSyncer{
+		typ:         "npm_packages",
+		source:      &npmPackagesSource{connection: connection, client: client},
+		placeholder: placeholderNpmDependency,
+	}
+}
+
+type npmPackagesSource struct {
+	connection *schema.NpmPackagesConnection
+	client     *npm.Client
+}
+
+// dependencies returns the configured versions of the npm package of the
+// given URL path which exist in the registry.
+func (s *npmPackagesSource) dependencies(ctx context.Context, repoURLPath string) ([]reposource.PackageDependency, error) {
+	pkg, err := reposource.ParseNpmPackageFromRepoURL(repoURLPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var dependencies []reposource.NpmDependency
+	for _, dependency := range s.connection.Dependencies {
+		if !pkg.MatchesDependencyString(dependency) {
+			continue
+		}
+		dependency, err := reposource.ParseNpmDependency(dependency)
+		if err != nil {
+			return nil, err
+		}
+		exists, err := s.client.Exists(ctx, dependency)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			// Silently ignore non-existent dependencies because they are
+			// already logged in the `GetRepo` method in
+			// internal/repos/npm_packages.go.
+			continue
+		}
+		dependencies = append(dependencies, dependency)
+	}
+
+	if len(dependencies) == 0 {
+		return nil, errors.Errorf("no npm dependencies for URL path %s", repoURLPath)
+	}
+
+	reposource.SortNpmDependencies(dependencies)
+	packageDependencies := make([]reposource.PackageDependency, 0, len(dependencies))
+	for _, dependency := range dependencies {
+		packageDependencies = append(packageDependencies, dependency)
+	}
+	return packageDependencies, nil
+}
+
+// download extracts the tarball of the given version into dir.
+func (s *npmPackagesSource) download(ctx context.Context, dir string, dependency reposource.PackageDependency) error {
+	tarball, err := s.client.FetchTarball(ctx, dependency.(reposource.NpmDependency))
+	if err != nil {
+		return err
+	}
+	defer tarball.Close()
+
+	return extractNpmTarball(tarball, dir)
+}
+
+// extractNpmTarball extracts the regular files of a gzipped npm package
+// tarball into dir.
+func extractNpmTarball(tarball io.Reader, dir string) error {
+	gzipReader, err := gzip.NewReader(tarball)
+	if err != nil {
+		return errors.Wrap(err, "failed to read gzip")
+	}
+	defer gzipReader.Close()
+
+	return extractTarball(gzipReader, dir)
+}
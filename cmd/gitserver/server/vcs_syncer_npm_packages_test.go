@@ -0,0 +1,123 @@
+package server
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/npmpackages/npm"
+	"github.com/sourcegraph/sourcegraph/internal/vcs"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+const exampleNpmPackageURL = "npm/example/example"
+
+// createNpmTarball returns a gzipped npm package tarball with the given files.
+func createNpmTarball(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buf)
+	tarWriter := tar.NewWriter(gzipWriter)
+	for name, contents := range files {
+		assert.Nil(t, tarWriter.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     name,
+			Mode:     0644,
+			Size:     int64(len(contents)),
+		}))
+		_, err := tarWriter.Write([]byte(contents))
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, tarWriter.Close())
+	assert.Nil(t, gzipWriter.Close())
+	return buf.Bytes()
+}
+
+// npmRegistry returns a registry serving the given tarballs of
+// @example/example by version.
+func npmRegistry(t *testing.T, tarballs map[string][]byte) *httptest.Server {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for version, tarball := range tarballs {
+			switch r.URL.EscapedPath() {
+			case "/@example%2Fexample/" + version:
+				fmt.Fprintf(w, `{"dist":{"tarball":"%s/@example/example/-/example-%s.tgz"}}`, srv.URL, version)
+				return
+			case "/@example/example/-/example-" + version + ".tgz":
+				w.Write(tarball)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	}))
+	return srv
+}
+
+func TestNpmCloneCommand(t *testing.T) {
+	dir, err := os.MkdirTemp("", "")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	registry := npmRegistry(t, map[string][]byte{
+		"1.0.0": createNpmTarball(t, map[string]string{
+			"package/index.js":     "module.exports = 1;\n",
+			"package/package.json": `{"name":"@example/example","version":"1.0.0"}`,
+		}),
+		"2.0.0": createNpmTarball(t, map[string]string{
+			"package/index.js":      "module.exports = 2;\n",
+			"package/lib/a.js":      "exports.a = 2;\n",
+			"package/.git/config":   "[core]\n",
+			"package/../../evil.js": "evil",
+		}),
+	})
+	defer registry.Close()
+
+	connection := &schema.NpmPackagesConnection{Registry: registry.URL}
+	client, err := npm.NewClient(connection, http.DefaultClient)
+	assert.Nil(t, err)
+	s := NewNpmPackagesSyncer(connection, client)
+
+	bareGitDirectory := path.Join(dir, "git")
+	runCloneCommand := func(dependencies ...string) {
+		t.Helper()
+		connection.Dependencies = dependencies
+		cmd, err := s.CloneCommand(context.Background(), &vcs.URL{URL: url.URL{Path: exampleNpmPackageURL}}, bareGitDirectory)
+		assert.Nil(t, err)
+		assert.Nil(t, cmd.Run())
+	}
+
+	runCloneCommand("@example/example@1.0.0", "@example/example@0.0.1")
+	assertCommandOutput(t, exec.Command("git", "tag", "--list"), bareGitDirectory, "v1.0.0\n")
+	assertCommandOutput(t, exec.Command("git", "show", "v1.0.0:index.js"), bareGitDirectory, "module.exports = 1;\n")
+	assertCommandOutput(t, exec.Command("git", "log", "--format=%s", "latest"), bareGitDirectory, "@example/example@1.0.0\n")
+
+	runCloneCommand("@example/example@1.0.0", "@example/example@2.0.0")
+	assertCommandOutput(t, exec.Command("git", "tag", "--list"), bareGitDirectory, "v1.0.0\nv2.0.0\n")
+	assertCommandOutput(t, exec.Command("git", "show", "v2.0.0:lib/a.js"), bareGitDirectory, "exports.a = 2;\n")
+	// The .git directory and the file outside of the package are skipped.
+	assertCommandOutput(t, exec.Command("git", "ls-tree", "-r", "--name-only", "v2.0.0"), bareGitDirectory, "index.js\nlib/a.js\n")
+	if _, err := os.Stat(path.Join(dir, "evil.js")); !os.IsNotExist(err) {
+		t.Fatalf("expected evil.js not to be extracted outside of the repository, got %v", err)
+	}
+
+	runCloneCommand("@example/example@1.0.0")
+	assertCommandOutput(t, exec.Command("git", "tag", "--list"), bareGitDirectory, "v1.0.0\n")
+
+	connection.Dependencies = []string{"@example/example@0.0.1"}
+	err = s.IsCloneable(context.Background(), &vcs.URL{URL: url.URL{Path: exampleNpmPackageURL}})
+	if err == nil || !strings.Contains(err.Error(), "no npm dependencies") {
+		t.Fatalf("expected no dependencies error, got %v", err)
+	}
+}
@@ -0,0 +1,279 @@
+package server
+
+import (
+	"archive/tar"
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/inconshreveable/log15"
+
+	"github.com/sourcegraph/sourcegraph/internal/conf/reposource"
+	"github.com/sourcegraph/sourcegraph/internal/vcs"
+)
+
+// packagesSource is a package registry which a packagesSyncer mirrors.
+type packagesSource interface {
+	// dependencies returns the versions to mirror of the package of the
+	// repository with the given URL path, the latest version first.
+	dependencies(ctx context.Context, repoURLPath string) ([]reposource.PackageDependency, error)
+	// download writes the files of the given version to the empty directory
+	// dir.
+	download(ctx context.Context, dir string, dependency reposource.PackageDependency) error
+}
+
+// packagesSyncer is a VCSSyncer for the packages of a package registry. Each
+// package is mirrored as a git repository with a tag per version, whose commit
+// adds the files of that version. The main branch points to the latest
+// version.
+type packagesSyncer struct {
+	typ    string
+	source packagesSource
+
+	// placeholder is used to set GIT_AUTHOR_NAME for git commands that don't
+	// create commits or tags. It should never be publicly visible.
+	placeholder reposource.PackageDependency
+}
+
+var _ VCSSyncer = &packagesSyncer{}
+
+func (s *packagesSyncer) Type() string {
+	return s.typ
+}
+
+// IsCloneable checks to see if the VCS remote URL is cloneable. Any non-nil
+// error indicates there is a problem.
+func (s *packagesSyncer) IsCloneable(ctx context.Context, remoteURL *vcs.URL) error {
+	_, err := s.source.dependencies(ctx, remoteURL.Path)
+	return err
+}
+
+// CloneCommand returns the command to be executed for cloning from remote.
+// Like for JVM packages, the actual cloning happens inside this method and the
+// returned command is a no-op.
+func (s *packagesSyncer) CloneCommand(ctx context.Context, remoteURL *vcs.URL, bareGitDirectory string) (*exec.Cmd, error) {
+	err := os.MkdirAll(bareGitDirectory, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.CommandContext(ctx, "git", "--bare", "init")
+	if _, err := runCommandInDirectory(ctx, cmd, bareGitDirectory, s.placeholder); err != nil {
+		return nil, err
+	}
+
+	// The Fetch method is responsible for cleaning up temporary directories.
+	if err := s.Fetch(ctx, remoteURL, GitDir(bareGitDirectory)); err != nil {
+		return nil, err
+	}
+
+	// no-op command to satisfy VCSSyncer interface, see docstring for more details.
+	return exec.CommandContext(ctx, "git", "--version"), nil
+}
+
+// Fetch adds git tags for newly added versions and removes git tags for
+// deleted versions.
+func (s *packagesSyncer) Fetch(ctx context.Context, remoteURL *vcs.URL, dir GitDir) error {
+	dependencies, err := s.source.dependencies(ctx, remoteURL.Path)
+	if err != nil {
+		return err
+	}
+
+	out, err := runCommandInDirectory(ctx, exec.CommandContext(ctx, "git", "tag"), string(dir), s.placeholder)
+	if err != nil {
+		return err
+	}
+
+	tags := map[string]bool{}
+	for _, line := range strings.Split(out, "\n") {
+		if len(line) == 0 {
+			continue
+		}
+		tags[line] = true
+	}
+
+	for i, dependency := range dependencies {
+		if tags[dependency.GitTagFromVersion()] {
+			continue
+		}
+		// the gitPushDependencyTag method is reponsible for cleaning up temporary directories.
+		if err := s.gitPushDependencyTag(ctx, string(dir), dependency, i == 0); err != nil {
+			return errors.Wrapf(err, "error pushing dependency %q", dependency.PackageManagerSyntax())
+		}
+	}
+
+	dependencyTags := make(map[string]struct{}, len(dependencies))
+	for _, dependency := range dependencies {
+		dependencyTags[dependency.GitTagFromVersion()] = struct{}{}
+	}
+
+	for tag := range tags {
+		if _, isDependencyTag := dependencyTags[tag]; !isDependencyTag {
+			cmd := exec.CommandContext(ctx, "git", "tag", "-d", tag)
+			if _, err := runCommandInDirectory(ctx, cmd, string(dir), s.placeholder); err != nil {
+				log15.Error("Failed to delete git tag", "error", err, "tag", tag)
+				continue
+			}
+		}
+	}
+
+	return nil
+}
+
+// RemoteShowCommand returns the command to be executed for showing remote.
+func (s *packagesSyncer) RemoteShowCommand(ctx context.Context, remoteURL *vcs.URL) (cmd *exec.Cmd, err error) {
+	return exec.CommandContext(ctx, "git", "remote", "show", "./"), nil
+}
+
+// gitPushDependencyTag pushes a git tag to the given bareGitDirectory path. The
+// tag points to a commit that adds all files of given dependency. When
+// isLatestVersion is true, the main branch of the bare git directory will also
+// be updated to point to the same commit as the git tag.
+func (s *packagesSyncer) gitPushDependencyTag(ctx context.Context, bareGitDirectory string, dependency reposource.PackageDependency, isLatestVersion bool) error {
+	tmpDirectory, err := os.MkdirTemp("", s.typ)
+	if err != nil {
+		return err
+	}
+	// Always clean up created temporary directories.
+	defer os.RemoveAll(tmpDirectory)
+
+	cmd := exec.CommandContext(ctx, "git", "init")
+	if _, err := runCommandInDirectory(ctx, cmd, tmpDirectory, dependency); err != nil {
+		return err
+	}
+
+	if err := s.commitDependency(ctx, tmpDirectory, dependency); err != nil {
+		return err
+	}
+
+	cmd = exec.CommandContext(ctx, "git", "remote", "add", "origin", bareGitDirectory)
+	if _, err := runCommandInDirectory(ctx, cmd, tmpDirectory, dependency); err != nil {
+		return err
+	}
+
+	// Use --no-verify for security reasons. See https://github.com/sourcegraph/sourcegraph/pull/23399
+	cmd = exec.CommandContext(ctx, "git", "push", "--no-verify", "--force", "origin", "--tags")
+	if _, err := runCommandInDirectory(ctx, cmd, tmpDirectory, dependency); err != nil {
+		return err
+	}
+
+	if isLatestVersion {
+		defaultBranch, err := runCommandInDirectory(ctx, exec.CommandContext(ctx, "git", "rev-parse", "--abbrev-ref", "HEAD"), tmpDirectory, dependency)
+		if err != nil {
+			return err
+		}
+		// Use --no-verify for security reasons. See https://github.com/sourcegraph/sourcegraph/pull/23399
+		cmd = exec.CommandContext(ctx, "git", "push", "--no-verify", "--force", "origin", strings.TrimSpace(defaultBranch)+":latest", dependency.GitTagFromVersion())
+		if _, err := runCommandInDirectory(ctx, cmd, tmpDirectory, dependency); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// commitDependency creates a git commit and tag in the given working directory
+// that adds all the files of the given dependency.
+func (s *packagesSyncer) commitDependency(ctx context.Context, workingDirectory string, dependency reposource.PackageDependency) error {
+	if err := s.source.download(ctx, workingDirectory, dependency); err != nil {
+		return errors.Wrapf(err, "failed to download %s", dependency.PackageManagerSyntax())
+	}
+
+	cmd := exec.CommandContext(ctx, "git", "add", ".")
+	if _, err := runCommandInDirectory(ctx, cmd, workingDirectory, dependency); err != nil {
+		return err
+	}
+
+	// Use --no-verify for security reasons. See https://github.com/sourcegraph/sourcegraph/pull/23399
+	cmd = exec.CommandContext(ctx, "git", "commit", "--no-verify", "--allow-empty", "-m", dependency.PackageManagerSyntax(), "--date", stableGitCommitDate)
+	if _, err := runCommandInDirectory(ctx, cmd, workingDirectory, dependency); err != nil {
+		return err
+	}
+
+	cmd = exec.CommandContext(ctx, "git", "tag", "-m", dependency.PackageManagerSyntax(), dependency.GitTagFromVersion())
+	if _, err := runCommandInDirectory(ctx, cmd, workingDirectory, dependency); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// extractTarball extracts the regular files of an uncompressed package
+// tarball into dir. The files of a package are in a single top-level
+// directory, like "package/" for npm packages, which is stripped.
+func extractTarball(tarball io.Reader, dir string) error {
+	tarReader := tar.NewReader(tarball)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "failed to read tar")
+		}
+		if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA {
+			// Skip directories, which we create as needed, as well as
+			// links, which could point outside of dir.
+			continue
+		}
+
+		// Cleaning the rooted name removes any "..", see "Zip Slip
+		// Vulnerability".
+		name := strings.TrimPrefix(path.Clean("/"+header.Name), "/")
+		i := strings.Index(name, "/")
+		if i < 0 {
+			continue
+		}
+		name = name[i+1:]
+		if isGitPath(name) {
+			// For security reasons, don't extract files under `.git/`
+			// directories. See https://github.com/sourcegraph/security-issues/issues/163
+			log15.Warn("Skipping .git file in package tarball", "name", header.Name)
+			continue
+		}
+
+		if err := copyTarFileEntry(tarReader, header, filepath.Join(dir, filepath.FromSlash(name))); err != nil {
+			return err
+		}
+	}
+}
+
+// isGitPath returns whether any component of the slash-separated path name
+// is a .git directory.
+func isGitPath(name string) bool {
+	for _, component := range strings.Split(name, "/") {
+		if strings.EqualFold(component, ".git") {
+			return true
+		}
+	}
+	return false
+}
+
+func copyTarFileEntry(reader io.Reader, header *tar.Header, outputPath string) (err error) {
+	if err = os.MkdirAll(filepath.Dir(outputPath), 0700); err != nil {
+		return err
+	}
+	// git only tracks whether files are executable.
+	var mode os.FileMode = 0600
+	if header.Mode&0111 != 0 {
+		mode = 0700
+	}
+	outputFile, err := os.OpenFile(outputPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err1 := outputFile.Close()
+		if err == nil {
+			err = err1
+		}
+	}()
+
+	_, err = io.Copy(outputFile, reader)
+	return err
+}
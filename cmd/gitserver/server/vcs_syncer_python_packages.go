@@ -0,0 +1,187 @@
+package server
+
+import (
+	"archive/zip"
+	"compress/bzip2"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/inconshreveable/log15"
+
+	"github.com/sourcegraph/sourcegraph/internal/conf/reposource"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/pythonpackages/pypi"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+// placeholderPythonDependency is used to set GIT_AUTHOR_NAME for git commands
+// that don't create commits or tags.
+var placeholderPythonDependency = reposource.PythonDependency{
+	PythonPackage: reposource.PythonPackage{Name: "sourcegraph"},
+	Version:       "1.0.0",
+}
+
+// NewPythonPackagesSyncer returns a VCSSyncer which mirrors the package
+// versions configured in the given connection from the Python package indexes
+// of client.
+func NewPythonPackagesSyncer(connection *schema.PythonPackagesConnection, client *pypi.Client) VCSSyncer {
+	return &packagesSyncer{
+		typ:         "python_packages",
+		source:      &pythonPackagesSource{connection: connection, client: client},
+		placeholder: placeholderPythonDependency,
+	}
+}
+
+type pythonPackagesSource struct {
+	connection *schema.PythonPackagesConnection
+	client     *pypi.Client
+}
+
+// dependencies returns the configured versions of the Python package of the
+// given URL path which exist in the indexes.
+func (s *pythonPackagesSource) dependencies(ctx context.Context, repoURLPath string) ([]reposource.PackageDependency, error) {
+	pkg, err := reposource.ParsePythonPackageFromRepoURL(repoURLPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var dependencies []reposource.PythonDependency
+	for _, dependency := range s.connection.Dependencies {
+		if !pkg.MatchesDependencyString(dependency) {
+			continue
+		}
+		dependency, err := reposource.ParsePythonDependency(dependency)
+		if err != nil {
+			return nil, err
+		}
+		exists, err := s.client.Exists(ctx, dependency)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			// Silently ignore non-existent dependencies because they are
+			// already logged in the `GetRepo` method in
+			// internal/repos/python_packages.go.
+			continue
+		}
+		dependencies = append(dependencies, dependency)
+	}
+
+	if len(dependencies) == 0 {
+		return nil, errors.Errorf("no Python dependencies for URL path %s", repoURLPath)
+	}
+
+	reposource.SortPythonDependencies(dependencies)
+	packageDependencies := make([]reposource.PackageDependency, 0, len(dependencies))
+	for _, dependency := range dependencies {
+		packageDependencies = append(packageDependencies, dependency)
+	}
+	return packageDependencies, nil
+}
+
+// download extracts the source distribution of the given version into dir,
+// or a wheel if the version has no source distribution.
+func (s *pythonPackagesSource) download(ctx context.Context, dir string, dependency reposource.PackageDependency) error {
+	f, err := s.client.Version(ctx, dependency.(reposource.PythonDependency))
+	if err != nil {
+		return err
+	}
+	body, err := s.client.Download(ctx, f)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	var tarball io.Reader
+	switch {
+	case strings.HasSuffix(f.Name, ".tar.gz"), strings.HasSuffix(f.Name, ".tgz"):
+		gzipReader, err := gzip.NewReader(body)
+		if err != nil {
+			return errors.Wrap(err, "failed to read gzip")
+		}
+		defer gzipReader.Close()
+		tarball = gzipReader
+	case strings.HasSuffix(f.Name, ".tar.bz2"):
+		tarball = bzip2.NewReader(body)
+	case strings.HasSuffix(f.Name, ".tar"):
+		tarball = body
+	case strings.HasSuffix(f.Name, ".zip"), f.IsWheel():
+	default:
+		// The client only returns the formats we handle above.
+		return errors.Errorf("unsupported Python distribution format: %s", f.Name)
+	}
+	if tarball != nil {
+		if err := extractTarball(tarball, dir); err != nil {
+			return err
+		}
+		// Read the rest of the body, so that its digest is verified.
+		_, err := io.Copy(io.Discard, body)
+		return err
+	}
+
+	// Reading a zip requires random access, so that we first save it to a
+	// temporary file.
+	zipFile, err := os.CreateTemp("", "python-package-*.zip")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(zipFile.Name())
+	_, err = io.Copy(zipFile, body)
+	if err1 := zipFile.Close(); err == nil {
+		err = err1
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to save %s", f.Name)
+	}
+
+	// Like tarballs, zipped source distributions have a single top-level
+	// directory. The files of wheels are at the top level.
+	return extractPythonZip(zipFile.Name(), dir, f.IsSourceDistribution())
+}
+
+// extractPythonZip extracts the files of the zip at zipPath into dir. When
+// stripFirstComponent is true, the top-level directory is stripped.
+func extractPythonZip(zipPath, dir string, stripFirstComponent bool) error {
+	reader, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return errors.Wrap(err, "failed to read zip")
+	}
+	defer reader.Close()
+
+	for _, entry := range reader.File {
+		if entry.FileInfo().IsDir() {
+			continue
+		}
+
+		// Cleaning the rooted name removes any "..", see "Zip Slip
+		// Vulnerability".
+		name := strings.TrimPrefix(path.Clean("/"+entry.Name), "/")
+		if name != entry.Name {
+			// copyZipFileEntry only opens valid names.
+			continue
+		}
+		if stripFirstComponent {
+			i := strings.Index(name, "/")
+			if i < 0 {
+				continue
+			}
+			name = name[i+1:]
+		}
+		if isGitPath(name) {
+			// For security reasons, don't extract files under `.git/`
+			// directories. See https://github.com/sourcegraph/security-issues/issues/163
+			log15.Warn("Skipping .git file in Python package zip", "name", entry.Name)
+			continue
+		}
+
+		if err := copyZipFileEntry(reader, entry, filepath.Join(dir, filepath.FromSlash(name))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/pythonpackages/pypi"
+	"github.com/sourcegraph/sourcegraph/internal/vcs"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+const examplePythonPackageURL = "python/example-package"
+
+// pythonPackageIndex returns a simple index serving the given distribution
+// files of example-package by file name.
+func pythonPackageIndex(t *testing.T, files map[string][]byte) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/example-package/" {
+			for name := range files {
+				fmt.Fprintf(w, "<a href=\"/files/%s\">%s</a>\n", name, name)
+			}
+			return
+		}
+		if contents, ok := files[strings.TrimPrefix(r.URL.Path, "/files/")]; ok {
+			w.Write(contents)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+}
+
+func TestPythonPackagesCloneCommand(t *testing.T) {
+	dir, err := os.MkdirTemp("", "")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	index := pythonPackageIndex(t, map[string][]byte{
+		// Source distributions are preferred over wheels.
+		"example_package-1.0.0-py3-none-any.whl": createZip(t, map[string]string{
+			"example_package/__init__.py": "wheel = True\n",
+		}),
+		"example-package-1.0.0.tar.gz": createNpmTarball(t, map[string]string{
+			"example-package-1.0.0/setup.py":                    "setup()\n",
+			"example-package-1.0.0/example_package/__init__.py": "version = 1\n",
+		}),
+		// We can't extract .tar.xz source distributions, so we use the
+		// wheel.
+		"example-package-2.0.0.tar.xz": []byte("not extracted"),
+		"example_package-2.0.0-py3-none-any.whl": createZip(t, map[string]string{
+			"example_package/__init__.py":                   "version = 2\n",
+			"example_package-2.0.0.dist-info/METADATA":      "Name: example-package\n",
+			"example_package/.git/config":                   "[core]\n",
+			"example_package/../../example_package/evil.py": "evil\n",
+		}),
+	})
+	defer index.Close()
+
+	connection := &schema.PythonPackagesConnection{Urls: []string{index.URL}}
+	client, err := pypi.NewClient(connection, http.DefaultClient)
+	assert.Nil(t, err)
+	s := NewPythonPackagesSyncer(connection, client)
+
+	bareGitDirectory := path.Join(dir, "git")
+	runCloneCommand := func(dependencies ...string) {
+		t.Helper()
+		connection.Dependencies = dependencies
+		cmd, err := s.CloneCommand(context.Background(), &vcs.URL{URL: url.URL{Path: examplePythonPackageURL}}, bareGitDirectory)
+		assert.Nil(t, err)
+		assert.Nil(t, cmd.Run())
+	}
+
+	runCloneCommand("example-package==1.0.0", "example-package==0.0.1")
+	assertCommandOutput(t, exec.Command("git", "tag", "--list"), bareGitDirectory, "v1.0.0\n")
+	assertCommandOutput(t, exec.Command("git", "show", "v1.0.0:example_package/__init__.py"), bareGitDirectory, "version = 1\n")
+	assertCommandOutput(t, exec.Command("git", "log", "--format=%s", "latest"), bareGitDirectory, "example-package==1.0.0\n")
+
+	runCloneCommand("example-package==1.0.0", "Example_Package==2.0.0")
+	assertCommandOutput(t, exec.Command("git", "tag", "--list"), bareGitDirectory, "v1.0.0\nv2.0.0\n")
+	assertCommandOutput(t, exec.Command("git", "log", "--format=%s", "latest"), bareGitDirectory, "example-package==2.0.0\n")
+	// The .git directory and the file with ".." in its name are skipped.
+	assertCommandOutput(t, exec.Command("git", "ls-tree", "-r", "--name-only", "v2.0.0"), bareGitDirectory, "example_package-2.0.0.dist-info/METADATA\nexample_package/__init__.py\n")
+
+	runCloneCommand("example-package==2.0.0")
+	assertCommandOutput(t, exec.Command("git", "tag", "--list"), bareGitDirectory, "v2.0.0\n")
+
+	connection.Dependencies = []string{"example-package==0.0.1"}
+	err = s.IsCloneable(context.Background(), &vcs.URL{URL: url.URL{Path: examplePythonPackageURL}})
+	if err == nil || !strings.Contains(err.Error(), "no Python dependencies") {
+		t.Fatalf("expected no dependencies error, got %v", err)
+	}
+}
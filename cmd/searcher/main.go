@@ -32,6 +32,7 @@ import (
 
 var cacheDir = env.Get("CACHE_DIR", "/tmp", "directory to store cached archives.")
 var cacheSizeMB = env.Get("SEARCHER_CACHE_SIZE_MB", "100000", "maximum size of the on disk cache in megabytes")
+var streamArchives, _ = strconv.ParseBool(env.Get("SEARCHER_STREAM_ARCHIVES", "false", "search the files of archives while fetching them from gitserver, instead of waiting for the whole archive to be cached"))
 
 const port = "3181"
 
@@ -59,14 +60,17 @@ func main() {
 
 	service := &search.Service{
 		Store: &store.Store{
-			FetchTar: func(ctx context.Context, repo api.RepoName, commit api.CommitID) (io.ReadCloser, error) {
-				return gitserver.DefaultClient.Archive(ctx, repo, gitserver.ArchiveOptions{Treeish: string(commit), Format: "tar"})
+			FetchTar: func(ctx context.Context, repo api.RepoName, commit api.CommitID, paths []string) (io.ReadCloser, error) {
+				// The paths are pathspecs of the file: filters of a search,
+				// which may match no files.
+				return gitserver.DefaultClient.Archive(ctx, repo, gitserver.ArchiveOptions{Treeish: string(commit), Format: "tar", Paths: paths, AllowUnmatchedPaths: true})
 			},
 			FilterTar:         search.NewFilter,
 			Path:              filepath.Join(cacheDir, "searcher-archives"),
 			MaxCacheSizeBytes: cacheSizeBytes,
 		},
-		Log: log15.Root(),
+		Log:            log15.Root(),
+		StreamArchives: streamArchives,
 	}
 	service.Store.Start()
 
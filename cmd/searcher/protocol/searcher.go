@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/search/proximity"
 )
 
 // Request represents a request to searcher
@@ -131,6 +132,11 @@ type PatternInfo struct {
 	// use it since selection is done after the query completes, but exposing it can enable
 	// optimizations.
 	Select string
+
+	// Proximity is set for NEAR/n and BEFORE/n expressions. Pattern is then a
+	// regular expression matching candidate lines, and only files with lines
+	// matching Proximity are returned.
+	Proximity *proximity.Pattern
 }
 
 func (p *PatternInfo) String() string {
@@ -166,6 +172,9 @@ func (p *PatternInfo) String() string {
 	if p.Select != "" {
 		args = append(args, fmt.Sprintf("select:%s", p.Select))
 	}
+	if p.Proximity != nil {
+		args = append(args, fmt.Sprintf("proximity:%d", p.Proximity.Distance))
+	}
 
 	path := "glob"
 	if p.PathPatternsAreRegExps {
@@ -4,10 +4,12 @@ import (
 	"archive/tar"
 	"bytes"
 	"context"
+	"regexp/syntax"
 	"strings"
 
 	"github.com/google/zoekt/ignore"
 
+	"github.com/sourcegraph/sourcegraph/cmd/searcher/protocol"
 	"github.com/sourcegraph/sourcegraph/internal/api"
 	"github.com/sourcegraph/sourcegraph/internal/store"
 	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
@@ -43,3 +45,231 @@ func newIgnoreMatcher(ctx context.Context, repo api.RepoName, commit api.CommitI
 	}
 	return ignore.ParseIgnoreFile(bytes.NewReader(ignoreFile))
 }
+
+// maxPathspecs is the maximum number of pathspecs we push down for a pattern.
+// Above it we fetch the whole archive and filter the paths ourselves.
+const maxPathspecs = 16
+
+// gitPathspecs returns git pathspecs which match a superset of the paths
+// matching the path patterns of p, so that gitserver only archives the files
+// we may search. For example, "file:^docs/" becomes ":(top)docs/*". It
+// returns nil if it can't narrow down the paths, in which case we fetch the
+// whole archive.
+func gitPathspecs(p *protocol.PatternInfo) []string {
+	if !p.PathPatternsAreRegExps {
+		return nil
+	}
+
+	var pathspecs []string
+
+	// A path must match all include patterns, so we only need to push down
+	// one of them. We pick the one which narrows down the paths most. git
+	// archive fails if one of its pathspecs matches no files, so we push down
+	// the common prefix of its branches as a single pathspec. Then nothing
+	// matches the pattern if the archive fails.
+	var (
+		include     string
+		includeFold bool
+	)
+	for _, pattern := range p.IncludePatterns {
+		prefixes, ok := regexpPrefixes(pattern)
+		if !ok {
+			continue
+		}
+		if prefix, fold := commonPrefix(prefixes); len(prefix) > len(include) {
+			include, includeFold = prefix, fold
+		}
+	}
+	if include != "" {
+		magic := ":(top)"
+		if includeFold || !p.PathPatternsAreCaseSensitive {
+			magic = ":(top,icase)"
+		}
+		pathspecs = append(pathspecs, magic+escapePathspec(include)+"*")
+	}
+
+	// We can only exclude paths which the exclude pattern matches entirely,
+	// e.g. "^vendor/" but not "^vendor/.*_test\.go$". Excluding them case
+	// sensitively is correct even if the pattern isn't, since we still filter
+	// the paths ourselves. An exclude pathspec only matches no files if it
+	// excludes all files.
+	if p.ExcludePattern != "" {
+		if prefixes, ok := regexpPrefixes(p.ExcludePattern); ok && allExact(prefixes) {
+			for _, prefix := range prefixes {
+				pathspecs = append(pathspecs, ":(top,exclude)"+escapePathspec(prefix.literal)+"*")
+			}
+		}
+	}
+
+	return pathspecs
+}
+
+// regexpPrefix is a literal prefix of all paths matching (a branch of) a
+// regular expression.
+type regexpPrefix struct {
+	literal string
+
+	// fold is whether the literal is matched case insensitively.
+	fold bool
+
+	// exact is whether the regular expression matches all paths starting with
+	// literal.
+	exact bool
+}
+
+// regexpPrefixes returns the literal prefixes which every path matching
+// pattern starts with, one per branch of the pattern. It returns false if
+// pattern matches paths without a non-empty literal prefix.
+func regexpPrefixes(pattern string) ([]regexpPrefix, bool) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil, false
+	}
+	re = re.Simplify()
+
+	branches := []*syntax.Regexp{re}
+	if re.Op == syntax.OpAlternate {
+		branches = re.Sub
+	}
+
+	var prefixes []regexpPrefix
+	for _, branch := range branches {
+		if branch.Op != syntax.OpConcat || len(branch.Sub) < 2 || branch.Sub[0].Op != syntax.OpBeginText {
+			return nil, false
+		}
+		branchPrefixes := expandPrefixes(branch.Sub[1:])
+		if len(prefixes)+len(branchPrefixes) > maxPathspecs {
+			return nil, false
+		}
+		prefixes = append(prefixes, branchPrefixes...)
+	}
+	for _, prefix := range prefixes {
+		if prefix.literal == "" {
+			return nil, false
+		}
+	}
+	return prefixes, true
+}
+
+// expandPrefixes returns the literal prefixes of the concatenation subs. It
+// expands alternations and small character classes, e.g. "(a|b)c" has the
+// prefixes "ac" and "bc".
+func expandPrefixes(subs []*syntax.Regexp) []regexpPrefix {
+	prefixes := []regexpPrefix{{}}
+	for i, sub := range subs {
+		for sub.Op == syntax.OpCapture {
+			sub = sub.Sub[0]
+		}
+
+		var literals []string
+		fold := sub.Flags&syntax.FoldCase != 0
+		switch sub.Op {
+		case syntax.OpLiteral:
+			literals = []string{string(sub.Rune)}
+		case syntax.OpCharClass:
+			literals = charClassLiterals(sub)
+		case syntax.OpAlternate:
+			for _, alt := range sub.Sub {
+				if alt.Op != syntax.OpLiteral {
+					literals = nil
+					break
+				}
+				literals = append(literals, string(alt.Rune))
+				fold = fold || alt.Flags&syntax.FoldCase != 0
+			}
+		}
+
+		if len(literals) == 0 || len(prefixes)*len(literals) > maxPathspecs {
+			// The prefixes end here. They match exactly if the rest of the
+			// pattern matches any path.
+			exact := i == len(subs)-1 && matchesAnything(sub)
+			for j := range prefixes {
+				prefixes[j].exact = exact
+			}
+			return prefixes
+		}
+
+		expanded := make([]regexpPrefix, 0, len(prefixes)*len(literals))
+		for _, prefix := range prefixes {
+			for _, literal := range literals {
+				expanded = append(expanded, regexpPrefix{
+					literal: prefix.literal + literal,
+					fold:    prefix.fold || fold,
+				})
+			}
+		}
+		prefixes = expanded
+	}
+
+	for j := range prefixes {
+		prefixes[j].exact = true
+	}
+	return prefixes
+}
+
+// charClassLiterals returns the runes of a small character class as
+// literals, or nil if the class is too large.
+func charClassLiterals(re *syntax.Regexp) []string {
+	var literals []string
+	for i := 0; i+1 < len(re.Rune); i += 2 {
+		for r := re.Rune[i]; r <= re.Rune[i+1]; r++ {
+			if len(literals) == maxPathspecs {
+				return nil
+			}
+			literals = append(literals, string(r))
+		}
+	}
+	return literals
+}
+
+// matchesAnything returns whether re matches any path, e.g. ".*".
+func matchesAnything(re *syntax.Regexp) bool {
+	if re.Op != syntax.OpStar {
+		return false
+	}
+	op := re.Sub[0].Op
+	// Paths never contain newlines.
+	return op == syntax.OpAnyChar || op == syntax.OpAnyCharNotNL
+}
+
+// commonPrefix returns the longest common prefix of prefixes, and whether it
+// is matched case insensitively.
+func commonPrefix(prefixes []regexpPrefix) (string, bool) {
+	if len(prefixes) == 0 {
+		return "", false
+	}
+	common := []rune(prefixes[0].literal)
+	fold := false
+	for _, prefix := range prefixes {
+		literal := []rune(prefix.literal)
+		n := 0
+		for n < len(common) && n < len(literal) && common[n] == literal[n] {
+			n++
+		}
+		common = common[:n]
+		fold = fold || prefix.fold
+	}
+	return string(common), fold
+}
+
+func allExact(prefixes []regexpPrefix) bool {
+	for _, prefix := range prefixes {
+		if !prefix.exact {
+			return false
+		}
+	}
+	return true
+}
+
+// escapePathspec escapes the wildcards of git pathspecs in literal.
+func escapePathspec(literal string) string {
+	var b strings.Builder
+	for _, r := range literal {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
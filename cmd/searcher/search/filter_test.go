@@ -8,6 +8,7 @@ import (
 	"github.com/cockroachdb/errors"
 	"github.com/google/zoekt/ignore"
 
+	"github.com/sourcegraph/sourcegraph/cmd/searcher/protocol"
 	"github.com/sourcegraph/sourcegraph/internal/api"
 	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
 )
@@ -41,3 +42,68 @@ func TestMissingIgnoreFile(t *testing.T) {
 		t.Error("newIgnoreMatchers should have returned &ignore.Matcher{} if the ignore-file is missing")
 	}
 }
+
+func TestGitPathspecs(t *testing.T) {
+	cases := []struct {
+		name string
+		p    protocol.PatternInfo
+		want []string
+	}{{
+		name: "prefix",
+		p:    protocol.PatternInfo{IncludePatterns: []string{"^docs/"}, PathPatternsAreCaseSensitive: true},
+		want: []string{":(top)docs/*"},
+	}, {
+		name: "case insensitive",
+		p:    protocol.PatternInfo{IncludePatterns: []string{"^docs/"}},
+		want: []string{":(top,icase)docs/*"},
+	}, {
+		name: "longest prefix",
+		p:    protocol.PatternInfo{IncludePatterns: []string{`\.go$`, "^cmd/", "^cmd/searcher/"}, PathPatternsAreCaseSensitive: true},
+		want: []string{":(top)cmd/searcher/*"},
+	}, {
+		name: "alternation",
+		p:    protocol.PatternInfo{IncludePatterns: []string{"^doc/(a|b)/.*\\.md$"}, PathPatternsAreCaseSensitive: true},
+		want: []string{":(top)doc/*"},
+	}, {
+		name: "alternation without common prefix",
+		p:    protocol.PatternInfo{IncludePatterns: []string{"^(docs|src)/"}, PathPatternsAreCaseSensitive: true},
+		want: nil,
+	}, {
+		name: "wildcards",
+		p:    protocol.PatternInfo{IncludePatterns: []string{`^a\*b\[`}, PathPatternsAreCaseSensitive: true},
+		want: []string{`:(top)a\*b\[*`},
+	}, {
+		name: "unanchored",
+		p:    protocol.PatternInfo{IncludePatterns: []string{"docs/"}},
+		want: nil,
+	}, {
+		name: "unanchored branch",
+		p:    protocol.PatternInfo{IncludePatterns: []string{"^docs/|README"}},
+		want: nil,
+	}, {
+		name: "exclude",
+		p:    protocol.PatternInfo{ExcludePattern: "^vendor/.*", PathPatternsAreCaseSensitive: true},
+		want: []string{":(top,exclude)vendor/*"},
+	}, {
+		name: "inexact exclude",
+		p:    protocol.PatternInfo{ExcludePattern: `^vendor/.*_test\.go$`, PathPatternsAreCaseSensitive: true},
+		want: nil,
+	}, {
+		name: "include and exclude",
+		p:    protocol.PatternInfo{IncludePatterns: []string{"^docs/"}, ExcludePattern: "^docs/(a|b)/", PathPatternsAreCaseSensitive: true},
+		want: []string{":(top)docs/*", ":(top,exclude)docs/a/*", ":(top,exclude)docs/b/*"},
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.p.PathPatternsAreRegExps = true
+			got := gitPathspecs(&tc.p)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+
+	if got := gitPathspecs(&protocol.PatternInfo{IncludePatterns: []string{"docs/**"}}); got != nil {
+		t.Errorf("expected no pathspecs for globs, got %q", got)
+	}
+}
@@ -56,6 +56,11 @@ const (
 type Service struct {
 	Store *store.Store
 	Log   log15.Logger
+
+	// StreamArchives, when true, makes regexp searches of archives which
+	// are not cached yet search the files of the archive while it is
+	// fetched, instead of waiting for the whole archive to be cached.
+	StreamArchives bool
 }
 
 // ServeHTTP handles HTTP based search requests
@@ -251,6 +256,16 @@ func (s *Service) search(ctx context.Context, p *protocol.Request, sender matchS
 		}
 	}
 
+	if s.StreamArchives && !p.IsStructuralPat {
+		// Matches are sent while the archive is fetched, so we don't
+		// enforce the fetch timeout.
+		streamed, err := s.streamingRegexSearch(ctx, p, rg, sender)
+		if streamed || err != nil {
+			return false, err
+		}
+		// The archive is cached, so we search it below.
+	}
+
 	if p.FetchTimeout == "" {
 		p.FetchTimeout = "500ms"
 	}
@@ -262,7 +277,7 @@ func (s *Service) search(ctx context.Context, p *protocol.Request, sender matchS
 	defer cancel()
 
 	getZf := func() (string, *store.ZipFile, error) {
-		path, err := s.Store.PrepareZip(prepareCtx, p.Repo, p.Commit)
+		path, err := s.Store.PrepareZip(prepareCtx, p.Repo, p.Commit, gitPathspecs(&p.PatternInfo))
 		if err != nil {
 			return "", nil, err
 		}
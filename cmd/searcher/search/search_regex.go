@@ -20,6 +20,7 @@ import (
 
 	"github.com/sourcegraph/sourcegraph/cmd/searcher/protocol"
 	"github.com/sourcegraph/sourcegraph/internal/pathmatch"
+	"github.com/sourcegraph/sourcegraph/internal/search/proximity"
 	"github.com/sourcegraph/sourcegraph/internal/store"
 	"github.com/sourcegraph/sourcegraph/internal/trace/ot"
 )
@@ -62,6 +63,10 @@ type readerGrep struct {
 	// re. It is the output of the longestLiteral function. It is only set if
 	// the regex has an empty LiteralPrefix.
 	literalSubstring []byte
+
+	// proximity, if set, filters the lines matched by re to those which are
+	// part of a match of a NEAR/n or BEFORE/n expression.
+	proximity *proximity.Matcher
 }
 
 // compile returns a readerGrep for matching p.
@@ -122,11 +127,20 @@ func compile(p *protocol.PatternInfo) (*readerGrep, error) {
 		return nil, err
 	}
 
+	var prox *proximity.Matcher
+	if p.Proximity != nil {
+		prox, err = proximity.Compile(p.Proximity)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &readerGrep{
 		re:               re,
 		ignoreCase:       !p.IsCaseSensitive,
 		matchPath:        matchPath,
 		literalSubstring: literalSubstring,
+		proximity:        prox,
 	}, nil
 }
 
@@ -138,6 +152,7 @@ func (rg *readerGrep) Copy() *readerGrep {
 		ignoreCase:       rg.ignoreCase,
 		matchPath:        rg.matchPath,
 		literalSubstring: rg.literalSubstring,
+		proximity:        rg.proximity,
 	}
 }
 
@@ -155,6 +170,7 @@ func (rg *readerGrep) matchString(s string) bool {
 
 // Find returns a LineMatch for each line that matches rg in reader.
 // LimitHit is true if some matches may not have been included in the result.
+// A negative limit returns all matches.
 // NOTE: This is not safe to use concurrently.
 func (rg *readerGrep) Find(zf *store.ZipFile, f *store.SrcFile, limit int) (matches []protocol.LineMatch, err error) {
 	// fileMatchBuf is what we run match on, fileBuf is the original
@@ -168,7 +184,8 @@ func (rg *readerGrep) Find(zf *store.ZipFile, f *store.SrcFile, limit int) (matc
 	// trade some correctness for perf by using a non-utf8 aware
 	// lowercase function.
 	if rg.ignoreCase {
-		if rg.transformBuf == nil {
+		if cap(rg.transformBuf) < len(fileBuf) {
+			// Files of streamed archives are each in their own zf.
 			rg.transformBuf = make([]byte, zf.MaxLen)
 		}
 		fileMatchBuf = rg.transformBuf[:len(fileBuf)]
@@ -187,7 +204,11 @@ func (rg *readerGrep) Find(zf *store.ZipFile, f *store.SrcFile, limit int) (matc
 	}
 
 	// find limit+1 matches so we know whether we hit the limit
-	locs := rg.re.FindAllIndex(fileMatchBuf, limit+1)
+	n := limit + 1
+	if limit < 0 {
+		n = -1
+	}
+	locs := rg.re.FindAllIndex(fileMatchBuf, n)
 	lastStart := 0
 	lastLineNumber := 0
 	lastMatchIndex := 0
@@ -287,7 +308,20 @@ func appendMatches(matches []protocol.LineMatch, fileBuf []byte, matchLineBuf []
 
 // FindZip is a convenience function to run Find on f.
 func (rg *readerGrep) FindZip(zf *store.ZipFile, f *store.SrcFile, limit int) (protocol.FileMatch, error) {
-	lm, err := rg.Find(zf, f, limit)
+	var lm []protocol.LineMatch
+	var err error
+	if rg.proximity == nil {
+		lm, err = rg.Find(zf, f, limit)
+	} else {
+		// The proximity filter needs all candidate lines of the file, so we
+		// apply the limit to the lines it keeps. Like Find, we keep one more
+		// line than the limit so that the caller knows it was hit.
+		lm, err = rg.Find(zf, f, -1)
+		lm = filterProximity(rg.proximity, lm)
+		if len(lm) > limit+1 {
+			lm = lm[:limit+1]
+		}
+	}
 	return protocol.FileMatch{
 		Path:        f.Name,
 		LineMatches: lm,
@@ -296,6 +330,24 @@ func (rg *readerGrep) FindZip(zf *store.ZipFile, f *store.SrcFile, limit int) (p
 	}, err
 }
 
+// filterProximity returns the line matches of lines which are part of a match
+// of m.
+func filterProximity(m *proximity.Matcher, lines []protocol.LineMatch) []protocol.LineMatch {
+	candidates := make([]proximity.Line, 0, len(lines))
+	for _, l := range lines {
+		candidates = append(candidates, proximity.Line{Number: l.LineNumber, Preview: l.Preview})
+	}
+	indexes := m.Filter(candidates)
+	if len(indexes) == 0 {
+		return nil
+	}
+	filtered := make([]protocol.LineMatch, 0, len(indexes))
+	for _, i := range indexes {
+		filtered = append(filtered, lines[i])
+	}
+	return filtered
+}
+
 func regexSearchBatch(ctx context.Context, rg *readerGrep, zf *store.ZipFile, limit int, patternMatchesContent, patternMatchesPaths bool, isPatternNegated bool) ([]protocol.FileMatch, bool, error) {
 	ctx, cancel, sender := newLimitedStreamCollector(ctx, limit)
 	defer cancel()
@@ -379,27 +431,14 @@ func regexSearch(ctx context.Context, rg *readerGrep, zf *store.ZipFile, limit i
 				filesmu.Unlock()
 
 				// decide whether to process, record that decision
-				if !rg.matchPath.MatchPath(f.Name) {
-					filesSkipped.Inc()
-					continue
-				}
-				filesSearched.Inc()
-
-				// process
-				fm, err := rg.FindZip(zf, f, sender.Remaining())
+				searched, err := rg.searchFile(zf, f, patternMatchesPaths, isPatternNegated, sender)
 				if err != nil {
 					return err
 				}
-				match := len(fm.LineMatches) > 0
-				if !match && patternMatchesPaths {
-					// Try matching against the file path.
-					match = rg.matchString(f.Name)
-					if match {
-						fm.Path = f.Name
-					}
-				}
-				if match == !isPatternNegated {
-					sender.Send(fm)
+				if searched {
+					filesSearched.Inc()
+				} else {
+					filesSkipped.Inc()
 				}
 			}
 			return nil
@@ -420,6 +459,31 @@ func regexSearch(ctx context.Context, rg *readerGrep, zf *store.ZipFile, limit i
 	return err
 }
 
+// searchFile sends the match of rg in f to sender, if any. It returns false
+// if f is excluded by the path patterns of rg.
+func (rg *readerGrep) searchFile(zf *store.ZipFile, f *store.SrcFile, patternMatchesPaths, isPatternNegated bool, sender matchSender) (bool, error) {
+	if !rg.matchPath.MatchPath(f.Name) {
+		return false, nil
+	}
+
+	fm, err := rg.FindZip(zf, f, sender.Remaining())
+	if err != nil {
+		return true, err
+	}
+	match := len(fm.LineMatches) > 0
+	if !match && patternMatchesPaths && rg.proximity == nil {
+		// Try matching against the file path.
+		match = rg.matchString(f.Name)
+		if match {
+			fm.Path = f.Name
+		}
+	}
+	if match == !isPatternNegated {
+		sender.Send(fm)
+	}
+	return true, nil
+}
+
 // lowerRegexpASCII lowers rune literals and expands char classes to include
 // lowercase. It does it inplace. We can't just use strings.ToLower since it
 // will change the meaning of regex shorthands like \S or \B.
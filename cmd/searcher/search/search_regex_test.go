@@ -16,6 +16,7 @@ import (
 
 	"github.com/sourcegraph/sourcegraph/cmd/searcher/protocol"
 	"github.com/sourcegraph/sourcegraph/internal/pathmatch"
+	"github.com/sourcegraph/sourcegraph/internal/search/proximity"
 	"github.com/sourcegraph/sourcegraph/internal/store"
 	"github.com/sourcegraph/sourcegraph/internal/testutil"
 )
@@ -249,7 +250,7 @@ func benchSearchRegex(b *testing.B, p *protocol.Request) {
 	}
 
 	ctx := context.Background()
-	path, err := githubStore.PrepareZip(ctx, p.Repo, p.Commit)
+	path, err := githubStore.PrepareZip(ctx, p.Repo, p.Commit, nil)
 	if err != nil {
 		b.Fatal(err)
 	}
@@ -475,6 +476,52 @@ func TestMaxMatches(t *testing.T) {
 	}
 }
 
+// Tests that the match limit applies to the lines kept by a proximity
+// expression, rather than to the candidate lines.
+func TestProximityLimit(t *testing.T) {
+	var content bytes.Buffer
+	for i := 0; i < 10; i++ {
+		content.WriteString("foo\n")
+	}
+	content.WriteString("bar\n")
+
+	zipData, err := testutil.CreateZip(map[string]string{"a": content.String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	zf, err := store.MockZipFile(zipData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prox := &proximity.Pattern{Patterns: []string{"foo", "bar"}, Distance: 1}
+	rg, err := compile(&protocol.PatternInfo{
+		Pattern:   prox.Regexp(),
+		IsRegExp:  true,
+		Proximity: prox,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileMatches, limitHit, err := regexSearchBatch(context.Background(), rg, zf, 3, true, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limitHit {
+		t.Fatal("unexpected limitHit")
+	}
+	if len(fileMatches) != 1 {
+		t.Fatalf("got %d file matches, want 1", len(fileMatches))
+	}
+	var lines []int
+	for _, lm := range fileMatches[0].LineMatches {
+		lines = append(lines, lm.LineNumber)
+	}
+	if want := []int{9, 10}; !reflect.DeepEqual(lines, want) || fileMatches[0].MatchCount != len(want) {
+		t.Fatalf("got lines %v and match count %d, want lines %v", lines, fileMatches[0].MatchCount, want)
+	}
+}
+
 // Tests that:
 //
 // - IncludePatterns can match the path in any order
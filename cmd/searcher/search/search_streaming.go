@@ -0,0 +1,136 @@
+package search
+
+import (
+	"context"
+
+	"github.com/cockroachdb/errors"
+	"github.com/opentracing/opentracing-go/ext"
+	otlog "github.com/opentracing/opentracing-go/log"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/sourcegraph/sourcegraph/cmd/searcher/protocol"
+	"github.com/sourcegraph/sourcegraph/internal/store"
+	"github.com/sourcegraph/sourcegraph/internal/trace/ot"
+)
+
+// streamingRegexSearch searches the archive of p.Repo at p.Commit with rg
+// while it is fetched from gitserver and written to the cache, so that we
+// send the first matches before we have the whole archive. Once sender hits
+// the limit, the fetch stops.
+//
+// It returns false if the archive is already cached, in which case callers
+// should search the cached archive instead.
+func (s *Service) streamingRegexSearch(ctx context.Context, p *protocol.Request, rg *readerGrep, sender matchSender) (streamed bool, err error) {
+	span, ctx := ot.StartSpanFromContext(ctx, "StreamingRegexSearch")
+	ext.Component.Set(span, "regex_search")
+	defer func() {
+		if err != nil {
+			ext.Error.Set(span, true)
+			span.SetTag("err", err.Error())
+		}
+		span.SetTag("streamed", streamed)
+		span.Finish()
+	}()
+
+	patternMatchesContent, patternMatchesPaths := p.PatternMatchesContent, p.PatternMatchesPath
+	if !patternMatchesContent && !patternMatchesPaths {
+		patternMatchesContent = true
+	}
+	pathsOnly := rg.re == nil || (patternMatchesPaths && !patternMatchesContent)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	g, ctx := errgroup.WithContext(ctx)
+
+	// StreamZip doesn't call onFile after it returns, so we close files
+	// then. Workers also stop when ctx is done.
+	files := make(chan *store.ZipFile, numWorkers)
+	for i := 0; i < numWorkers; i++ {
+		rg := rg.Copy()
+		g.Go(func() error {
+			for {
+				select {
+				case zf, ok := <-files:
+					if !ok || ctx.Err() != nil {
+						return nil
+					}
+					if _, err := rg.searchFile(zf, &zf.Files[0], patternMatchesPaths, p.IsNegated, sender); err != nil {
+						return err
+					}
+				case <-ctx.Done():
+					return nil
+				}
+			}
+		})
+	}
+
+	var nFiles, bytes int64
+	onFile := func(name string, content []byte) error {
+		// Don't send matches once the search is done.
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		nFiles++
+		bytes += int64(len(content))
+
+		if pathsOnly {
+			if match := rg.matchPath.MatchPath(name) && rg.matchString(name); match == !p.IsNegated {
+				sender.Send(protocol.FileMatch{Path: name, MatchCount: 1})
+			}
+			return nil
+		}
+
+		zf := &store.ZipFile{
+			Files:  []store.SrcFile{{Name: name, Len: int32(len(content))}},
+			MaxLen: len(content),
+			Data:   content,
+		}
+		select {
+		case files <- zf:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	path, streamed, err := s.Store.StreamZip(ctx, p.Repo, p.Commit, gitPathspecs(&p.PatternInfo), onFile)
+	if err == nil && streamed {
+		// Files above the size limit are not streamed. Search them in the
+		// cached archive.
+		var zf *store.ZipFile
+		zf, err = s.Store.ZipCache.Get(path)
+		if err == nil {
+			defer zf.Close()
+			for _, f := range zf.LargeFiles() {
+				if err = onFile(f.Name, zf.DataFor(f)); err != nil {
+					break
+				}
+			}
+		}
+	}
+	close(files)
+	if err != nil {
+		cancel()
+	}
+	if werr := g.Wait(); werr != nil {
+		err = werr
+	}
+
+	if sender.LimitHit() {
+		// We stopped the fetch ourselves.
+		return true, nil
+	}
+	if err != nil {
+		return true, errors.Wrap(err, "failed to stream archive")
+	}
+
+	if streamed {
+		span.LogFields(
+			otlog.Int64("archive.files", nFiles),
+			otlog.Int64("archive.size", bytes))
+		archiveFiles.Observe(float64(nFiles))
+		archiveSize.Observe(float64(bytes))
+	}
+	return streamed, nil
+}
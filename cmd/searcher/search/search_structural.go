@@ -1,12 +1,16 @@
 package search
 
 import (
+	"archive/zip"
 	"context"
+	"io"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/google/zoekt"
 	"github.com/inconshreveable/log15"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
@@ -14,6 +18,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/cmd/searcher/protocol"
 	"github.com/sourcegraph/sourcegraph/internal/api"
 	"github.com/sourcegraph/sourcegraph/internal/comby"
+	"github.com/sourcegraph/sourcegraph/internal/env"
 	"github.com/sourcegraph/sourcegraph/internal/lazyregexp"
 	"github.com/sourcegraph/sourcegraph/internal/search"
 	"github.com/sourcegraph/sourcegraph/internal/store"
@@ -243,26 +248,31 @@ var All UniversalSet = struct{}{}
 func structuralSearch(ctx context.Context, zipPath string, paths filePatterns, extensionHint, pattern, rule string, languages []string, repo api.RepoName, sender matchSender) error {
 	log15.Info("structural search", "repo", string(repo))
 
-	// Cap the number of forked processes to limit the size of zip contents being mapped to memory. Resolving #7133 could help to lift this restriction.
-	numWorkers := 4
-
-	matcher := toMatcher(languages, extensionHint)
-
 	var filePatterns []string
 	if v, ok := paths.(Subset); ok {
 		filePatterns = []string(v)
 	}
 
 	args := comby.Args{
-		Input:         comby.ZipPath(zipPath),
-		Matcher:       matcher,
+		Matcher:       toMatcher(languages, extensionHint),
 		MatchTemplate: pattern,
 		MatchOnly:     true,
-		FilePatterns:  filePatterns,
 		Rule:          rule,
-		NumWorkers:    numWorkers,
 	}
 
+	if m, ok := compileNative(args); ok {
+		return nativeStructuralSearch(ctx, m, zipFiles(zipPath, filePatterns), sender)
+	}
+
+	args.Input = comby.ZipPath(zipPath)
+	args.FilePatterns = filePatterns
+	return combyStructuralSearch(ctx, args, sender)
+}
+
+func combyStructuralSearch(ctx context.Context, args comby.Args, sender matchSender) error {
+	// Cap the number of forked processes to limit the size of zip contents being mapped to memory. Resolving #7133 could help to lift this restriction.
+	args.NumWorkers = 4
+
 	combyMatches, err := comby.Matches(ctx, args)
 	if err != nil {
 		return err
@@ -277,6 +287,106 @@ func structuralSearch(ctx context.Context, zipPath string, paths filePatterns, e
 	return nil
 }
 
+// useNativeMatcher is true if structural searches supported by the native
+// matcher run in-process instead of with the comby binary.
+var useNativeMatcher, _ = strconv.ParseBool(env.Get("SEARCHER_NATIVE_STRUCTURAL_SEARCH", "true", "Run structural searches in-process for languages supported by the native matcher, instead of with comby."))
+
+// compileNative compiles args for the native matcher. It returns false if
+// the search must run with the comby binary.
+func compileNative(args comby.Args) (*comby.NativeMatcher, bool) {
+	if !useNativeMatcher {
+		return nil, false
+	}
+	m, err := comby.CompileNative(args)
+	if err != nil {
+		log15.Debug("structural search: falling back to comby", "matcher", args.Matcher, "error", err)
+		return nil, false
+	}
+	requestTotalNativeStructuralSearch.WithLabelValues(args.Matcher).Inc()
+	return m, true
+}
+
+// fileVisitor visits the path and content of files to search.
+type fileVisitor func(visit func(path string, content []byte) error) error
+
+// zipFiles returns a fileVisitor for the files in the zip archive at
+// zipPath. If filePatterns is non-empty, only paths ending with one of them
+// are visited, like comby's -f flag.
+func zipFiles(zipPath string, filePatterns []string) fileVisitor {
+	return func(visit func(path string, content []byte) error) error {
+		r, err := zip.OpenReader(zipPath)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+
+		for _, f := range r.File {
+			if f.FileInfo().IsDir() || !hasAnySuffix(f.Name, filePatterns) {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return err
+			}
+			content, err := io.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				return err
+			}
+			if err := visit(f.Name, content); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// zoektFiles returns a fileVisitor for the content of files matched by Zoekt.
+func zoektFiles(fileMatches []zoekt.FileMatch) fileVisitor {
+	return func(visit func(path string, content []byte) error) error {
+		for _, fm := range fileMatches {
+			if err := visit(fm.FileName, fm.Content); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func hasAnySuffix(name string, suffixes []string) bool {
+	if len(suffixes) == 0 {
+		return true
+	}
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// nativeStructuralSearch matches m against each file of files and sends the
+// matches of each file as soon as it is searched.
+func nativeStructuralSearch(ctx context.Context, m *comby.NativeMatcher, files fileVisitor, sender matchSender) error {
+	err := files(func(path string, content []byte) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		matches := m.Matches(content)
+		if len(matches) == 0 {
+			return nil
+		}
+		sender.Send(toFileMatch(comby.FileMatch{URI: path, Matches: matches}))
+		return nil
+	})
+	if ctx.Err() != nil {
+		// Like for comby, a canceled search, for example because the
+		// sender hit its limit, is not an error.
+		return nil
+	}
+	return err
+}
+
 func structuralSearchWithZoekt(ctx context.Context, p *protocol.Request, sender matchSender) (deadlineHit bool, err error) {
 	patternInfo := &search.TextPatternInfo{
 		Pattern:                      p.Pattern,
@@ -309,6 +419,26 @@ func structuralSearchWithZoekt(ctx context.Context, p *protocol.Request, sender
 		return false, nil
 	}
 
+	var extensionHint string
+	if len(zoektMatches) > 0 {
+		filename := zoektMatches[0].FileName
+		extensionHint = filepath.Ext(filename)
+	}
+
+	log15.Info("structural search", "repo", string(p.Repo))
+	args := comby.Args{
+		Matcher:       toMatcher(p.Languages, extensionHint),
+		MatchTemplate: p.Pattern,
+		MatchOnly:     true,
+		Rule:          p.CombyRule,
+	}
+
+	// The native matcher searches the content of the candidate files Zoekt
+	// returned directly, while comby needs them in an archive on disk.
+	if m, ok := compileNative(args); ok {
+		return false, nativeStructuralSearch(ctx, m, zoektFiles(zoektMatches), sender)
+	}
+
 	zipFile, err := os.CreateTemp("", "*.zip")
 	if err != nil {
 		return false, err
@@ -320,16 +450,16 @@ func structuralSearchWithZoekt(ctx context.Context, p *protocol.Request, sender
 		return false, err
 	}
 
-	var extensionHint string
-	if len(zoektMatches) > 0 {
-		filename := zoektMatches[0].FileName
-		extensionHint = filepath.Ext(filename)
-	}
-
-	return false, structuralSearch(ctx, zipFile.Name(), All, extensionHint, p.Pattern, p.CombyRule, p.Languages, p.Repo, sender)
+	args.Input = comby.ZipPath(zipFile.Name())
+	return false, combyStructuralSearch(ctx, args, sender)
 }
 
 var requestTotalStructuralSearch = promauto.NewCounterVec(prometheus.CounterOpts{
 	Name: "searcher_service_request_total_structural_search",
 	Help: "Number of returned structural search requests.",
 }, []string{"language"})
+
+var requestTotalNativeStructuralSearch = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "searcher_service_request_total_native_structural_search",
+	Help: "Number of structural search requests run with the native matcher.",
+}, []string{"language"})
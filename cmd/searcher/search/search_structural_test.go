@@ -624,3 +624,103 @@ func TestBuildQuery(t *testing.T) {
 		}
 	})
 }
+
+// Tests that structural search runs Go searches with the native matcher, which
+// does not depend on comby.
+func TestNativeInferredMatcher(t *testing.T) {
+	if !useNativeMatcher {
+		t.Skip("native structural matcher disabled")
+	}
+
+	input := map[string]string{
+		"main.go": `
+/* This foo(ignore string) {} is in a Go comment should not match */
+func foo(real string) {}
+`,
+	}
+
+	zipData, err := testutil.CreateZip(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zPath, cleanup, err := testutil.TempZipFileOnDisk(zipData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	zFile, err := testutil.MockZipFile(zipData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := &protocol.PatternInfo{
+		Pattern: "foo(:[args])",
+		Limit:   30,
+	}
+	ctx, cancel, sender := newLimitedStreamCollector(context.Background(), 1000000000)
+	defer cancel()
+	err = filteredStructuralSearch(ctx, zPath, zFile, p, "foo", sender)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []protocol.FileMatch{{
+		Path: "main.go",
+		LineMatches: []protocol.LineMatch{{
+			LineNumber:       2,
+			OffsetAndLengths: [][2]int{{5, 16}},
+			Preview:          "foo(real string)",
+		}},
+		MatchCount: 1,
+	}}
+	if diff := cmp.Diff(want, sender.collected); diff != "" {
+		t.Fatalf("unexpected file matches (-want +got):\n%s", diff)
+	}
+}
+
+func TestNativeRule(t *testing.T) {
+	if !useNativeMatcher {
+		t.Skip("native structural matcher disabled")
+	}
+
+	input := map[string]string{
+		"file.go": "func foo(success) {} func bar(fail) {}",
+	}
+
+	zipData, err := testutil.CreateZip(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zf, cleanup, err := testutil.TempZipFileOnDisk(zipData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	p := &protocol.PatternInfo{
+		Pattern:         "func :[[fn]](:[args])",
+		IncludePatterns: []string{".go"},
+		CombyRule:       `where :[args] == "success"`,
+	}
+
+	ctx, cancel, sender := newLimitedStreamCollector(context.Background(), 1000000000)
+	defer cancel()
+	err = structuralSearch(ctx, zf, Subset(p.IncludePatterns), "", p.Pattern, p.CombyRule, p.Languages, "repo", sender)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []protocol.FileMatch{{
+		Path: "file.go",
+		LineMatches: []protocol.LineMatch{{
+			LineNumber:       0,
+			OffsetAndLengths: [][2]int{{0, 17}},
+			Preview:          "func foo(success)",
+		}},
+		MatchCount: 1,
+	}}
+	if diff := cmp.Diff(want, sender.collected); diff != "" {
+		t.Fatalf("unexpected file matches (-want +got):\n%s", diff)
+	}
+}
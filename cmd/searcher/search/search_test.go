@@ -210,15 +210,16 @@ milton.png
 `},
 	}
 
-	s, cleanup, err := newStore(files)
-	if err != nil {
-		t.Fatal(err)
-	}
-	s.FilterTar = func(_ context.Context, _ api.RepoName, _ api.CommitID) (store.FilterFunc, error) {
+	filterTar := func(_ context.Context, _ api.RepoName, _ api.CommitID) (store.FilterFunc, error) {
 		return func(hdr *tar.Header) bool {
 			return hdr.Name == "ignore.me"
 		}, nil
 	}
+	s, cleanup, err := newStore(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.FilterTar = filterTar
 	defer cleanup()
 	ts := httptest.NewServer(&search.Service{Store: s})
 	defer ts.Close()
@@ -243,27 +244,45 @@ milton.png
 				PatternInfo:  test.arg,
 				FetchTimeout: fetchTimeout.String(),
 			}
-			m, err := doSearch(ts.URL, &req)
-			if err != nil {
-				t.Fatalf("%s failed: %s", test.arg.String(), err)
-			}
-			sort.Sort(sortByPath(m))
-			got := toString(m)
-			err = sanityCheckSorted(m)
-			if err != nil {
-				t.Fatalf("%s malformed response: %s\n%s", test.arg.String(), err, got)
-			}
 			// We have an extra newline to make expected readable
 			if len(test.want) > 0 {
 				test.want = test.want[1:]
 			}
-			if got != test.want {
-				d, err := testutil.Diff(test.want, got)
+			check := func(u string) {
+				m, err := doSearch(u, &req)
 				if err != nil {
-					t.Fatal(err)
+					t.Fatalf("%s failed: %s", test.arg.String(), err)
+				}
+				sort.Sort(sortByPath(m))
+				got := toString(m)
+				err = sanityCheckSorted(m)
+				if err != nil {
+					t.Fatalf("%s malformed response: %s\n%s", test.arg.String(), err, got)
+				}
+				if got != test.want {
+					d, err := testutil.Diff(test.want, got)
+					if err != nil {
+						t.Fatal(err)
+					}
+					t.Fatalf("%s unexpected response:\n%s", test.arg.String(), d)
 				}
-				t.Fatalf("%s unexpected response:\n%s", test.arg.String(), d)
 			}
+			check(ts.URL)
+
+			if test.arg.IsStructuralPat {
+				return
+			}
+			// Search the archive while it is fetched. It isn't cached in
+			// a new store.
+			s, cleanup, err := newStore(files)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s.FilterTar = filterTar
+			defer cleanup()
+			streamingTS := httptest.NewServer(&search.Service{Store: s, StreamArchives: true})
+			defer streamingTS.Close()
+			check(streamingTS.URL)
 		})
 	}
 }
@@ -459,7 +478,7 @@ func newStore(files map[string]string) (*store.Store, func(), error) {
 		return nil, nil, err
 	}
 	return &store.Store{
-		FetchTar: func(ctx context.Context, repo api.RepoName, commit api.CommitID) (io.ReadCloser, error) {
+		FetchTar: func(ctx context.Context, repo api.RepoName, commit api.CommitID, paths []string) (io.ReadCloser, error) {
 			return io.NopCloser(bytes.NewReader(buf.Bytes())), nil
 		},
 		Path: d,
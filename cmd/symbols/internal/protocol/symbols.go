@@ -22,6 +22,11 @@ type SearchArgs struct {
 	// when finding matches.
 	IsCaseSensitive bool
 
+	// IsFuzzy if true matches symbol names which contain the characters of
+	// Query in order, ranking the best matches first. It takes precedence
+	// over IsRegExp.
+	IsFuzzy bool
+
 	// IncludePatterns is a list of regexes that symbol's file paths
 	// need to match to get included in the result
 	//
@@ -0,0 +1,226 @@
+package symbols
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"strings"
+
+	"github.com/sourcegraph/sourcegraph/internal/search/result"
+)
+
+// GoParser parses Go files in-process with go/parser. Unlike ctags, it
+// records the signature and the full range of each symbol.
+type GoParser struct{}
+
+func (GoParser) Parse(ctx context.Context, path string, content []byte) ([]result.Symbol, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, content, parser.SkipObjectResolution)
+	if file == nil {
+		return nil, err
+	}
+	// Like ctags, we return the symbols of files with syntax errors as far
+	// as we could parse them.
+
+	p := &goSymbols{
+		fset:  fset,
+		path:  path,
+		lines: bytes.Split(content, []byte("\n")),
+		kinds: map[string]string{},
+	}
+	pkg := file.Name.Name
+	p.add(file.Name, "package", "", "", "", file)
+
+	// Methods may be declared before their receiver type.
+	for _, decl := range file.Decls {
+		if decl, ok := decl.(*ast.GenDecl); ok && decl.Tok == token.TYPE {
+			for _, spec := range decl.Specs {
+				spec := spec.(*ast.TypeSpec)
+				p.kinds[spec.Name.Name] = goTypeKind(spec)
+			}
+		}
+	}
+
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if decl.Recv == nil || len(decl.Recv.List) == 0 {
+				p.add(decl.Name, "func", pkg, "package", p.funcSignature(decl.Type), decl)
+				continue
+			}
+			recv := receiverTypeName(decl.Recv.List[0].Type)
+			recvKind, ok := p.kinds[recv]
+			if !ok {
+				recvKind = "type"
+			}
+			p.add(decl.Name, "method", recv, recvKind, p.funcSignature(decl.Type), decl)
+
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				// Declarations of a single spec span their keyword.
+				var node ast.Node = spec
+				if len(decl.Specs) == 1 {
+					node = decl
+				}
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					p.addType(spec, pkg, node)
+				case *ast.ValueSpec:
+					kind := "variable"
+					if decl.Tok == token.CONST {
+						kind = "constant"
+					}
+					for _, name := range spec.Names {
+						p.add(name, kind, pkg, "package", p.exprString(spec.Type), node)
+					}
+				}
+			}
+		}
+	}
+	return p.symbols, nil
+}
+
+type goSymbols struct {
+	fset  *token.FileSet
+	path  string
+	lines [][]byte
+
+	// kinds are the kinds of the types declared in the file, by name.
+	kinds map[string]string
+
+	symbols []result.Symbol
+}
+
+func (p *goSymbols) addType(spec *ast.TypeSpec, pkg string, node ast.Node) {
+	kind := goTypeKind(spec)
+	p.add(spec.Name, kind, pkg, "package", "", node)
+
+	switch t := spec.Type.(type) {
+	case *ast.StructType:
+		for _, field := range t.Fields.List {
+			if len(field.Names) == 0 {
+				p.add(embeddedTypeName(field.Type), "anonMember", spec.Name.Name, kind, p.exprString(field.Type), field)
+				continue
+			}
+			for _, name := range field.Names {
+				p.add(name, "field", spec.Name.Name, kind, p.exprString(field.Type), field)
+			}
+		}
+	case *ast.InterfaceType:
+		for _, method := range t.Methods.List {
+			funcType, ok := method.Type.(*ast.FuncType)
+			if !ok {
+				// An embedded interface.
+				continue
+			}
+			for _, name := range method.Names {
+				p.add(name, "methodSpec", spec.Name.Name, kind, p.funcSignature(funcType), method)
+			}
+		}
+	}
+}
+
+// add adds the symbol declared by name, whose definition is node.
+func (p *goSymbols) add(name *ast.Ident, kind, parent, parentKind, signature string, node ast.Node) {
+	if name == nil || name.Name == "_" {
+		return
+	}
+	start := p.fset.Position(name.Pos())
+	end := p.fset.Position(node.End())
+	p.symbols = append(p.symbols, result.Symbol{
+		Name:         name.Name,
+		Path:         p.path,
+		Line:         start.Line,
+		Kind:         kind,
+		Language:     "Go",
+		Parent:       parent,
+		ParentKind:   parentKind,
+		Signature:    signature,
+		Pattern:      p.pattern(start.Line),
+		EndLine:      end.Line,
+		EndCharacter: end.Column - 1,
+	})
+}
+
+// pattern returns a ctags-style pattern matching the given line, which
+// result.Symbol uses to compute the range of the name of a symbol.
+func (p *goSymbols) pattern(line int) string {
+	if line < 1 || line > len(p.lines) {
+		return ""
+	}
+	text := strings.TrimSuffix(string(p.lines[line-1]), "\r")
+	text = strings.NewReplacer(`\`, `\\`, `/`, `\/`).Replace(text)
+	return fmt.Sprintf("/^%s$/", text)
+}
+
+// funcSignature returns the parameters and results of a function, e.g.
+// "(ctx context.Context) error".
+func (p *goSymbols) funcSignature(funcType *ast.FuncType) string {
+	return strings.TrimPrefix(p.exprString(funcType), "func")
+}
+
+func (p *goSymbols) exprString(expr ast.Expr) string {
+	if expr == nil {
+		return ""
+	}
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, p.fset, expr); err != nil {
+		return ""
+	}
+	return buf.String()
+}
+
+func goTypeKind(spec *ast.TypeSpec) string {
+	switch spec.Type.(type) {
+	case *ast.StructType:
+		return "struct"
+	case *ast.InterfaceType:
+		return "interface"
+	default:
+		return "type"
+	}
+}
+
+// receiverTypeName returns the name of the type of a method receiver, e.g.
+// "T" for "*T", "T[K]" and "T[K, V]".
+func receiverTypeName(expr ast.Expr) string {
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.ParenExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.Ident:
+			return e.Name
+		default:
+			x, ok := indexListExprX(expr)
+			if !ok {
+				return ""
+			}
+			expr = x
+		}
+	}
+}
+
+// embeddedTypeName returns the identifier of the name of an embedded field,
+// e.g. "Mutex" for "*sync.Mutex".
+func embeddedTypeName(expr ast.Expr) *ast.Ident {
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.SelectorExpr:
+			return e.Sel
+		case *ast.Ident:
+			return e
+		default:
+			return nil
+		}
+	}
+}
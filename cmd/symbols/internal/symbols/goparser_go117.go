@@ -0,0 +1,12 @@
+//go:build !go1.18
+// +build !go1.18
+
+package symbols
+
+import "go/ast"
+
+// indexListExprX always returns false because go/ast has no
+// IndexListExpr before Go 1.18.
+func indexListExprX(expr ast.Expr) (ast.Expr, bool) {
+	return nil, false
+}
@@ -0,0 +1,15 @@
+//go:build go1.18
+// +build go1.18
+
+package symbols
+
+import "go/ast"
+
+// indexListExprX returns the generic type of an instantiation with several
+// type arguments, e.g. "T" for "T[K, V]".
+func indexListExprX(expr ast.Expr) (ast.Expr, bool) {
+	if e, ok := expr.(*ast.IndexListExpr); ok {
+		return e.X, true
+	}
+	return nil, false
+}
@@ -0,0 +1,39 @@
+//go:build go1.18
+// +build go1.18
+
+package symbols
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGoParserGenericReceivers(t *testing.T) {
+	content := `package foo
+
+type List[T any] []T
+
+func (l List[T]) Len() int { return len(l) }
+
+type Map[K comparable, V any] map[K]V
+
+func (m *Map[K, V]) Get(k K) V { return (*m)[k] }
+`
+	symbols, err := GoParser{}.Parse(context.Background(), "foo.go", []byte(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parents := map[string]string{}
+	for _, s := range symbols {
+		if s.Kind == "method" {
+			parents[s.Name] = s.Parent
+		}
+	}
+	want := map[string]string{"Len": "List", "Get": "Map"}
+	if diff := cmp.Diff(want, parents); diff != "" {
+		t.Errorf("unexpected method parents (-want +got):\n%s", diff)
+	}
+}
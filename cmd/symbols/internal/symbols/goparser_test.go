@@ -0,0 +1,71 @@
+package symbols
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGoParser(t *testing.T) {
+	content := `package foo
+
+type Server struct {
+	*sync.Mutex
+	Addr string
+}
+
+type Handler interface {
+	Serve(ctx context.Context) error
+}
+
+// ListenAndServe listens.
+func (s *Server) ListenAndServe(addr string) error {
+	return nil
+}
+
+const (
+	a = 1
+	_ = 2
+)
+
+var x, y int
+
+func New() *Server {
+	return &Server{}
+}
+`
+	symbols, err := GoParser{}.Parse(context.Background(), "foo.go", []byte(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type symbol struct {
+		Name, Kind, Parent, ParentKind, Signature string
+		Line, EndLine, EndCharacter               int
+	}
+	var got []symbol
+	for _, s := range symbols {
+		got = append(got, symbol{s.Name, s.Kind, s.Parent, s.ParentKind, s.Signature, s.Line, s.EndLine, s.EndCharacter})
+	}
+	want := []symbol{
+		{"foo", "package", "", "", "", 1, 26, 1},
+		{"Server", "struct", "foo", "package", "", 3, 6, 1},
+		{"Mutex", "anonMember", "Server", "struct", "*sync.Mutex", 4, 4, 12},
+		{"Addr", "field", "Server", "struct", "string", 5, 5, 12},
+		{"Handler", "interface", "foo", "package", "", 8, 10, 1},
+		{"Serve", "methodSpec", "Handler", "interface", "(ctx context.Context) error", 9, 9, 33},
+		{"ListenAndServe", "method", "Server", "struct", "(addr string) error", 13, 15, 1},
+		{"a", "constant", "foo", "package", "", 18, 18, 6},
+		{"x", "variable", "foo", "package", "int", 22, 22, 12},
+		{"y", "variable", "foo", "package", "int", 22, 22, 12},
+		{"New", "func", "foo", "package", "() *Server", 24, 26, 1},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected symbols (-want +got):\n%s", diff)
+	}
+
+	if want := "/^func (s *Server) ListenAndServe(addr string) error {$/"; symbols[6].Pattern != want {
+		t.Errorf("got pattern %q, want %q", symbols[6].Pattern, want)
+	}
+}
@@ -0,0 +1,223 @@
+package symbols
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/cockroachdb/errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+)
+
+// maxIncrementalPaths is the maximum number of changed paths for which we
+// update the database of an earlier commit. Above it, parsing the whole
+// repository is about as fast and we don't want to pass that many paths to
+// gitserver.
+const maxIncrementalPaths = 1000
+
+// maxIncrementalPathBytes bounds the size of the changed paths we fetch.
+// FetchTar passes them to gitserver as pathspecs in the query string of a GET
+// request, which has to stay below the 8KB URL limit common to HTTP servers
+// and proxies.
+const maxIncrementalPathBytes = 6 * 1024
+
+// Changes are the paths which changed between two commits.
+type Changes struct {
+	Added    []string
+	Modified []string
+	Deleted  []string
+}
+
+// ParseGitDiffNameStatus parses the output of
+// `git diff -z --name-status --no-renames`.
+func ParseGitDiffNameStatus(out []byte) (Changes, error) {
+	var changes Changes
+	fields := bytes.Split(bytes.TrimRight(out, "\x00"), []byte{0})
+	if len(fields) == 1 && len(fields[0]) == 0 {
+		return changes, nil
+	}
+	if len(fields)%2 != 0 {
+		return Changes{}, errors.Errorf("unexpected git diff output %q", out)
+	}
+	for i := 0; i < len(fields); i += 2 {
+		status, path := fields[i], string(fields[i+1])
+		if len(status) == 0 {
+			return Changes{}, errors.Errorf("unexpected git diff output %q", out)
+		}
+		switch status[0] {
+		case 'A':
+			changes.Added = append(changes.Added, path)
+		case 'M', 'T':
+			changes.Modified = append(changes.Modified, path)
+		case 'D':
+			changes.Deleted = append(changes.Deleted, path)
+		default:
+			return Changes{}, errors.Errorf("unexpected git diff status %q for %q", status, path)
+		}
+	}
+	return changes, nil
+}
+
+// writeSymbolsIncrementally writes the symbols of repo@commit to the blank
+// database file `dbFile` by copying the latest database of the repo and
+// re-parsing only the paths which changed since its commit. It returns false
+// if there is no database to start from or too many paths changed.
+func (s *Service) writeSymbolsIncrementally(ctx context.Context, dbFile string, sel parserSelection, repoName api.RepoName, commitID api.CommitID) (bool, error) {
+	prevDBFile, err := s.latestDB(repoName, sel)
+	if err != nil || prevDBFile == "" {
+		return false, err
+	}
+
+	// The latest database may be evicted from the cache at any time, but it
+	// stays readable once we opened it.
+	prev, err := os.Open(prevDBFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	err = copyFile(dbFile, prev)
+	prev.Close()
+	if err != nil {
+		return false, err
+	}
+
+	db, err := sqlx.Open("sqlite3_with_pcre", dbFile)
+	if err != nil {
+		return false, err
+	}
+	defer db.Close()
+
+	var prevCommitID string
+	if err := db.Get(&prevCommitID, `SELECT commit_id FROM meta`); err != nil {
+		return false, errors.Wrap(err, "reading commit of latest database")
+	}
+
+	changes, err := s.GitDiff(ctx, repoName, api.CommitID(prevCommitID), commitID)
+	if err != nil {
+		return false, errors.Wrapf(err, "diffing %s..%s", prevCommitID, commitID)
+	}
+	changed := append(append([]string{}, changes.Added...), changes.Modified...)
+	if len(changed)+len(changes.Deleted) > maxIncrementalPaths || archivePathsSize(changed) > maxIncrementalPathBytes {
+		return false, nil
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	deleteStatement, err := tx.Preparex(`DELETE FROM symbols WHERE path = ?`)
+	if err != nil {
+		return false, err
+	}
+	defer deleteStatement.Close()
+	for _, paths := range [][]string{changed, changes.Deleted} {
+		for _, path := range paths {
+			if _, err := deleteStatement.Exec(path); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	if _, err := tx.Exec(`UPDATE meta SET commit_id = ?`, string(commitID)); err != nil {
+		return false, err
+	}
+
+	// insertSymbols parses all files when passed no paths.
+	if len(changed) > 0 {
+		if err := s.insertSymbols(ctx, tx, sel, repoName, commitID, changed); err != nil {
+			return false, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	incrementalIndexes.Inc()
+	return true, nil
+}
+
+// copyFile overwrites the file at path with the contents of src.
+func copyFile(path string, src io.Reader) error {
+	dst, err := os.OpenFile(path, os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, src)
+	if err1 := dst.Close(); err == nil {
+		err = err1
+	}
+	return err
+}
+
+// latestDBPointer returns the path of the file which records the path of the
+// database of the commit of repo we indexed last with the parsers of sel. It
+// includes the version of the database schema, so that we never update an
+// incompatible database.
+func (s *Service) latestDBPointer(repoName api.RepoName, sel parserSelection) string {
+	h := sha256.Sum256([]byte(string(repoName) + " " + sel.key))
+	return filepath.Join(s.Path, fmt.Sprintf("latest-%d", symbolsDBVersion), hex.EncodeToString(h[:]))
+}
+
+// latestDB returns the path of the database of the commit of repo we indexed
+// last, or "" if we didn't index repo yet.
+func (s *Service) latestDB(repoName api.RepoName, sel parserSelection) (string, error) {
+	path, err := os.ReadFile(s.latestDBPointer(repoName, sel))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	return string(path), err
+}
+
+// setLatestDB records dbFile as the database of the commit of repo we indexed
+// last. Active repos are usually indexed at their latest commit, so it is
+// likely the closest one to the next commit we index.
+func (s *Service) setLatestDB(repoName api.RepoName, sel parserSelection, dbFile string) error {
+	pointer := s.latestDBPointer(repoName, sel)
+	if err := os.MkdirAll(filepath.Dir(pointer), 0700); err != nil {
+		return err
+	}
+	// Write to a temporary file first, so readers never see a partially
+	// written path.
+	tmp, err := os.CreateTemp(filepath.Dir(pointer), filepath.Base(pointer)+".*.part")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.WriteString(dbFile)
+	if err1 := tmp.Close(); err == nil {
+		err = err1
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), pointer)
+}
+
+var incrementalIndexes = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "symbols_store_incremental_indexes",
+	Help: "The total number of databases created by updating the database of an earlier commit.",
+})
+
+// archivePathsSize returns the number of bytes paths take up in the query
+// string of a gitserver archive request.
+func archivePathsSize(paths []string) int {
+	size := 0
+	for _, path := range paths {
+		size += len("&path=") + len(url.QueryEscape(":(literal)"+path))
+	}
+	return size
+}
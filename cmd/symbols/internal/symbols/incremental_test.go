@@ -0,0 +1,136 @@
+package symbols
+
+import (
+	"context"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/sourcegraph/go-ctags"
+
+	"github.com/sourcegraph/sourcegraph/cmd/symbols/internal/protocol"
+	"github.com/sourcegraph/sourcegraph/cmd/symbols/internal/sqliteutil"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+)
+
+func TestParseGitDiffNameStatus(t *testing.T) {
+	out := []byte("A\x00new.go\x00M\x00dir/changed.go\x00D\x00old.go\x00T\x00link\x00")
+	got, err := ParseGitDiffNameStatus(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Changes{
+		Added:    []string{"new.go"},
+		Modified: []string{"dir/changed.go", "link"},
+		Deleted:  []string{"old.go"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	if got, err := ParseGitDiffNameStatus(nil); err != nil || !reflect.DeepEqual(got, Changes{}) {
+		t.Errorf("got %+v, %v for empty output", got, err)
+	}
+
+	if _, err := ParseGitDiffNameStatus([]byte("R100\x00a\x00")); err == nil {
+		t.Error("expected an error for renames")
+	}
+}
+
+func TestIncrementalIndexing(t *testing.T) {
+	sqliteutil.MustRegisterSqlite3WithPcre()
+
+	tmpDir, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { os.RemoveAll(tmpDir) }()
+
+	commits := map[api.CommitID]map[string]string{
+		"a": {"a.go": "foo", "b.go": "bar", "c.go": "baz"},
+		"b": {"a.go": "foo", "b.go": "bar2", "d.go": "qux"},
+	}
+	var fetchedPaths [][]string
+	service := Service{
+		FetchTar: func(ctx context.Context, repo api.RepoName, commit api.CommitID, paths []string) (io.ReadCloser, error) {
+			fetchedPaths = append(fetchedPaths, paths)
+			files := map[string]string{}
+			for name, body := range commits[commit] {
+				if len(paths) == 0 || contains(paths, name) {
+					files[name] = body
+				}
+			}
+			return createTar(files)
+		},
+		GitDiff: func(ctx context.Context, repo api.RepoName, commitA, commitB api.CommitID) (Changes, error) {
+			if commitA != "a" || commitB != "b" {
+				t.Fatalf("unexpected diff %s..%s", commitA, commitB)
+			}
+			return Changes{Added: []string{"d.go"}, Modified: []string{"b.go"}, Deleted: []string{"c.go"}}, nil
+		},
+		NewParser: func() (ctags.Parser, error) {
+			return contentParser{}, nil
+		},
+		Path: tmpDir,
+	}
+	if err := service.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		commit      api.CommitID
+		wantFetched []string
+		want        []string
+	}{
+		{commit: "a", wantFetched: nil, want: []string{"a.go:foo", "b.go:bar", "c.go:baz"}},
+		{commit: "b", wantFetched: []string{"d.go", "b.go"}, want: []string{"a.go:foo", "b.go:bar2", "d.go:qux"}},
+	} {
+		fetchedPaths = nil
+		res, err := service.search(context.Background(), protocol.SearchArgs{Repo: "r", CommitID: test.commit, First: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, symbol := range *res {
+			got = append(got, symbol.Path+":"+symbol.Name)
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("commit %s: got symbols %v, want %v", test.commit, got, test.want)
+		}
+		if want := [][]string{test.wantFetched}; !reflect.DeepEqual(fetchedPaths, want) {
+			t.Errorf("commit %s: fetched paths %v, want %v", test.commit, fetchedPaths, want)
+		}
+	}
+}
+
+func TestArchivePathsSize(t *testing.T) {
+	if got, want := archivePathsSize([]string{"a b.go", "c.go"}), len("&path=%3A%28literal%29a+b.go&path=%3A%28literal%29c.go"); got != want {
+		t.Errorf("got size %d, want %d", got, want)
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// contentParser returns a symbol for each word in a file.
+type contentParser struct{}
+
+func (contentParser) Parse(name string, content []byte) ([]*ctags.Entry, error) {
+	var entries []*ctags.Entry
+	for _, word := range strings.Fields(string(content)) {
+		entries = append(entries, &ctags.Entry{Name: word, Path: name})
+	}
+	return entries, nil
+}
+
+func (contentParser) Close() {}
@@ -39,7 +39,9 @@ func (s *Service) startParsers() error {
 	return nil
 }
 
-func (s *Service) parseUncached(ctx context.Context, repo api.RepoName, commitID api.CommitID, callback func(symbol result.Symbol) error) (err error) {
+// parseUncached parses the given paths of repo@commitID, or all files if paths
+// is empty, with the parsers of sel and calls callback for each symbol.
+func (s *Service) parseUncached(ctx context.Context, sel parserSelection, repo api.RepoName, commitID api.CommitID, paths []string, callback func(symbol result.Symbol) error) (err error) {
 	span, ctx := ot.StartSpanFromContext(ctx, "parseUncached")
 	defer func() {
 		if err != nil {
@@ -50,9 +52,10 @@ func (s *Service) parseUncached(ctx context.Context, repo api.RepoName, commitID
 	}()
 	span.SetTag("repo", string(repo))
 	span.SetTag("commit", string(commitID))
+	span.SetTag("paths", len(paths))
 
 	tr := nettrace.New("parseUncached", string(repo))
-	tr.LazyPrintf("commitID: %s", commitID)
+	tr.LazyPrintf("commitID: %s paths: %d", commitID, len(paths))
 
 	totalSymbols := 0
 	defer func() {
@@ -65,7 +68,7 @@ func (s *Service) parseUncached(ctx context.Context, repo api.RepoName, commitID
 	}()
 
 	tr.LazyPrintf("fetch")
-	parseRequests, errChan, err := s.fetchRepositoryArchive(ctx, repo, commitID)
+	parseRequests, errChan, err := s.fetchRepositoryArchive(ctx, repo, commitID, paths)
 	tr.LazyPrintf("fetch (returned chans)")
 	if err != nil {
 		return err
@@ -98,21 +101,18 @@ func (s *Service) parseUncached(ctx context.Context, repo api.RepoName, commitID
 				wg.Done()
 				<-sem
 			}()
-			entries, parseErr := s.parse(ctx, req)
+			symbols, parseErr := sel.parserFor(req.path).Parse(ctx, req.path, req.data)
 			if parseErr != nil && parseErr != context.Canceled && parseErr != context.DeadlineExceeded {
 				log15.Error("Error parsing symbols.", "repo", repo, "commitID", commitID, "path", req.path, "dataSize", len(req.data), "error", parseErr)
 			}
-			if len(entries) > 0 {
+			if len(symbols) > 0 {
 				mu.Lock()
 				defer mu.Unlock()
-				for _, e := range entries {
-					if e.Name == "" || strings.HasPrefix(e.Name, "__anon") || strings.HasPrefix(e.Parent, "__anon") || strings.HasPrefix(e.Name, "AnonymousFunction") || strings.HasPrefix(e.Parent, "AnonymousFunction") {
-						continue
-					}
+				for _, symbol := range symbols {
 					totalSymbols++
-					err = callback(entryToSymbol(e))
+					err = callback(symbol)
 					if err != nil {
-						log15.Error("Failed to add symbol", "symbol", e, "error", err)
+						log15.Error("Failed to add symbol", "symbol", symbol, "error", err)
 						return
 					}
 				}
@@ -177,6 +177,23 @@ func (s *Service) parse(ctx context.Context, req parseRequest) (entries []*ctags
 	}
 }
 
+// ctagsParser parses files with the ctags processes of a service.
+type ctagsParser struct {
+	s *Service
+}
+
+func (p ctagsParser) Parse(ctx context.Context, path string, content []byte) ([]result.Symbol, error) {
+	entries, err := p.s.parse(ctx, parseRequest{path: path, data: content})
+	symbols := make([]result.Symbol, 0, len(entries))
+	for _, e := range entries {
+		if e.Name == "" || strings.HasPrefix(e.Name, "__anon") || strings.HasPrefix(e.Parent, "__anon") || strings.HasPrefix(e.Name, "AnonymousFunction") || strings.HasPrefix(e.Parent, "AnonymousFunction") {
+			continue
+		}
+		symbols = append(symbols, entryToSymbol(e))
+	}
+	return symbols, err
+}
+
 func entryToSymbol(e *ctags.Entry) result.Symbol {
 	return result.Symbol{
 		Name:        e.Name,
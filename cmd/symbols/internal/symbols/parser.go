@@ -0,0 +1,101 @@
+package symbols
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/go-enry/go-enry/v2"
+
+	"github.com/sourcegraph/sourcegraph/internal/search/result"
+)
+
+// Parser parses the symbols of a file.
+type Parser interface {
+	Parse(ctx context.Context, path string, content []byte) ([]result.Symbol, error)
+}
+
+// DefaultParsers returns the in-process parsers which can be selected for a
+// language instead of ctags, by name.
+func DefaultParsers() map[string]Parser {
+	return map[string]Parser{
+		"go": GoParser{},
+	}
+}
+
+// parserLanguages maps the names of in-process parsers which only support
+// some languages to the canonical names of these languages.
+var parserLanguages = map[string][]string{
+	"go": {"Go"},
+}
+
+// parserSelection is the parser to use for each language.
+type parserSelection struct {
+	// byLanguage maps canonical language names, as returned by enry, to the
+	// parser for their files.
+	byLanguage map[string]Parser
+
+	// ctags parses the files of all other languages.
+	ctags Parser
+
+	// key identifies the selection. Databases created with different
+	// selections contain different symbols.
+	key string
+}
+
+// selectParsers returns the current parser selection of the service.
+func (s *Service) selectParsers() parserSelection {
+	sel := parserSelection{
+		byLanguage: map[string]Parser{},
+		ctags:      ctagsParser{s: s},
+	}
+	if s.ParserConfig == nil {
+		return sel
+	}
+
+	var keys []string
+	for alias, name := range s.ParserConfig() {
+		parser, ok := s.Parsers[name]
+		if !ok {
+			// Either ctags or an unknown parser, which site config
+			// validation rejects.
+			continue
+		}
+		language, ok := enry.GetLanguageByAlias(alias)
+		if !ok || !supportsLanguage(name, language) {
+			continue
+		}
+		sel.byLanguage[language] = parser
+		keys = append(keys, language+"="+name)
+	}
+	sort.Strings(keys)
+	sel.key = strings.Join(keys, ",")
+	return sel
+}
+
+// supportsLanguage returns true if the parser with the given name can parse
+// files of the language.
+func supportsLanguage(name, language string) bool {
+	languages, ok := parserLanguages[name]
+	if !ok {
+		return true
+	}
+	for _, l := range languages {
+		if l == language {
+			return true
+		}
+	}
+	return false
+}
+
+// parserFor returns the parser for the file at path.
+func (sel parserSelection) parserFor(path string) Parser {
+	if len(sel.byLanguage) > 0 {
+		if language, _ := enry.GetLanguageByExtension(path); language != "" {
+			if parser, ok := sel.byLanguage[language]; ok {
+				return parser
+			}
+		}
+	}
+	return sel.ctags
+}
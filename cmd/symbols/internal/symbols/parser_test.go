@@ -0,0 +1,35 @@
+package symbols
+
+import (
+	"testing"
+)
+
+func TestSelectParsers(t *testing.T) {
+	s := &Service{
+		Parsers: DefaultParsers(),
+		ParserConfig: func() map[string]string {
+			return map[string]string{"golang": "go", "python": "ctags", "rust": "unknown", "java": "go"}
+		},
+	}
+	sel := s.selectParsers()
+
+	if want := "Go=go"; sel.key != want {
+		t.Errorf("got key %q, want %q", sel.key, want)
+	}
+	for path, want := range map[string]Parser{
+		"cmd/main.go":  GoParser{},
+		"main.py":      ctagsParser{s: s},
+		"src/lib.rs":   ctagsParser{s: s},
+		"Main.java":    ctagsParser{s: s},
+		"README":       ctagsParser{s: s},
+		"dir.go/x.txt": ctagsParser{s: s},
+	} {
+		if got := sel.parserFor(path); got != want {
+			t.Errorf("parserFor(%q) = %T, want %T", path, got, want)
+		}
+	}
+
+	if sel := (&Service{}).selectParsers(); sel.key != "" || len(sel.byLanguage) != 0 {
+		t.Errorf("expected ctags for all languages without config, got %+v", sel)
+	}
+}
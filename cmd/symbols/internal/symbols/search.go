@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"os"
 	"regexp/syntax"
 	"strings"
 	"time"
@@ -22,6 +23,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/internal/trace/ot"
 
 	"github.com/sourcegraph/sourcegraph/internal/search/result"
+	"github.com/sourcegraph/sourcegraph/internal/search/symbol/fuzzy"
 )
 
 // maxFileSize is the limit on file size in bytes. Only files smaller than this are processed.
@@ -97,16 +99,22 @@ func (s *Service) search(ctx context.Context, args protocol.SearchArgs) (*result
 
 // getDBFile returns the path to the sqlite3 database for the repo@commit
 // specified in `args`. If the database doesn't already exist in the disk cache,
-// it will create a new one and write all the symbols into it.
+// it will create a new one, preferably by updating the database of the commit
+// of the repo we indexed last.
 func (s *Service) getDBFile(ctx context.Context, args protocol.SearchArgs) (string, error) {
-	diskcacheFile, err := s.cache.OpenWithPath(ctx, fmt.Sprintf("%d-%s@%s", symbolsDBVersion, args.Repo, args.CommitID), func(fetcherCtx context.Context, tempDBFile string) error {
-		err := s.writeAllSymbolsToNewDB(fetcherCtx, tempDBFile, args.Repo, args.CommitID)
+	// Databases depend on the parsers used for each language.
+	sel := s.selectParsers()
+
+	created := false
+	diskcacheFile, err := s.cache.OpenWithPath(ctx, fmt.Sprintf("%d-%s@%s %s", symbolsDBVersion, args.Repo, args.CommitID, sel.key), func(fetcherCtx context.Context, tempDBFile string) error {
+		err := s.writeSymbolsToNewDB(fetcherCtx, tempDBFile, sel, args.Repo, args.CommitID)
 		if err != nil {
 			if err == context.Canceled {
 				log15.Error("Unable to parse repository symbols within the context", "repo", args.Repo, "commit", args.CommitID, "query", args.Query)
 			}
 			return err
 		}
+		created = true
 		return nil
 	})
 	if err != nil {
@@ -114,6 +122,12 @@ func (s *Service) getDBFile(ctx context.Context, args protocol.SearchArgs) (stri
 	}
 	defer diskcacheFile.File.Close()
 
+	if created {
+		if err := s.setLatestDB(args.Repo, sel, diskcacheFile.Path); err != nil {
+			log15.Warn("Failed to record latest symbols database", "repo", args.Repo, "commit", args.CommitID, "error", err)
+		}
+	}
+
 	return diskcacheFile.File.Name(), err
 }
 
@@ -185,8 +199,24 @@ func filterSymbols(ctx context.Context, db *sqlx.DB, args protocol.SearchArgs) (
 		return newConditions
 	}
 
+	// Fuzzy queries are scored in Go. We only select the names containing
+	// the characters of the query in order, ignoring case. SQLite only folds
+	// the case of ASCII characters in LIKE, so we match the lowercase names.
+	fuzzyQuery := strings.Join(strings.Fields(args.Query), "")
+	isFuzzy := args.IsFuzzy && fuzzyQuery != ""
+	limit := args.First
+	if isFuzzy {
+		limit = fuzzy.MaxCandidates
+	}
+
 	var conditions []*sqlf.Query
-	conditions = append(conditions, makeCondition("name", args.Query)...)
+	if args.IsFuzzy {
+		if fuzzyQuery != "" {
+			conditions = append(conditions, sqlf.Sprintf(`namelowercase LIKE %s ESCAPE '\'`, fuzzyLikePattern(strings.ToLower(fuzzyQuery))))
+		}
+	} else {
+		conditions = append(conditions, makeCondition("name", args.Query)...)
+	}
 	for _, includePattern := range args.IncludePatterns {
 		conditions = append(conditions, makeCondition("path", includePattern)...)
 	}
@@ -194,9 +224,14 @@ func filterSymbols(ctx context.Context, db *sqlx.DB, args protocol.SearchArgs) (
 
 	var sqlQuery *sqlf.Query
 	if len(conditions) == 0 {
-		sqlQuery = sqlf.Sprintf("SELECT * FROM symbols LIMIT %s", args.First)
+		sqlQuery = sqlf.Sprintf("SELECT * FROM symbols LIMIT %s", limit)
+	} else if isFuzzy {
+		// Prefer short names, which score higher, when there are more
+		// candidates than we score. The order also makes the candidates
+		// deterministic.
+		sqlQuery = sqlf.Sprintf("SELECT * FROM symbols WHERE %s ORDER BY length(name), path, line LIMIT %s", sqlf.Join(conditions, "AND"), limit)
 	} else {
-		sqlQuery = sqlf.Sprintf("SELECT * FROM symbols WHERE %s LIMIT %s", sqlf.Join(conditions, "AND"), args.First)
+		sqlQuery = sqlf.Sprintf("SELECT * FROM symbols WHERE %s LIMIT %s", sqlf.Join(conditions, "AND"), limit)
 	}
 
 	var symbolsInDB []symbolInDB
@@ -209,15 +244,37 @@ func filterSymbols(ctx context.Context, db *sqlx.DB, args protocol.SearchArgs) (
 		res = append(res, symbolInDBToSymbol(symbolInDB))
 	}
 
+	if isFuzzy {
+		res = fuzzy.Rank(res, fuzzyQuery, args.IsCaseSensitive)
+		if len(res) > args.First {
+			res = res[:args.First]
+		}
+	}
+
 	span.SetTag("hits", len(res))
 	return res, nil
 }
 
+// fuzzyLikePattern returns a LIKE pattern matching the names which contain
+// the characters of pattern in order. It is escaped with `\`.
+func fuzzyLikePattern(pattern string) string {
+	var b strings.Builder
+	b.WriteByte('%')
+	for _, r := range pattern {
+		if r == '%' || r == '_' || r == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+		b.WriteByte('%')
+	}
+	return b.String()
+}
+
 // The version of the symbols database schema. This is included in the database
 // filenames to prevent a newer version of the symbols service from attempting
 // to read from a database created by an older (and likely incompatible) symbols
 // service. Increment this when you change the database schema.
-const symbolsDBVersion = 3
+const symbolsDBVersion = 5
 
 // symbolInDB is the same as `protocol.Symbol`, but with two additional columns:
 // namelowercase and pathlowercase, which enable indexed case insensitive
@@ -234,6 +291,8 @@ type symbolInDB struct {
 	ParentKind    string
 	Signature     string
 	Pattern       string
+	EndLine       int
+	EndCharacter  int
 
 	FileLimited bool
 }
@@ -251,6 +310,8 @@ func symbolToSymbolInDB(symbol result.Symbol) symbolInDB {
 		ParentKind:    symbol.ParentKind,
 		Signature:     symbol.Signature,
 		Pattern:       symbol.Pattern,
+		EndLine:       symbol.EndLine,
+		EndCharacter:  symbol.EndCharacter,
 
 		FileLimited: symbol.FileLimited,
 	}
@@ -258,23 +319,48 @@ func symbolToSymbolInDB(symbol result.Symbol) symbolInDB {
 
 func symbolInDBToSymbol(symbolInDB symbolInDB) result.Symbol {
 	return result.Symbol{
-		Name:       symbolInDB.Name,
-		Path:       symbolInDB.Path,
-		Line:       symbolInDB.Line,
-		Kind:       symbolInDB.Kind,
-		Language:   symbolInDB.Language,
-		Parent:     symbolInDB.Parent,
-		ParentKind: symbolInDB.ParentKind,
-		Signature:  symbolInDB.Signature,
-		Pattern:    symbolInDB.Pattern,
+		Name:         symbolInDB.Name,
+		Path:         symbolInDB.Path,
+		Line:         symbolInDB.Line,
+		Kind:         symbolInDB.Kind,
+		Language:     symbolInDB.Language,
+		Parent:       symbolInDB.Parent,
+		ParentKind:   symbolInDB.ParentKind,
+		Signature:    symbolInDB.Signature,
+		Pattern:      symbolInDB.Pattern,
+		EndLine:      symbolInDB.EndLine,
+		EndCharacter: symbolInDB.EndCharacter,
 
 		FileLimited: symbolInDB.FileLimited,
 	}
 }
 
+// writeSymbolsToNewDB writes the symbols of repo@commit to the blank database
+// file `dbFile`. It updates a copy of the latest database of the repo if it
+// can, and parses the whole repo otherwise.
+func (s *Service) writeSymbolsToNewDB(ctx context.Context, dbFile string, sel parserSelection, repoName api.RepoName, commitID api.CommitID) error {
+	if s.GitDiff != nil {
+		ok, err := s.writeSymbolsIncrementally(ctx, dbFile, sel, repoName, commitID)
+		if ok && err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			log15.Warn("Failed to index symbols incrementally, parsing all files", "repo", repoName, "commit", commitID, "error", err)
+		}
+		// Start over with a blank database.
+		if err := os.Truncate(dbFile, 0); err != nil {
+			return err
+		}
+	}
+	return s.writeAllSymbolsToNewDB(ctx, dbFile, sel, repoName, commitID)
+}
+
 // writeAllSymbolsToNewDB fetches the repo@commit from gitserver, parses all the
 // symbols, and writes them to the blank database file `dbFile`.
-func (s *Service) writeAllSymbolsToNewDB(ctx context.Context, dbFile string, repoName api.RepoName, commitID api.CommitID) error {
+func (s *Service) writeAllSymbolsToNewDB(ctx context.Context, dbFile string, sel parserSelection, repoName api.RepoName, commitID api.CommitID) error {
 	db, err := sqlx.Open("sqlite3_with_pcre", dbFile)
 	if err != nil {
 		return err
@@ -286,11 +372,29 @@ func (s *Service) writeAllSymbolsToNewDB(ctx context.Context, dbFile string, rep
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
+
+	if err := createSymbolsTables(tx); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`INSERT INTO meta (commit_id) VALUES (?)`, string(commitID)); err != nil {
+		return err
+	}
+
+	if err := s.insertSymbols(ctx, tx, sel, repoName, commitID, nil); err != nil {
+		return err
+	}
 
+	return tx.Commit()
+}
+
+// createSymbolsTables creates the tables of a symbols database.
+func createSymbolsTables(tx *sqlx.Tx) error {
 	// The column names are the lowercase version of fields in `symbolInDB`
 	// because sqlx lowercases struct fields by default. See
 	// http://jmoiron.github.io/sqlx/#query
-	_, err = tx.Exec(
+	_, err := tx.Exec(
 		`CREATE TABLE IF NOT EXISTS symbols (
 			name VARCHAR(256) NOT NULL,
 			namelowercase VARCHAR(256) NOT NULL,
@@ -303,6 +407,8 @@ func (s *Service) writeAllSymbolsToNewDB(ctx context.Context, dbFile string, rep
 			parentkind VARCHAR(255) NOT NULL,
 			signature VARCHAR(255) NOT NULL,
 			pattern VARCHAR(255) NOT NULL,
+			endline INT NOT NULL,
+			endcharacter INT NOT NULL,
 			filelimited BOOLEAN NOT NULL
 		)`)
 	if err != nil {
@@ -330,28 +436,28 @@ func (s *Service) writeAllSymbolsToNewDB(ctx context.Context, dbFile string, rep
 		return err
 	}
 
+	// `meta` records the commit the symbols are from, so that we can update
+	// a copy of the database for a later commit.
+	_, err = tx.Exec(`CREATE TABLE IF NOT EXISTS meta (commit_id VARCHAR(40) NOT NULL)`)
+	return err
+}
+
+// insertSymbols parses the given paths of repo@commit, or all files if paths
+// is empty, and inserts their symbols.
+func (s *Service) insertSymbols(ctx context.Context, tx *sqlx.Tx, sel parserSelection, repoName api.RepoName, commitID api.CommitID, paths []string) error {
 	insertStatement, err := tx.PrepareNamed(
 		fmt.Sprintf(
 			"INSERT INTO symbols %s VALUES %s",
-			"( name,  namelowercase,  path,  pathlowercase,  line,  kind,  language,  parent,  parentkind,  signature,  pattern,  filelimited)",
-			"(:name, :namelowercase, :path, :pathlowercase, :line, :kind, :language, :parent, :parentkind, :signature, :pattern, :filelimited)"))
+			"( name,  namelowercase,  path,  pathlowercase,  line,  kind,  language,  parent,  parentkind,  signature,  pattern,  endline,  endcharacter,  filelimited)",
+			"(:name, :namelowercase, :path, :pathlowercase, :line, :kind, :language, :parent, :parentkind, :signature, :pattern, :endline, :endcharacter, :filelimited)"))
 	if err != nil {
 		return err
 	}
+	defer insertStatement.Close()
 
-	err = s.parseUncached(ctx, repoName, commitID, func(symbol result.Symbol) error {
+	return s.parseUncached(ctx, sel, repoName, commitID, paths, func(symbol result.Symbol) error {
 		symbolInDBValue := symbolToSymbolInDB(symbol)
 		_, err := insertStatement.Exec(&symbolInDBValue)
 		return err
 	})
-	if err != nil {
-		return err
-	}
-
-	err = tx.Commit()
-	if err != nil {
-		return err
-	}
-
-	return nil
 }
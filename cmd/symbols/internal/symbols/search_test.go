@@ -3,6 +3,7 @@ package symbols
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"path"
 	"testing"
@@ -11,6 +12,7 @@ import (
 
 	"github.com/sourcegraph/sourcegraph/cmd/symbols/internal/protocol"
 	"github.com/sourcegraph/sourcegraph/cmd/symbols/internal/sqliteutil"
+	"github.com/sourcegraph/sourcegraph/internal/api"
 	"github.com/sourcegraph/sourcegraph/internal/testutil"
 )
 
@@ -20,7 +22,9 @@ func BenchmarkSearch(b *testing.B) {
 	log15.Root().SetHandler(log15.LvlFilterHandler(log15.LvlError, log15.Root().GetHandler()))
 
 	service := Service{
-		FetchTar:  testutil.FetchTarFromGithub,
+		FetchTar: func(ctx context.Context, repo api.RepoName, commit api.CommitID, paths []string) (io.ReadCloser, error) {
+			return testutil.FetchTarFromGithub(ctx, repo, commit, paths)
+		},
 		NewParser: NewParser,
 		Path:      "/tmp/symbols-cache",
 	}
@@ -51,7 +55,7 @@ func BenchmarkSearch(b *testing.B) {
 					b.Fatal(err)
 				}
 				defer os.Remove(tempFile.Name())
-				err = service.writeAllSymbolsToNewDB(ctx, tempFile.Name(), test.Repo, test.CommitID)
+				err = service.writeAllSymbolsToNewDB(ctx, tempFile.Name(), service.selectParsers(), test.Repo, test.CommitID)
 				if err != nil {
 					b.Fatal(err)
 				}
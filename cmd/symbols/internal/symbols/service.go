@@ -19,9 +19,15 @@ import (
 // Service is the symbols service.
 type Service struct {
 	// FetchTar returns an io.ReadCloser to a tar archive of a repository at the specified Git
-	// remote URL and commit ID. If the error implements "BadRequest() bool", it will be used to
-	// determine if the error is a bad request (eg invalid repo).
-	FetchTar func(context.Context, api.RepoName, api.CommitID) (io.ReadCloser, error)
+	// remote URL and commit ID. If paths is non-empty, the archive only contains those paths.
+	// If the error implements "BadRequest() bool", it will be used to determine if the error
+	// is a bad request (eg invalid repo).
+	FetchTar func(ctx context.Context, repo api.RepoName, commit api.CommitID, paths []string) (io.ReadCloser, error)
+
+	// GitDiff returns the paths which changed between two commits of a repository. When set,
+	// the symbols of a new commit are indexed by updating a copy of the database of the commit
+	// we indexed last instead of parsing the whole repository.
+	GitDiff func(ctx context.Context, repo api.RepoName, commitA, commitB api.CommitID) (Changes, error)
 
 	// MaxConcurrentFetchTar is the maximum number of concurrent calls allowed
 	// to FetchTar. It defaults to 15.
@@ -29,6 +35,14 @@ type Service struct {
 
 	NewParser func() (ctags.Parser, error)
 
+	// Parsers are the in-process parsers, by name, which can be used instead of ctags for
+	// some languages. It defaults to DefaultParsers().
+	Parsers map[string]Parser
+
+	// ParserConfig returns a map from language name to the name of the parser in Parsers to
+	// use for files of that language. Other languages are parsed with ctags.
+	ParserConfig func() map[string]string
+
 	// NumParserProcesses is the maximum number of ctags parser child processes to run.
 	NumParserProcesses int
 
@@ -63,6 +77,10 @@ func (s *Service) Start() error {
 	}
 	s.fetchSem = make(chan int, s.MaxConcurrentFetchTar)
 
+	if s.Parsers == nil {
+		s.Parsers = DefaultParsers()
+	}
+
 	s.cache = &diskcache.Store{
 		Dir:               s.Path,
 		Component:         "symbols",
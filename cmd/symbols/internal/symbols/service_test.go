@@ -61,6 +61,12 @@ func TestIsLiteralEquality(t *testing.T) {
 	}
 }
 
+func TestFuzzyLikePattern(t *testing.T) {
+	if got, want := fuzzyLikePattern(`a_b%\`), `%a%\_%b%\%%\\%`; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
 func TestService(t *testing.T) {
 	sqliteutil.MustRegisterSqlite3WithPcre()
 
@@ -72,7 +78,7 @@ func TestService(t *testing.T) {
 
 	files := map[string]string{"a.js": "var x = 1"}
 	service := Service{
-		FetchTar: func(ctx context.Context, repo api.RepoName, commit api.CommitID) (io.ReadCloser, error) {
+		FetchTar: func(ctx context.Context, repo api.RepoName, commit api.CommitID, paths []string) (io.ReadCloser, error) {
 			return createTar(files)
 		},
 		NewParser: func() (ctags.Parser, error) {
@@ -154,6 +160,85 @@ func TestService(t *testing.T) {
 	}
 }
 
+func TestServiceFuzzy(t *testing.T) {
+	sqliteutil.MustRegisterSqlite3WithPcre()
+
+	tmpDir, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { os.RemoveAll(tmpDir) }()
+
+	files := map[string]string{"a.go": "func NewServer() {}"}
+	service := Service{
+		FetchTar: func(ctx context.Context, repo api.RepoName, commit api.CommitID, paths []string) (io.ReadCloser, error) {
+			return createTar(files)
+		},
+		NewParser: func() (ctags.Parser, error) {
+			return mockParser{"NewServer", "newServe", "Écrire", "a_b"}, nil
+		},
+		Path: tmpDir,
+	}
+
+	if err := service.Start(); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(service.Handler())
+	defer server.Close()
+	client := symbolsclient.Client{
+		URL:        server.URL,
+		HTTPClient: httpcli.InternalDoer,
+	}
+	newServer := result.Symbol{Name: "NewServer", Path: "a.go"}
+	newServe := result.Symbol{Name: "newServe", Path: "a.go"}
+	ecrire := result.Symbol{Name: "Écrire", Path: "a.go"}
+	ab := result.Symbol{Name: "a_b", Path: "a.go"}
+
+	tests := map[string]struct {
+		args search.SymbolsParameters
+		want result.Symbols
+	}{
+		"subsequence": {
+			args: search.SymbolsParameters{Query: "nsrv", IsFuzzy: true, First: 10},
+			want: []result.Symbol{newServe, newServer},
+		},
+		"casesensitive": {
+			args: search.SymbolsParameters{Query: "NSrv", IsFuzzy: true, IsCaseSensitive: true, First: 10},
+			want: []result.Symbol{newServer},
+		},
+		"nonascii": {
+			args: search.SymbolsParameters{Query: "écr", IsFuzzy: true, First: 10},
+			want: []result.Symbol{ecrire},
+		},
+		"nonasciicasesensitive": {
+			args: search.SymbolsParameters{Query: "écr", IsFuzzy: true, IsCaseSensitive: true, First: 10},
+			want: nil,
+		},
+		"likewildcards": {
+			args: search.SymbolsParameters{Query: "_", IsFuzzy: true, First: 10},
+			want: []result.Symbol{ab},
+		},
+		"nomatches": {
+			args: search.SymbolsParameters{Query: "srvn", IsFuzzy: true, First: 10},
+			want: nil,
+		},
+	}
+	for label, test := range tests {
+		t.Run(label, func(t *testing.T) {
+			result, err := client.Search(context.Background(), test.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result != nil && !reflect.DeepEqual(*result, test.want) {
+				t.Errorf("got %+v, want %+v", *result, test.want)
+			}
+			if result == nil && test.want != nil {
+				t.Errorf("got nil, want %+v", test.want)
+			}
+		})
+	}
+}
+
 func createTar(files map[string]string) (io.ReadCloser, error) {
 	buf := new(bytes.Buffer)
 	w := tar.NewWriter(buf)
@@ -4,6 +4,7 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"log"
 	"net"
@@ -14,6 +15,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/cockroachdb/errors"
 	"github.com/inconshreveable/log15"
 
 	"github.com/sourcegraph/sourcegraph/cmd/symbols/internal/sqliteutil"
@@ -56,11 +58,29 @@ func main() {
 	go debugserver.NewServerRoutine(ready).Start()
 
 	service := symbols.Service{
-		FetchTar: func(ctx context.Context, repo api.RepoName, commit api.CommitID) (io.ReadCloser, error) {
-			return gitserver.DefaultClient.Archive(ctx, repo, gitserver.ArchiveOptions{Treeish: string(commit), Format: "tar"})
+		FetchTar: func(ctx context.Context, repo api.RepoName, commit api.CommitID, paths []string) (io.ReadCloser, error) {
+			// git archive interprets paths as pathspecs, but we pass it the
+			// names of changed files, which may contain glob characters.
+			pathspecs := make([]string, 0, len(paths))
+			for _, path := range paths {
+				pathspecs = append(pathspecs, ":(literal)"+path)
+			}
+			return gitserver.DefaultClient.Archive(ctx, repo, gitserver.ArchiveOptions{Treeish: string(commit), Format: "tar", Paths: pathspecs})
+		},
+		GitDiff: func(ctx context.Context, repo api.RepoName, commitA, commitB api.CommitID) (symbols.Changes, error) {
+			cmd := gitserver.DefaultClient.Command("git", "diff", "-z", "--name-status", "--no-renames", string(commitA), string(commitB))
+			cmd.Repo = repo
+			out, err := cmd.Output(ctx)
+			if err != nil {
+				return symbols.Changes{}, errors.WithMessage(err, fmt.Sprintf("git command %v failed", cmd.Args))
+			}
+			return symbols.ParseGitDiffNameStatus(out)
 		},
 		NewParser: symbols.NewParser,
-		Path:      cacheDir,
+		ParserConfig: func() map[string]string {
+			return conf.Get().SearchSymbolsParsers
+		},
+		Path: cacheDir,
 	}
 	if mb, err := strconv.ParseInt(cacheSizeMB, 10, 64); err != nil {
 		log.Fatalf("Invalid SYMBOLS_CACHE_SIZE_MB: %s", err)
@@ -3,9 +3,12 @@ package main
 import (
 	"github.com/sourcegraph/sourcegraph/cmd/worker/shared"
 	"github.com/sourcegraph/sourcegraph/internal/authz"
+	"github.com/sourcegraph/sourcegraph/internal/search/commitindex"
 )
 
 func main() {
 	authz.SetProviders(true, []authz.Provider{})
-	shared.Start(nil)
+	shared.Start(map[string]shared.Job{
+		"search-commit-indexer": commitindex.NewIndexingJob(),
+	})
 }
@@ -0,0 +1,50 @@
+package searchexports
+
+import (
+	"time"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/codeintel/stores/uploadstore"
+	"github.com/sourcegraph/sourcegraph/internal/env"
+)
+
+type Config struct {
+	env.BaseConfig
+
+	NumHandlers int
+	Retention   time.Duration
+	URLTTL      time.Duration
+
+	UploadStoreConfig *uploadstore.Config
+}
+
+var config = &Config{}
+
+func init() {
+	config.NumHandlers = config.GetInt("SEARCH_EXPORT_CONCURRENCY", "1", "The maximum number of search exports each frontend runs at a time.")
+	config.Retention = config.GetInterval("SEARCH_EXPORT_RETENTION", "72h", "How long exported search results are kept.")
+	config.URLTTL = config.GetInterval("SEARCH_EXPORT_DOWNLOAD_URL_TTL", "1h", "How long the signed download URLs of exported search results are valid.")
+
+	// Exports are stored in the blob storage of code intelligence uploads,
+	// but in a bucket of their own. Objects in it expire a day after the
+	// janitor should have deleted them, in case it doesn't.
+	uploadStoreConfig := &uploadstore.Config{}
+	uploadStoreConfig.Load()
+	codeIntelBucket := uploadStoreConfig.Bucket
+	uploadStoreConfig.Bucket = config.Get("SEARCH_EXPORT_UPLOAD_BUCKET", "search-exports", "The name of the bucket to store exported search results in.")
+	if uploadStoreConfig.Bucket == codeIntelBucket {
+		config.AddError(errors.New("SEARCH_EXPORT_UPLOAD_BUCKET must differ from PRECISE_CODE_INTEL_UPLOAD_BUCKET, since objects expire at different times"))
+	}
+	uploadStoreConfig.TTL = config.Retention + 24*time.Hour
+	config.UploadStoreConfig = uploadStoreConfig
+}
+
+// Validate returns the errors of loading the search export and upload store
+// configuration.
+func (c *Config) Validate() error {
+	if err := c.BaseConfig.Validate(); err != nil {
+		return err
+	}
+	return c.UploadStoreConfig.Validate()
+}
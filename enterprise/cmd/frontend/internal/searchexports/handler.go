@@ -0,0 +1,282 @@
+package searchexports
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/inconshreveable/log15"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/globals"
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/codeintel/stores/uploadstore"
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/searchexports"
+	"github.com/sourcegraph/sourcegraph/internal/actor"
+	"github.com/sourcegraph/sourcegraph/internal/search/query"
+)
+
+// handler serves the search export API:
+//
+//   POST /.api/search/export?q=...&format=csv     creates an export
+//   GET  /.api/search/export                      lists the exports of the user
+//   GET  /.api/search/export/{id}                 returns an export
+//   POST /.api/search/export/{id}/cancel          cancels an export
+//   GET  /.api/search/export/download/{id}?...    downloads an export with a signed URL
+type handler struct {
+	store       *searchexports.Store
+	uploadStore uploadstore.Store
+
+	// urlTTL is how long signed download URLs are valid.
+	urlTTL time.Duration
+
+	// retention is how long finished exports are kept.
+	retention time.Duration
+
+	now func() time.Time
+}
+
+func newHandler(store *searchexports.Store, uploadStore uploadstore.Store, urlTTL, retention time.Duration) http.Handler {
+	return &handler{
+		store:       store,
+		uploadStore: uploadStore,
+		urlTTL:      urlTTL,
+		retention:   retention,
+		now:         time.Now,
+	}
+}
+
+const routePrefix = "/.api/search/export"
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, routePrefix), "/")
+	parts := strings.Split(path, "/")
+
+	// 🚨 SECURITY: Downloads are authorized by the signature of their URL,
+	// all other requests by the user.
+	if len(parts) == 2 && parts[0] == "download" && r.Method == "GET" {
+		h.serveDownload(w, r, parts[1])
+		return
+	}
+
+	a := actor.FromContext(r.Context())
+	if !a.IsAuthenticated() {
+		http.Error(w, "not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	switch {
+	case path == "" && r.Method == "POST":
+		h.serveCreate(w, r, a.UID)
+	case path == "" && r.Method == "GET":
+		h.serveList(w, r, a.UID)
+	case len(parts) == 1 && r.Method == "GET":
+		if job, ok := h.userJob(w, r, a.UID, parts[0]); ok {
+			h.writeJSON(w, http.StatusOK, h.toJSON(job))
+		}
+	case len(parts) == 2 && parts[1] == "cancel" && r.Method == "POST":
+		if job, ok := h.userJob(w, r, a.UID, parts[0]); ok {
+			if err := h.store.Cancel(r.Context(), job.ID); err != nil {
+				h.internalError(w, err)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		}
+	default:
+		http.Error(w, "no route", http.StatusNotFound)
+	}
+}
+
+func (h *handler) serveCreate(w http.ResponseWriter, r *http.Request, userID int32) {
+	q := r.FormValue("q")
+	if q == "" {
+		http.Error(w, "no query found", http.StatusBadRequest)
+		return
+	}
+	if patternType := r.FormValue("patternType"); patternType != "" {
+		q += " patterntype:" + patternType
+	}
+	if _, err := query.Pipeline(query.Init(q, query.SearchTypeLiteral)); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	format := r.FormValue("format")
+	switch format {
+	case "":
+		format = searchexports.FormatJSONLines
+	case searchexports.FormatJSONLines, searchexports.FormatCSV:
+	default:
+		http.Error(w, "format must be csv or jsonl, got "+format, http.StatusBadRequest)
+		return
+	}
+
+	job, err := h.store.Create(r.Context(), userID, q, format)
+	if err != nil {
+		h.internalError(w, err)
+		return
+	}
+	h.writeJSON(w, http.StatusCreated, h.toJSON(job))
+}
+
+func (h *handler) serveList(w http.ResponseWriter, r *http.Request, userID int32) {
+	jobs, err := h.store.ListByUser(r.Context(), userID)
+	if err != nil {
+		h.internalError(w, err)
+		return
+	}
+	payload := make([]jobJSON, 0, len(jobs))
+	for _, job := range jobs {
+		payload = append(payload, h.toJSON(job))
+	}
+	h.writeJSON(w, http.StatusOK, payload)
+}
+
+// userJob returns the job with the given ID if it belongs to userID, and
+// writes a 404 otherwise.
+func (h *handler) userJob(w http.ResponseWriter, r *http.Request, userID int32, rawID string) (*searchexports.Job, bool) {
+	job, ok, err := h.getJob(r.Context(), rawID)
+	if err != nil {
+		h.internalError(w, err)
+		return nil, false
+	}
+	// 🚨 SECURITY: Users can only see their own exports.
+	if !ok || job.UserID != userID {
+		http.Error(w, "export not found", http.StatusNotFound)
+		return nil, false
+	}
+	return job, true
+}
+
+func (h *handler) getJob(ctx context.Context, rawID string) (*searchexports.Job, bool, error) {
+	id, err := strconv.Atoi(rawID)
+	if err != nil {
+		return nil, false, nil
+	}
+	return h.store.GetByID(ctx, id)
+}
+
+func (h *handler) serveDownload(w http.ResponseWriter, r *http.Request, rawID string) {
+	job, ok, err := h.getJob(r.Context(), rawID)
+	if err != nil {
+		h.internalError(w, err)
+		return
+	}
+	expires, _ := strconv.ParseInt(r.URL.Query().Get("expires"), 10, 64)
+	// 🚨 SECURITY: Check the signature before we reveal anything about the
+	// export, including whether it exists.
+	if !ok || !verifySignature(job, expires, r.URL.Query().Get("signature")) {
+		http.Error(w, "invalid download URL", http.StatusForbidden)
+		return
+	}
+	if h.now().Unix() > expires {
+		http.Error(w, "download URL expired", http.StatusForbidden)
+		return
+	}
+	// Exports of deleted users are about to be deleted.
+	if job.State != "completed" || job.UserID == 0 {
+		http.Error(w, "export not found", http.StatusNotFound)
+		return
+	}
+
+	rc, err := h.uploadStore.Get(r.Context(), job.ObjectKey())
+	if err != nil {
+		h.internalError(w, err)
+		return
+	}
+	defer rc.Close()
+
+	contentType := "application/x-ndjson"
+	if job.Format == searchexports.FormatCSV {
+		contentType = "text/csv"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=search-export-%d.%s", job.ID, job.Format))
+	if _, err := io.Copy(w, rc); err != nil {
+		log15.Error("search export: failed to write download", "id", job.ID, "error", err)
+	}
+}
+
+// jobJSON is the representation of a job in the API.
+type jobJSON struct {
+	ID             int        `json:"id"`
+	Query          string     `json:"query"`
+	Format         string     `json:"format"`
+	State          string     `json:"state"`
+	FailureMessage *string    `json:"failureMessage,omitempty"`
+	ResultCount    int        `json:"resultCount"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	StartedAt      *time.Time `json:"startedAt,omitempty"`
+	FinishedAt     *time.Time `json:"finishedAt,omitempty"`
+
+	// ExpiresAt is when the export is deleted.
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+
+	// DownloadURL is a signed URL to download a completed export. It is
+	// valid for a limited time, anyone who knows it can download the export.
+	DownloadURL string `json:"downloadURL,omitempty"`
+}
+
+func (h *handler) toJSON(job *searchexports.Job) jobJSON {
+	payload := jobJSON{
+		ID:             job.ID,
+		Query:          job.Query,
+		Format:         job.Format,
+		State:          job.State,
+		FailureMessage: job.FailureMessage,
+		ResultCount:    job.ResultCount,
+		CreatedAt:      job.CreatedAt,
+		StartedAt:      job.StartedAt,
+		FinishedAt:     job.FinishedAt,
+	}
+	if job.FinishedAt != nil {
+		expiresAt := job.FinishedAt.Add(h.retention)
+		payload.ExpiresAt = &expiresAt
+	}
+	if job.State == "completed" {
+		payload.DownloadURL = downloadURL(globals.ExternalURL(), job, h.now().Add(h.urlTTL).Unix())
+	}
+	return payload
+}
+
+// downloadURL returns the URL to download job, signed with its secret, which
+// expires at the given Unix time.
+func downloadURL(externalURL *url.URL, job *searchexports.Job, expires int64) string {
+	u := *externalURL
+	u.Path = fmt.Sprintf("%s/download/%d", routePrefix, job.ID)
+	u.RawQuery = url.Values{
+		"expires":   []string{strconv.FormatInt(expires, 10)},
+		"signature": []string{signature(job, expires)},
+	}.Encode()
+	return u.String()
+}
+
+func signature(job *searchexports.Job, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(job.DownloadSecret))
+	fmt.Fprintf(mac, "%d:%d", job.ID, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func verifySignature(job *searchexports.Job, expires int64, sig string) bool {
+	return hmac.Equal([]byte(signature(job, expires)), []byte(sig))
+}
+
+func (h *handler) writeJSON(w http.ResponseWriter, status int, payload interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(payload); err != nil {
+		log15.Error("search export: failed to write response", "error", err)
+	}
+}
+
+func (h *handler) internalError(w http.ResponseWriter, err error) {
+	log15.Error("search export: request failed", "error", err)
+	http.Error(w, "internal error", http.StatusInternalServerError)
+}
@@ -0,0 +1,38 @@
+package searchexports
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/searchexports"
+)
+
+func TestDownloadURL(t *testing.T) {
+	externalURL, _ := url.Parse("https://sourcegraph.example.com")
+	job := &searchexports.Job{ID: 42, DownloadSecret: "secret"}
+
+	u, err := url.Parse(downloadURL(externalURL, job, 1600000000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "/.api/search/export/download/42"; u.Path != want {
+		t.Fatalf("got path %q, want %q", u.Path, want)
+	}
+	if u.Query().Get("expires") != "1600000000" {
+		t.Fatalf("got expires %q", u.Query().Get("expires"))
+	}
+
+	sig := u.Query().Get("signature")
+	if !verifySignature(job, 1600000000, sig) {
+		t.Fatal("expected signature to be valid")
+	}
+	if verifySignature(job, 1600000001, sig) {
+		t.Fatal("expected signature of a different expiry to be invalid")
+	}
+	if verifySignature(&searchexports.Job{ID: 43, DownloadSecret: "secret"}, 1600000000, sig) {
+		t.Fatal("expected signature of a different job to be invalid")
+	}
+	if verifySignature(&searchexports.Job{ID: 42, DownloadSecret: "other"}, 1600000000, sig) {
+		t.Fatal("expected signature with a different secret to be invalid")
+	}
+}
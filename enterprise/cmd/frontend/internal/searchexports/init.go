@@ -0,0 +1,67 @@
+package searchexports
+
+import (
+	"context"
+
+	"github.com/cockroachdb/errors"
+	"github.com/inconshreveable/log15"
+	"github.com/opentracing/opentracing-go"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/enterprise"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend"
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/codeintel/stores/uploadstore"
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/searchexports"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
+	"github.com/sourcegraph/sourcegraph/internal/goroutine"
+	"github.com/sourcegraph/sourcegraph/internal/observation"
+	"github.com/sourcegraph/sourcegraph/internal/oobmigration"
+	"github.com/sourcegraph/sourcegraph/internal/search/streaming"
+	"github.com/sourcegraph/sourcegraph/internal/trace"
+)
+
+// Init registers the search export API and starts the workers which run
+// search exports. Exported results are stored in a bucket of their own in the
+// blob storage of code intelligence uploads.
+func Init(ctx context.Context, db dbutil.DB, outOfBandMigrationRunner *oobmigration.Runner, enterpriseServices *enterprise.Services) error {
+	if err := config.Validate(); err != nil {
+		return errors.Wrap(err, "invalid search export config")
+	}
+
+	observationContext := &observation.Context{
+		Logger:     log15.Root(),
+		Tracer:     &trace.Tracer{Tracer: opentracing.GlobalTracer()},
+		Registerer: prometheus.DefaultRegisterer,
+	}
+
+	uploadStore, err := uploadstore.CreateLazy(ctx, config.UploadStoreConfig, observationContext)
+	if err != nil {
+		return errors.Wrap(err, "failed to initialize search export upload store")
+	}
+
+	routines := searchexports.NewBackgroundRoutines(ctx, db, uploadStore, newSearchFunc(db), searchexports.WorkerOptions{
+		NumHandlers: config.NumHandlers,
+		Retention:   config.Retention,
+	}, observationContext)
+	go goroutine.MonitorBackgroundRoutines(ctx, routines...)
+
+	enterpriseServices.SearchExportHandler = newHandler(searchexports.NewStore(db), uploadStore, config.URLTTL, config.Retention)
+	return nil
+}
+
+// newSearchFunc returns a SearchFunc which runs searches in this process,
+// like the streaming search API.
+func newSearchFunc(db dbutil.DB) searchexports.SearchFunc {
+	return func(ctx context.Context, query string, stream streaming.Sender) error {
+		search, err := graphqlbackend.NewSearchImplementer(ctx, db, &graphqlbackend.SearchArgs{
+			Query:   query,
+			Version: "V2",
+			Stream:  stream,
+		})
+		if err != nil {
+			return err
+		}
+		_, err = search.Results(ctx)
+		return err
+	}
+}
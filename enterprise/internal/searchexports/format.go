@@ -0,0 +1,122 @@
+package searchexports
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/sourcegraph/sourcegraph/internal/search/result"
+)
+
+// Row is one exported search result. File matches result in a row per
+// matching line or symbol.
+type Row struct {
+	Type       string `json:"type"`
+	Repository string `json:"repository"`
+	Commit     string `json:"commit,omitempty"`
+	Path       string `json:"path,omitempty"`
+	LineNumber int    `json:"lineNumber,omitempty"`
+	Preview    string `json:"preview,omitempty"`
+}
+
+// Rows returns the rows to export for match.
+func Rows(match result.Match) []Row {
+	switch m := match.(type) {
+	case *result.FileMatch:
+		file := Row{
+			Type:       "path",
+			Repository: string(m.Repo.Name),
+			Commit:     string(m.CommitID),
+			Path:       m.Path,
+		}
+		if len(m.LineMatches) == 0 && len(m.Symbols) == 0 {
+			return []Row{file}
+		}
+		rows := make([]Row, 0, len(m.LineMatches)+len(m.Symbols))
+		for _, lm := range m.LineMatches {
+			row := file
+			row.Type = "content"
+			row.LineNumber = int(lm.LineNumber) + 1
+			row.Preview = lm.Preview
+			rows = append(rows, row)
+		}
+		for _, sm := range m.Symbols {
+			row := file
+			row.Type = "symbol"
+			row.LineNumber = sm.Symbol.Line
+			row.Preview = sm.Symbol.Name
+			rows = append(rows, row)
+		}
+		return rows
+	case *result.CommitMatch:
+		typ := "commit"
+		if m.DiffPreview != nil {
+			typ = "diff"
+		}
+		return []Row{{
+			Type:       typ,
+			Repository: string(m.Repo.Name),
+			Commit:     string(m.Commit.ID),
+			Preview:    m.Commit.Message.Subject(),
+		}}
+	case *result.RepoMatch:
+		return []Row{{
+			Type:       "repo",
+			Repository: string(m.Name),
+		}}
+	default:
+		return nil
+	}
+}
+
+// RowWriter writes exported rows in one of the export formats.
+type RowWriter interface {
+	Write(Row) error
+	// Close flushes buffered rows. It doesn't close the underlying writer.
+	Close() error
+}
+
+// NewRowWriter returns a RowWriter for format which writes to w.
+func NewRowWriter(format string, w io.Writer) (RowWriter, error) {
+	switch format {
+	case FormatJSONLines:
+		return &jsonLinesWriter{enc: json.NewEncoder(w)}, nil
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(csvHeader); err != nil {
+			return nil, err
+		}
+		return &csvWriter{w: cw}, nil
+	default:
+		return nil, errors.Errorf("unknown export format %q", format)
+	}
+}
+
+type jsonLinesWriter struct {
+	enc *json.Encoder
+}
+
+func (w *jsonLinesWriter) Write(row Row) error { return w.enc.Encode(row) }
+func (w *jsonLinesWriter) Close() error        { return nil }
+
+var csvHeader = []string{"type", "repository", "commit", "path", "line", "preview"}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func (w *csvWriter) Write(row Row) error {
+	line := ""
+	if row.LineNumber > 0 {
+		line = strconv.Itoa(row.LineNumber)
+	}
+	return w.w.Write([]string{row.Type, row.Repository, row.Commit, row.Path, line, row.Preview})
+}
+
+func (w *csvWriter) Close() error {
+	w.w.Flush()
+	return w.w.Error()
+}
@@ -0,0 +1,81 @@
+package searchexports
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/sourcegraph/sourcegraph/internal/search/result"
+	"github.com/sourcegraph/sourcegraph/internal/types"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+)
+
+func TestRowWriter(t *testing.T) {
+	matches := []result.Match{
+		&result.FileMatch{
+			File: result.File{Repo: types.RepoName{Name: "github.com/a/b"}, CommitID: "deadbeef", Path: "main.go"},
+			LineMatches: []*result.LineMatch{
+				{Preview: "func main() {", LineNumber: 2},
+				{Preview: `	fmt.Println("a, b")`, LineNumber: 3},
+			},
+		},
+		&result.FileMatch{
+			File: result.File{Repo: types.RepoName{Name: "github.com/a/b"}, CommitID: "deadbeef", Path: "README.md"},
+		},
+		&result.CommitMatch{
+			Repo:   types.RepoName{Name: "github.com/a/b"},
+			Commit: git.Commit{ID: "cafe", Message: "Fix bug\n\nDetails"},
+		},
+		&result.RepoMatch{Name: "github.com/a/c"},
+	}
+
+	cases := []struct {
+		format string
+		want   string
+	}{{
+		format: FormatCSV,
+		want: `type,repository,commit,path,line,preview
+content,github.com/a/b,deadbeef,main.go,3,func main() {
+content,github.com/a/b,deadbeef,main.go,4,"	fmt.Println(""a, b"")"
+path,github.com/a/b,deadbeef,README.md,,
+commit,github.com/a/b,cafe,,,Fix bug
+repo,github.com/a/c,,,,
+`,
+	}, {
+		format: FormatJSONLines,
+		want: `{"type":"content","repository":"github.com/a/b","commit":"deadbeef","path":"main.go","lineNumber":3,"preview":"func main() {"}
+{"type":"content","repository":"github.com/a/b","commit":"deadbeef","path":"main.go","lineNumber":4,"preview":"\tfmt.Println(\"a, b\")"}
+{"type":"path","repository":"github.com/a/b","commit":"deadbeef","path":"README.md"}
+{"type":"commit","repository":"github.com/a/b","commit":"cafe","preview":"Fix bug"}
+{"type":"repo","repository":"github.com/a/c"}
+`,
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := NewRowWriter(tc.format, &buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, match := range matches {
+				for _, row := range Rows(match) {
+					if err := w.Write(row); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, buf.String()); diff != "" {
+				t.Errorf("unexpected output (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	if _, err := NewRowWriter("xml", &bytes.Buffer{}); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
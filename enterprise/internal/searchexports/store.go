@@ -0,0 +1,251 @@
+package searchexports
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/keegancsmith/sqlf"
+
+	"github.com/sourcegraph/sourcegraph/internal/database/basestore"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
+	"github.com/sourcegraph/sourcegraph/internal/workerutil"
+	dbworkerstore "github.com/sourcegraph/sourcegraph/internal/workerutil/dbworker/store"
+)
+
+// The formats results can be exported in.
+const (
+	FormatJSONLines = "jsonl"
+	FormatCSV       = "csv"
+)
+
+// Job is a request to export the results of a search query. Its UserID is 0
+// if the user who created it was deleted.
+type Job struct {
+	ID             int
+	UserID         int32
+	Query          string
+	Format         string
+	DownloadSecret string
+	ResultCount    int
+	Cancel         bool
+	CreatedAt      time.Time
+	State          string
+	FailureMessage *string
+	StartedAt      *time.Time
+	FinishedAt     *time.Time
+	ProcessAfter   *time.Time
+	NumResets      int
+	NumFailures    int
+}
+
+func (j *Job) RecordID() int {
+	return j.ID
+}
+
+// ObjectKey returns the key of the exported results in the upload store.
+func (j *Job) ObjectKey() string {
+	return fmt.Sprintf("search-exports/%d.%s", j.ID, j.Format)
+}
+
+// Store reads and writes search export jobs.
+type Store struct {
+	*basestore.Store
+}
+
+// NewStore returns a new Store backed by the given database.
+func NewStore(db dbutil.DB) *Store {
+	return &Store{Store: basestore.NewWithDB(db, sql.TxOptions{})}
+}
+
+// Columns are the columns of search_export_jobs read by the store and the
+// worker.
+var Columns = []*sqlf.Query{
+	sqlf.Sprintf("search_export_jobs.id"),
+	sqlf.Sprintf("search_export_jobs.user_id"),
+	sqlf.Sprintf("search_export_jobs.query"),
+	sqlf.Sprintf("search_export_jobs.format"),
+	sqlf.Sprintf("search_export_jobs.download_secret"),
+	sqlf.Sprintf("search_export_jobs.result_count"),
+	sqlf.Sprintf("search_export_jobs.cancel"),
+	sqlf.Sprintf("search_export_jobs.created_at"),
+	sqlf.Sprintf("search_export_jobs.state"),
+	sqlf.Sprintf("search_export_jobs.failure_message"),
+	sqlf.Sprintf("search_export_jobs.started_at"),
+	sqlf.Sprintf("search_export_jobs.finished_at"),
+	sqlf.Sprintf("search_export_jobs.process_after"),
+	sqlf.Sprintf("search_export_jobs.num_resets"),
+	sqlf.Sprintf("search_export_jobs.num_failures"),
+}
+
+// NewWorkerStore returns the dbworker store the export worker dequeues jobs
+// from.
+func NewWorkerStore(s *Store) dbworkerstore.Store {
+	return dbworkerstore.New(s.Handle(), dbworkerstore.Options{
+		Name:              "search_export_jobs_worker_store",
+		TableName:         "search_export_jobs",
+		ColumnExpressions: Columns,
+		Scan:              ScanFirstJob,
+		StalledMaxAge:     time.Minute,
+		MaxNumResets:      3,
+		// Exports are expensive, so we don't retry failed ones. Users can
+		// export again.
+		RetryAfter:        0,
+		MaxNumRetries:     0,
+		OrderByExpression: sqlf.Sprintf("search_export_jobs.id"),
+	})
+}
+
+const createJobFmtStr = `
+INSERT INTO search_export_jobs (user_id, query, format, download_secret)
+VALUES (%s, %s, %s, %s)
+RETURNING %s
+`
+
+// Create enqueues a new job which exports the results of query for userID.
+func (s *Store) Create(ctx context.Context, userID int32, query, format string) (*Job, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	job, _, err := scanFirstJob(s.Query(ctx, sqlf.Sprintf(
+		createJobFmtStr,
+		userID,
+		query,
+		format,
+		hex.EncodeToString(secret),
+		sqlf.Join(Columns, ", "),
+	)))
+	return job, err
+}
+
+const getJobFmtStr = `
+SELECT %s FROM search_export_jobs WHERE id = %s
+`
+
+// GetByID returns the job with the given ID, or false if it doesn't exist.
+func (s *Store) GetByID(ctx context.Context, id int) (*Job, bool, error) {
+	return scanFirstJob(s.Query(ctx, sqlf.Sprintf(getJobFmtStr, sqlf.Join(Columns, ", "), id)))
+}
+
+const listJobsFmtStr = `
+SELECT %s FROM search_export_jobs WHERE user_id = %s ORDER BY id DESC
+`
+
+// ListByUser returns the jobs of userID, newest first.
+func (s *Store) ListByUser(ctx context.Context, userID int32) ([]*Job, error) {
+	return scanJobs(s.Query(ctx, sqlf.Sprintf(listJobsFmtStr, sqlf.Join(Columns, ", "), userID)))
+}
+
+const updateProgressFmtStr = `
+UPDATE search_export_jobs SET result_count = %s WHERE id = %s
+`
+
+// UpdateProgress records the number of results written by a running job.
+func (s *Store) UpdateProgress(ctx context.Context, id, resultCount int) error {
+	return s.Exec(ctx, sqlf.Sprintf(updateProgressFmtStr, resultCount, id))
+}
+
+const cancelJobFmtStr = `
+UPDATE search_export_jobs
+SET
+	cancel = TRUE,
+	-- Jobs which aren't running yet fail right away. Running jobs are
+	-- failed by the worker once it notices.
+	state = CASE WHEN state IN ('queued', 'errored') THEN 'failed' ELSE state END,
+	failure_message = CASE WHEN state IN ('queued', 'errored') THEN 'Canceled' ELSE failure_message END,
+	finished_at = CASE WHEN state IN ('queued', 'errored') THEN now() ELSE finished_at END
+WHERE id = %s AND state IN ('queued', 'errored', 'processing')
+`
+
+// Cancel cancels the job with the given ID if it hasn't finished yet.
+func (s *Store) Cancel(ctx context.Context, id int) error {
+	return s.Exec(ctx, sqlf.Sprintf(cancelJobFmtStr, id))
+}
+
+const canceledJobIDsFmtStr = `
+SELECT id FROM search_export_jobs WHERE state = 'processing' AND cancel
+`
+
+// CanceledJobIDs returns the IDs of the running jobs which are canceled.
+func (s *Store) CanceledJobIDs(ctx context.Context) ([]int, error) {
+	return basestore.ScanInts(s.Query(ctx, sqlf.Sprintf(canceledJobIDsFmtStr)))
+}
+
+const expiredJobsFmtStr = `
+SELECT %s FROM search_export_jobs
+WHERE
+	(state IN ('completed', 'failed') AND finished_at < %s) OR
+	-- The worker fails running jobs of deleted users.
+	(state != 'processing' AND (
+		user_id IS NULL OR
+		EXISTS (SELECT 1 FROM users WHERE users.id = search_export_jobs.user_id AND users.deleted_at IS NOT NULL)
+	))
+ORDER BY id
+LIMIT %s
+`
+
+// ExpiredJobs returns up to limit jobs which finished before the given time
+// or whose user was deleted.
+func (s *Store) ExpiredJobs(ctx context.Context, finishedBefore time.Time, limit int) ([]*Job, error) {
+	return scanJobs(s.Query(ctx, sqlf.Sprintf(expiredJobsFmtStr, sqlf.Join(Columns, ", "), finishedBefore, limit)))
+}
+
+const deleteJobFmtStr = `
+DELETE FROM search_export_jobs WHERE id = %s
+`
+
+// Delete deletes the job with the given ID. It doesn't delete its exported
+// results from the upload store.
+func (s *Store) Delete(ctx context.Context, id int) error {
+	return s.Exec(ctx, sqlf.Sprintf(deleteJobFmtStr, id))
+}
+
+// ScanFirstJob scans the first job of rows for the dbworker store.
+func ScanFirstJob(rows *sql.Rows, err error) (workerutil.Record, bool, error) {
+	return scanFirstJob(rows, err)
+}
+
+func scanFirstJob(rows *sql.Rows, err error) (*Job, bool, error) {
+	jobs, err := scanJobs(rows, err)
+	if err != nil || len(jobs) == 0 {
+		return &Job{}, false, err
+	}
+	return jobs[0], true, nil
+}
+
+func scanJobs(rows *sql.Rows, queryErr error) (_ []*Job, err error) {
+	if queryErr != nil {
+		return nil, queryErr
+	}
+	defer func() { err = basestore.CloseRows(rows, err) }()
+
+	var jobs []*Job
+	for rows.Next() {
+		var j Job
+		if err := rows.Scan(
+			&j.ID,
+			&dbutil.NullInt32{N: &j.UserID},
+			&j.Query,
+			&j.Format,
+			&j.DownloadSecret,
+			&j.ResultCount,
+			&j.Cancel,
+			&j.CreatedAt,
+			&j.State,
+			&j.FailureMessage,
+			&j.StartedAt,
+			&j.FinishedAt,
+			&j.ProcessAfter,
+			&j.NumResets,
+			&j.NumFailures,
+		); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, &j)
+	}
+	return jobs, nil
+}
@@ -0,0 +1,273 @@
+package searchexports
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/inconshreveable/log15"
+	"go.uber.org/atomic"
+
+	"github.com/sourcegraph/sourcegraph/enterprise/internal/codeintel/stores/uploadstore"
+	"github.com/sourcegraph/sourcegraph/internal/actor"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/database"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
+	"github.com/sourcegraph/sourcegraph/internal/goroutine"
+	"github.com/sourcegraph/sourcegraph/internal/lazyregexp"
+	"github.com/sourcegraph/sourcegraph/internal/observation"
+	"github.com/sourcegraph/sourcegraph/internal/search/result"
+	"github.com/sourcegraph/sourcegraph/internal/search/streaming"
+	"github.com/sourcegraph/sourcegraph/internal/workerutil"
+	"github.com/sourcegraph/sourcegraph/internal/workerutil/dbworker"
+)
+
+// SearchFunc runs query to completion and sends its results to stream. It
+// searches as the actor of ctx.
+type SearchFunc func(ctx context.Context, query string, stream streaming.Sender) error
+
+// progressInterval is how often running jobs record their progress.
+const progressInterval = 5 * time.Second
+
+// WorkerOptions configure the background routines of search exports.
+type WorkerOptions struct {
+	NumHandlers int
+
+	// Retention is how long the results of finished jobs are kept. The
+	// upload store must keep objects for longer.
+	Retention time.Duration
+}
+
+// NewBackgroundRoutines returns the routines which run search export jobs,
+// cancel them and delete expired exports.
+func NewBackgroundRoutines(ctx context.Context, db dbutil.DB, uploadStore uploadstore.Store, search SearchFunc, opts WorkerOptions, observationContext *observation.Context) []goroutine.BackgroundRoutine {
+	store := NewStore(db)
+	workerStore := NewWorkerStore(store)
+
+	worker := dbworker.NewWorker(ctx, workerStore, &handler{
+		db:          db,
+		store:       store,
+		uploadStore: uploadStore,
+		search:      search,
+	}, workerutil.WorkerOptions{
+		Name:              "search_export_jobs_worker",
+		NumHandlers:       opts.NumHandlers,
+		Interval:          5 * time.Second,
+		HeartbeatInterval: 15 * time.Second,
+		Metrics:           workerutil.NewMetrics(observationContext, "search_export_jobs", nil),
+	})
+
+	resetter := dbworker.NewResetter(workerStore, dbworker.ResetterOptions{
+		Name:     "search_export_jobs_worker_resetter",
+		Interval: time.Minute,
+		Metrics:  *dbworker.NewMetrics(observationContext, "search_export_jobs"),
+	})
+
+	canceler := goroutine.NewPeriodicGoroutine(ctx, 5*time.Second, goroutine.NewHandlerWithErrorMessage(
+		"search_export_jobs_canceler",
+		func(ctx context.Context) error {
+			ids, err := store.CanceledJobIDs(ctx)
+			if err != nil {
+				return err
+			}
+			// Cancel is a no-op for jobs that another frontend runs.
+			for _, id := range ids {
+				worker.Cancel(id)
+			}
+			return nil
+		},
+	))
+
+	janitor := goroutine.NewPeriodicGoroutine(ctx, time.Hour, goroutine.NewHandlerWithErrorMessage(
+		"search_export_jobs_janitor",
+		func(ctx context.Context) error {
+			return deleteExpiredJobs(ctx, store, uploadStore, time.Now().Add(-opts.Retention))
+		},
+	))
+
+	return []goroutine.BackgroundRoutine{worker, resetter, canceler, janitor}
+}
+
+// deleteExpiredJobs deletes the jobs that finished before the given time or
+// whose user was deleted. It deletes their exported results before the jobs,
+// so that we retry if it fails.
+func deleteExpiredJobs(ctx context.Context, store *Store, uploadStore uploadstore.Store, finishedBefore time.Time) error {
+	for {
+		jobs, err := store.ExpiredJobs(ctx, finishedBefore, 100)
+		if err != nil || len(jobs) == 0 {
+			return err
+		}
+		for _, job := range jobs {
+			if err := uploadStore.Delete(ctx, job.ObjectKey()); err != nil {
+				// Failed jobs usually didn't upload anything.
+				if job.State == "completed" {
+					return errors.Wrapf(err, "deleting export of job %d", job.ID)
+				}
+				log15.Debug("search export: failed to delete export of failed job", "id", job.ID, "error", err)
+			}
+			if err := store.Delete(ctx, job.ID); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+type handler struct {
+	db          dbutil.DB
+	store       *Store
+	uploadStore uploadstore.Store
+	search      SearchFunc
+}
+
+var _ workerutil.Handler = &handler{}
+
+// countFieldRegexp matches queries which limit their number of results.
+var countFieldRegexp = lazyregexp.New(`(?i)\bcount:`)
+
+func (h *handler) Handle(ctx context.Context, record workerutil.Record) (err error) {
+	job := record.(*Job)
+	if job.UserID == 0 {
+		// The janitor deletes the job.
+		return errors.New("user was deleted")
+	}
+
+	// 🚨 SECURITY: We search as the user who created the export, so that it
+	// only contains results from repositories they can access.
+	jobCtx := actor.WithActor(ctx, actor.FromUser(job.UserID))
+	ctx, cancel := context.WithCancel(jobCtx)
+	defer cancel()
+
+	// Exports are over all results, unless the query limits them.
+	query := job.Query
+	if !countFieldRegexp.MatchString(query) {
+		query += " count:all"
+	}
+
+	pr, pw := io.Pipe()
+	uploaded := make(chan error, 1)
+	go func() {
+		_, err := h.uploadStore.Upload(ctx, job.ObjectKey(), pr)
+		// Unblock the writer if the upload fails.
+		pr.CloseWithError(err)
+		uploaded <- err
+	}()
+
+	out, err := NewRowWriter(job.Format, pw)
+	if err != nil {
+		pw.CloseWithError(err)
+		<-uploaded
+		return err
+	}
+
+	var count atomic.Int64
+	progressDone := make(chan struct{})
+	go func() {
+		defer close(progressDone)
+		h.recordProgress(ctx, job.ID, &count)
+	}()
+
+	var (
+		mu       sync.Mutex
+		writeErr error
+	)
+	searchErr := h.search(ctx, query, streaming.StreamFunc(func(event streaming.SearchEvent) {
+		matches, err := h.accessibleMatches(ctx, event.Results)
+
+		mu.Lock()
+		defer mu.Unlock()
+		if writeErr != nil {
+			return
+		}
+		if err != nil {
+			// Fail the job rather than publish an export which silently
+			// misses results.
+			writeErr = err
+			cancel()
+			return
+		}
+		for _, match := range matches {
+			for _, row := range Rows(match) {
+				if writeErr = out.Write(row); writeErr != nil {
+					cancel()
+					return
+				}
+				count.Inc()
+			}
+		}
+	}))
+	if writeErr != nil {
+		// The search failed because we canceled it.
+		searchErr = writeErr
+	}
+	if err := jobCtx.Err(); err != nil {
+		// The job was canceled. Searches may return partial results
+		// without an error in that case.
+		searchErr = err
+	}
+	if searchErr == nil {
+		searchErr = out.Close()
+	}
+
+	// Closing the pipe with a nil error finishes the upload.
+	pw.CloseWithError(searchErr)
+	uploadErr := <-uploaded
+	cancel()
+	<-progressDone
+
+	if searchErr != nil {
+		return searchErr
+	}
+	if uploadErr != nil {
+		return errors.Wrap(uploadErr, "uploading export")
+	}
+	return h.store.UpdateProgress(jobCtx, job.ID, int(count.Load()))
+}
+
+// recordProgress periodically records count as the progress of job id
+// until ctx is done.
+func (h *handler) recordProgress(ctx context.Context, id int, count *atomic.Int64) {
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := h.store.UpdateProgress(ctx, id, int(count.Load())); err != nil && ctx.Err() == nil {
+				log15.Warn("search export: failed to record progress", "id", id, "error", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// accessibleMatches returns the matches in repositories the actor of ctx can
+// access. Like streaming search, we double check this against the database.
+func (h *handler) accessibleMatches(ctx context.Context, matches []result.Match) ([]result.Match, error) {
+	if len(matches) == 0 {
+		return nil, nil
+	}
+	ids := make([]api.RepoID, 0, len(matches))
+	for _, match := range matches {
+		ids = append(ids, match.RepoName().ID)
+	}
+	repos, err := database.Repos(h.db).Metadata(ctx, ids...)
+	if err != nil {
+		return nil, errors.Wrap(err, "getting repo metadata")
+	}
+	accessible := make(map[api.RepoID]api.RepoName, len(repos))
+	for _, repo := range repos {
+		accessible[repo.ID] = repo.Name
+	}
+
+	filtered := make([]result.Match, 0, len(matches))
+	for _, match := range matches {
+		repo := match.RepoName()
+		if name, ok := accessible[repo.ID]; ok && name == repo.Name {
+			filtered = append(filtered, match)
+		}
+	}
+	return filtered, nil
+}
@@ -0,0 +1,417 @@
+package comby
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/cockroachdb/errors"
+)
+
+// ErrUnsupportedNative is returned by CompileNative for arguments the native
+// matcher does not support. Callers should fall back to the comby binary.
+var ErrUnsupportedNative = errors.New("not supported by the native structural matcher")
+
+// maxSteps bounds the work the native matcher does per file, since templates
+// with many holes may backtrack a lot. Matching a file stops once it is
+// exceeded.
+const maxSteps = 10_000_000
+
+// NativeMatcher is an in-process implementation of comby's matching for the
+// languages in nativeSyntaxes. It supports the hole syntax :[x], :[[x]],
+// :[x.], :[x\n], :[ x], :[x~regexp] and ..., and rules which are where
+// clauses of == and != constraints. Matches are the same as comby's for
+// templates it supports, except that it never reports overlapping matches.
+type NativeMatcher struct {
+	tokens      []token
+	constraints []constraint
+	syntax      *syntax
+}
+
+// CompileNative compiles the match template, rule and matcher of args for
+// the native matcher. It returns an error wrapping ErrUnsupportedNative if
+// args requires the comby binary, such as for a rewrite.
+func CompileNative(args Args) (*NativeMatcher, error) {
+	if args.RewriteTemplate != "" && !args.MatchOnly {
+		return nil, errors.Wrap(ErrUnsupportedNative, "rewrite")
+	}
+	matcher := args.Matcher
+	if matcher == "" {
+		matcher = ".generic"
+	}
+	syntax, ok := nativeSyntaxes[matcher]
+	if !ok {
+		return nil, errors.Wrapf(ErrUnsupportedNative, "matcher %s", matcher)
+	}
+	tokens, err := tokenizeTemplate(args.MatchTemplate)
+	if err != nil {
+		return nil, err
+	}
+	constraints, err := parseRule(args.Rule)
+	if err != nil {
+		return nil, err
+	}
+	return &NativeMatcher{tokens: tokens, constraints: constraints, syntax: syntax}, nil
+}
+
+// Matches returns the non-overlapping matches of m in content, in order.
+func (m *NativeMatcher) Matches(content []byte) []Match {
+	src := newSource(content, m.syntax)
+
+	if len(m.tokens) == 0 {
+		// Like comby, an empty template matches every file once.
+		return []Match{src.match(0, 0)}
+	}
+
+	var matches []Match
+	steps := 0
+	for start := 0; start < len(content); start++ {
+		// Matches never start inside strings or comments, so we skip them
+		// whole unless a match starts at the beginning of a string.
+		regionEnd, inRegion := src.regionEnd[start]
+		if src.kinds[start] == kindComment {
+			start = regionEnd - 1
+			continue
+		}
+		if t := m.tokens[0]; t.kind == tokenLiteral && content[start] != t.literal[0] {
+			if inRegion {
+				start = regionEnd - 1
+			}
+			continue
+		}
+		st := &state{src: src, tokens: m.tokens, constraints: m.constraints, steps: &steps}
+		end, ok := st.matchFrom(0, start, nil)
+		if steps > maxSteps {
+			break
+		}
+		if !ok || end == start {
+			if inRegion {
+				start = regionEnd - 1
+			}
+			continue
+		}
+		match := src.match(start, end)
+		for _, b := range st.env {
+			match.Environment = append(match.Environment, EnvironmentEntry{
+				Variable: b.name,
+				Value:    b.value,
+				Range:    Range{Start: src.location(b.start), End: src.location(b.end)},
+			})
+		}
+		matches = append(matches, match)
+		start = end - 1
+	}
+	return matches
+}
+
+const (
+	kindCode byte = iota
+	kindString
+	kindComment
+)
+
+// source is a file prepared for matching.
+type source struct {
+	content []byte
+	syntax  *syntax
+
+	// kinds is the kind of each byte of content.
+	kinds []byte
+
+	// regionEnd maps the start of each string and comment to its end.
+	regionEnd map[int]int
+
+	// lines are the offsets at which lines start.
+	lines []int
+}
+
+func newSource(content []byte, syntax *syntax) *source {
+	s := &source{
+		content:   content,
+		syntax:    syntax,
+		kinds:     make([]byte, len(content)),
+		regionEnd: map[int]int{},
+		lines:     []int{0},
+	}
+	for i, c := range content {
+		if c == '\n' {
+			s.lines = append(s.lines, i+1)
+		}
+	}
+
+	for i := 0; i < len(content); {
+		end, kind := s.region(i)
+		if kind == kindCode {
+			i++
+			continue
+		}
+		for j := i; j < end; j++ {
+			s.kinds[j] = kind
+		}
+		s.regionEnd[i] = end
+		i = end
+	}
+	return s
+}
+
+// region returns the end and kind of the string or comment starting at i, if
+// any.
+func (s *source) region(i int) (int, byte) {
+	rest := s.content[i:]
+	for _, prefix := range s.syntax.lineComments {
+		if bytes.HasPrefix(rest, []byte(prefix)) {
+			if j := bytes.IndexByte(rest, '\n'); j >= 0 {
+				return i + j, kindComment
+			}
+			return len(s.content), kindComment
+		}
+	}
+	for _, delims := range s.syntax.blockComments {
+		if bytes.HasPrefix(rest, []byte(delims[0])) {
+			if j := bytes.Index(rest[len(delims[0]):], []byte(delims[1])); j >= 0 {
+				return i + len(delims[0]) + j + len(delims[1]), kindComment
+			}
+			return len(s.content), kindComment
+		}
+	}
+	for _, str := range s.syntax.strings {
+		if !bytes.HasPrefix(rest, []byte(str.open)) {
+			continue
+		}
+		for j := len(str.open); j < len(rest); j++ {
+			switch {
+			case str.escape != 0 && rest[j] == str.escape:
+				j++
+			case bytes.HasPrefix(rest[j:], []byte(str.close)):
+				return i + j + len(str.close), kindString
+			case rest[j] == '\n' && !str.multiline:
+				return i + j, kindString
+			}
+		}
+		return len(s.content), kindString
+	}
+	return i, kindCode
+}
+
+func (s *source) location(offset int) Location {
+	line := sort.Search(len(s.lines), func(i int) bool { return s.lines[i] > offset }) - 1
+	return Location{
+		Offset: offset,
+		Line:   line + 1,
+		Column: offset - s.lines[line] + 1,
+	}
+}
+
+func (s *source) match(start, end int) Match {
+	return Match{
+		Range:   Range{Start: s.location(start), End: s.location(end)},
+		Matched: string(s.content[start:end]),
+	}
+}
+
+// environment binds the names of holes to the text they matched.
+type environment []binding
+
+type binding struct {
+	name, value string
+	start, end  int
+}
+
+func (env environment) lookup(name string) (string, bool) {
+	for _, b := range env {
+		if b.name == name {
+			return b.value, true
+		}
+	}
+	return "", false
+}
+
+// state is the state of matching a template at one position of a source.
+type state struct {
+	src         *source
+	tokens      []token
+	constraints []constraint
+	steps       *int
+
+	// env is the environment of the match once matchFrom succeeds.
+	env environment
+}
+
+// matchFrom matches tokens[i:] at offset pos and returns the end of the
+// match. It backtracks over the possible extents of holes, shortest first.
+func (st *state) matchFrom(i, pos int, env environment) (int, bool) {
+	*st.steps++
+	if *st.steps > maxSteps {
+		return 0, false
+	}
+	if i == len(st.tokens) {
+		for _, c := range st.constraints {
+			if !c.satisfied(env) {
+				return 0, false
+			}
+		}
+		st.env = env
+		return pos, true
+	}
+
+	content := st.src.content
+	t := st.tokens[i]
+	switch t.kind {
+	case tokenLiteral:
+		if !bytes.HasPrefix(content[pos:], []byte(t.literal)) {
+			return 0, false
+		}
+		for j := pos; j < pos+len(t.literal); j++ {
+			if st.src.kinds[j] == kindComment {
+				return 0, false
+			}
+		}
+		return st.matchFrom(i+1, pos+len(t.literal), env)
+
+	case tokenSpace:
+		end := st.src.skipSpace(pos)
+		if end == pos {
+			return 0, false
+		}
+		return st.matchFrom(i+1, end, env)
+	}
+
+	// Holes. The last hole of a template extends as far as it can, since
+	// there is nothing after it to match.
+	var matchEnd int
+	var matched bool
+	try := func(end int) bool {
+		if t.bound() {
+			value := string(content[pos:end])
+			if previous, ok := env.lookup(t.name); ok {
+				if previous != value {
+					return false
+				}
+			} else {
+				env := append(env[:len(env):len(env)], binding{name: t.name, value: value, start: pos, end: end})
+				matchEnd, matched = st.matchFrom(i+1, end, env)
+				return matched
+			}
+		}
+		matchEnd, matched = st.matchFrom(i+1, end, env)
+		return matched
+	}
+	last := i == len(st.tokens)-1
+
+	switch t.hole {
+	case holeAny:
+		if last {
+			end := st.src.balancedEnd(pos)
+			for end > pos && isSpace(content[end-1]) {
+				end--
+			}
+			try(end)
+		} else {
+			st.src.balancedEnds(pos, try)
+		}
+
+	case holeWord, holePunctuated, holeBlank:
+		end := pos
+		for end < len(content) && st.src.kinds[end] != kindComment && st.src.holeByte(t.hole, content[end]) {
+			end++
+		}
+		for ; end > pos && !try(end); end-- {
+		}
+
+	case holeLine:
+		end := bytes.IndexByte(content[pos:], '\n')
+		if end < 0 {
+			end = len(content)
+		} else {
+			end += pos + 1
+		}
+		try(end)
+
+	case holeRegexp:
+		if loc := t.re.FindIndex(content[pos:]); loc != nil {
+			try(pos + loc[1])
+		}
+	}
+	return matchEnd, matched
+}
+
+// holeByte reports whether a hole of kind may contain c.
+func (s *source) holeByte(kind holeKind, c byte) bool {
+	switch kind {
+	case holeWord:
+		return isWord(c)
+	case holePunctuated:
+		_, open := s.syntax.delimiters[c]
+		return !isSpace(c) && !open && !s.syntax.isClosing(c) && c != '"' && c != '\'' && c != '`'
+	case holeBlank:
+		return c == ' ' || c == '\t'
+	}
+	return false
+}
+
+// skipSpace returns the offset of the first byte at or after pos which is
+// neither whitespace nor part of a comment.
+func (s *source) skipSpace(pos int) int {
+	for pos < len(s.content) {
+		if s.kinds[pos] == kindComment {
+			pos = s.regionEnd[pos]
+			continue
+		}
+		if !isSpace(s.content[pos]) {
+			break
+		}
+		pos++
+	}
+	return pos
+}
+
+// balancedEnds calls try with each offset end after pos such that
+// content[pos:end] is balanced, in increasing order, until try returns true.
+// Strings and comments are never split.
+func (s *source) balancedEnds(pos int, try func(end int) bool) bool {
+	if _, ok := s.regionEnd[pos]; !ok && pos < len(s.content) && s.kinds[pos] == kindString {
+		// The hole starts inside a string, as in "...", so it only
+		// matches within that string.
+		for p := pos; p < len(s.content) && s.kinds[p] == kindString; p++ {
+			if try(p) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var stack []byte
+	for p := pos; ; {
+		if len(stack) == 0 && try(p) {
+			return true
+		}
+		if p >= len(s.content) {
+			return false
+		}
+		if end, ok := s.regionEnd[p]; ok {
+			p = end
+			continue
+		}
+		if c := s.content[p]; s.kinds[p] == kindCode {
+			if close, ok := s.syntax.delimiters[c]; ok {
+				stack = append(stack, close)
+			} else if s.syntax.isClosing(c) {
+				if len(stack) == 0 || stack[len(stack)-1] != c {
+					return false
+				}
+				stack = stack[:len(stack)-1]
+			}
+		}
+		p++
+	}
+}
+
+// balancedEnd returns the largest offset end after pos such that
+// content[pos:end] is balanced.
+func (s *source) balancedEnd(pos int) int {
+	end := pos
+	s.balancedEnds(pos, func(p int) bool {
+		end = p
+		return false
+	})
+	return end
+}
@@ -0,0 +1,223 @@
+package comby
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNativeMatcher(t *testing.T) {
+	const goComment = `
+/* This foo(plain string) {} is in a Go comment should not match in Go, but should match in plaintext */
+func foo(go string) {}
+`
+	const twoFuncs = `
+func foo() {
+    fmt.Println("foo")
+}
+
+func bar() {
+    fmt.Println("bar")
+}
+`
+
+	cases := []struct {
+		name     string
+		matcher  string
+		template string
+		rule     string
+		source   string
+		want     []string
+	}{{
+		name:     "generic matches in comments",
+		matcher:  ".generic",
+		template: "foo(:[args])",
+		source:   goComment,
+		want:     []string{"foo(plain string)", "foo(go string)"},
+	}, {
+		name:     "text matches in comments",
+		matcher:  ".txt",
+		template: "foo(:[args])",
+		source:   goComment,
+		want:     []string{"foo(plain string)", "foo(go string)"},
+	}, {
+		name:     "go skips comments",
+		matcher:  ".go",
+		template: "foo(:[args])",
+		source:   goComment,
+		want:     []string{"foo(go string)"},
+	}, {
+		name:     "word hole and rule",
+		matcher:  ".go",
+		template: "func :[[fn]](:[args])",
+		rule:     `where :[args] == "success"`,
+		source:   "func foo(success) {} func bar(fail) {}",
+		want:     []string{"func foo(success)"},
+	}, {
+		name:     "negated rule",
+		matcher:  ".go",
+		template: "func :[[fn]](:[args])",
+		rule:     `where :[args] != "success"`,
+		source:   "func foo(success) {} func bar(fail) {}",
+		want:     []string{"func bar(fail)"},
+	}, {
+		name:     "balanced braces span lines",
+		matcher:  ".go",
+		template: "{:[body]}",
+		source:   twoFuncs,
+		want:     []string{"{\n    fmt.Println(\"foo\")\n}", "{\n    fmt.Println(\"bar\")\n}"},
+	}, {
+		name:     "empty and nested parentheses",
+		matcher:  ".go",
+		template: "(:[_])",
+		source:   twoFuncs,
+		want:     []string{"()", `("foo")`, "()", `("bar")`},
+	}, {
+		name:     "delimiters in strings are ignored",
+		matcher:  ".go",
+		template: "Println(:[x])",
+		source:   `fmt.Println(")", f(a)) // Println(not)`,
+		want:     []string{`Println(")", f(a))`},
+	}, {
+		name:     "hole inside string",
+		matcher:  ".js",
+		template: `log(":[msg]")`,
+		source:   `log("hello (world")`,
+		want:     []string{`log("hello (world")`},
+	}, {
+		name:     "hole inside string matches one string",
+		matcher:  ".go",
+		template: `fmt.Sprintf("...")`,
+		source:   `fmt.Sprintf("foo", "bar"); fmt.Sprintf("baz")`,
+		want:     []string{`fmt.Sprintf("baz")`},
+	}, {
+		name:     "template literals",
+		matcher:  ".ts",
+		template: "f(:[x])",
+		source:   "f(`a)b`)",
+		want:     []string{"f(`a)b`)"},
+	}, {
+		name:     "whitespace matches any whitespace",
+		matcher:  ".java",
+		template: "if (:[c]) { return :[v]; }",
+		source:   "if (x == null) {\n\treturn   y;\n}",
+		want:     []string{"if (x == null) {\n\treturn   y;\n}"},
+	}, {
+		name:     "whitespace skips comments",
+		matcher:  ".java",
+		template: "a = b",
+		source:   "a /* set */ = b",
+		want:     []string{"a /* set */ = b"},
+	}, {
+		name:     "python comments and triple quoted strings",
+		matcher:  ".py",
+		template: "print(:[x])",
+		source:   "# print(no)\nprint(\"\"\"a)\nb\"\"\")",
+		want:     []string{"print(\"\"\"a)\nb\"\"\")"},
+	}, {
+		name:     "repeated holes must be equal",
+		matcher:  ".go",
+		template: ":[[x]] == :[[x]]",
+		source:   "a == b; c == c",
+		want:     []string{"c == c"},
+	}, {
+		name:     "ellipsis",
+		matcher:  ".go",
+		template: "if ... {",
+		source:   "if err != nil {",
+		want:     []string{"if err != nil {"},
+	}, {
+		name:     "punctuated hole",
+		matcher:  ".go",
+		template: "import :[path.]",
+		source:   "import fmt.Sprintf(x)",
+		want:     []string{"import fmt.Sprintf"},
+	}, {
+		name:     "line hole",
+		matcher:  ".py",
+		template: `return :[x\n]`,
+		source:   "return a + b\nreturn c",
+		want:     []string{"return a + b\n", "return c"},
+	}, {
+		name:     "regexp hole",
+		matcher:  ".go",
+		template: "x := :[v~[0-9]+]",
+		source:   "x := 42; x := y",
+		want:     []string{"x := 42"},
+	}, {
+		name:     "trailing hole extends to enclosing delimiter",
+		matcher:  ".go",
+		template: "return :[x]",
+		source:   "{ return a, b }",
+		want:     []string{"return a, b"},
+	}, {
+		name:     "unbalanced hole does not match",
+		matcher:  ".go",
+		template: "f(:[x]) + 1",
+		source:   "f(a)) + 1",
+		want:     nil,
+	}, {
+		name:     "empty template matches once",
+		matcher:  ".go",
+		template: "",
+		source:   "package main",
+		want:     []string{""},
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			m, err := CompileNative(Args{MatchTemplate: c.template, Rule: c.rule, Matcher: c.matcher, MatchOnly: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, match := range m.Matches([]byte(c.source)) {
+				got = append(got, match.Matched)
+			}
+			if diff := cmp.Diff(c.want, got); diff != "" {
+				t.Errorf("unexpected matches (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNativeMatcherRange(t *testing.T) {
+	m, err := CompileNative(Args{MatchTemplate: "{:[body]}", Matcher: ".go", MatchOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := m.Matches([]byte("func f() {\n\treturn\n}"))
+	want := []Match{{
+		Range: Range{
+			Start: Location{Offset: 9, Line: 1, Column: 10},
+			End:   Location{Offset: 20, Line: 3, Column: 2},
+		},
+		Environment: []EnvironmentEntry{{
+			Variable: "body",
+			Value:    "\n\treturn\n",
+			Range: Range{
+				Start: Location{Offset: 10, Line: 1, Column: 11},
+				End:   Location{Offset: 19, Line: 3, Column: 1},
+			},
+		}},
+		Matched: "{\n\treturn\n}",
+	}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected matches (-want +got):\n%s", diff)
+	}
+}
+
+func TestCompileNativeUnsupported(t *testing.T) {
+	cases := []Args{
+		{MatchTemplate: "foo", Matcher: ".rs", MatchOnly: true},
+		{MatchTemplate: "foo", Matcher: ".go", RewriteTemplate: "bar"},
+		{MatchTemplate: "foo(:[x])", Matcher: ".go", Rule: `where match :[x] { | "a" -> true }`, MatchOnly: true},
+		{MatchTemplate: "foo(:[x])", Matcher: ".go", Rule: `where rewrite :[x] { "a" -> "b" }`, MatchOnly: true},
+	}
+	for _, args := range cases {
+		if _, err := CompileNative(args); !errors.Is(err, ErrUnsupportedNative) {
+			t.Errorf("CompileNative(%+v): got error %v, want ErrUnsupportedNative", args, err)
+		}
+	}
+}
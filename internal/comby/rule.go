@@ -0,0 +1,109 @@
+package comby
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+)
+
+// constraint is a constraint of a rule, like :[x] == "foo" or :[x] != :[y].
+type constraint struct {
+	left    string // The name of the hole on the left.
+	negated bool   // True for !=.
+
+	// Exactly one of right and rightHole is set.
+	right     string
+	rightHole string
+}
+
+// parseRule parses the subset of comby's rule language supported by the
+// native matcher: a where clause of equality and inequality constraints
+// between holes and strings.
+func parseRule(rule string) ([]constraint, error) {
+	rule = strings.TrimSpace(rule)
+	if rule == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(rule, "where ") {
+		return nil, errors.Wrapf(ErrUnsupportedNative, "rule %q", rule)
+	}
+
+	var constraints []constraint
+	for _, part := range splitConstraints(strings.TrimPrefix(rule, "where ")) {
+		c, err := parseConstraint(strings.TrimSpace(part))
+		if err != nil {
+			return nil, errors.Wrapf(err, "rule %q", rule)
+		}
+		constraints = append(constraints, c)
+	}
+	return constraints, nil
+}
+
+// splitConstraints splits s on commas outside of string literals.
+func splitConstraints(s string) []string {
+	var parts []string
+	inString := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			inString = !inString
+		case ',':
+			if !inString {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+func parseConstraint(s string) (constraint, error) {
+	var c constraint
+	op := "=="
+	i := strings.Index(s, op)
+	if j := strings.Index(s, "!="); j >= 0 && (i < 0 || j < i) {
+		op, i, c.negated = "!=", j, true
+	}
+	if i < 0 {
+		return c, ErrUnsupportedNative
+	}
+
+	var ok bool
+	if c.left, ok = parseHoleReference(strings.TrimSpace(s[:i])); !ok {
+		return c, ErrUnsupportedNative
+	}
+
+	right := strings.TrimSpace(s[i+len(op):])
+	if name, ok := parseHoleReference(right); ok {
+		c.rightHole = name
+		return c, nil
+	}
+	value, err := strconv.Unquote(right)
+	if err != nil {
+		return c, ErrUnsupportedNative
+	}
+	c.right = value
+	return c, nil
+}
+
+// parseHoleReference parses a reference to a hole like :[x].
+func parseHoleReference(s string) (string, bool) {
+	if !strings.HasPrefix(s, ":[") || !strings.HasSuffix(s, "]") {
+		return "", false
+	}
+	name := s[len(":[") : len(s)-1]
+	return name, name != "" && holeName.MatchString(name)
+}
+
+func (c constraint) satisfied(env environment) bool {
+	left, _ := env.lookup(c.left)
+	right := c.right
+	if c.rightHole != "" {
+		right, _ = env.lookup(c.rightHole)
+	}
+	return (left == right) != c.negated
+}
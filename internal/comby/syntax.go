@@ -0,0 +1,100 @@
+package comby
+
+// syntax describes the lexical structure of a language which the native
+// matcher needs to know about: balanced delimiters, string literals and
+// comments. Holes never split a string literal or a comment, and delimiters
+// inside them are ignored.
+type syntax struct {
+	// delimiters maps opening delimiters to their closing delimiter.
+	delimiters map[byte]byte
+
+	// strings are the kinds of string literals, longest delimiter first.
+	strings []stringSyntax
+
+	lineComments  []string
+	blockComments [][2]string
+}
+
+type stringSyntax struct {
+	open, close string
+
+	// escape is the escape character, or 0 if the literal has no escapes.
+	escape byte
+
+	// multiline is true if the literal may span lines. Unterminated single
+	// line literals end at the end of the line.
+	multiline bool
+}
+
+var (
+	defaultDelimiters = map[byte]byte{'(': ')', '[': ']', '{': '}'}
+
+	doubleQuoted = stringSyntax{open: `"`, close: `"`, escape: '\\'}
+	singleQuoted = stringSyntax{open: `'`, close: `'`, escape: '\\'}
+
+	cComments = [][2]string{{"/*", "*/"}}
+)
+
+// nativeSyntaxes are the languages supported by the native matcher, keyed by
+// the matcher names comby uses.
+var nativeSyntaxes = map[string]*syntax{
+	".go": {
+		delimiters: defaultDelimiters,
+		strings: []stringSyntax{
+			{open: "`", close: "`", multiline: true},
+			doubleQuoted,
+			singleQuoted,
+		},
+		lineComments:  []string{"//"},
+		blockComments: cComments,
+	},
+	".js": javascript,
+	".ts": javascript,
+	".java": {
+		delimiters: defaultDelimiters,
+		strings: []stringSyntax{
+			{open: `"""`, close: `"""`, escape: '\\', multiline: true},
+			doubleQuoted,
+			singleQuoted,
+		},
+		lineComments:  []string{"//"},
+		blockComments: cComments,
+	},
+	".py": {
+		delimiters: defaultDelimiters,
+		strings: []stringSyntax{
+			{open: `"""`, close: `"""`, escape: '\\', multiline: true},
+			{open: `'''`, close: `'''`, escape: '\\', multiline: true},
+			doubleQuoted,
+			singleQuoted,
+		},
+		lineComments: []string{"#"},
+	},
+	".generic": {
+		delimiters: defaultDelimiters,
+		strings:    []stringSyntax{doubleQuoted},
+	},
+	".txt": {
+		delimiters: defaultDelimiters,
+	},
+}
+
+var javascript = &syntax{
+	delimiters: defaultDelimiters,
+	strings: []stringSyntax{
+		{open: "`", close: "`", escape: '\\', multiline: true},
+		doubleQuoted,
+		singleQuoted,
+	},
+	lineComments:  []string{"//"},
+	blockComments: cComments,
+}
+
+func (s *syntax) isClosing(c byte) bool {
+	for _, close := range s.delimiters {
+		if c == close {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,163 @@
+package comby
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/cockroachdb/errors"
+)
+
+type tokenKind int
+
+const (
+	tokenLiteral tokenKind = iota
+	tokenSpace
+	tokenHole
+)
+
+type holeKind int
+
+const (
+	holeAny        holeKind = iota // :[x] and ...
+	holeWord                       // :[[x]]
+	holePunctuated                 // :[x.]
+	holeLine                       // :[x\n]
+	holeBlank                      // :[ x]
+	holeRegexp                     // :[x~regexp]
+)
+
+type token struct {
+	kind tokenKind
+
+	// literal is the text of a tokenLiteral.
+	literal string
+
+	// name and hole describe a tokenHole. Holes named _ or with an empty
+	// name are not bound.
+	name string
+	hole holeKind
+	re   *regexp.Regexp
+}
+
+func (t token) bound() bool {
+	return t.kind == tokenHole && t.name != "" && t.name != "_"
+}
+
+var holeName = regexp.MustCompile(`^[A-Za-z0-9_]*$`)
+
+// tokenizeTemplate splits a match template into literals, whitespace and holes.
+func tokenizeTemplate(template string) ([]token, error) {
+	template = strings.TrimSpace(template)
+
+	var tokens []token
+	var literal strings.Builder
+	flush := func() {
+		if literal.Len() > 0 {
+			tokens = append(tokens, token{kind: tokenLiteral, literal: literal.String()})
+			literal.Reset()
+		}
+	}
+
+	for i := 0; i < len(template); {
+		switch {
+		case isSpace(template[i]):
+			flush()
+			for i < len(template) && isSpace(template[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenSpace})
+		case strings.HasPrefix(template[i:], "..."):
+			flush()
+			tokens = append(tokens, token{kind: tokenHole, hole: holeAny})
+			i += len("...")
+		case strings.HasPrefix(template[i:], ":["):
+			hole, n, err := parseHole(template[i:])
+			if err != nil {
+				return nil, err
+			}
+			flush()
+			tokens = append(tokens, hole)
+			i += n
+		default:
+			literal.WriteByte(template[i])
+			i++
+		}
+	}
+	flush()
+	return tokens, nil
+}
+
+// parseHole parses the hole at the start of s and returns it with its length.
+func parseHole(s string) (token, int, error) {
+	if strings.HasPrefix(s, ":[[") {
+		end := strings.Index(s, "]]")
+		if end < 0 {
+			return token{}, 0, errors.Errorf("unterminated hole in %q", s)
+		}
+		name := s[len(":[["):end]
+		if !holeName.MatchString(name) {
+			return token{}, 0, errors.Errorf("invalid hole name %q", name)
+		}
+		return token{kind: tokenHole, name: name, hole: holeWord}, end + len("]]"), nil
+	}
+
+	// Find the closing bracket, allowing balanced brackets in regular
+	// expressions such as :[x~[a-z]+].
+	depth := 0
+	end := -1
+	for i := len(":["); i < len(s) && end < 0; i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '[':
+			depth++
+		case ']':
+			if depth == 0 {
+				end = i
+			}
+			depth--
+		}
+	}
+	if end < 0 {
+		return token{}, 0, errors.Errorf("unterminated hole in %q", s)
+	}
+	body := s[len(":["):end]
+	n := end + 1
+
+	if i := strings.IndexByte(body, '~'); i >= 0 {
+		name := body[:i]
+		if !holeName.MatchString(name) {
+			return token{}, 0, errors.Errorf("invalid hole name %q", name)
+		}
+		re, err := regexp.Compile(`^(?:` + body[i+1:] + `)`)
+		if err != nil {
+			return token{}, 0, err
+		}
+		return token{kind: tokenHole, name: name, hole: holeRegexp, re: re}, n, nil
+	}
+
+	kind := holeAny
+	switch {
+	case strings.HasPrefix(body, " "):
+		kind, body = holeBlank, body[1:]
+	case strings.HasSuffix(body, "."):
+		kind, body = holePunctuated, strings.TrimSuffix(body, ".")
+	case strings.HasSuffix(body, `\n`):
+		kind, body = holeLine, strings.TrimSuffix(body, `\n`)
+	}
+	if !holeName.MatchString(body) {
+		return token{}, 0, errors.Errorf("invalid hole name %q", body)
+	}
+	return token{kind: tokenHole, name: body, hole: kind}, n, nil
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// isWord reports whether c is part of an identifier. Bytes of multibyte
+// characters are part of identifiers, so that holes never split them.
+func isWord(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c >= utf8.RuneSelf
+}
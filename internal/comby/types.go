@@ -51,8 +51,16 @@ type Range struct {
 
 // Match represents a range of matched characters and the matched content
 type Match struct {
-	Range   Range  `json:"range"`
-	Matched string `json:"matched"`
+	Range       Range              `json:"range"`
+	Environment []EnvironmentEntry `json:"environment,omitempty"`
+	Matched     string             `json:"matched"`
+}
+
+// EnvironmentEntry is the value and range of a named hole in a match
+type EnvironmentEntry struct {
+	Variable string `json:"variable"`
+	Value    string `json:"value"`
+	Range    Range  `json:"range"`
 }
 
 // FileMatch represents all the matches in a single file
@@ -0,0 +1,84 @@
+package compute
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/sourcegraph/sourcegraph/internal/search/result"
+)
+
+// Command is a compute operation that runs on each file of search results.
+type Command interface {
+	command()
+	// Run computes the result of the command on a file match. It returns
+	// nil if there is nothing to compute for the file.
+	Run(context.Context, *result.FileMatch) (Result, error)
+	String() string
+}
+
+var (
+	_ Command = (*MatchOnly)(nil)
+	_ Command = (*Replace)(nil)
+	_ Command = (*Output)(nil)
+)
+
+// MatchOnly computes the values matched by a pattern and their environment of
+// submatches.
+type MatchOnly struct {
+	MatchPattern MatchPattern
+}
+
+// Replace computes the diff of replacing values matched by a pattern with a
+// template. Templates refer to submatches in the environment of a match with
+// $name or ${name} for regular expressions, and :[name] for structural
+// patterns.
+type Replace struct {
+	MatchPattern   MatchPattern
+	ReplacePattern string
+}
+
+// Output renders each match of a pattern through a list of fields. A field
+// is one of the variables repo, commit, path and line, or a template that
+// refers to submatches like a Replace template for a regular expression. $0
+// is the whole match.
+type Output struct {
+	MatchPattern MatchPattern
+	Fields       []string
+}
+
+func (*MatchOnly) command() {}
+func (*Replace) command()   {}
+func (*Output) command()    {}
+
+func (c *MatchOnly) String() string {
+	return fmt.Sprintf("Match only: %s", c.MatchPattern.String())
+}
+
+func (c *Replace) String() string {
+	return fmt.Sprintf("Replace: (%s) -> (%s)", c.MatchPattern.String(), c.ReplacePattern)
+}
+
+func (c *Output) String() string {
+	return fmt.Sprintf("Output: (%s) -> (%s)", c.MatchPattern.String(), strings.Join(c.Fields, ","))
+}
+
+func (c *MatchOnly) Run(_ context.Context, fm *result.FileMatch) (Result, error) {
+	switch p := c.MatchPattern.(type) {
+	case *Regexp:
+		return FromFileMatch(fm, p.Value), nil
+	}
+	return nil, errors.Errorf("compute match only does not support %s patterns", c.MatchPattern.String())
+}
+
+// Result is the result of running a command: a *MatchContext, *Text or
+// *Table.
+type Result interface {
+	result()
+}
+
+func (*MatchContext) result() {}
+func (*Text) result()         {}
+func (*Table) result()        {}
@@ -0,0 +1,33 @@
+package compute
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// MatchPattern is a pattern that a command matches in file contents.
+type MatchPattern interface {
+	pattern()
+	String() string
+}
+
+// Regexp is a regular expression pattern.
+type Regexp struct {
+	Value *regexp.Regexp
+}
+
+// Comby is a structural search pattern, matched with comby's syntax.
+type Comby struct {
+	Value string
+}
+
+func (*Regexp) pattern() {}
+func (*Comby) pattern()  {}
+
+func (p *Regexp) String() string {
+	return fmt.Sprintf("Regexp: %s", p.Value.String())
+}
+
+func (p *Comby) String() string {
+	return fmt.Sprintf("Comby: %s", p.Value)
+}
@@ -0,0 +1,49 @@
+package compute
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/sourcegraph/sourcegraph/internal/search/result"
+)
+
+// outputVariables are the variables that a field of an Output command may
+// consist of, besides templates that refer to submatches of a match.
+var outputVariables = map[string]func(fm *result.FileMatch, l *result.LineMatch) string{
+	"repo":   func(fm *result.FileMatch, _ *result.LineMatch) string { return string(fm.Repo.Name) },
+	"commit": func(fm *result.FileMatch, _ *result.LineMatch) string { return string(fm.CommitID) },
+	"path":   func(fm *result.FileMatch, _ *result.LineMatch) string { return fm.Path },
+	"line":   func(_ *result.FileMatch, l *result.LineMatch) string { return strconv.Itoa(int(l.LineNumber) + 1) },
+}
+
+// Run renders each match of the pattern of c in fm through its fields. It
+// returns nil if there are no matches.
+func (c *Output) Run(_ context.Context, fm *result.FileMatch) (Result, error) {
+	p, ok := c.MatchPattern.(*Regexp)
+	if !ok {
+		return nil, errors.Errorf("compute output does not support %s patterns", c.MatchPattern.String())
+	}
+
+	var rows [][]string
+	for _, l := range fm.LineMatches {
+		for _, m := range p.Value.FindAllStringSubmatchIndex(l.Preview, -1) {
+			env := environmentFromSubmatches(m, p.Value.SubexpNames(), l.Preview)
+			env["0"] = Data{Value: l.Preview[m[0]:m[1]]}
+			row := make([]string, 0, len(c.Fields))
+			for _, field := range c.Fields {
+				if variable, ok := outputVariables[field]; ok {
+					row = append(row, variable(fm, l))
+				} else {
+					row = append(row, substituteRegexp(field, env))
+				}
+			}
+			rows = append(rows, row)
+		}
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	return &Table{Columns: c.Fields, Rows: rows}, nil
+}
@@ -0,0 +1,53 @@
+package compute
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/hexops/autogold"
+
+	"github.com/sourcegraph/sourcegraph/internal/search/result"
+	"github.com/sourcegraph/sourcegraph/internal/types"
+)
+
+func TestOutput(t *testing.T) {
+	data := &result.FileMatch{
+		File: result.File{
+			Repo:     types.RepoName{Name: "github.com/sourcegraph/sourcegraph"},
+			CommitID: "deadbeef",
+			Path:     "go.mod",
+		},
+		LineMatches: []*result.LineMatch{
+			{Preview: "\tgithub.com/a/b v1.0.0", LineNumber: 4},
+			{Preview: "\tgithub.com/c/d v0.2.0 // indirect", LineNumber: 5},
+		},
+	}
+
+	test := func(pattern string, fields ...string) *Table {
+		c := &Output{MatchPattern: &Regexp{Value: regexp.MustCompile(pattern)}, Fields: fields}
+		r, err := c.Run(context.Background(), data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r == nil {
+			return nil
+		}
+		return r.(*Table)
+	}
+
+	autogold.Want("variables and submatches", &Table{
+		Columns: []string{"repo", "path", "line", "$1", "${version}"},
+		Rows: [][]string{
+			{"github.com/sourcegraph/sourcegraph", "go.mod", "5", "github.com/a/b", "v1.0.0"},
+			{"github.com/sourcegraph/sourcegraph", "go.mod", "6", "github.com/c/d", "v0.2.0"},
+		},
+	}).Equal(t, test(`(\S+) (?P<version>v\S+)`, "repo", "path", "line", "$1", "${version}"))
+
+	autogold.Want("templates", &Table{
+		Columns: []string{"$1@$2", "commit", "$0"},
+		Rows:    [][]string{{"github.com/a/b@v1.0.0", "deadbeef", "github.com/a/b v1.0.0"}},
+	}).Equal(t, test(`(github\.com/a/\S+) (v\S+)`, "$1@$2", "commit", "$0"))
+
+	autogold.Want("no matches", (*Table)(nil)).Equal(t, test(`nothing`, "repo"))
+}
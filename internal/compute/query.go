@@ -0,0 +1,243 @@
+package compute
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/sourcegraph/sourcegraph/internal/lazyregexp"
+	"github.com/sourcegraph/sourcegraph/internal/search/query"
+)
+
+// Query is a compute query: a search query and a command that computes values
+// from its results.
+type Query struct {
+	Command Command
+
+	// Search is the parse tree of the search query whose results the
+	// command runs on.
+	Search []query.Node
+}
+
+// ToSearchQuery returns the search query to run for q.
+func (q Query) ToSearchQuery() string {
+	return query.StringHuman(joinConcat(q.Search))
+}
+
+// joinConcat replaces concatenations of patterns in nodes by a single pattern
+// separating them by whitespace, as in the original query. Unlike a search
+// query, a compute query is not processed before it is printed, so its parse
+// tree still contains concatenations, which query.StringHuman does not
+// separate.
+func joinConcat(nodes []query.Node) []query.Node {
+	joined := make([]query.Node, 0, len(nodes))
+	for _, node := range nodes {
+		operator, ok := node.(query.Operator)
+		if !ok {
+			joined = append(joined, node)
+			continue
+		}
+		operator.Operands = joinConcat(operator.Operands)
+		if operator.Kind == query.Concat {
+			if pattern, ok := joinPatterns(operator.Operands); ok {
+				joined = append(joined, pattern)
+				continue
+			}
+		}
+		joined = append(joined, operator)
+	}
+	return joined
+}
+
+// joinPatterns returns the pattern separating the values of nodes by
+// whitespace. Quoted values stay quoted. It returns false if a node is not a
+// pattern we can join without changing its meaning.
+func joinPatterns(nodes []query.Node) (query.Pattern, bool) {
+	values := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		pattern, ok := node.(query.Pattern)
+		if !ok || pattern.Negated {
+			return query.Pattern{}, false
+		}
+		if pattern.Annotation.Labels.IsSet(query.Quoted) {
+			values = append(values, strconv.Quote(pattern.Value))
+		} else {
+			values = append(values, pattern.Value)
+		}
+	}
+	annotation := nodes[0].(query.Pattern).Annotation
+	annotation.Labels &^= query.Quoted
+	return query.Pattern{Value: strings.Join(values, " "), Annotation: annotation}, true
+}
+
+// Parse parses a compute query. A query with a content:replace(pattern ->
+// template) parameter computes replacements for the pattern. A query with a
+// compute:output(fields) parameter renders the matches of its regular
+// expression pattern through fields. Any other query computes the match
+// context of its regular expression pattern.
+func Parse(q string) (*Query, error) {
+	q, fields, err := extractOutput(q)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := query.Parse(q, query.SearchTypeRegex)
+	if err != nil {
+		return nil, err
+	}
+
+	replace, matchValue, err := parseReplace(nodes)
+	if err != nil {
+		return nil, err
+	}
+	if replace != nil {
+		if fields != nil {
+			return nil, errors.New("compute endpoint does not support content:replace(...) and compute:output(...) together")
+		}
+		// Search for the pattern we replace in place of the replace
+		// parameter.
+		search := query.MapParameter(nodes, func(field, value string, negated bool, annotation query.Annotation) query.Node {
+			if field == query.FieldContent && strings.HasPrefix(value, "replace(") {
+				value = matchValue
+				annotation.Labels = query.Quoted
+			}
+			return query.Parameter{Field: field, Value: value, Negated: negated, Annotation: annotation}
+		})
+		return &Query{Command: replace, Search: search}, nil
+	}
+
+	pattern, err := regexpFromQuery(q)
+	if err != nil {
+		return nil, err
+	}
+	if pattern == nil {
+		return nil, errors.New("compute endpoint needs a search pattern")
+	}
+	if fields != nil {
+		return &Query{Command: &Output{MatchPattern: &Regexp{Value: pattern}, Fields: fields}, Search: nodes}, nil
+	}
+	return &Query{Command: &MatchOnly{MatchPattern: &Regexp{Value: pattern}}, Search: nodes}, nil
+}
+
+// computeParameter matches the start of a compute: parameter. compute: is not
+// a search field, so we extract it from the query before parsing it.
+var computeParameter = lazyregexp.New(`(^|\s)compute:`)
+
+// extractOutput removes a compute:output(fields) parameter from q. It returns
+// the rest of q and the comma-separated fields, or nil if q has no compute:
+// parameter.
+func extractOutput(q string) (string, []string, error) {
+	loc := computeParameter.FindStringSubmatchIndex(q)
+	if loc == nil {
+		return q, nil, nil
+	}
+	start, rest := loc[1]-len("compute:"), q[loc[1]:]
+	if !strings.HasPrefix(rest, "output(") {
+		return "", nil, errors.New("invalid compute parameter: expected compute:output(field, ...)")
+	}
+	args, n, ok := query.ScanBalancedParens([]byte(rest[len("output"):]))
+	if !ok {
+		return "", nil, errors.New("invalid compute parameter: unbalanced parentheses in compute:output(...)")
+	}
+
+	var fields []string
+	for _, field := range strings.Split(args[1:len(args)-1], ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+	if len(fields) == 0 {
+		return "", nil, errors.New("invalid compute parameter: compute:output(...) needs at least one field")
+	}
+
+	end := loc[1] + len("output") + n
+	return strings.TrimSpace(q[:start] + q[end:]), fields, nil
+}
+
+// parseReplace returns the Replace command of a content:replace(pattern ->
+// template) parameter in nodes and its pattern, or nil if there is none.
+func parseReplace(nodes []query.Node) (*Replace, string, error) {
+	var values []string
+	var negated bool
+	query.VisitField(nodes, query.FieldContent, func(value string, n bool, _ query.Annotation) {
+		if strings.HasPrefix(value, "replace(") {
+			values = append(values, value)
+			negated = negated || n
+		}
+	})
+	if len(values) == 0 {
+		return nil, "", nil
+	}
+	if len(values) > 1 {
+		return nil, "", errors.New("compute endpoint supports only one content:replace(...) parameter")
+	}
+	if negated {
+		return nil, "", errors.New("compute endpoint does not support negated content:replace(...) parameters")
+	}
+
+	value := values[0]
+	if !strings.HasSuffix(value, ")") {
+		return nil, "", errors.Errorf("invalid replace %q: expected content:replace(pattern -> template)", value)
+	}
+	args := value[len("replace(") : len(value)-len(")")]
+	i := strings.Index(args, "->")
+	if i < 0 {
+		return nil, "", errors.Errorf("invalid replace %q: expected content:replace(pattern -> template)", value)
+	}
+	matchValue := strings.TrimSpace(args[:i])
+	replaceValue := strings.TrimSpace(args[i+len("->"):])
+	if matchValue == "" {
+		return nil, "", errors.Errorf("invalid replace %q: the pattern to replace is empty", value)
+	}
+
+	var patternType string
+	query.VisitField(nodes, query.FieldPatternType, func(value string, _ bool, _ query.Annotation) {
+		patternType = strings.ToLower(value)
+	})
+
+	var pattern MatchPattern
+	switch patternType {
+	case "structural":
+		pattern = &Comby{Value: matchValue}
+	case "literal":
+		pattern = &Regexp{Value: regexp.MustCompile(regexp.QuoteMeta(matchValue))}
+	default:
+		rp, err := regexp.Compile(matchValue)
+		if err != nil {
+			return nil, "", errors.Wrap(err, "regular expression is not valid for compute endpoint")
+		}
+		pattern = &Regexp{Value: rp}
+	}
+	return &Replace{MatchPattern: pattern, ReplacePattern: replaceValue}, matchValue, nil
+}
+
+func regexpFromQuery(q string) (*regexp.Regexp, error) {
+	plan, err := query.Pipeline(query.Init(q, query.SearchTypeRegex))
+	if err != nil {
+		return nil, err
+	}
+	if len(plan) != 1 {
+		return nil, errors.New("compute endpoint only supports one search pattern currently ('and' or 'or' operators are not supported yet)")
+	}
+	switch node := plan[0].Pattern.(type) {
+	case query.Operator:
+		if len(node.Operands) == 1 {
+			if pattern, ok := node.Operands[0].(query.Pattern); ok && !pattern.Negated {
+				rp, err := regexp.Compile(pattern.Value)
+				if err != nil {
+					return nil, errors.Wrap(err, "regular expression is not valid for compute endpoint")
+				}
+				return rp, nil
+			}
+		}
+		return nil, errors.New("compute endpoint only supports one search pattern currently ('and' or 'or' operators are not supported yet)")
+	case query.Pattern:
+		if !node.Negated {
+			return regexp.Compile(node.Value)
+		}
+	}
+	// unreachable
+	return nil, nil
+}
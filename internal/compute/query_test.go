@@ -0,0 +1,70 @@
+package compute
+
+import (
+	"testing"
+
+	"github.com/hexops/autogold"
+
+	"github.com/sourcegraph/sourcegraph/internal/search/query"
+)
+
+func TestParse(t *testing.T) {
+	test := func(input string) string {
+		q, err := Parse(input)
+		if err != nil {
+			return err.Error()
+		}
+		return q.Command.String() + "\n" + q.ToSearchQuery()
+	}
+
+	autogold.Want("match only", "Match only: Regexp: a(b)\nrepo:foo a(b)").
+		Equal(t, test("repo:foo a(b)"))
+
+	autogold.Want("regexp replace", "Replace: (Regexp: (\\w+)\\(\\)) -> ($1(ctx))\nrepo:foo content:\"(\\\\w+)\\\\(\\\\)\"").
+		Equal(t, test(`repo:foo content:replace((\w+)\(\) -> $1(ctx))`))
+
+	autogold.Want("structural replace", "Replace: (Comby: foo(:[x])) -> (bar(:[x]))\ncontent:\"foo(:[x])\" patterntype:structural").
+		Equal(t, test(`content:replace(foo(:[x]) -> bar(:[x])) patterntype:structural`))
+
+	autogold.Want("literal replace", "Replace: (Regexp: a\\.b) -> (c)\ncontent:\"a.b\" patterntype:literal").
+		Equal(t, test(`content:replace(a.b -> c) patterntype:literal`))
+
+	autogold.Want("output", "Output: (Regexp: (\\w+)@(\\S+)) -> (repo,path,$1)\nrepo:foo (\\w+)@(\\S+)").
+		Equal(t, test(`repo:foo compute:output(repo, path, $1) (\w+)@(\S+)`))
+
+	autogold.Want("output without fields", "invalid compute parameter: compute:output(...) needs at least one field").
+		Equal(t, test(`compute:output() foo`))
+
+	autogold.Want("unknown compute parameter", "invalid compute parameter: expected compute:output(field, ...)").
+		Equal(t, test(`compute:foo(bar) foo`))
+
+	autogold.Want("replace without arrow", `invalid replace "replace(foo)": expected content:replace(pattern -> template)`).
+		Equal(t, test(`content:replace(foo)`))
+
+	autogold.Want("multiple replace", "compute endpoint supports only one content:replace(...) parameter").
+		Equal(t, test(`content:replace(a -> b) content:replace(c -> d)`))
+}
+
+func TestToSearchQuery_RoundTrip(t *testing.T) {
+	for _, input := range []string{
+		`repo:foo (\S+) v`,
+		"a b or c",
+		`file:bar "a b" c`,
+		"repo:foo a -content:b",
+	} {
+		t.Run(input, func(t *testing.T) {
+			q, err := Parse(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			printed := q.ToSearchQuery()
+			reparsed, err := query.Parse(printed, query.SearchTypeRegex)
+			if err != nil {
+				t.Fatalf("parsing %q: %s", printed, err)
+			}
+			if got, want := query.Q(reparsed).String(), query.Q(q.Search).String(); got != want {
+				t.Fatalf("%q parses to %s, want %s", printed, got, want)
+			}
+		})
+	}
+}
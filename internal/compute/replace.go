@@ -0,0 +1,158 @@
+package compute
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/hexops/gotextdiff"
+	"github.com/hexops/gotextdiff/myers"
+	"github.com/hexops/gotextdiff/span"
+
+	"github.com/sourcegraph/sourcegraph/internal/comby"
+	"github.com/sourcegraph/sourcegraph/internal/search/result"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+)
+
+// Run computes the unified diff of replacing matches in the file of fm. It
+// returns nil if nothing in the file is replaced.
+func (c *Replace) Run(ctx context.Context, fm *result.FileMatch) (Result, error) {
+	content, err := git.ReadFile(ctx, fm.Repo.Name, fm.CommitID, fm.Path, 0)
+	if err != nil {
+		return nil, err
+	}
+	replaced, err := c.replace(fm.Path, string(content))
+	if err != nil {
+		return nil, err
+	}
+	if replaced == string(content) {
+		return nil, nil
+	}
+	return &Text{Value: unifiedDiff(fm.Path, string(content), replaced), Kind: "diff"}, nil
+}
+
+// replace returns content with every match of the pattern of c replaced by
+// its template.
+func (c *Replace) replace(path, content string) (string, error) {
+	switch p := c.MatchPattern.(type) {
+	case *Regexp:
+		return replaceRegexp(p.Value, c.ReplacePattern, content), nil
+	case *Comby:
+		return replaceComby(p.Value, c.ReplacePattern, path, content)
+	}
+	return "", errors.Errorf("compute replace does not support %s patterns", c.MatchPattern.String())
+}
+
+func replaceRegexp(r *regexp.Regexp, template, content string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range r.FindAllStringSubmatchIndex(content, -1) {
+		b.WriteString(content[last:m[0]])
+		b.WriteString(substituteRegexp(template, environmentFromSubmatches(m, r.SubexpNames(), content)))
+		last = m[1]
+	}
+	b.WriteString(content[last:])
+	return b.String()
+}
+
+// environmentFromSubmatches returns the environment of the capture groups of
+// a regular expression match. Named groups are bound to their name and all
+// groups are bound to their index.
+func environmentFromSubmatches(m []int, names []string, content string) Environment {
+	env := make(Environment)
+	for j := 2; j < len(m); j += 2 {
+		if m[j] < 0 {
+			// The group did not participate in the match.
+			continue
+		}
+		data := Data{Value: content[m[j]:m[j+1]], Range: Range{
+			Start: newLocation(-1, -1, m[j]),
+			End:   newLocation(-1, -1, m[j+1]),
+		}}
+		env[strconv.Itoa(j/2)] = data
+		if name := names[j/2]; name != "" {
+			env[name] = data
+		}
+	}
+	return env
+}
+
+var regexpVariable = regexp.MustCompile(`\$(?:\{([a-zA-Z0-9_]+)\}|([a-zA-Z0-9_]+))|\$\$`)
+
+// substituteRegexp replaces $name and ${name} in template with their values
+// in env, like regexp.Expand. Variables that are not in env are replaced by
+// the empty string and $$ is replaced by $.
+func substituteRegexp(template string, env Environment) string {
+	return regexpVariable.ReplaceAllStringFunc(template, func(v string) string {
+		if v == "$$" {
+			return "$"
+		}
+		name := strings.Trim(v, "${}")
+		return env[name].Value
+	})
+}
+
+func replaceComby(pattern, template, path, content string) (string, error) {
+	args := comby.Args{
+		MatchTemplate: pattern,
+		Matcher:       filepath.Ext(path),
+		MatchOnly:     true,
+	}
+	m, err := comby.CompileNative(args)
+	if errors.Is(err, comby.ErrUnsupportedNative) {
+		// Match files in languages the native matcher does not know
+		// generically.
+		args.Matcher = ".generic"
+		m, err = comby.CompileNative(args)
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "structural pattern is not valid for compute endpoint")
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, match := range m.Matches([]byte(content)) {
+		env := make(Environment)
+		for _, e := range match.Environment {
+			env[e.Variable] = Data{Value: e.Value, Range: fromCombyRange(e.Range)}
+		}
+		b.WriteString(content[last:match.Range.Start.Offset])
+		b.WriteString(substituteComby(template, env))
+		last = match.Range.End.Offset
+	}
+	b.WriteString(content[last:])
+	return b.String(), nil
+}
+
+func fromCombyRange(r comby.Range) Range {
+	return Range{
+		Start: newLocation(r.Start.Line, r.Start.Column, r.Start.Offset),
+		End:   newLocation(r.End.Line, r.End.Column, r.End.Offset),
+	}
+}
+
+var combyHole = regexp.MustCompile(`:\[\[([a-zA-Z0-9_]+)\]\]|:\[ ?([a-zA-Z0-9_]+)(?:\.|\\n)?\]`)
+
+// substituteComby replaces holes like :[name] in template with their values
+// in env. Holes that are not in env are left as they are.
+func substituteComby(template string, env Environment) string {
+	return combyHole.ReplaceAllStringFunc(template, func(hole string) string {
+		m := combyHole.FindStringSubmatch(hole)
+		name := m[1] + m[2]
+		if data, ok := env[name]; ok {
+			return data.Value
+		}
+		return hole
+	})
+}
+
+// unifiedDiff returns the unified diff of changing a file at path from before
+// to after.
+func unifiedDiff(path, before, after string) string {
+	edits := myers.ComputeEdits(span.URIFromPath(path), before, after)
+	return fmt.Sprint(gotextdiff.ToUnified("a/"+path, "b/"+path, before, edits))
+}
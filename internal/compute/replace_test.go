@@ -0,0 +1,57 @@
+package compute
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/hexops/autogold"
+)
+
+func TestReplace(t *testing.T) {
+	test := func(c *Replace, path, content string) string {
+		replaced, err := c.replace(path, content)
+		if err != nil {
+			return err.Error()
+		}
+		return replaced
+	}
+
+	regexpReplace := func(pattern, template string) *Replace {
+		return &Replace{MatchPattern: &Regexp{Value: regexp.MustCompile(pattern)}, ReplacePattern: template}
+	}
+	combyReplace := func(pattern, template string) *Replace {
+		return &Replace{MatchPattern: &Comby{Value: pattern}, ReplacePattern: template}
+	}
+
+	autogold.Want("regexp numbered groups", "b(a) b(c)").
+		Equal(t, test(regexpReplace(`(\w)\((\w)\)`, "$2($1)"), "main.go", "a(b) c(b)"))
+
+	autogold.Want("regexp named groups and literal dollar", "x = $1; y = $2").
+		Equal(t, test(regexpReplace(`(?P<name>\w) := (\d)`, "${name} = $$$2"), "main.go", "x := 1; y := 2"))
+
+	autogold.Want("regexp undefined variable", "f()").
+		Equal(t, test(regexpReplace(`f\((\w+)\)`, "f($missing)"), "main.go", "f(x)"))
+
+	autogold.Want("comby holes", `errors.Wrap(err, "read")`).
+		Equal(t, test(combyReplace(`fmt.Errorf(":[msg]: %w", :[[e]])`, `errors.Wrap(:[e], ":[msg]")`), "main.go", `fmt.Errorf("read: %w", err)`))
+
+	autogold.Want("comby skips comments in go", "// f(a)\ng(b)").
+		Equal(t, test(combyReplace("f(:[x])", "g(:[x])"), "main.go", "// f(a)\nf(b)"))
+
+	autogold.Want("comby generic for unsupported languages", "g(a)").
+		Equal(t, test(combyReplace("f(:[x])", "g(:[x])"), "main.rs", "f(a)"))
+
+	autogold.Want("comby unbound hole", "g(:[y])").
+		Equal(t, test(combyReplace("f(:[x])", "g(:[y])"), "main.go", "f(a)"))
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	autogold.Want("unified diff", `--- a/main.go
++++ b/main.go
+@@ -1,3 +1,3 @@
+ package main
+ 
+-var x = f(a)
++var x = g(a)
+`).Equal(t, unifiedDiff("main.go", "package main\n\nvar x = f(a)\n", "package main\n\nvar x = g(a)\n"))
+}
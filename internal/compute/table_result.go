@@ -0,0 +1,8 @@
+package compute
+
+// Table is the result of an Output command: a row for each match, with a
+// value for each column.
+type Table struct {
+	Columns []string   `json:"columns"`
+	Rows    [][]string `json:"rows"`
+}
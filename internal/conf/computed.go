@@ -321,6 +321,28 @@ func AndOrQueryEnabled() bool {
 	return val == "enabled"
 }
 
+// CommitIndexRepos returns the names of the repositories whose commits are
+// indexed for commit and diff search.
+func CommitIndexRepos() []api.RepoName {
+	names := ExperimentalFeatures().SearchCommitIndexRepositories
+	repos := make([]api.RepoName, 0, len(names))
+	for _, name := range names {
+		repos = append(repos, api.RepoName(name))
+	}
+	return repos
+}
+
+// CommitIndexEnabled returns true if the commits of repo are indexed for commit
+// and diff search.
+func CommitIndexEnabled(repo api.RepoName) bool {
+	for _, name := range ExperimentalFeatures().SearchCommitIndexRepositories {
+		if api.RepoName(name) == repo {
+			return true
+		}
+	}
+	return false
+}
+
 func ExperimentalFeatures() schema.ExperimentalFeatures {
 	val := Get().ExperimentalFeatures
 	if val == nil {
@@ -398,6 +420,16 @@ func GitMaxCodehostRequestsPerSecond() int {
 	return *val
 }
 
+// GitserverReplicationFactor returns the number of gitservers each repo is
+// cloned on. If not set, it returns the default value 1.
+func GitserverReplicationFactor() int {
+	val := ExperimentalFeatures().GitserverReplicationFactor
+	if val < 1 {
+		return 1
+	}
+	return val
+}
+
 func UserReposMaxPerUser() int {
 	v := Get().UserReposMaxPerUser
 	if v == 0 {
@@ -151,6 +151,42 @@ func TestGitMaxCodehostRequestsPerSecond(t *testing.T) {
 	}
 }
 
+func TestGitserverReplicationFactor(t *testing.T) {
+	tests := []struct {
+		name string
+		sc   *Unified
+		want int
+	}{
+		{
+			name: "not set should return default",
+			sc:   &Unified{SiteConfiguration: schema.SiteConfiguration{}},
+			want: 1,
+		},
+		{
+			name: "bad value should return default",
+			sc: &Unified{SiteConfiguration: schema.SiteConfiguration{ExperimentalFeatures: &schema.ExperimentalFeatures{
+				GitserverReplicationFactor: -1,
+			}}},
+			want: 1,
+		},
+		{
+			name: "set should return value",
+			sc: &Unified{SiteConfiguration: schema.SiteConfiguration{ExperimentalFeatures: &schema.ExperimentalFeatures{
+				GitserverReplicationFactor: 2,
+			}}},
+			want: 2,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			Mock(test.sc)
+			if got, want := GitserverReplicationFactor(), test.want; got != want {
+				t.Fatalf("GitserverReplicationFactor() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
 func setenv(t *testing.T, keyval string) func() {
 	t.Helper()
 
@@ -0,0 +1,122 @@
+package reposource
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+)
+
+// GoModule is a Go module, identified by its module path.
+type GoModule struct {
+	Path string
+}
+
+// ParseGoModule returns the Go module with the given module path.
+func ParseGoModule(path string) (GoModule, error) {
+	if err := module.CheckPath(path); err != nil {
+		return GoModule{}, err
+	}
+	return GoModule{Path: path}, nil
+}
+
+func (m GoModule) MatchesDependencyString(dependency string) bool {
+	return dependency == m.Path || strings.HasPrefix(dependency, m.Path+"@")
+}
+
+func (m GoModule) RepoName() api.RepoName {
+	return api.RepoName("go/" + m.Path)
+}
+
+func (m GoModule) CloneURL() string {
+	cloneURL := url.URL{Path: string(m.RepoName())}
+	return cloneURL.String()
+}
+
+// GoDependency is a version of a Go module.
+type GoDependency struct {
+	GoModule
+	Version string
+}
+
+var _ PackageDependency = GoDependency{}
+
+func (d GoDependency) PackageSyntax() string {
+	return d.Path
+}
+
+// PackageManagerSyntax returns the dependency in the "module@version" syntax
+// of the go command, e.g. "golang.org/x/mod@v0.4.2".
+func (d GoDependency) PackageManagerSyntax() string {
+	return fmt.Sprintf("%s@%s", d.Path, d.Version)
+}
+
+// GitTagFromVersion returns the version itself, since Go module versions
+// already start with "v" like the tags of the module's own repository.
+func (d GoDependency) GitTagFromVersion() string {
+	return d.Version
+}
+
+// SortGoDependencies sorts the dependencies by semantic version in descending
+// order. The latest version of a module becomes the first element of the
+// slice.
+func SortGoDependencies(dependencies []GoDependency) {
+	sort.Slice(dependencies, func(i, j int) bool {
+		if dependencies[i].GoModule == dependencies[j].GoModule {
+			return semver.Compare(dependencies[i].Version, dependencies[j].Version) > 0
+		}
+		return dependencies[i].Path > dependencies[j].Path
+	})
+}
+
+// NewGoDependency returns the given version of module, which must be a valid
+// semantic version.
+func NewGoDependency(mod GoModule, version string) (GoDependency, error) {
+	if err := module.Check(mod.Path, version); err != nil {
+		return GoDependency{}, err
+	}
+	return GoDependency{GoModule: mod, Version: version}, nil
+}
+
+// ParseGoDependency parses a dependency string in the "module@version" syntax
+// of the go command into a GoDependency.
+func ParseGoDependency(dependency string) (GoDependency, error) {
+	i := strings.Index(dependency, "@")
+	if i < 0 {
+		return GoDependency{}, fmt.Errorf("dependency %q must be of the form module@version", dependency)
+	}
+	mod, err := ParseGoModule(dependency[:i])
+	if err != nil {
+		return GoDependency{}, err
+	}
+	return NewGoDependency(mod, dependency[i+1:])
+}
+
+// ParseGoDependencyConfig parses an entry of the "dependencies" of a Go modules
+// connection, which is either "module@version" or a module path. The version
+// of the returned dependency is empty for a module path, meaning that all
+// versions of the module are mirrored.
+func ParseGoDependencyConfig(dependency string) (GoDependency, error) {
+	if strings.Contains(dependency, "@") {
+		return ParseGoDependency(dependency)
+	}
+	mod, err := ParseGoModule(dependency)
+	if err != nil {
+		return GoDependency{}, err
+	}
+	return GoDependency{GoModule: mod}, nil
+}
+
+// ParseGoModuleFromRepoURL returns the Go module of the repository with the
+// provided URL path, without a leading `/`.
+func ParseGoModuleFromRepoURL(urlPath string) (GoModule, error) {
+	if !strings.HasPrefix(urlPath, "go/") {
+		return GoModule{}, fmt.Errorf("failed to parse a Go module from the path %s", urlPath)
+	}
+	return ParseGoModule(strings.TrimPrefix(urlPath, "go/"))
+}
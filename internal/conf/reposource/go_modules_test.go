@@ -0,0 +1,94 @@
+package reposource
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+)
+
+func TestParseGoDependency(t *testing.T) {
+	for _, tc := range []struct {
+		dependency string
+		want       GoDependency
+	}{
+		{"golang.org/x/mod@v0.4.2", GoDependency{GoModule{"golang.org/x/mod"}, "v0.4.2"}},
+		{"github.com/Azure/go-autorest@v14.2.0+incompatible", GoDependency{GoModule{"github.com/Azure/go-autorest"}, "v14.2.0+incompatible"}},
+		{"github.com/pkg/errors@v0.0.0-20210101000000-abcdefabcdef", GoDependency{GoModule{"github.com/pkg/errors"}, "v0.0.0-20210101000000-abcdefabcdef"}},
+	} {
+		got, err := ParseGoDependency(tc.dependency)
+		if err != nil {
+			t.Fatalf("ParseGoDependency(%q): %s", tc.dependency, err)
+		}
+		assert.Equal(t, tc.want, got)
+		assert.Equal(t, tc.dependency, got.PackageManagerSyntax())
+		assert.Equal(t, tc.want.Version, got.GitTagFromVersion())
+	}
+
+	for _, dependency := range []string{
+		"golang.org/x/mod",
+		"golang.org/x/mod@",
+		"golang.org/x/mod@0.4.2",
+		"golang.org/x/mod@latest",
+		"../mod@v1.0.0",
+		"golang.org/x/mod/v2@v1.0.0",
+	} {
+		if _, err := ParseGoDependency(dependency); err == nil {
+			t.Errorf("ParseGoDependency(%q): expected an error", dependency)
+		}
+	}
+}
+
+func TestParseGoDependencyConfig(t *testing.T) {
+	got, err := ParseGoDependencyConfig("golang.org/x/mod")
+	assert.Nil(t, err)
+	assert.Equal(t, GoDependency{GoModule: GoModule{"golang.org/x/mod"}}, got)
+
+	got, err = ParseGoDependencyConfig("golang.org/x/mod@v0.4.2")
+	assert.Nil(t, err)
+	assert.Equal(t, GoDependency{GoModule{"golang.org/x/mod"}, "v0.4.2"}, got)
+
+	_, err = ParseGoDependencyConfig("golang.org/x/mod@latest")
+	assert.True(t, err != nil)
+}
+
+func TestParseGoModuleFromRepoURL(t *testing.T) {
+	mod, err := ParseGoModuleFromRepoURL("go/github.com/google/go-cmp")
+	assert.Nil(t, err)
+	assert.Equal(t, GoModule{Path: "github.com/google/go-cmp"}, mod)
+	assert.Equal(t, api.RepoName("go/github.com/google/go-cmp"), mod.RepoName())
+	assert.True(t, mod.MatchesDependencyString("github.com/google/go-cmp"))
+	assert.True(t, mod.MatchesDependencyString("github.com/google/go-cmp@v0.5.6"))
+	assert.False(t, mod.MatchesDependencyString("github.com/google/go-cmp/v2@v2.0.0"))
+
+	for _, urlPath := range []string{"go", "go/", "npm/lodash", "go/../etc"} {
+		if _, err := ParseGoModuleFromRepoURL(urlPath); err == nil {
+			t.Errorf("ParseGoModuleFromRepoURL(%q): expected an error", urlPath)
+		}
+	}
+}
+
+func TestSortGoDependencies(t *testing.T) {
+	parse := func(dependency string) GoDependency {
+		d, err := ParseGoDependency(dependency)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	dependencies := []GoDependency{
+		parse("a.com/a@v1.2.0"),
+		parse("b.com/b@v1.2.0-rc.1"),
+		parse("b.com/b@v1.11.0"),
+		parse("b.com/b@v1.2.0"),
+	}
+	expected := []GoDependency{
+		parse("b.com/b@v1.11.0"),
+		parse("b.com/b@v1.2.0"),
+		parse("b.com/b@v1.2.0-rc.1"),
+		parse("a.com/a@v1.2.0"),
+	}
+	SortGoDependencies(dependencies)
+	assert.Equal(t, expected, dependencies)
+}
@@ -56,6 +56,8 @@ type MavenDependency struct {
 	Version string
 }
 
+var _ PackageDependency = MavenDependency{}
+
 // SortDependencies sorts the dependencies by the semantic version in descending
 // order. The latest version of a dependency becomes the first element of the
 // slice
@@ -76,6 +78,14 @@ func (d MavenDependency) CoursierSyntax() string {
 	return fmt.Sprintf("%s:%s:%s", d.MavenModule.GroupID, d.MavenModule.ArtifactID, d.Version)
 }
 
+func (d MavenDependency) PackageSyntax() string {
+	return d.MavenModule.CoursierSyntax()
+}
+
+func (d MavenDependency) PackageManagerSyntax() string {
+	return d.CoursierSyntax()
+}
+
 func (d MavenDependency) GitTagFromVersion() string {
 	return "v" + d.Version
 }
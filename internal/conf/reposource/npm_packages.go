@@ -0,0 +1,141 @@
+package reposource
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+)
+
+// NpmPackage is an npm package, optionally in a scope like "@types/node".
+type NpmPackage struct {
+	// Scope is the scope of the package without the leading "@", or empty
+	// for unscoped packages.
+	Scope string
+	Name  string
+}
+
+// npmPackageNamePattern matches the names of packages published to the
+// public npm registry, see https://github.com/npm/validate-npm-package-name.
+var npmPackageNamePattern = regexp.MustCompile(`^[a-z0-9~-][a-z0-9._~-]*$`)
+
+// npmVersionPattern matches semantic versions, which npm requires for
+// published packages, and rejects anything that could escape a path or a git
+// ref.
+var npmVersionPattern = regexp.MustCompile(`^[0-9A-Za-z][0-9A-Za-z.+-]*$`)
+
+func NewNpmPackage(scope, name string) (NpmPackage, error) {
+	if scope != "" && !npmPackageNamePattern.MatchString(scope) {
+		return NpmPackage{}, fmt.Errorf("invalid npm package scope %q", scope)
+	}
+	if !npmPackageNamePattern.MatchString(name) {
+		return NpmPackage{}, fmt.Errorf("invalid npm package name %q", name)
+	}
+	return NpmPackage{Scope: scope, Name: name}, nil
+}
+
+// PackageSyntax returns the name of the package as written in a
+// package.json file, e.g. "@types/node" or "lodash".
+func (p NpmPackage) PackageSyntax() string {
+	if p.Scope == "" {
+		return p.Name
+	}
+	return fmt.Sprintf("@%s/%s", p.Scope, p.Name)
+}
+
+func (p NpmPackage) MatchesDependencyString(dependency string) bool {
+	return strings.HasPrefix(dependency, p.PackageSyntax()+"@")
+}
+
+func (p NpmPackage) RepoName() api.RepoName {
+	if p.Scope == "" {
+		return api.RepoName("npm/" + p.Name)
+	}
+	return api.RepoName(fmt.Sprintf("npm/%s/%s", p.Scope, p.Name))
+}
+
+func (p NpmPackage) CloneURL() string {
+	cloneURL := url.URL{Path: string(p.RepoName())}
+	return cloneURL.String()
+}
+
+// NpmDependency is a version of an npm package.
+type NpmDependency struct {
+	NpmPackage
+	Version string
+}
+
+var _ PackageDependency = NpmDependency{}
+
+// PackageManagerSyntax returns the dependency in the "package@version" syntax
+// of the npm command-line tool, e.g. "@types/node@16.11.7".
+func (d NpmDependency) PackageManagerSyntax() string {
+	return fmt.Sprintf("%s@%s", d.PackageSyntax(), d.Version)
+}
+
+func (d NpmDependency) GitTagFromVersion() string {
+	return "v" + d.Version
+}
+
+// SortNpmDependencies sorts the dependencies by the semantic version in
+// descending order. The latest version of a package becomes the first element
+// of the slice.
+func SortNpmDependencies(dependencies []NpmDependency) {
+	sort.Slice(dependencies, func(i, j int) bool {
+		if dependencies[i].NpmPackage == dependencies[j].NpmPackage {
+			return versionGreaterThan(dependencies[i].Version, dependencies[j].Version)
+		}
+		return dependencies[i].PackageSyntax() > dependencies[j].PackageSyntax()
+	})
+}
+
+// ParseNpmDependency parses a dependency string in the "package@version"
+// syntax of the npm command-line tool, e.g. "lodash@4.17.21" or
+// "@types/node@16.11.7", into an NpmDependency.
+func ParseNpmDependency(dependency string) (NpmDependency, error) {
+	// The scope of a package starts with an "@" as well.
+	i := strings.LastIndex(dependency, "@")
+	if i <= 0 || i == len(dependency)-1 {
+		return NpmDependency{}, fmt.Errorf("dependency %q must be of the form package@version", dependency)
+	}
+	pkg, err := parseNpmPackageSyntax(dependency[:i])
+	if err != nil {
+		return NpmDependency{}, err
+	}
+	version := dependency[i+1:]
+	if !npmVersionPattern.MatchString(version) {
+		return NpmDependency{}, fmt.Errorf("invalid version %q of dependency %q", version, dependency)
+	}
+	return NpmDependency{NpmPackage: pkg, Version: version}, nil
+}
+
+func parseNpmPackageSyntax(name string) (NpmPackage, error) {
+	if !strings.HasPrefix(name, "@") {
+		return NewNpmPackage("", name)
+	}
+	parts := strings.Split(strings.TrimPrefix(name, "@"), "/")
+	if len(parts) != 2 {
+		return NpmPackage{}, fmt.Errorf("scoped npm package %q must be of the form @scope/name", name)
+	}
+	return NewNpmPackage(parts[0], parts[1])
+}
+
+// ParseNpmPackageFromRepoURL returns the npm package of the repository with
+// the provided URL path, without a leading `/`.
+func ParseNpmPackageFromRepoURL(urlPath string) (NpmPackage, error) {
+	if !strings.HasPrefix(urlPath, "npm/") {
+		return NpmPackage{}, fmt.Errorf("failed to parse an npm package from the path %s", urlPath)
+	}
+	parts := strings.Split(strings.TrimPrefix(urlPath, "npm/"), "/")
+	switch len(parts) {
+	case 1:
+		return NewNpmPackage("", parts[0])
+	case 2:
+		return NewNpmPackage(parts[0], parts[1])
+	default:
+		return NpmPackage{}, fmt.Errorf("failed to parse an npm package from the path %s", urlPath)
+	}
+}
@@ -0,0 +1,90 @@
+package reposource
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+)
+
+func TestParseNpmDependency(t *testing.T) {
+	for _, tc := range []struct {
+		dependency string
+		want       NpmDependency
+	}{
+		{"lodash@4.17.21", NpmDependency{NpmPackage{"", "lodash"}, "4.17.21"}},
+		{"@types/node@16.11.7", NpmDependency{NpmPackage{"types", "node"}, "16.11.7"}},
+		{"left-pad@1.0.0-beta.1", NpmDependency{NpmPackage{"", "left-pad"}, "1.0.0-beta.1"}},
+	} {
+		got, err := ParseNpmDependency(tc.dependency)
+		if err != nil {
+			t.Fatalf("ParseNpmDependency(%q): %s", tc.dependency, err)
+		}
+		assert.Equal(t, tc.want, got)
+		assert.Equal(t, tc.dependency, got.PackageManagerSyntax())
+	}
+
+	for _, dependency := range []string{
+		"lodash",
+		"lodash@",
+		"@types/node",
+		"@types@1.0.0",
+		"@a/b/c@1.0.0",
+		"Lodash@1.0.0",
+		"../lodash@1.0.0",
+		"lodash@../../1.0.0",
+		"lodash@..",
+	} {
+		if _, err := ParseNpmDependency(dependency); err == nil {
+			t.Errorf("ParseNpmDependency(%q): expected an error", dependency)
+		}
+	}
+}
+
+func TestParseNpmPackageFromRepoURL(t *testing.T) {
+	pkg, err := ParseNpmPackageFromRepoURL("npm/types/node")
+	assert.Nil(t, err)
+	assert.Equal(t, NpmPackage{Scope: "types", Name: "node"}, pkg)
+	assert.Equal(t, api.RepoName("npm/types/node"), pkg.RepoName())
+	assert.Equal(t, "@types/node", pkg.PackageSyntax())
+
+	pkg, err = ParseNpmPackageFromRepoURL("npm/lodash")
+	assert.Nil(t, err)
+	assert.Equal(t, NpmPackage{Name: "lodash"}, pkg)
+	assert.Equal(t, api.RepoName("npm/lodash"), pkg.RepoName())
+	assert.True(t, pkg.MatchesDependencyString("lodash@4.17.21"))
+	assert.False(t, pkg.MatchesDependencyString("lodash.get@4.4.2"))
+
+	for _, urlPath := range []string{"npm", "npm/a/b/c", "maven/a/b", "npm/.."} {
+		if _, err := ParseNpmPackageFromRepoURL(urlPath); err == nil {
+			t.Errorf("ParseNpmPackageFromRepoURL(%q): expected an error", urlPath)
+		}
+	}
+}
+
+func TestSortNpmDependencies(t *testing.T) {
+	parse := func(dependency string) NpmDependency {
+		d, err := ParseNpmDependency(dependency)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	dependencies := []NpmDependency{
+		parse("a@1.2.0"),
+		parse("@b/a@1.0.0"),
+		parse("b@1.11.0"),
+		parse("b@1.2.0"),
+		parse("b@1.2.0-rc.1"),
+	}
+	expected := []NpmDependency{
+		parse("b@1.11.0"),
+		parse("b@1.2.0"),
+		parse("b@1.2.0-rc.1"),
+		parse("a@1.2.0"),
+		parse("@b/a@1.0.0"),
+	}
+	SortNpmDependencies(dependencies)
+	assert.Equal(t, expected, dependencies)
+}
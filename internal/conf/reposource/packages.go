@@ -0,0 +1,13 @@
+package reposource
+
+// PackageDependency is a version of a package from a package registry, which
+// gitserver mirrors as a tag of the git repository of the package.
+type PackageDependency interface {
+	// PackageSyntax returns the name of the package without the version.
+	PackageSyntax() string
+	// PackageManagerSyntax returns the package and the version in the
+	// syntax of the package manager.
+	PackageManagerSyntax() string
+	// GitTagFromVersion returns the git tag of the version.
+	GitTagFromVersion() string
+}
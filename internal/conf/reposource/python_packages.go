@@ -0,0 +1,129 @@
+package reposource
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+)
+
+// PythonPackage is a Python package, identified by its normalized name.
+type PythonPackage struct {
+	// Name is the name of the package normalized as described in PEP 503,
+	// e.g. "python-dateutil" for "Python_Dateutil".
+	Name string
+}
+
+// pythonPackageNamePattern matches valid package names, see
+// https://www.python.org/dev/peps/pep-0508/#names.
+var pythonPackageNamePattern = regexp.MustCompile(`^([A-Za-z0-9]|[A-Za-z0-9][A-Za-z0-9._-]*[A-Za-z0-9])$`)
+
+// pythonNameSeparators matches the runs of separators that PEP 503
+// normalization replaces with a single "-".
+var pythonNameSeparators = regexp.MustCompile(`[-_.]+`)
+
+// pythonVersionPattern matches PEP 440 versions, including epochs and local
+// versions, and rejects anything that could escape a path or a git ref.
+var pythonVersionPattern = regexp.MustCompile(`^[0-9A-Za-z][0-9A-Za-z.!+_-]*$`)
+
+// NewPythonPackage returns the package with the given name, which is
+// normalized.
+func NewPythonPackage(name string) (PythonPackage, error) {
+	if !pythonPackageNamePattern.MatchString(name) {
+		return PythonPackage{}, fmt.Errorf("invalid Python package name %q", name)
+	}
+	return PythonPackage{Name: NormalizePythonPackageName(name)}, nil
+}
+
+// NormalizePythonPackageName returns the name normalized as described in
+// https://www.python.org/dev/peps/pep-0503/#normalized-names.
+func NormalizePythonPackageName(name string) string {
+	return strings.ToLower(pythonNameSeparators.ReplaceAllString(name, "-"))
+}
+
+func (p PythonPackage) MatchesDependencyString(dependency string) bool {
+	i := strings.Index(dependency, "==")
+	return i > 0 && NormalizePythonPackageName(dependency[:i]) == p.Name
+}
+
+func (p PythonPackage) RepoName() api.RepoName {
+	return api.RepoName("python/" + p.Name)
+}
+
+func (p PythonPackage) CloneURL() string {
+	cloneURL := url.URL{Path: string(p.RepoName())}
+	return cloneURL.String()
+}
+
+// PythonDependency is a version of a Python package.
+type PythonDependency struct {
+	PythonPackage
+	Version string
+}
+
+var _ PackageDependency = PythonDependency{}
+
+func (d PythonDependency) PackageSyntax() string {
+	return d.Name
+}
+
+// PackageManagerSyntax returns the dependency in the "package==version"
+// syntax of pip requirements, e.g. "numpy==1.22.0".
+func (d PythonDependency) PackageManagerSyntax() string {
+	return fmt.Sprintf("%s==%s", d.Name, d.Version)
+}
+
+func (d PythonDependency) GitTagFromVersion() string {
+	return "v" + d.Version
+}
+
+// SortPythonDependencies sorts the dependencies by version in descending
+// order. The latest version of a package becomes the first element of the
+// slice.
+func SortPythonDependencies(dependencies []PythonDependency) {
+	sort.Slice(dependencies, func(i, j int) bool {
+		if dependencies[i].PythonPackage == dependencies[j].PythonPackage {
+			return versionGreaterThan(dependencies[i].Version, dependencies[j].Version)
+		}
+		return dependencies[i].Name > dependencies[j].Name
+	})
+}
+
+// ParsePythonDependency parses a dependency string in the "package==version"
+// syntax of pip requirements, e.g. "numpy==1.22.0", into a PythonDependency.
+func ParsePythonDependency(dependency string) (PythonDependency, error) {
+	i := strings.Index(dependency, "==")
+	if i <= 0 {
+		return PythonDependency{}, fmt.Errorf("dependency %q must be of the form package==version", dependency)
+	}
+	pkg, err := NewPythonPackage(dependency[:i])
+	if err != nil {
+		return PythonDependency{}, err
+	}
+	version := dependency[i+2:]
+	if !pythonVersionPattern.MatchString(version) {
+		return PythonDependency{}, fmt.Errorf("invalid version %q of dependency %q", version, dependency)
+	}
+	return PythonDependency{PythonPackage: pkg, Version: version}, nil
+}
+
+// ParsePythonPackageFromRepoURL returns the Python package of the repository
+// with the provided URL path, without a leading `/`.
+func ParsePythonPackageFromRepoURL(urlPath string) (PythonPackage, error) {
+	if !strings.HasPrefix(urlPath, "python/") {
+		return PythonPackage{}, fmt.Errorf("failed to parse a Python package from the path %s", urlPath)
+	}
+	name := strings.TrimPrefix(urlPath, "python/")
+	pkg, err := NewPythonPackage(name)
+	if err != nil {
+		return PythonPackage{}, err
+	}
+	if pkg.Name != name {
+		// Repository names always contain normalized package names.
+		return PythonPackage{}, fmt.Errorf("failed to parse a Python package from the path %s", urlPath)
+	}
+	return pkg, nil
+}
@@ -0,0 +1,83 @@
+package reposource
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+)
+
+func TestParsePythonDependency(t *testing.T) {
+	for _, tc := range []struct {
+		dependency string
+		want       PythonDependency
+		syntax     string
+	}{
+		{"numpy==1.22.0", PythonDependency{PythonPackage{"numpy"}, "1.22.0"}, "numpy==1.22.0"},
+		{"Python_Dateutil==2.8.2", PythonDependency{PythonPackage{"python-dateutil"}, "2.8.2"}, "python-dateutil==2.8.2"},
+		{"zope.interface==5.4.0", PythonDependency{PythonPackage{"zope-interface"}, "5.4.0"}, "zope-interface==5.4.0"},
+		{"pandas==1.4.0rc0", PythonDependency{PythonPackage{"pandas"}, "1.4.0rc0"}, "pandas==1.4.0rc0"},
+		{"twisted==1!21.7.0+local", PythonDependency{PythonPackage{"twisted"}, "1!21.7.0+local"}, "twisted==1!21.7.0+local"},
+	} {
+		got, err := ParsePythonDependency(tc.dependency)
+		if err != nil {
+			t.Fatalf("ParsePythonDependency(%q): %s", tc.dependency, err)
+		}
+		assert.Equal(t, tc.want, got)
+		assert.Equal(t, tc.syntax, got.PackageManagerSyntax())
+	}
+
+	for _, dependency := range []string{
+		"numpy",
+		"numpy==",
+		"numpy>=1.22.0",
+		"==1.22.0",
+		"-numpy==1.22.0",
+		"../numpy==1.22.0",
+		"numpy==../1.22.0",
+		"numpy==..",
+	} {
+		if _, err := ParsePythonDependency(dependency); err == nil {
+			t.Errorf("ParsePythonDependency(%q): expected an error", dependency)
+		}
+	}
+}
+
+func TestParsePythonPackageFromRepoURL(t *testing.T) {
+	pkg, err := ParsePythonPackageFromRepoURL("python/python-dateutil")
+	assert.Nil(t, err)
+	assert.Equal(t, PythonPackage{Name: "python-dateutil"}, pkg)
+	assert.Equal(t, api.RepoName("python/python-dateutil"), pkg.RepoName())
+	assert.True(t, pkg.MatchesDependencyString("python-dateutil==2.8.2"))
+	assert.True(t, pkg.MatchesDependencyString("Python_Dateutil==2.8.2"))
+	assert.False(t, pkg.MatchesDependencyString("python-dateutil-extra==1.0.0"))
+
+	for _, urlPath := range []string{"python", "python/", "python/Python_Dateutil", "python/a/b", "npm/lodash", "python/.."} {
+		if _, err := ParsePythonPackageFromRepoURL(urlPath); err == nil {
+			t.Errorf("ParsePythonPackageFromRepoURL(%q): expected an error", urlPath)
+		}
+	}
+}
+
+func TestSortPythonDependencies(t *testing.T) {
+	parse := func(dependency string) PythonDependency {
+		d, err := ParsePythonDependency(dependency)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	dependencies := []PythonDependency{
+		parse("numpy==1.9.0"),
+		parse("pandas==1.3.5"),
+		parse("numpy==1.22.0"),
+	}
+	expected := []PythonDependency{
+		parse("pandas==1.3.5"),
+		parse("numpy==1.22.0"),
+		parse("numpy==1.9.0"),
+	}
+	SortPythonDependencies(dependencies)
+	assert.Equal(t, expected, dependencies)
+}
@@ -0,0 +1,266 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/keegancsmith/sqlf"
+	"github.com/lib/pq"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/database/basestore"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
+	"github.com/sourcegraph/sourcegraph/internal/types"
+)
+
+// IndexedCommit is a commit in the commit index, with the diff of the changes
+// it made.
+type IndexedCommit struct {
+	ID             api.CommitID
+	AuthorName     string
+	AuthorEmail    string
+	AuthorDate     time.Time
+	CommitterName  string
+	CommitterEmail string
+	CommitterDate  time.Time
+	Message        string
+	Parents        []string
+
+	// Paths are the paths the commit touched.
+	Paths []string
+
+	// Diff is the --unified=0 diff of the commit to its first parent, without
+	// a/ and b/ prefixes.
+	Diff string
+}
+
+// CommitIndexSearchOptions specifies which commits (*CommitIndexStore).Search
+// returns. Patterns are POSIX regular expressions and combine like the
+// --grep, --author and --committer flags of `git log --all-match`.
+type CommitIndexSearchOptions struct {
+	RepoID api.RepoID
+
+	MessagePatterns   []string // all match the commit message
+	AuthorPatterns    []string // any matches "name <email>" of the author
+	CommitterPatterns []string // any matches "name <email>" of the committer
+	DiffPattern       string   // matches the diff
+
+	IsCaseSensitive bool
+
+	// Since and Until, if set, limit the committer date of commits.
+	Since *time.Time
+	Until *time.Time
+
+	*LimitOffset
+}
+
+// CommitIndexStore stores an index of the commits of the default branch of
+// repositories, so commit and diff searches don't have to run `git log` for
+// commits that were already indexed.
+type CommitIndexStore struct {
+	*basestore.Store
+}
+
+// CommitIndex instantiates and returns a new CommitIndexStore with prepared statements.
+func CommitIndex(db dbutil.DB) *CommitIndexStore {
+	return &CommitIndexStore{Store: basestore.NewWithDB(db, sql.TxOptions{})}
+}
+
+func (s *CommitIndexStore) Transact(ctx context.Context) (*CommitIndexStore, error) {
+	txBase, err := s.Store.Transact(ctx)
+	return &CommitIndexStore{Store: txBase}, err
+}
+
+// IndexedCommitID returns the commit up to which a repository is indexed: all
+// non-merge commits reachable from it are in the index. It returns false if
+// the repository is not indexed.
+func (s *CommitIndexStore) IndexedCommitID(ctx context.Context, repoID api.RepoID) (_ api.CommitID, ok bool, err error) {
+	if Mocks.CommitIndex.IndexedCommitID != nil {
+		return Mocks.CommitIndex.IndexedCommitID(ctx, repoID)
+	}
+
+	q := `
+	SELECT indexed_commit
+	FROM commit_index_metadata
+	WHERE repo_id = %s
+	`
+	var commitID api.CommitID
+	if err := s.QueryRow(ctx, sqlf.Sprintf(q, repoID)).Scan(&commitID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+	return commitID, true, nil
+}
+
+// commitIndexInsertBatchSize is the number of commits inserted per statement.
+const commitIndexInsertBatchSize = 100
+
+// Add adds commits to the index of a repository and marks it as indexed up to
+// indexedCommitID. The caller must add all non-merge commits reachable from
+// indexedCommitID that are not in the index yet.
+func (s *CommitIndexStore) Add(ctx context.Context, repoID api.RepoID, commits []*IndexedCommit, indexedCommitID api.CommitID) (err error) {
+	tx, err := s.Transact(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { err = tx.Done(err) }()
+
+	for len(commits) > 0 {
+		batch := commits
+		if len(batch) > commitIndexInsertBatchSize {
+			batch = batch[:commitIndexInsertBatchSize]
+		}
+		commits = commits[len(batch):]
+
+		values := make([]*sqlf.Query, 0, len(batch))
+		for _, c := range batch {
+			values = append(values, sqlf.Sprintf("(%s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s)",
+				repoID, c.ID,
+				c.AuthorName, c.AuthorEmail, c.AuthorDate,
+				c.CommitterName, c.CommitterEmail, c.CommitterDate,
+				c.Message, pq.Array(c.Parents), pq.Array(c.Paths), c.Diff,
+			))
+		}
+		q := `
+		INSERT INTO commit_index (repo_id, commit_id, author_name, author_email, author_date, committer_name, committer_email, committer_date, message, parents, paths, diff)
+		VALUES %s
+		ON CONFLICT (repo_id, commit_id) DO NOTHING
+		`
+		if err := tx.Exec(ctx, sqlf.Sprintf(q, sqlf.Join(values, ", "))); err != nil {
+			return err
+		}
+	}
+
+	q := `
+	INSERT INTO commit_index_metadata (repo_id, indexed_commit, last_indexed_at)
+	VALUES (%s, %s, now())
+	ON CONFLICT (repo_id) DO UPDATE
+	SET indexed_commit = EXCLUDED.indexed_commit, last_indexed_at = EXCLUDED.last_indexed_at
+	`
+	return tx.Exec(ctx, sqlf.Sprintf(q, repoID, indexedCommitID))
+}
+
+// Touch records that a repository was checked for new commits without adding
+// any, so other repositories are indexed first.
+func (s *CommitIndexStore) Touch(ctx context.Context, repoID api.RepoID) error {
+	q := `
+	UPDATE commit_index_metadata
+	SET last_indexed_at = now()
+	WHERE repo_id = %s
+	`
+	return s.Exec(ctx, sqlf.Sprintf(q, repoID))
+}
+
+// Reset removes the index of a repository, for example because its default
+// branch was force-pushed and the indexed commits are no longer on it.
+func (s *CommitIndexStore) Reset(ctx context.Context, repoID api.RepoID) (err error) {
+	tx, err := s.Transact(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { err = tx.Done(err) }()
+
+	if err := tx.Exec(ctx, sqlf.Sprintf(`DELETE FROM commit_index_metadata WHERE repo_id = %s`, repoID)); err != nil {
+		return err
+	}
+	return tx.Exec(ctx, sqlf.Sprintf(`DELETE FROM commit_index WHERE repo_id = %s`, repoID))
+}
+
+// ReposToIndex returns up to limit cloned repositories with one of the given
+// names, the ones whose index was updated least recently (or never) first.
+func (s *CommitIndexStore) ReposToIndex(ctx context.Context, names []api.RepoName, limit int) ([]types.RepoName, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	q := `
+	SELECT repo.id, repo.name
+	FROM repo
+	JOIN gitserver_repos gr ON gr.repo_id = repo.id
+	LEFT JOIN commit_index_metadata m ON m.repo_id = repo.id
+	WHERE repo.deleted_at IS NULL
+		AND gr.clone_status = 'cloned'
+		AND repo.name = ANY (%s)
+	ORDER BY m.last_indexed_at ASC NULLS FIRST, repo.id
+	LIMIT %s
+	`
+	rows, err := s.Query(ctx, sqlf.Sprintf(q, pq.Array(names), limit))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var repos []types.RepoName
+	for rows.Next() {
+		var r types.RepoName
+		if err := rows.Scan(&r.ID, &r.Name); err != nil {
+			return nil, err
+		}
+		repos = append(repos, r)
+	}
+	return repos, rows.Err()
+}
+
+// Search returns the indexed commits of a repository that match opts, the
+// most recently committed first.
+func (s *CommitIndexStore) Search(ctx context.Context, opts CommitIndexSearchOptions) ([]*IndexedCommit, error) {
+	if Mocks.CommitIndex.Search != nil {
+		return Mocks.CommitIndex.Search(ctx, opts)
+	}
+
+	match := "~*"
+	if opts.IsCaseSensitive {
+		match = "~"
+	}
+	conds := []*sqlf.Query{sqlf.Sprintf("repo_id = %s", opts.RepoID)}
+	for _, p := range opts.MessagePatterns {
+		conds = append(conds, sqlf.Sprintf("message "+match+" %s", p))
+	}
+	if len(opts.AuthorPatterns) > 0 {
+		conds = append(conds, sqlf.Sprintf("(author_name || ' <' || author_email || '>') "+match+" ANY (%s)", pq.Array(opts.AuthorPatterns)))
+	}
+	if len(opts.CommitterPatterns) > 0 {
+		conds = append(conds, sqlf.Sprintf("(committer_name || ' <' || committer_email || '>') "+match+" ANY (%s)", pq.Array(opts.CommitterPatterns)))
+	}
+	if opts.DiffPattern != "" {
+		conds = append(conds, sqlf.Sprintf("diff "+match+" %s", opts.DiffPattern))
+	}
+	if opts.Since != nil {
+		conds = append(conds, sqlf.Sprintf("committer_date >= %s", *opts.Since))
+	}
+	if opts.Until != nil {
+		conds = append(conds, sqlf.Sprintf("committer_date <= %s", *opts.Until))
+	}
+
+	q := `
+	SELECT commit_id, author_name, author_email, author_date, committer_name, committer_email, committer_date, message, parents, paths, diff
+	FROM commit_index
+	WHERE %s
+	ORDER BY committer_date DESC, commit_id
+	%s
+	`
+	rows, err := s.Query(ctx, sqlf.Sprintf(q, sqlf.Join(conds, "AND"), opts.LimitOffset.SQL()))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var commits []*IndexedCommit
+	for rows.Next() {
+		var c IndexedCommit
+		if err := rows.Scan(
+			&c.ID,
+			&c.AuthorName, &c.AuthorEmail, &c.AuthorDate,
+			&c.CommitterName, &c.CommitterEmail, &c.CommitterDate,
+			&c.Message, pq.Array(&c.Parents), pq.Array(&c.Paths), &c.Diff,
+		); err != nil {
+			return nil, err
+		}
+		commits = append(commits, &c)
+	}
+	return commits, rows.Err()
+}
@@ -0,0 +1,12 @@
+package database
+
+import (
+	"context"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+)
+
+type MockCommitIndex struct {
+	IndexedCommitID func(ctx context.Context, repoID api.RepoID) (api.CommitID, bool, error)
+	Search          func(ctx context.Context, opts CommitIndexSearchOptions) ([]*IndexedCommit, error)
+}
@@ -0,0 +1,131 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/sourcegraph/sourcegraph/internal/actor"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbtest"
+	"github.com/sourcegraph/sourcegraph/internal/types"
+)
+
+func TestCommitIndex(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	t.Parallel()
+	db := dbtest.NewDB(t, "")
+	ctx := actor.WithInternalActor(context.Background())
+	index := CommitIndex(db)
+
+	cloned := mustCreate(ctx, t, db, &types.Repo{Name: "a/cloned"}, types.CloneStatusCloned)[0]
+	mustCreate(ctx, t, db, &types.Repo{Name: "a/not-cloned"}, types.CloneStatusNotCloned)
+	mustCreate(ctx, t, db, &types.Repo{Name: "a/not-listed"}, types.CloneStatusCloned)
+
+	date := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	commits := []*IndexedCommit{
+		{
+			ID:             "a",
+			AuthorName:     "Alice",
+			AuthorEmail:    "alice@example.com",
+			AuthorDate:     date,
+			CommitterName:  "Alice",
+			CommitterEmail: "alice@example.com",
+			CommitterDate:  date,
+			Message:        "add README",
+			Parents:        []string{},
+			Paths:          []string{"README.md"},
+			Diff:           "diff --git README.md README.md\n@@ -0,0 +1 @@\n+hello\n",
+		},
+		{
+			ID:             "b",
+			AuthorName:     "Bob",
+			AuthorEmail:    "bob@example.com",
+			AuthorDate:     date.Add(time.Hour),
+			CommitterName:  "Bob",
+			CommitterEmail: "bob@example.com",
+			CommitterDate:  date.Add(time.Hour),
+			Message:        "fix typo",
+			Parents:        []string{"a"},
+			Paths:          []string{"README.md"},
+			Diff:           "diff --git README.md README.md\n@@ -1 +1 @@\n-hello\n+Hello\n",
+		},
+	}
+
+	t.Run("ReposToIndex", func(t *testing.T) {
+		repos, err := index.ReposToIndex(ctx, []api.RepoName{cloned.Name, "a/not-cloned"}, 10)
+		require.NoError(t, err)
+		require.Equal(t, []types.RepoName{{ID: cloned.ID, Name: cloned.Name}}, repos)
+
+		repos, err = index.ReposToIndex(ctx, nil, 10)
+		require.NoError(t, err)
+		require.Empty(t, repos)
+	})
+
+	t.Run("Add", func(t *testing.T) {
+		_, ok, err := index.IndexedCommitID(ctx, cloned.ID)
+		require.NoError(t, err)
+		require.False(t, ok)
+
+		require.NoError(t, index.Add(ctx, cloned.ID, commits, "b"))
+		// Adding commits again is a no-op.
+		require.NoError(t, index.Add(ctx, cloned.ID, commits[1:], "b"))
+
+		commitID, ok, err := index.IndexedCommitID(ctx, cloned.ID)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, api.CommitID("b"), commitID)
+	})
+
+	t.Run("Search", func(t *testing.T) {
+		ids := func(opts CommitIndexSearchOptions) []api.CommitID {
+			t.Helper()
+			opts.RepoID = cloned.ID
+			got, err := index.Search(ctx, opts)
+			require.NoError(t, err)
+			var ids []api.CommitID
+			for _, c := range got {
+				ids = append(ids, c.ID)
+			}
+			return ids
+		}
+
+		require.Equal(t, []api.CommitID{"b", "a"}, ids(CommitIndexSearchOptions{}))
+		require.Equal(t, []api.CommitID{"b"}, ids(CommitIndexSearchOptions{MessagePatterns: []string{"TYPO"}}))
+		require.Nil(t, ids(CommitIndexSearchOptions{MessagePatterns: []string{"TYPO"}, IsCaseSensitive: true}))
+		require.Equal(t, []api.CommitID{"a"}, ids(CommitIndexSearchOptions{AuthorPatterns: []string{"alice@"}}))
+		require.Equal(t, []api.CommitID{"b", "a"}, ids(CommitIndexSearchOptions{AuthorPatterns: []string{"alice@", "^Bob "}}))
+		require.Nil(t, ids(CommitIndexSearchOptions{MessagePatterns: []string{"add", "typo"}}))
+		require.Equal(t, []api.CommitID{"b"}, ids(CommitIndexSearchOptions{DiffPattern: `\+Hello`, IsCaseSensitive: true}))
+		until := date.Add(time.Minute)
+		require.Equal(t, []api.CommitID{"a"}, ids(CommitIndexSearchOptions{Until: &until}))
+		require.Equal(t, []api.CommitID{"b"}, ids(CommitIndexSearchOptions{Since: &until}))
+		require.Equal(t, []api.CommitID{"a"}, ids(CommitIndexSearchOptions{LimitOffset: &LimitOffset{Limit: 1, Offset: 1}}))
+
+		got, err := index.Search(ctx, CommitIndexSearchOptions{RepoID: cloned.ID, MessagePatterns: []string{"typo"}})
+		require.NoError(t, err)
+		require.Len(t, got, 1)
+		require.Equal(t, commits[1].Message, got[0].Message)
+		require.Equal(t, commits[1].Parents, got[0].Parents)
+		require.Equal(t, commits[1].Paths, got[0].Paths)
+		require.Equal(t, commits[1].Diff, got[0].Diff)
+		require.True(t, commits[1].CommitterDate.Equal(got[0].CommitterDate))
+	})
+
+	t.Run("Reset", func(t *testing.T) {
+		require.NoError(t, index.Reset(ctx, cloned.ID))
+
+		_, ok, err := index.IndexedCommitID(ctx, cloned.ID)
+		require.NoError(t, err)
+		require.False(t, ok)
+
+		got, err := index.Search(ctx, CommitIndexSearchOptions{RepoID: cloned.ID})
+		require.NoError(t, err)
+		require.Empty(t, got)
+	})
+}
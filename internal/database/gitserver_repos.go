@@ -8,6 +8,7 @@ import (
 
 	"github.com/cockroachdb/errors"
 	"github.com/keegancsmith/sqlf"
+	"github.com/lib/pq"
 
 	"github.com/sourcegraph/sourcegraph/internal/api"
 	"github.com/sourcegraph/sourcegraph/internal/database/basestore"
@@ -241,6 +242,183 @@ SET (last_fetched, shard_id, updated_at) =
 	return errors.Wrap(err, "setting last fetched")
 }
 
+// UpsertReplicas adds rows representing the state of replicas of repos on
+// gitservers other than the one they live on. Only the clone status of existing
+// rows is updated, their last error and last fetched time are set by the
+// gitserver holding the replica as it fetches.
+func (s *GitserverRepoStore) UpsertReplicas(ctx context.Context, replicas ...*types.GitserverRepoReplica) error {
+	values := make([]*sqlf.Query, 0, len(replicas))
+	for _, r := range replicas {
+		q := sqlf.Sprintf("(%s, %s, %s, %s, %s, now())",
+			r.RepoID,
+			r.ShardID,
+			r.CloneStatus,
+			dbutil.NewNullString(sanitizeToUTF8(r.LastError)),
+			r.LastFetched,
+		)
+
+		values = append(values, q)
+	}
+
+	err := s.Exec(ctx, sqlf.Sprintf(`
+-- source: internal/database/gitserver_repos.go:GitserverRepoStore.UpsertReplicas
+INSERT INTO
+    gitserver_repo_replicas(repo_id, shard_id, clone_status, last_error, last_fetched, updated_at)
+    VALUES %s
+    ON CONFLICT (repo_id, shard_id) DO UPDATE
+    SET (clone_status, updated_at) =
+        (EXCLUDED.clone_status, now())
+    WHERE gitserver_repo_replicas.clone_status IS DISTINCT FROM EXCLUDED.clone_status
+`, sqlf.Join(values, ",")))
+
+	return errors.Wrap(err, "creating GitserverRepoReplica")
+}
+
+// GetReplicasByID returns the replicas of a repo, ordered by shard.
+func (s *GitserverRepoStore) GetReplicasByID(ctx context.Context, id api.RepoID) ([]*types.GitserverRepoReplica, error) {
+	q := `
+-- source: internal/database/gitserver_repos.go:GitserverRepoStore.GetReplicasByID
+SELECT
+       repo_id,
+       shard_id,
+       clone_status,
+       last_error,
+       last_fetched,
+       updated_at
+FROM gitserver_repo_replicas
+WHERE repo_id = %s
+ORDER BY shard_id
+`
+
+	rows, err := s.Query(ctx, sqlf.Sprintf(q, id))
+	if err != nil {
+		return nil, errors.Wrap(err, "getting GitserverRepoReplicas")
+	}
+	defer rows.Close()
+
+	var replicas []*types.GitserverRepoReplica
+	for rows.Next() {
+		var r types.GitserverRepoReplica
+		var cloneStatus string
+		if err := rows.Scan(
+			&r.RepoID,
+			&r.ShardID,
+			&cloneStatus,
+			&dbutil.NullString{S: &r.LastError},
+			&r.LastFetched,
+			&r.UpdatedAt,
+		); err != nil {
+			return nil, errors.Wrap(err, "scanning GitserverRepoReplica")
+		}
+		r.CloneStatus = types.ParseCloneStatus(cloneStatus)
+		replicas = append(replicas, &r)
+	}
+
+	return replicas, errors.Wrap(rows.Err(), "iterating rows")
+}
+
+// ListReplicaRepoIDs returns, in order, the IDs of up to limit repos greater
+// than after which have a replica on the given shard.
+func (s *GitserverRepoStore) ListReplicaRepoIDs(ctx context.Context, shardID string, after api.RepoID, limit int) ([]api.RepoID, error) {
+	q := `
+-- source: internal/database/gitserver_repos.go:GitserverRepoStore.ListReplicaRepoIDs
+SELECT repo_id
+FROM gitserver_repo_replicas
+WHERE shard_id = %s AND repo_id > %s
+ORDER BY repo_id
+LIMIT %s
+`
+
+	rows, err := s.Query(ctx, sqlf.Sprintf(q, shardID, after, limit))
+	if err != nil {
+		return nil, errors.Wrap(err, "listing GitserverRepoReplicas")
+	}
+	defer rows.Close()
+
+	var ids []api.RepoID
+	for rows.Next() {
+		var id api.RepoID
+		if err := rows.Scan(&id); err != nil {
+			return nil, errors.Wrap(err, "scanning repo id")
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, errors.Wrap(rows.Err(), "iterating rows")
+}
+
+// DeleteReplicas deletes the replicas of the given repos on the given shard.
+// It is used to forget about replicas of repos which are no longer replicated
+// on the shard.
+func (s *GitserverRepoStore) DeleteReplicas(ctx context.Context, shardID string, ids ...api.RepoID) error {
+	repoIDs := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		repoIDs = append(repoIDs, int64(id))
+	}
+
+	err := s.Exec(ctx, sqlf.Sprintf(`
+-- source: internal/database/gitserver_repos.go:GitserverRepoStore.DeleteReplicas
+DELETE FROM gitserver_repo_replicas
+WHERE shard_id = %s AND repo_id = ANY(%s)
+`, shardID, pq.Array(repoIDs)))
+
+	return errors.Wrap(err, "deleting GitserverRepoReplicas")
+}
+
+// SetReplicaCloneStatus is like SetCloneStatus for the replica of a repo on
+// the given shard.
+func (s *GitserverRepoStore) SetReplicaCloneStatus(ctx context.Context, name api.RepoName, status types.CloneStatus, shardID string) error {
+	err := s.Exec(ctx, sqlf.Sprintf(`
+-- source: internal/database/gitserver_repos.go:GitserverRepoStore.SetReplicaCloneStatus
+INSERT INTO gitserver_repo_replicas(repo_id, shard_id, clone_status, updated_at)
+SELECT id, %s, %s, now()
+FROM repo
+WHERE name = %s
+ON CONFLICT (repo_id, shard_id) DO UPDATE
+SET (clone_status, updated_at) =
+    (EXCLUDED.clone_status, now())
+    WHERE gitserver_repo_replicas.clone_status IS DISTINCT FROM EXCLUDED.clone_status
+`, shardID, status, name))
+
+	return errors.Wrap(err, "setting replica clone status")
+}
+
+// SetReplicaLastError is like SetLastError for the replica of a repo on the
+// given shard.
+func (s *GitserverRepoStore) SetReplicaLastError(ctx context.Context, name api.RepoName, error, shardID string) error {
+	ns := dbutil.NewNullString(sanitizeToUTF8(error))
+
+	err := s.Exec(ctx, sqlf.Sprintf(`
+-- source: internal/database/gitserver_repos.go:GitserverRepoStore.SetReplicaLastError
+INSERT INTO gitserver_repo_replicas(repo_id, shard_id, last_error, updated_at)
+SELECT id, %s, %s, now()
+FROM repo
+WHERE name = %s
+ON CONFLICT (repo_id, shard_id) DO UPDATE
+    SET (last_error, updated_at) =
+            (EXCLUDED.last_error, now())
+WHERE gitserver_repo_replicas.last_error IS DISTINCT FROM EXCLUDED.last_error
+`, shardID, ns, name))
+
+	return errors.Wrap(err, "setting replica last error")
+}
+
+// SetReplicaLastFetched is like SetLastFetched for the replica of a repo on
+// the given shard.
+func (s *GitserverRepoStore) SetReplicaLastFetched(ctx context.Context, name api.RepoName, lastFetched time.Time, shardID string) error {
+	err := s.Exec(ctx, sqlf.Sprintf(`
+-- source: internal/database/gitserver_repos.go:GitserverRepoStore.SetReplicaLastFetched
+INSERT INTO gitserver_repo_replicas(repo_id, shard_id, last_fetched, updated_at)
+SELECT id, %s, %s, now()
+FROM repo WHERE name = %s
+ON CONFLICT (repo_id, shard_id) DO UPDATE
+SET (last_fetched, updated_at) =
+    (EXCLUDED.last_fetched, now())
+`, shardID, lastFetched, name))
+
+	return errors.Wrap(err, "setting replica last fetched")
+}
+
 // sanitizeToUTF8 will remove any null character terminated string. The null character can be
 // represented in one of the following ways in Go:
 //
@@ -3,6 +3,7 @@ package database
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
@@ -468,6 +469,175 @@ func TestGitserverRepoUpsert(t *testing.T) {
 	}
 }
 
+func TestGitserverRepoReplicas(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	db := dbtest.NewDB(t, "")
+	ctx := context.Background()
+
+	repo1 := &types.Repo{
+		Name:         "github.com/sourcegraph/repo1",
+		URI:          "github.com/sourcegraph/repo1",
+		ExternalRepo: api.ExternalRepoSpec{},
+	}
+	repo2 := &types.Repo{
+		Name:         "github.com/sourcegraph/repo2",
+		URI:          "github.com/sourcegraph/repo2",
+		ExternalRepo: api.ExternalRepoSpec{},
+	}
+
+	// Create two test repos
+	err := Repos(db).Create(ctx, repo1, repo2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The replicas are tracked separately from the gitserver the repo lives on
+	gitserverRepo := &types.GitserverRepo{
+		RepoID:      repo1.ID,
+		ShardID:     "gitserver1",
+		CloneStatus: types.CloneStatusCloned,
+	}
+	if err := GitserverRepos(db).Upsert(ctx, gitserverRepo); err != nil {
+		t.Fatal(err)
+	}
+
+	replicas := []*types.GitserverRepoReplica{
+		{
+			RepoID:      repo1.ID,
+			ShardID:     "gitserver2",
+			CloneStatus: types.CloneStatusCloning,
+		},
+		{
+			RepoID:      repo1.ID,
+			ShardID:     "gitserver3",
+			CloneStatus: types.CloneStatusNotCloned,
+			LastError:   "oops",
+		},
+		{
+			RepoID:      repo2.ID,
+			ShardID:     "gitserver2",
+			CloneStatus: types.CloneStatusCloned,
+		},
+	}
+	if err := GitserverRepos(db).UpsertReplicas(ctx, replicas...); err != nil {
+		t.Fatal(err)
+	}
+
+	fromDB, err := GitserverRepos(db).GetReplicasByID(ctx, repo1.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(replicas[:2], fromDB, cmpopts.IgnoreFields(types.GitserverRepoReplica{}, "UpdatedAt", "LastFetched")); diff != "" {
+		t.Fatal(diff)
+	}
+
+	gr, err := GitserverRepos(db).GetByID(ctx, repo1.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(gitserverRepo, gr, cmpopts.IgnoreFields(types.GitserverRepo{}, "UpdatedAt", "LastFetched")); diff != "" {
+		t.Fatal(diff)
+	}
+
+	// Update the replica on gitserver2
+	if err := GitserverRepos(db).SetReplicaCloneStatus(ctx, repo1.Name, types.CloneStatusCloned, "gitserver2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := GitserverRepos(db).SetReplicaLastError(ctx, repo1.Name, "fetch failed", "gitserver2"); err != nil {
+		t.Fatal(err)
+	}
+	lastFetched := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := GitserverRepos(db).SetReplicaLastFetched(ctx, repo1.Name, lastFetched, "gitserver2"); err != nil {
+		t.Fatal(err)
+	}
+
+	fromDB, err = GitserverRepos(db).GetReplicasByID(ctx, repo1.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replicas[0].CloneStatus = types.CloneStatusCloned
+	replicas[0].LastError = "fetch failed"
+	if diff := cmp.Diff(replicas[:2], fromDB, cmpopts.IgnoreFields(types.GitserverRepoReplica{}, "UpdatedAt", "LastFetched")); diff != "" {
+		t.Fatal(diff)
+	}
+	if !fromDB[0].LastFetched.Equal(lastFetched) {
+		t.Fatalf("Want last fetched %v, got %v", lastFetched, fromDB[0].LastFetched)
+	}
+
+	// Upserting only updates the clone status of existing replicas
+	if err := GitserverRepos(db).UpsertReplicas(ctx, &types.GitserverRepoReplica{
+		RepoID:      repo1.ID,
+		ShardID:     "gitserver2",
+		CloneStatus: types.CloneStatusNotCloned,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	fromDB, err = GitserverRepos(db).GetReplicasByID(ctx, repo1.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replicas[0].CloneStatus = types.CloneStatusNotCloned
+	if diff := cmp.Diff(replicas[:2], fromDB, cmpopts.IgnoreFields(types.GitserverRepoReplica{}, "UpdatedAt", "LastFetched")); diff != "" {
+		t.Fatal(diff)
+	}
+	if !fromDB[0].LastFetched.Equal(lastFetched) {
+		t.Fatalf("Want last fetched %v, got %v", lastFetched, fromDB[0].LastFetched)
+	}
+
+	// Setting the status of a replica should work even if no row exists
+	if err := GitserverRepos(db).SetReplicaCloneStatus(ctx, repo2.Name, types.CloneStatusCloning, "gitserver3"); err != nil {
+		t.Fatal(err)
+	}
+
+	ids, err := GitserverRepos(db).ListReplicaRepoIDs(ctx, "gitserver2", 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]api.RepoID{repo1.ID, repo2.ID}, ids); diff != "" {
+		t.Fatal(diff)
+	}
+	ids, err = GitserverRepos(db).ListReplicaRepoIDs(ctx, "gitserver2", repo1.ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]api.RepoID{repo2.ID}, ids); diff != "" {
+		t.Fatal(diff)
+	}
+
+	// Forget about the replica of repo1 on gitserver2
+	if err := GitserverRepos(db).DeleteReplicas(ctx, "gitserver2", repo1.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	fromDB, err = GitserverRepos(db).GetReplicasByID(ctx, repo1.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(replicas[1:2], fromDB, cmpopts.IgnoreFields(types.GitserverRepoReplica{}, "UpdatedAt", "LastFetched")); diff != "" {
+		t.Fatal(diff)
+	}
+
+	fromDB, err = GitserverRepos(db).GetReplicasByID(ctx, repo2.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*types.GitserverRepoReplica{
+		replicas[2],
+		{
+			RepoID:      repo2.ID,
+			ShardID:     "gitserver3",
+			CloneStatus: types.CloneStatusCloning,
+		},
+	}
+	if diff := cmp.Diff(want, fromDB, cmpopts.IgnoreFields(types.GitserverRepoReplica{}, "UpdatedAt", "LastFetched")); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
 func TestSanitizeToUTF8(t *testing.T) {
 	testSet := map[string]string{
 		"test\x00":     "test",
@@ -7,6 +7,7 @@ type MockStores struct {
 	AccessTokens MockAccessTokens
 
 	Repos           MockRepos
+	RepoKVPs        MockRepoKVPs
 	Namespaces      MockNamespaces
 	Orgs            MockOrgs
 	OrgMembers      MockOrgMembers
@@ -31,4 +32,6 @@ type MockStores struct {
 	EventLogs MockEventLogs
 
 	TemporarySettings MockTemporarySettings
+
+	CommitIndex MockCommitIndex
 }
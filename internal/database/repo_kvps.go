@@ -0,0 +1,164 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/cockroachdb/errors"
+	"github.com/keegancsmith/sqlf"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/database/basestore"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
+)
+
+// KeyValuePair is a piece of metadata attached to a repository, like
+// team=payments. The value is optional, so a pair may also be a plain tag.
+type KeyValuePair struct {
+	Key   string
+	Value *string
+}
+
+// RepoKVPStore stores the key/value metadata of repositories.
+type RepoKVPStore struct {
+	*basestore.Store
+}
+
+// RepoKVPs instantiates and returns a new RepoKVPStore with prepared statements.
+func RepoKVPs(db dbutil.DB) *RepoKVPStore {
+	return &RepoKVPStore{Store: basestore.NewWithDB(db, sql.TxOptions{})}
+}
+
+// RepoKVPNotFoundErr is returned when a repository does not have a key.
+type RepoKVPNotFoundErr struct {
+	RepoID api.RepoID
+	Key    string
+}
+
+func (e *RepoKVPNotFoundErr) Error() string {
+	return fmt.Sprintf("repo %d has no metadata key %q", e.RepoID, e.Key)
+}
+
+func (e *RepoKVPNotFoundErr) NotFound() bool {
+	return true
+}
+
+// Create adds a key/value pair to a repository. It is an error if the
+// repository already has the key.
+func (s *RepoKVPStore) Create(ctx context.Context, repoID api.RepoID, kvp KeyValuePair) error {
+	if Mocks.RepoKVPs.Create != nil {
+		return Mocks.RepoKVPs.Create(ctx, repoID, kvp)
+	}
+
+	q := `
+	INSERT INTO repo_kvps (repo_id, key, value)
+	VALUES (%s, %s, %s)
+	`
+	if err := s.Exec(ctx, sqlf.Sprintf(q, repoID, kvp.Key, kvp.Value)); err != nil {
+		if dbutil.IsPostgresError(err, "23505") {
+			return errors.Errorf("repo %d already has metadata key %q", repoID, kvp.Key)
+		}
+		return err
+	}
+	return nil
+}
+
+// Get returns the key/value pair of a repository with the given key.
+func (s *RepoKVPStore) Get(ctx context.Context, repoID api.RepoID, key string) (KeyValuePair, error) {
+	if Mocks.RepoKVPs.Get != nil {
+		return Mocks.RepoKVPs.Get(ctx, repoID, key)
+	}
+
+	q := `
+	SELECT key, value
+	FROM repo_kvps
+	WHERE repo_id = %s
+		AND key = %s
+	`
+	kvp, err := scanKVP(s.QueryRow(ctx, sqlf.Sprintf(q, repoID, key)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return KeyValuePair{}, &RepoKVPNotFoundErr{RepoID: repoID, Key: key}
+	}
+	return kvp, err
+}
+
+// List returns the key/value pairs of a repository, ordered by key.
+func (s *RepoKVPStore) List(ctx context.Context, repoID api.RepoID) ([]KeyValuePair, error) {
+	if Mocks.RepoKVPs.List != nil {
+		return Mocks.RepoKVPs.List(ctx, repoID)
+	}
+
+	q := `
+	SELECT key, value
+	FROM repo_kvps
+	WHERE repo_id = %s
+	ORDER BY key
+	`
+	rows, err := s.Query(ctx, sqlf.Sprintf(q, repoID))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var kvps []KeyValuePair
+	for rows.Next() {
+		kvp, err := scanKVP(rows)
+		if err != nil {
+			return nil, err
+		}
+		kvps = append(kvps, kvp)
+	}
+	return kvps, rows.Err()
+}
+
+// Update sets the value of a key of a repository and returns the updated
+// pair.
+func (s *RepoKVPStore) Update(ctx context.Context, repoID api.RepoID, kvp KeyValuePair) (KeyValuePair, error) {
+	if Mocks.RepoKVPs.Update != nil {
+		return Mocks.RepoKVPs.Update(ctx, repoID, kvp)
+	}
+
+	q := `
+	UPDATE repo_kvps
+	SET value = %s
+	WHERE repo_id = %s
+		AND key = %s
+	RETURNING key, value
+	`
+	updated, err := scanKVP(s.QueryRow(ctx, sqlf.Sprintf(q, kvp.Value, repoID, kvp.Key)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return KeyValuePair{}, &RepoKVPNotFoundErr{RepoID: repoID, Key: kvp.Key}
+	}
+	return updated, err
+}
+
+// Delete removes a key from a repository.
+func (s *RepoKVPStore) Delete(ctx context.Context, repoID api.RepoID, key string) error {
+	if Mocks.RepoKVPs.Delete != nil {
+		return Mocks.RepoKVPs.Delete(ctx, repoID, key)
+	}
+
+	q := `
+	DELETE FROM repo_kvps
+	WHERE repo_id = %s
+		AND key = %s
+	`
+	res, err := s.ExecResult(ctx, sqlf.Sprintf(q, repoID, key))
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return &RepoKVPNotFoundErr{RepoID: repoID, Key: key}
+	}
+	return nil
+}
+
+func scanKVP(sc dbutil.Scanner) (KeyValuePair, error) {
+	var kvp KeyValuePair
+	return kvp, sc.Scan(&kvp.Key, &kvp.Value)
+}
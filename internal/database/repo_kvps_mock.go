@@ -0,0 +1,15 @@
+package database
+
+import (
+	"context"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+)
+
+type MockRepoKVPs struct {
+	Create func(ctx context.Context, repoID api.RepoID, kvp KeyValuePair) error
+	Get    func(ctx context.Context, repoID api.RepoID, key string) (KeyValuePair, error)
+	List   func(ctx context.Context, repoID api.RepoID) ([]KeyValuePair, error)
+	Update func(ctx context.Context, repoID api.RepoID, kvp KeyValuePair) (KeyValuePair, error)
+	Delete func(ctx context.Context, repoID api.RepoID, key string) error
+}
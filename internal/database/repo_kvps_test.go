@@ -0,0 +1,73 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sourcegraph/sourcegraph/internal/actor"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbtest"
+	"github.com/sourcegraph/sourcegraph/internal/errcode"
+	"github.com/sourcegraph/sourcegraph/internal/types"
+)
+
+func TestRepoKVPs(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	t.Parallel()
+	db := dbtest.NewDB(t, "")
+	ctx := actor.WithInternalActor(context.Background())
+	kvps := RepoKVPs(db)
+
+	repo := mustCreate(ctx, t, db, &types.Repo{Name: "a/r"}, types.CloneStatusNotCloned)[0]
+	strPtr := func(s string) *string { return &s }
+
+	t.Run("Create", func(t *testing.T) {
+		require.NoError(t, kvps.Create(ctx, repo.ID, KeyValuePair{Key: "team", Value: strPtr("payments")}))
+		require.NoError(t, kvps.Create(ctx, repo.ID, KeyValuePair{Key: "deprecated"}))
+
+		err := kvps.Create(ctx, repo.ID, KeyValuePair{Key: "team", Value: strPtr("search")})
+		require.Error(t, err)
+	})
+
+	t.Run("Get", func(t *testing.T) {
+		kvp, err := kvps.Get(ctx, repo.ID, "team")
+		require.NoError(t, err)
+		require.Equal(t, KeyValuePair{Key: "team", Value: strPtr("payments")}, kvp)
+
+		_, err = kvps.Get(ctx, repo.ID, "tier")
+		require.True(t, errcode.IsNotFound(err))
+	})
+
+	t.Run("List", func(t *testing.T) {
+		got, err := kvps.List(ctx, repo.ID)
+		require.NoError(t, err)
+		require.Equal(t, []KeyValuePair{
+			{Key: "deprecated"},
+			{Key: "team", Value: strPtr("payments")},
+		}, got)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		kvp, err := kvps.Update(ctx, repo.ID, KeyValuePair{Key: "team", Value: strPtr("search")})
+		require.NoError(t, err)
+		require.Equal(t, KeyValuePair{Key: "team", Value: strPtr("search")}, kvp)
+
+		_, err = kvps.Update(ctx, repo.ID, KeyValuePair{Key: "tier", Value: strPtr("1")})
+		var notFound *RepoKVPNotFoundErr
+		require.True(t, errors.As(err, &notFound))
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		require.NoError(t, kvps.Delete(ctx, repo.ID, "team"))
+		require.True(t, errcode.IsNotFound(kvps.Delete(ctx, repo.ID, "team")))
+
+		got, err := kvps.List(ctx, repo.ID)
+		require.NoError(t, err)
+		require.Equal(t, []KeyValuePair{{Key: "deprecated"}}, got)
+	})
+}
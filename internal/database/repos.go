@@ -35,9 +35,12 @@ import (
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/github"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitlab"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitolite"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/gomodules"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/jvmpackages"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/npmpackages"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/perforce"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/phabricator"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/pythonpackages"
 	"github.com/sourcegraph/sourcegraph/internal/trace"
 	"github.com/sourcegraph/sourcegraph/internal/types"
 )
@@ -477,6 +480,12 @@ func scanRepo(rows *sql.Rows, r *types.Repo) (err error) {
 		r.Metadata = new(extsvc.OtherRepoMetadata)
 	case extsvc.TypeJVMPackages:
 		r.Metadata = new(jvmpackages.Metadata)
+	case extsvc.TypeNpmPackages:
+		r.Metadata = new(npmpackages.Metadata)
+	case extsvc.TypeGoModules:
+		r.Metadata = new(gomodules.Metadata)
+	case extsvc.TypePythonPackages:
+		r.Metadata = new(pythonpackages.Metadata)
 	default:
 		log15.Warn("scanRepo - unknown service type", "typ", typ)
 		return nil
@@ -552,6 +561,10 @@ type ReposListOptions struct {
 	// the query are strings which are regular expression patterns.
 	PatternQuery query.Q
 
+	// KVPFilters filters repositories by their key/value metadata. Every
+	// filter must match a key/value pair of the repository.
+	KVPFilters []RepoKVPFilter
+
 	// NoForks excludes forks from the list.
 	NoForks bool
 
@@ -620,6 +633,13 @@ type ReposListOptions struct {
 	*LimitOffset
 }
 
+// RepoKVPFilter matches repositories with a key/value pair. A nil Value
+// matches any value of the key.
+type RepoKVPFilter struct {
+	Key   string
+	Value *string
+}
+
 type RepoListOrderBy []RepoListSort
 
 func (r RepoListOrderBy) SQL() *sqlf.Query {
@@ -877,6 +897,14 @@ func (s *RepoStore) listSQL(ctx context.Context, opt ReposListOptions) (*sqlf.Qu
 		where = append(where, sqlf.Sprintf("(%s)", sqlf.Join(er, "\n AND ")))
 	}
 
+	for _, filter := range opt.KVPFilters {
+		if filter.Value != nil {
+			where = append(where, sqlf.Sprintf("EXISTS (SELECT 1 FROM repo_kvps WHERE repo_id = repo.id AND key = %s AND value = %s)", filter.Key, *filter.Value))
+		} else {
+			where = append(where, sqlf.Sprintf("EXISTS (SELECT 1 FROM repo_kvps WHERE repo_id = repo.id AND key = %s)", filter.Key))
+		}
+	}
+
 	if opt.NoForks {
 		where = append(where, sqlf.Sprintf("NOT fork"))
 	}
@@ -490,6 +490,57 @@ func TestRepos_List_fork(t *testing.T) {
 	}
 }
 
+func TestRepos_List_kvps(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	t.Parallel()
+	db := dbtest.NewDB(t, "")
+	ctx := actor.WithInternalActor(context.Background())
+
+	payments := mustCreate(ctx, t, db, &types.Repo{Name: "a/payments"}, types.CloneStatusNotCloned)
+	search := mustCreate(ctx, t, db, &types.Repo{Name: "b/search"}, types.CloneStatusNotCloned)
+	mustCreate(ctx, t, db, &types.Repo{Name: "c/untagged"}, types.CloneStatusNotCloned)
+
+	strPtr := func(s string) *string { return &s }
+	for _, kvp := range []struct {
+		repo *types.Repo
+		KeyValuePair
+	}{
+		{payments[0], KeyValuePair{Key: "team", Value: strPtr("payments")}},
+		{payments[0], KeyValuePair{Key: "tier", Value: strPtr("1")}},
+		{search[0], KeyValuePair{Key: "team", Value: strPtr("search")}},
+		{search[0], KeyValuePair{Key: "deprecated"}},
+	} {
+		if err := RepoKVPs(db).Create(ctx, kvp.repo.ID, kvp.KeyValuePair); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		opt  ReposListOptions
+		want []*types.Repo
+	}{
+		{"key and value", ReposListOptions{KVPFilters: []RepoKVPFilter{{Key: "team", Value: strPtr("payments")}}}, payments},
+		{"any value", ReposListOptions{KVPFilters: []RepoKVPFilter{{Key: "team"}}}, append(append([]*types.Repo(nil), payments...), search...)},
+		{"key without value", ReposListOptions{KVPFilters: []RepoKVPFilter{{Key: "deprecated"}}}, search},
+		{"all filters must match", ReposListOptions{KVPFilters: []RepoKVPFilter{{Key: "team"}, {Key: "tier", Value: strPtr("1")}}}, payments},
+		{"no match", ReposListOptions{KVPFilters: []RepoKVPFilter{{Key: "team", Value: strPtr("billing")}}}, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repos, err := Repos(db).List(ctx, test.opt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertJSONEqual(t, test.want, repos)
+		})
+	}
+}
+
 func TestRepos_List_FailedSync(t *testing.T) {
 	if testing.Short() {
 		t.Skip()
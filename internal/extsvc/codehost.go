@@ -24,10 +24,22 @@ var (
 	MavenURL    = &url.URL{Host: "maven"}
 	JVMPackages = NewCodeHost(MavenURL, TypeJVMPackages)
 
+	NpmURL      = &url.URL{Host: "npm"}
+	NpmPackages = NewCodeHost(NpmURL, TypeNpmPackages)
+
+	GoModulesURL = &url.URL{Host: "go"}
+	GoModules    = NewCodeHost(GoModulesURL, TypeGoModules)
+
+	PythonURL      = &url.URL{Host: "python"}
+	PythonPackages = NewCodeHost(PythonURL, TypePythonPackages)
+
 	PublicCodeHosts = []*CodeHost{
 		GitHubDotCom,
 		GitLabDotCom,
 		JVMPackages,
+		NpmPackages,
+		GoModules,
+		PythonPackages,
 	}
 )
 
@@ -0,0 +1,179 @@
+// Package proxy is a client for Go module proxies implementing the GOPROXY
+// protocol, such as https://proxy.golang.org or a self-hosted Athens. See
+// https://golang.org/ref/mod#goproxy-protocol.
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+	"golang.org/x/time/rate"
+
+	"github.com/sourcegraph/sourcegraph/internal/conf/reposource"
+	"github.com/sourcegraph/sourcegraph/internal/httpcli"
+	"github.com/sourcegraph/sourcegraph/internal/ratelimit"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+// Client fetches module versions and zips from a list of Go module proxies.
+type Client struct {
+	urls    []*url.URL
+	cli     httpcli.Doer
+	limiter *rate.Limiter
+}
+
+// NewClient returns a client for the proxies of the given connection, which
+// sends its requests with cli.
+func NewClient(config *schema.GoModulesConnection, cli httpcli.Doer) (*Client, error) {
+	if len(config.Urls) == 0 {
+		return nil, errors.New("no Go module proxy URLs configured")
+	}
+	urls := make([]*url.URL, 0, len(config.Urls))
+	for _, rawURL := range config.Urls {
+		u, err := url.Parse(strings.TrimSuffix(rawURL, "/"))
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid Go module proxy URL %q", rawURL)
+		}
+		urls = append(urls, u)
+	}
+	return &Client{
+		urls: urls,
+		cli:  cli,
+		// Like in extsvc.GetLimitFromConfig, all proxies share the rate
+		// limit of the first one.
+		limiter: ratelimit.DefaultRegistry.Get(config.Urls[0]),
+	}, nil
+}
+
+// IsNotFound reports whether err is returned for a module or module version
+// that none of the proxies have.
+func IsNotFound(err error) bool {
+	var e *notFoundError
+	return errors.As(err, &e)
+}
+
+type notFoundError struct {
+	what string
+}
+
+func (e *notFoundError) Error() string {
+	return fmt.Sprintf("Go module not found: %s", e.what)
+}
+
+// ListVersions returns the versions of the given module known to the first
+// proxy which has the module, in no particular order. Pseudo-versions are not
+// listed.
+func (c *Client) ListVersions(ctx context.Context, mod reposource.GoModule) ([]string, error) {
+	resp, err := c.get(ctx, mod.Path, "list", mod.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading versions of %s", mod.Path)
+	}
+
+	var versions []string
+	for _, line := range strings.Split(string(body), "\n") {
+		// Some proxies append more fields to each line, the version is
+		// always the first one.
+		fields := strings.Fields(line)
+		if len(fields) == 0 || !semver.IsValid(fields[0]) {
+			continue
+		}
+		versions = append(versions, fields[0])
+	}
+	return versions, nil
+}
+
+// Exists reports whether a proxy has the given module version.
+func (c *Client) Exists(ctx context.Context, dependency reposource.GoDependency) (bool, error) {
+	escapedVersion, err := module.EscapeVersion(dependency.Version)
+	if err != nil {
+		return false, err
+	}
+	resp, err := c.get(ctx, dependency.Path, escapedVersion+".info", dependency.PackageManagerSyntax())
+	if IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+	return true, nil
+}
+
+// FetchZip returns the zip of the given module version. Callers must close
+// it.
+func (c *Client) FetchZip(ctx context.Context, dependency reposource.GoDependency) (io.ReadCloser, error) {
+	escapedVersion, err := module.EscapeVersion(dependency.Version)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.get(ctx, dependency.Path, escapedVersion+".zip", dependency.PackageManagerSyntax())
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// get requests the given file of the "@v" directory of modulePath from each
+// proxy in turn, until one of them doesn't respond with 404 or 410 like the
+// go command does. what describes the request in errors.
+func (c *Client) get(ctx context.Context, modulePath, file, what string) (*http.Response, error) {
+	escapedPath, err := module.EscapePath(modulePath)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, proxyURL := range c.urls {
+		// The escaped module path only contains characters which are valid
+		// in URL paths, so that it can be used as is.
+		u := *proxyURL
+		u.Path = proxyURL.Path + "/" + escapedPath + "/@v/" + file
+		u.RawPath = proxyURL.EscapedPath() + "/" + escapedPath + "/@v/" + file
+		req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasSuffix(file, ".zip") {
+			// Zips can be large, don't keep them in the HTTP cache.
+			req.Header.Set("Cache-Control", "no-store")
+		}
+
+		resp, err := c.do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		switch resp.StatusCode {
+		case http.StatusOK:
+			return resp, nil
+		case http.StatusNotFound, http.StatusGone:
+			resp.Body.Close()
+			continue
+		default:
+			body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+			resp.Body.Close()
+			return nil, errors.Errorf("unexpected status %d fetching %s from Go module proxy %s: %s", resp.StatusCode, what, proxyURL.Redacted(), body)
+		}
+	}
+
+	return nil, &notFoundError{what: what}
+}
+
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	if err := c.limiter.Wait(req.Context()); err != nil {
+		return nil, err
+	}
+	return c.cli.Do(req)
+}
@@ -0,0 +1,96 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/internal/conf/reposource"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+func TestClient(t *testing.T) {
+	// The first proxy only has golang.org/x/mod, so that requests for other
+	// modules fall back to the second one.
+	first := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.EscapedPath() {
+		case "/golang.org/x/mod/@v/list":
+			w.Write([]byte("v0.4.2\nv0.5.0\n"))
+		case "/golang.org/x/mod/@v/v0.4.2.info":
+			w.Write([]byte(`{"Version":"v0.4.2"}`))
+		case "/golang.org/x/mod/@v/v0.4.2.zip":
+			w.Write([]byte("zip"))
+		default:
+			http.Error(w, "gone", http.StatusGone)
+		}
+	}))
+	defer first.Close()
+	second := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.EscapedPath() {
+		// Upper case letters are escaped as "!" followed by the lower case
+		// letter.
+		case "/proxy/github.com/!azure/go-autorest/@v/list":
+			w.Write([]byte("v14.2.0+incompatible 2020-06-03T00:00:00Z\nnot-a-version\n"))
+		case "/proxy/github.com/!azure/go-autorest/@v/v14.2.0+incompatible.info":
+			w.Write([]byte(`{"Version":"v14.2.0+incompatible"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer second.Close()
+
+	client, err := NewClient(&schema.GoModulesConnection{Urls: []string{first.URL, second.URL + "/proxy/"}}, http.DefaultClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	mod := reposource.GoModule{Path: "golang.org/x/mod"}
+	versions, err := client.ListVersions(ctx, mod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"v0.4.2", "v0.5.0"}; !reflect.DeepEqual(versions, want) {
+		t.Fatalf("ListVersions(%s) = %v, want %v", mod.Path, versions, want)
+	}
+
+	dependency := reposource.GoDependency{GoModule: mod, Version: "v0.4.2"}
+	if exists, err := client.Exists(ctx, dependency); err != nil || !exists {
+		t.Fatalf("Exists(%s) = %v, %v, want true", dependency.PackageManagerSyntax(), exists, err)
+	}
+	zip, err := client.FetchZip(ctx, dependency)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zip.Close()
+	if content, err := io.ReadAll(zip); err != nil || string(content) != "zip" {
+		t.Fatalf("got zip %q, %v", content, err)
+	}
+
+	autorest := reposource.GoModule{Path: "github.com/Azure/go-autorest"}
+	versions, err = client.ListVersions(ctx, autorest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"v14.2.0+incompatible"}; !reflect.DeepEqual(versions, want) {
+		t.Fatalf("ListVersions(%s) = %v, want %v", autorest.Path, versions, want)
+	}
+	dependency = reposource.GoDependency{GoModule: autorest, Version: "v14.2.0+incompatible"}
+	if exists, err := client.Exists(ctx, dependency); err != nil || !exists {
+		t.Fatalf("Exists(%s) = %v, %v, want true", dependency.PackageManagerSyntax(), exists, err)
+	}
+
+	missing := reposource.GoDependency{GoModule: mod, Version: "v0.0.1"}
+	if exists, err := client.Exists(ctx, missing); err != nil || exists {
+		t.Fatalf("Exists(%s) = %v, %v, want false", missing.PackageManagerSyntax(), exists, err)
+	}
+	if _, err := client.FetchZip(ctx, missing); !IsNotFound(err) {
+		t.Fatalf("FetchZip(%s): got error %v, want not found", missing.PackageManagerSyntax(), err)
+	}
+	if _, err := client.ListVersions(ctx, reposource.GoModule{Path: "example.com/missing"}); !IsNotFound(err) {
+		t.Fatalf("ListVersions(example.com/missing): got error %v, want not found", err)
+	}
+}
@@ -0,0 +1,162 @@
+// Package npm is a client for npm-compatible package registries, such as
+// https://registry.npmjs.org or a self-hosted Verdaccio or Artifactory.
+package npm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"golang.org/x/time/rate"
+
+	"github.com/sourcegraph/sourcegraph/internal/conf/reposource"
+	"github.com/sourcegraph/sourcegraph/internal/httpcli"
+	"github.com/sourcegraph/sourcegraph/internal/ratelimit"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+// Client fetches package metadata and tarballs from an npm registry.
+type Client struct {
+	registryURL *url.URL
+	credentials string
+	cli         httpcli.Doer
+	limiter     *rate.Limiter
+}
+
+// NewClient returns a client for the registry of the given connection, which
+// sends its requests with cli.
+func NewClient(config *schema.NpmPackagesConnection, cli httpcli.Doer) (*Client, error) {
+	registryURL, err := url.Parse(strings.TrimSuffix(config.Registry, "/"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid npm registry URL %q", config.Registry)
+	}
+	return &Client{
+		registryURL: registryURL,
+		credentials: config.Credentials,
+		cli:         cli,
+		limiter:     ratelimit.DefaultRegistry.Get(config.Registry),
+	}, nil
+}
+
+// IsNotFound reports whether err is returned for a package version that the
+// registry doesn't have.
+func IsNotFound(err error) bool {
+	var e *notFoundError
+	return errors.As(err, &e)
+}
+
+type notFoundError struct {
+	dependency reposource.NpmDependency
+}
+
+func (e *notFoundError) Error() string {
+	return fmt.Sprintf("npm package not found: %s", e.dependency.PackageManagerSyntax())
+}
+
+// versionMetadata is the part of the metadata of a package version we use,
+// see https://github.com/npm/registry/blob/master/docs/responses/package-metadata.md.
+type versionMetadata struct {
+	Dist struct {
+		Tarball string `json:"tarball"`
+	} `json:"dist"`
+}
+
+// Exists reports whether the registry has the given package version.
+func (c *Client) Exists(ctx context.Context, dependency reposource.NpmDependency) (bool, error) {
+	_, err := c.versionMetadata(ctx, dependency)
+	if IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// FetchTarball returns the gzipped tarball of the given package version.
+// Callers must close it.
+func (c *Client) FetchTarball(ctx context.Context, dependency reposource.NpmDependency) (io.ReadCloser, error) {
+	metadata, err := c.versionMetadata(ctx, dependency)
+	if err != nil {
+		return nil, err
+	}
+	if metadata.Dist.Tarball == "" {
+		return nil, errors.Errorf("npm registry returned no tarball for %s", dependency.PackageManagerSyntax())
+	}
+	tarballURL, err := c.registryURL.Parse(metadata.Dist.Tarball)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid tarball URL for %s", dependency.PackageManagerSyntax())
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", tarballURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	// Tarballs can be large, don't keep them in the HTTP cache.
+	req.Header.Set("Cache-Control", "no-store")
+	// Only send the credentials to the registry itself, registries may
+	// serve tarballs from a CDN.
+	if tarballURL.Host == c.registryURL.Host {
+		c.authorize(req)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, errors.Errorf("unexpected status %d fetching tarball of %s", resp.StatusCode, dependency.PackageManagerSyntax())
+	}
+	return resp.Body, nil
+}
+
+func (c *Client) versionMetadata(ctx context.Context, dependency reposource.NpmDependency) (*versionMetadata, error) {
+	// Registries expect the "/" of scoped packages to be escaped.
+	name := dependency.PackageSyntax()
+	u := *c.registryURL
+	u.Path = c.registryURL.Path + "/" + name + "/" + dependency.Version
+	u.RawPath = c.registryURL.EscapedPath() + "/" + url.PathEscape(name) + "/" + url.PathEscape(dependency.Version)
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	c.authorize(req)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, &notFoundError{dependency: dependency}
+	default:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, errors.Errorf("unexpected status %d fetching %s from npm registry: %s", resp.StatusCode, dependency.PackageManagerSyntax(), body)
+	}
+
+	var metadata versionMetadata
+	if err := json.NewDecoder(resp.Body).Decode(&metadata); err != nil {
+		return nil, errors.Wrapf(err, "decoding metadata of %s", dependency.PackageManagerSyntax())
+	}
+	return &metadata, nil
+}
+
+func (c *Client) authorize(req *http.Request) {
+	if c.credentials != "" {
+		req.Header.Set("Authorization", "Bearer "+c.credentials)
+	}
+}
+
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	if err := c.limiter.Wait(req.Context()); err != nil {
+		return nil, err
+	}
+	return c.cli.Do(req)
+}
@@ -0,0 +1,65 @@
+package npm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/internal/conf/reposource"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+func TestClient(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Registries expect the "/" of scoped packages to be escaped.
+		switch r.URL.EscapedPath() {
+		case "/@types%2Fnode/16.11.7":
+			if got, want := r.Header.Get("Authorization"), "Bearer secret"; got != want {
+				t.Errorf("got Authorization %q, want %q", got, want)
+			}
+			fmt.Fprintf(w, `{"name":"@types/node","version":"16.11.7","dist":{"tarball":"%s/@types/node/-/node-16.11.7.tgz"}}`, srv.URL)
+		case "/@types/node/-/node-16.11.7.tgz":
+			w.Write([]byte("tarball"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(&schema.NpmPackagesConnection{Registry: srv.URL + "/", Credentials: "secret"}, http.DefaultClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	dependency, err := reposource.ParseNpmDependency("@types/node@16.11.7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exists, err := client.Exists(ctx, dependency); err != nil || !exists {
+		t.Fatalf("Exists(%s) = %v, %v, want true", dependency.PackageManagerSyntax(), exists, err)
+	}
+	tarball, err := client.FetchTarball(ctx, dependency)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tarball.Close()
+	if content, err := io.ReadAll(tarball); err != nil || string(content) != "tarball" {
+		t.Fatalf("got tarball %q, %v", content, err)
+	}
+
+	missing, err := reposource.ParseNpmDependency("@types/node@0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exists, err := client.Exists(ctx, missing); err != nil || exists {
+		t.Fatalf("Exists(%s) = %v, %v, want false", missing.PackageManagerSyntax(), exists, err)
+	}
+	if _, err := client.FetchTarball(ctx, missing); !IsNotFound(err) {
+		t.Fatalf("FetchTarball(%s): got error %v, want not found", missing.PackageManagerSyntax(), err)
+	}
+}
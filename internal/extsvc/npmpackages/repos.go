@@ -0,0 +1,7 @@
+package npmpackages
+
+import "github.com/sourcegraph/sourcegraph/internal/conf/reposource"
+
+type Metadata struct {
+	Package reposource.NpmPackage
+}
@@ -0,0 +1,325 @@
+// Package pypi is a client for Python package indexes implementing the simple
+// repository API of PEP 503, such as https://pypi.org/simple or a self-hosted
+// devpi. See https://www.python.org/dev/peps/pep-0503/.
+package pypi
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/time/rate"
+
+	"github.com/sourcegraph/sourcegraph/internal/conf/reposource"
+	"github.com/sourcegraph/sourcegraph/internal/httpcli"
+	"github.com/sourcegraph/sourcegraph/internal/ratelimit"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+// Client fetches the files of packages from a list of Python package indexes.
+type Client struct {
+	urls    []*url.URL
+	cli     httpcli.Doer
+	limiter *rate.Limiter
+}
+
+// NewClient returns a client for the indexes of the given connection, which
+// sends its requests with cli.
+func NewClient(config *schema.PythonPackagesConnection, cli httpcli.Doer) (*Client, error) {
+	if len(config.Urls) == 0 {
+		return nil, errors.New("no Python package index URLs configured")
+	}
+	urls := make([]*url.URL, 0, len(config.Urls))
+	for _, rawURL := range config.Urls {
+		u, err := url.Parse(strings.TrimSuffix(rawURL, "/"))
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid Python package index URL %q", rawURL)
+		}
+		urls = append(urls, u)
+	}
+	return &Client{
+		urls: urls,
+		cli:  cli,
+		// Like in extsvc.GetLimitFromConfig, all indexes share the rate
+		// limit of the first one.
+		limiter: ratelimit.DefaultRegistry.Get(config.Urls[0]),
+	}, nil
+}
+
+// IsNotFound reports whether err is returned for a package or package version
+// that none of the indexes have.
+func IsNotFound(err error) bool {
+	var e *notFoundError
+	return errors.As(err, &e)
+}
+
+type notFoundError struct {
+	what string
+}
+
+func (e *notFoundError) Error() string {
+	return fmt.Sprintf("Python package not found: %s", e.what)
+}
+
+// File is a distribution file of a package, as listed on its project page.
+type File struct {
+	// Name is the file name, e.g. "numpy-1.22.0.zip".
+	Name string
+	// URL is the absolute URL of the file.
+	URL string
+	// SHA256 is the hex-encoded SHA-256 digest of the file, or empty if the
+	// index didn't provide one.
+	SHA256 string
+}
+
+// sourceDistributionSuffixes are the file name suffixes of the source
+// distribution formats we can extract. Indexes also host source
+// distributions in older formats, like .tar.xz and .tar.Z, which we skip.
+var sourceDistributionSuffixes = []string{".tar.gz", ".tgz", ".tar.bz2", ".tar", ".zip"}
+
+// IsSourceDistribution reports whether f is a tarball or zip of the sources
+// of a package version in a format we can extract, as opposed to a wheel.
+func (f File) IsSourceDistribution() bool {
+	return f.sourceDistributionSuffix() != ""
+}
+
+func (f File) sourceDistributionSuffix() string {
+	for _, suffix := range sourceDistributionSuffixes {
+		if strings.HasSuffix(f.Name, suffix) {
+			return suffix
+		}
+	}
+	return ""
+}
+
+// IsWheel reports whether f is a wheel, see
+// https://www.python.org/dev/peps/pep-0427/.
+func (f File) IsWheel() bool {
+	return strings.HasSuffix(f.Name, ".whl")
+}
+
+// Project returns the files listed on the project page of the given package
+// by the first index which has the package.
+func (c *Client) Project(ctx context.Context, pkg reposource.PythonPackage) ([]File, error) {
+	for _, indexURL := range c.urls {
+		// Indexes redirect to the URL with a trailing slash otherwise.
+		u := *indexURL
+		u.Path = indexURL.Path + "/" + pkg.Name + "/"
+		u.RawPath = ""
+		req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "text/html")
+
+		resp, err := c.do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		switch resp.StatusCode {
+		case http.StatusOK:
+		case http.StatusNotFound:
+			resp.Body.Close()
+			continue
+		default:
+			body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+			resp.Body.Close()
+			return nil, errors.Errorf("unexpected status %d fetching %s from Python package index %s: %s", resp.StatusCode, pkg.Name, indexURL.Redacted(), body)
+		}
+
+		// Links are relative to the page after redirects.
+		files, err := parseProjectPage(resp.Body, resp.Request.URL)
+		resp.Body.Close()
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing project page of %s", pkg.Name)
+		}
+		return files, nil
+	}
+
+	return nil, &notFoundError{what: pkg.Name}
+}
+
+// Version returns the file of the given package version to mirror, which is
+// the source distribution if there is one, otherwise a wheel.
+func (c *Client) Version(ctx context.Context, dependency reposource.PythonDependency) (File, error) {
+	files, err := c.Project(ctx, dependency.PythonPackage)
+	if err != nil {
+		return File{}, err
+	}
+
+	var wheel *File
+	for i, f := range files {
+		if !isFileOfVersion(f, dependency) {
+			continue
+		}
+		if f.IsSourceDistribution() {
+			return f, nil
+		}
+		if f.IsWheel() && wheel == nil {
+			wheel = &files[i]
+		}
+	}
+	if wheel != nil {
+		return *wheel, nil
+	}
+	return File{}, &notFoundError{what: dependency.PackageManagerSyntax()}
+}
+
+// Exists reports whether an index has a file of the given package version.
+func (c *Client) Exists(ctx context.Context, dependency reposource.PythonDependency) (bool, error) {
+	_, err := c.Version(ctx, dependency)
+	if IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Download returns the contents of the given file. When the index provided
+// the digest of the file, reading it fails at the end on a mismatch. Callers
+// must close it.
+func (c *Client) Download(ctx context.Context, f File) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", f.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	// Distribution files can be large, don't keep them in the HTTP cache.
+	req.Header.Set("Cache-Control", "no-store")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, errors.Errorf("unexpected status %d downloading %s", resp.StatusCode, f.Name)
+	}
+	if f.SHA256 == "" {
+		return resp.Body, nil
+	}
+	return &verifyingReader{ReadCloser: resp.Body, hash: sha256.New(), want: f.SHA256, name: f.Name}, nil
+}
+
+// verifyingReader returns an error at EOF if the SHA-256 digest of the data
+// read doesn't match want.
+type verifyingReader struct {
+	io.ReadCloser
+	hash hash.Hash
+	want string
+	name string
+}
+
+func (r *verifyingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.hash.Write(p[:n])
+	if err == io.EOF {
+		if got := hex.EncodeToString(r.hash.Sum(nil)); got != r.want {
+			return n, errors.Errorf("SHA-256 digest of %s is %s, index lists %s", r.name, got, r.want)
+		}
+	}
+	return n, err
+}
+
+// parseProjectPage returns the files linked from a project page of the simple
+// repository API. Each anchor links to a file, with the file name as text and
+// optionally the digest of the file in the URL fragment.
+func parseProjectPage(r io.Reader, pageURL *url.URL) ([]File, error) {
+	var files []File
+	tokenizer := html.NewTokenizer(r)
+	var href string
+	inAnchor := false
+	var text strings.Builder
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			if err := tokenizer.Err(); err != io.EOF {
+				return nil, err
+			}
+			return files, nil
+
+		case html.StartTagToken:
+			token := tokenizer.Token()
+			if token.DataAtom != atom.A {
+				continue
+			}
+			inAnchor = true
+			href = ""
+			text.Reset()
+			for _, attr := range token.Attr {
+				if attr.Key == "href" {
+					href = attr.Val
+				}
+			}
+
+		case html.TextToken:
+			if inAnchor {
+				text.Write(tokenizer.Text())
+			}
+
+		case html.EndTagToken:
+			token := tokenizer.Token()
+			if token.DataAtom != atom.A || !inAnchor {
+				continue
+			}
+			inAnchor = false
+			if href == "" {
+				continue
+			}
+			fileURL, err := pageURL.Parse(href)
+			if err != nil {
+				continue
+			}
+			f := File{Name: strings.TrimSpace(text.String())}
+			if digest := strings.TrimPrefix(fileURL.Fragment, "sha256="); digest != fileURL.Fragment {
+				f.SHA256 = strings.ToLower(digest)
+			}
+			fileURL.Fragment = ""
+			f.URL = fileURL.String()
+			files = append(files, f)
+		}
+	}
+}
+
+// isFileOfVersion reports whether the name of f is the name of a
+// distribution file of the given package version.
+func isFileOfVersion(f File, dependency reposource.PythonDependency) bool {
+	var base string
+	switch {
+	case f.IsWheel():
+		// Wheels are named {distribution}-{version}(-{build tag})?-{python
+		// tag}-{abi tag}-{platform tag}.whl.
+		parts := strings.Split(strings.TrimSuffix(f.Name, ".whl"), "-")
+		if len(parts) < 5 {
+			return false
+		}
+		base = parts[0] + "-" + parts[1]
+	case f.IsSourceDistribution():
+		base = strings.TrimSuffix(f.Name, f.sourceDistributionSuffix())
+	default:
+		return false
+	}
+
+	// The name of a source distribution may contain "-" itself, so that we
+	// split it from the version we're looking for.
+	suffix := "-" + dependency.Version
+	if !strings.HasSuffix(base, suffix) {
+		return false
+	}
+	return reposource.NormalizePythonPackageName(strings.TrimSuffix(base, suffix)) == dependency.Name
+}
+
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	if err := c.limiter.Wait(req.Context()); err != nil {
+		return nil, err
+	}
+	return c.cli.Do(req)
+}
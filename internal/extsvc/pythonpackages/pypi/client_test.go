@@ -0,0 +1,127 @@
+package pypi
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/internal/conf/reposource"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+func TestClient(t *testing.T) {
+	sdist := []byte("sdist")
+	digest := sha256.Sum256(sdist)
+
+	// The first index doesn't have any packages, so that requests fall back
+	// to the second one.
+	first := httptest.NewServer(http.NotFoundHandler())
+	defer first.Close()
+	second := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/simple/python-dateutil/":
+			fmt.Fprintf(w, `<!DOCTYPE html>
+<html><body>
+<a href="../../files/python_dateutil-2.8.2-py2.py3-none-any.whl#sha256=0000">python_dateutil-2.8.2-py2.py3-none-any.whl</a><br/>
+<a href="../../files/python-dateutil-2.8.2.tar.gz#sha256=%s">python-dateutil-2.8.2.tar.gz</a><br/>
+<a href="../../files/python-dateutil-2.8.1.tar.gz#sha256=0000">python-dateutil-2.8.1.tar.gz</a><br/>
+<a href="../../files/python_dateutil-2.7.0-py2.py3-none-any.whl">python_dateutil-2.7.0-py2.py3-none-any.whl</a><br/>
+</body></html>`, hex.EncodeToString(digest[:]))
+		case "/files/python-dateutil-2.8.2.tar.gz", "/files/python-dateutil-2.8.1.tar.gz":
+			w.Write(sdist)
+		case "/files/python_dateutil-2.7.0-py2.py3-none-any.whl":
+			w.Write([]byte("wheel"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer second.Close()
+
+	client, err := NewClient(&schema.PythonPackagesConnection{Urls: []string{first.URL, second.URL + "/simple/"}}, http.DefaultClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	parse := func(dependency string) reposource.PythonDependency {
+		d, err := reposource.ParsePythonDependency(dependency)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	download := func(f File) (string, error) {
+		t.Helper()
+		body, err := client.Download(ctx, f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer body.Close()
+		content, err := io.ReadAll(body)
+		return string(content), err
+	}
+
+	// The source distribution is preferred over the wheel.
+	f, err := client.Version(ctx, parse("python-dateutil==2.8.2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "python-dateutil-2.8.2.tar.gz"; f.Name != want || !f.IsSourceDistribution() {
+		t.Fatalf("got file %+v, want %s", f, want)
+	}
+	if content, err := download(f); err != nil || content != "sdist" {
+		t.Fatalf("got content %q, %v", content, err)
+	}
+
+	// Without a source distribution, the wheel is used.
+	f, err = client.Version(ctx, parse("Python_Dateutil==2.7.0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !f.IsWheel() || f.SHA256 != "" {
+		t.Fatalf("got file %+v, want a wheel without digest", f)
+	}
+	if content, err := download(f); err != nil || content != "wheel" {
+		t.Fatalf("got content %q, %v", content, err)
+	}
+
+	f, err = client.Version(ctx, parse("python-dateutil==2.8.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := download(f); err == nil {
+		t.Fatalf("expected a digest mismatch downloading %s", f.Name)
+	}
+
+	if exists, err := client.Exists(ctx, parse("python-dateutil==1.0.0")); err != nil || exists {
+		t.Fatalf("Exists(python-dateutil==1.0.0) = %v, %v, want false", exists, err)
+	}
+	if exists, err := client.Exists(ctx, parse("numpy==1.22.0")); err != nil || exists {
+		t.Fatalf("Exists(numpy==1.22.0) = %v, %v, want false", exists, err)
+	}
+}
+
+func TestFile_IsSourceDistribution(t *testing.T) {
+	for name, want := range map[string]bool{
+		"numpy-1.22.0.tar.gz":                  true,
+		"numpy-1.22.0.tgz":                     true,
+		"numpy-1.22.0.tar.bz2":                 true,
+		"numpy-1.22.0.tar":                     true,
+		"numpy-1.22.0.zip":                     true,
+		"numpy-1.22.0.tar.xz":                  false,
+		"numpy-1.22.0.tar.Z":                   false,
+		"numpy-1.22.0-cp310-none-any.whl":      false,
+		"numpy-1.22.0.win-amd64-py2.7.exe":     false,
+		"numpy-1.22.0-py2.7.egg":               false,
+		"numpy-1.22.0.linux-x86_64.tar.gz.sig": false,
+	} {
+		if got := (File{Name: name}).IsSourceDistribution(); got != want {
+			t.Errorf("IsSourceDistribution(%q) = %t, want %t", name, got, want)
+		}
+	}
+}
@@ -83,6 +83,9 @@ const (
 	KindPerforce        = "PERFORCE"
 	KindPhabricator     = "PHABRICATOR"
 	KindJVMPackages     = "JVMPACKAGES"
+	KindNpmPackages     = "NPMPACKAGES"
+	KindGoModules       = "GOMODULES"
+	KindPythonPackages  = "PYTHONPACKAGES"
 	KindOther           = "OTHER"
 )
 
@@ -122,6 +125,15 @@ const (
 	// TypeJVMPackages is the (api.ExternalRepoSpec).ServiceType value for Maven packages (Java/JVM ecosystem libraries).
 	TypeJVMPackages = "jvmPackages"
 
+	// TypeNpmPackages is the (api.ExternalRepoSpec).ServiceType value for npm packages (JavaScript/TypeScript ecosystem libraries).
+	TypeNpmPackages = "npmPackages"
+
+	// TypeGoModules is the (api.ExternalRepoSpec).ServiceType value for Go modules served by a module proxy.
+	TypeGoModules = "goModules"
+
+	// TypePythonPackages is the (api.ExternalRepoSpec).ServiceType value for Python packages served by a PyPI-compatible simple index.
+	TypePythonPackages = "pythonPackages"
+
 	// TypeOther is the (api.ExternalRepoSpec).ServiceType value for other projects.
 	TypeOther = "other"
 
@@ -150,6 +162,12 @@ func KindToType(kind string) string {
 		return TypePerforce
 	case KindJVMPackages:
 		return TypeJVMPackages
+	case KindNpmPackages:
+		return TypeNpmPackages
+	case KindGoModules:
+		return TypeGoModules
+	case KindPythonPackages:
+		return TypePythonPackages
 	case KindOther:
 		return TypeOther
 	default:
@@ -179,6 +197,12 @@ func TypeToKind(t string) string {
 		return KindPhabricator
 	case TypeJVMPackages:
 		return KindJVMPackages
+	case TypeNpmPackages:
+		return KindNpmPackages
+	case TypeGoModules:
+		return KindGoModules
+	case TypePythonPackages:
+		return KindPythonPackages
 	case TypeOther:
 		return KindOther
 	default:
@@ -191,6 +215,9 @@ var (
 	bbsLower = strings.ToLower(TypeBitbucketServer)
 	bbcLower = strings.ToLower(TypeBitbucketCloud)
 	jvmLower = strings.ToLower(TypeJVMPackages)
+	npmLower = strings.ToLower(TypeNpmPackages)
+	goLower  = strings.ToLower(TypeGoModules)
+	pyLower  = strings.ToLower(TypePythonPackages)
 )
 
 // ParseServiceType will return a ServiceType constant after doing a case insensitive match on s.
@@ -215,6 +242,12 @@ func ParseServiceType(s string) (string, bool) {
 		return TypePhabricator, true
 	case jvmLower:
 		return TypeJVMPackages, true
+	case npmLower:
+		return TypeNpmPackages, true
+	case goLower:
+		return TypeGoModules, true
+	case pyLower:
+		return TypePythonPackages, true
 	case TypeOther:
 		return TypeOther, true
 	default:
@@ -244,6 +277,12 @@ func ParseServiceKind(s string) (string, bool) {
 		return KindPhabricator, true
 	case KindJVMPackages:
 		return KindJVMPackages, true
+	case KindNpmPackages:
+		return KindNpmPackages, true
+	case KindGoModules:
+		return KindGoModules, true
+	case KindPythonPackages:
+		return KindPythonPackages, true
 	case KindOther:
 		return KindOther, true
 	default:
@@ -285,6 +324,12 @@ func ParseConfig(kind, config string) (cfg interface{}, _ error) {
 		cfg = &schema.PhabricatorConnection{}
 	case KindJVMPackages:
 		cfg = &schema.JVMPackagesConnection{}
+	case KindNpmPackages:
+		cfg = &schema.NpmPackagesConnection{}
+	case KindGoModules:
+		cfg = &schema.GoModulesConnection{}
+	case KindPythonPackages:
+		cfg = &schema.PythonPackagesConnection{}
 	case KindOther:
 		cfg = &schema.OtherExternalServiceConnection{}
 	default:
@@ -410,6 +455,35 @@ func GetLimitFromConfig(kind string, config interface{}) (rlc RateLimitConfig, e
 			rlc.IsDefault = false
 		}
 		rlc.BaseURL = "maven"
+	case *schema.NpmPackagesConnection:
+		rlc.Limit = defaultRateLimit
+		if c != nil && c.RateLimit != nil {
+			rlc.Limit = limitOrInf(c.RateLimit.Enabled, c.RateLimit.RequestsPerHour)
+			rlc.IsDefault = false
+		}
+		rlc.BaseURL = c.Registry
+	case *schema.GoModulesConnection:
+		rlc.Limit = defaultRateLimit
+		if c != nil && c.RateLimit != nil {
+			rlc.Limit = limitOrInf(c.RateLimit.Enabled, c.RateLimit.RequestsPerHour)
+			rlc.IsDefault = false
+		}
+		// All proxies of a connection share the rate limit of the first
+		// one.
+		if c != nil && len(c.Urls) > 0 {
+			rlc.BaseURL = c.Urls[0]
+		}
+	case *schema.PythonPackagesConnection:
+		rlc.Limit = defaultRateLimit
+		if c != nil && c.RateLimit != nil {
+			rlc.Limit = limitOrInf(c.RateLimit.Enabled, c.RateLimit.RequestsPerHour)
+			rlc.IsDefault = false
+		}
+		// Like for Go modules, all indexes of a connection share the rate
+		// limit of the first one.
+		if c != nil && len(c.Urls) > 0 {
+			rlc.BaseURL = c.Urls[0]
+		}
 	default:
 		return rlc, ErrRateLimitUnsupported{codehostKind: kind}
 	}
@@ -507,6 +581,12 @@ func UniqueCodeHostIdentifier(kind, config string) (string, error) {
 		return c.P4Port, nil
 	case *schema.JVMPackagesConnection:
 		return KindJVMPackages, nil
+	case *schema.NpmPackagesConnection:
+		return KindNpmPackages, nil
+	case *schema.GoModulesConnection:
+		return KindGoModules, nil
+	case *schema.PythonPackagesConnection:
+		return KindPythonPackages, nil
 	default:
 		return "", errors.Errorf("unknown external service kind: %s", kind)
 	}
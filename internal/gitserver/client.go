@@ -30,6 +30,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/internal/actor"
 	"github.com/sourcegraph/sourcegraph/internal/api"
 	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/endpoint"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitolite"
 	"github.com/sourcegraph/sourcegraph/internal/gitserver/protocol"
 	"github.com/sourcegraph/sourcegraph/internal/httpcli"
@@ -50,6 +51,9 @@ func NewClient(cli httpcli.Doer) *Client {
 		Addrs: func() []string {
 			return conf.Get().ServiceConnections.GitServers
 		},
+		ReplicationFactor: func() int {
+			return conf.GitserverReplicationFactor()
+		},
 		HTTPClient:  cli,
 		HTTPLimiter: parallel.NewRun(500),
 		// Use the binary name for UserAgent. This should effectively identify
@@ -72,6 +76,11 @@ type Client struct {
 	// concurrent use. It may return different results at different times.
 	Addrs func() []string
 
+	// ReplicationFactor is a function which should return the number of
+	// gitservers each repository is cloned on. It is called each time a
+	// request is made. If nil, each repository is cloned on one gitserver.
+	ReplicationFactor func() int
+
 	// UserAgent is a string identifying who the client is. It will be logged in
 	// the telemetry in gitserver.
 	UserAgent string
@@ -86,6 +95,21 @@ func (c *Client) AddrForRepo(repo api.RepoName) string {
 	return AddrForRepo(repo, addrs)
 }
 
+// AddrsForRepo returns the addresses of the gitservers the given repo is
+// cloned on. The first one is the address returned by AddrForRepo, the others
+// are the replicas to read from if it is unavailable.
+func (c *Client) AddrsForRepo(repo api.RepoName) []string {
+	addrs := c.Addrs()
+	if len(addrs) == 0 {
+		panic("unexpected state: no gitserver addresses")
+	}
+	replicationFactor := 1
+	if c.ReplicationFactor != nil {
+		replicationFactor = c.ReplicationFactor()
+	}
+	return AddrsForRepo(repo, addrs, replicationFactor)
+}
+
 // addrForKey returns the gitserver address to use for the given string key,
 // which is hashed for sharding purposes.
 func (c *Client) addrForKey(key string) string {
@@ -103,6 +127,63 @@ func AddrForRepo(repo api.RepoName, addrs []string) string {
 	return addrForKey(string(repo), addrs)
 }
 
+// AddrsForRepo returns the addresses of the gitservers the given repo is
+// cloned on with the given replication factor, starting with the address
+// returned by AddrForRepo. It should never be called with an empty slice.
+//
+// The replicas are picked by consistent hashing over addrs, so that adding or
+// removing a gitserver only moves the replicas it holds or is picked for. The
+// gitserver a repo lives on is still picked by AddrForRepo, so that enabling
+// replication doesn't move repos between gitservers.
+func AddrsForRepo(repo api.RepoName, addrs []string, replicationFactor int) []string {
+	if replicationFactor < 1 {
+		replicationFactor = 1
+	}
+	if replicationFactor > len(addrs) {
+		replicationFactor = len(addrs)
+	}
+
+	repo = protocol.NormalizeRepo(repo) // in case the caller didn't already normalize it
+	primary := addrForKey(string(repo), addrs)
+	replicas := []string{primary}
+	if replicationFactor == 1 {
+		return replicas
+	}
+
+	// The primary may be among the closest gitservers, so we ask for one
+	// more than we need. Static maps never return an error.
+	closest, _ := replicaMap(addrs).GetN(string(repo), replicationFactor)
+	for _, addr := range closest {
+		if len(replicas) == replicationFactor {
+			break
+		}
+		if addr != primary {
+			replicas = append(replicas, addr)
+		}
+	}
+	return replicas
+}
+
+// replicaMaps caches the consistent hash map over the gitserver addresses,
+// which only change when gitservers are added or removed.
+var replicaMaps struct {
+	sync.Mutex
+	addrs string
+	m     *endpoint.Map
+}
+
+func replicaMap(addrs []string) *endpoint.Map {
+	key := strings.Join(addrs, " ")
+
+	replicaMaps.Lock()
+	defer replicaMaps.Unlock()
+	if replicaMaps.m == nil || replicaMaps.addrs != key {
+		replicaMaps.addrs = key
+		replicaMaps.m = endpoint.Static(addrs...)
+	}
+	return replicaMaps.m
+}
+
 // addrForKey returns the gitserver address to use for the given string key,
 // which is hashed for sharding purposes.
 func addrForKey(key string, addrs []string) string {
@@ -116,15 +197,22 @@ type ArchiveOptions struct {
 	Treeish string   // the tree or commit to produce an archive for
 	Format  string   // format of the resulting archive (usually "tar" or "zip")
 	Paths   []string // if nonempty, only include these paths
+
+	// AllowUnmatchedPaths makes the archive empty instead of failing if one
+	// of Paths matches no files.
+	AllowUnmatchedPaths bool
 }
 
 // archiveReader wraps the StdoutReader yielded by gitserver's
 // Cmd.StdoutReader with one that knows how to report a repository-not-found
 // error more carefully.
 type archiveReader struct {
-	base io.ReadCloser
-	repo api.RepoName
-	spec string
+	base    io.ReadCloser
+	trailer http.Header
+	repo    api.RepoName
+	spec    string
+
+	allowUnmatchedPaths bool
 }
 
 // Read checks the known output behavior of the StdoutReader.
@@ -135,6 +223,11 @@ func (a *archiveReader) Read(p []byte) (int, error) {
 		if strings.Contains(err.Error(), "Not a valid object") {
 			return 0, &RevisionNotFoundError{Repo: a.repo, Spec: a.spec}
 		}
+		// git archive checks that each path matches some files before it
+		// writes anything, so there is nothing to archive.
+		if a.allowUnmatchedPaths && strings.Contains(a.trailer.Get("X-Exec-Stderr"), "did not match any files") {
+			return 0, io.EOF
+		}
 	}
 	return n, err
 }
@@ -184,7 +277,7 @@ func (c *Client) Archive(ctx context.Context, repo api.RepoName, opt ArchiveOpti
 	}
 
 	u := c.ArchiveURL(repo, opt)
-	resp, err := c.do(ctx, repo, "GET", u.String(), nil)
+	resp, err := c.doRead(ctx, repo, "GET", "archive?"+u.RawQuery, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -196,8 +289,11 @@ func (c *Client) Archive(ctx context.Context, repo api.RepoName, opt ArchiveOpti
 				rc:      resp.Body,
 				trailer: resp.Trailer,
 			},
-			repo: repo,
-			spec: opt.Treeish,
+			trailer: resp.Trailer,
+			repo:    repo,
+			spec:    opt.Treeish,
+
+			allowUnmatchedPaths: len(opt.Paths) > 0 && opt.AllowUnmatchedPaths,
 		}, nil
 	case http.StatusNotFound:
 		var payload protocol.NotFoundPayload
@@ -249,7 +345,7 @@ func (c *Cmd) sendExec(ctx context.Context) (_ io.ReadCloser, _ http.Header, err
 		EnsureRevision: c.EnsureRevision,
 		Args:           c.Args[1:],
 	}
-	resp, err := c.client.httpPost(ctx, repoName, "exec", req)
+	resp, err := c.client.doRead(ctx, repoName, "POST", "exec", req)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -321,6 +417,11 @@ var deadlineExceededCounter = promauto.NewCounter(prometheus.CounterOpts{
 	Help: "Times that Client.sendExec() returned context.DeadlineExceeded",
 })
 
+var readFailoverCounter = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "src_gitserver_client_read_failover_total",
+	Help: "Times that a read of a repo was retried on a replica of an unavailable gitserver",
+})
+
 // Cmd represents a command to be executed remotely.
 type Cmd struct {
 	client *Client
@@ -533,12 +634,41 @@ func (c *Client) doListOne(ctx context.Context, urlSuffix, addr string) ([]strin
 // Repo updates are not guaranteed to occur. If a repo has been updated
 // recently (within the Since duration specified in the request), the
 // update won't happen.
+//
+// The repo is updated on all gitservers it is cloned on, which also clones it
+// on replicas which don't have it yet. The response of the gitserver the repo
+// lives on is returned, unless the update failed there but not on a replica.
 func (c *Client) RequestRepoUpdate(ctx context.Context, repo api.RepoName, since time.Duration) (*protocol.RepoUpdateResponse, error) {
+	addrs := c.AddrsForRepo(repo)
+	infos := make([]*protocol.RepoUpdateResponse, len(addrs))
+	errs := make([]error, len(addrs))
+	var wg sync.WaitGroup
+	for i, addr := range addrs {
+		wg.Add(1)
+		go func(i int, addr string) {
+			defer wg.Done()
+			infos[i], errs[i] = c.requestRepoUpdate(ctx, addr, repo, since)
+		}(i, addr)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err == nil {
+			return infos[i], nil
+		}
+		if i > 0 {
+			log15.Warn("Updating replica of repo", "repo", repo, "addr", addrs[i], "error", err)
+		}
+	}
+	return nil, errs[0]
+}
+
+func (c *Client) requestRepoUpdate(ctx context.Context, addr string, repo api.RepoName, since time.Duration) (*protocol.RepoUpdateResponse, error) {
 	req := &protocol.RepoUpdateRequest{
 		Repo:  repo,
 		Since: since,
 	}
-	resp, err := c.httpPost(ctx, repo, "repo-update", req)
+	resp, err := c.httpPost(ctx, repo, "http://"+addr+"/repo-update", req)
 	if err != nil {
 		return nil, err
 	}
@@ -565,7 +695,7 @@ func (c *Client) IsRepoCloneable(ctx context.Context, repo api.RepoName) error {
 	req := &protocol.IsRepoCloneableRequest{
 		Repo: repo,
 	}
-	r, err := c.httpPost(ctx, repo, "is-repo-cloneable", req)
+	r, err := c.doRead(ctx, repo, "POST", "is-repo-cloneable", req)
 	if err != nil {
 		return err
 	}
@@ -616,7 +746,20 @@ func (c *Client) IsRepoCloned(ctx context.Context, repo api.RepoName) (bool, err
 	req := &protocol.IsRepoClonedRequest{
 		Repo: repo,
 	}
-	resp, err := c.httpPost(ctx, repo, "is-repo-cloned", req)
+	resp, err := c.doRead(ctx, repo, "POST", "is-repo-cloned", req)
+	return isRepoClonedResponse(resp, err)
+}
+
+// isRepoClonedOn is like IsRepoCloned, but only asks the gitserver at addr.
+func (c *Client) isRepoClonedOn(ctx context.Context, addr string, repo api.RepoName) (bool, error) {
+	req := &protocol.IsRepoClonedRequest{
+		Repo: repo,
+	}
+	resp, err := c.httpPost(ctx, repo, "http://"+addr+"/is-repo-cloned", req)
+	return isRepoClonedResponse(resp, err)
+}
+
+func isRepoClonedResponse(resp *http.Response, err error) (bool, error) {
 	if err != nil {
 		return false, err
 	}
@@ -818,12 +961,22 @@ func (c *Client) doReposStats(ctx context.Context, addr string) (*protocol.Repos
 	return &stats, nil
 }
 
-// Remove removes the repository clone from gitserver.
+// Remove removes the repository clone from all gitservers it is cloned on.
 func (c *Client) Remove(ctx context.Context, repo api.RepoName) error {
+	var err error
+	for _, addr := range c.AddrsForRepo(repo) {
+		if e := c.removeFrom(ctx, addr, repo); e != nil {
+			err = multierror.Append(err, e)
+		}
+	}
+	return err
+}
+
+func (c *Client) removeFrom(ctx context.Context, addr string, repo api.RepoName) error {
 	req := &protocol.RepoDeleteRequest{
 		Repo: repo,
 	}
-	resp, err := c.httpPost(ctx, repo, "delete", req)
+	resp, err := c.httpPost(ctx, repo, "http://"+addr+"/delete", req)
 	if err != nil {
 		return err
 	}
@@ -887,6 +1040,50 @@ func (c *Client) do(ctx context.Context, repo api.RepoName, method, op string, p
 	return c.HTTPClient.Do(req)
 }
 
+// doRead is like do, for requests which only read the repo. If the gitserver
+// the repo lives on is unavailable, the request is retried in order on the
+// replicas of the repo which have cloned it.
+func (c *Client) doRead(ctx context.Context, repo api.RepoName, method, op string, payload interface{}) (*http.Response, error) {
+	addrs := c.AddrsForRepo(repo)
+	addr := addrs[0]
+	resp, err := c.do(ctx, repo, method, "http://"+addr+"/"+op, payload)
+	for _, replica := range addrs[1:] {
+		if ctx.Err() != nil || !isUnavailable(resp, err) {
+			break
+		}
+		// Replicas which are still cloning the repo would report it as not
+		// found, and start cloning it if they haven't yet.
+		if cloned, cerr := c.isRepoClonedOn(ctx, replica, repo); cerr != nil || !cloned {
+			continue
+		}
+
+		reason := err
+		if err == nil {
+			resp.Body.Close()
+			reason = errors.Errorf("http status %d", resp.StatusCode)
+		}
+		readFailoverCounter.Inc()
+		log15.Warn("Reading repo from replica of unavailable gitserver", "repo", repo, "addr", addr, "replica", replica, "error", reason)
+
+		addr = replica
+		resp, err = c.do(ctx, repo, method, "http://"+addr+"/"+op, payload)
+	}
+	return resp, err
+}
+
+// isUnavailable reports whether a gitserver couldn't handle a request, as
+// opposed to handling it with an error.
+func isUnavailable(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
 func userFromContext(ctx context.Context) string {
 	a := actor.FromContext(ctx)
 	if a == nil {
@@ -900,8 +1097,47 @@ func userFromContext(ctx context.Context) string {
 
 // CreateCommitFromPatch will attempt to create a commit from a patch
 // If possible, the error returned will be of type protocol.CreateCommitFromPatchError
+//
+// The commit is created on the gitserver the repo lives on, and then on its
+// replicas: replicas fetch it from the code host if it was pushed there, and
+// otherwise create the same commit themselves.
 func (c *Client) CreateCommitFromPatch(ctx context.Context, req protocol.CreateCommitFromPatchRequest) (string, error) {
-	resp, err := c.httpPost(ctx, req.Repo, "create-commit-from-patch", req)
+	addrs := c.AddrsForRepo(req.Repo)
+	rev, err := c.createCommitFromPatch(ctx, addrs[0], req)
+	if err != nil || len(addrs) == 1 {
+		return rev, err
+	}
+
+	// The commit date is part of the request, so replicas create the same
+	// commit. The ref was picked by the first gitserver if it had to be
+	// unique.
+	replicaReq := req
+	replicaReq.UniqueRef = false
+	if req.UniqueRef {
+		replicaReq.TargetRef = rev
+	}
+	var wg sync.WaitGroup
+	for _, addr := range addrs[1:] {
+		wg.Add(1)
+		go func(addr string) {
+			defer wg.Done()
+			var err error
+			if req.Push != nil {
+				_, err = c.requestRepoUpdate(ctx, addr, req.Repo, 0)
+			} else {
+				_, err = c.createCommitFromPatch(ctx, addr, replicaReq)
+			}
+			if err != nil {
+				log15.Warn("Creating commit from patch on replica of repo", "repo", req.Repo, "addr", addr, "error", err)
+			}
+		}(addr)
+	}
+	wg.Wait()
+	return rev, nil
+}
+
+func (c *Client) createCommitFromPatch(ctx context.Context, addr string, req protocol.CreateCommitFromPatchRequest) (string, error) {
+	resp, err := c.httpPost(ctx, req.Repo, "http://"+addr+"/create-commit-from-patch", req)
 	if err != nil {
 		return "", err
 	}
@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
@@ -14,6 +15,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"sort"
+	"sync"
 	"testing"
 
 	"github.com/cockroachdb/errors"
@@ -23,6 +25,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/cmd/gitserver/server"
 	"github.com/sourcegraph/sourcegraph/internal/api"
 	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver/protocol"
 	"github.com/sourcegraph/sourcegraph/internal/httpcli"
 )
 
@@ -65,19 +68,39 @@ func TestClient_Archive(t *testing.T) {
 	}
 	defer os.RemoveAll(root)
 
+	simple := createSimpleGitRepo(t, root)
 	tests := map[api.RepoName]struct {
-		remote string
-		want   map[string]string
-		err    error
+		remote         string
+		paths          []string
+		allowUnmatched bool
+		want           map[string]string
+		err            error
+		readErr        bool
 	}{
 		"simple": {
-			remote: createSimpleGitRepo(t, root),
+			remote: simple,
 			want: map[string]string{
 				"dir1/":      "",
 				"dir1/file1": "infile1",
 				"file 2":     "infile2",
 			},
 		},
+		"simple-paths": {
+			remote: simple,
+			paths:  []string{"file 2"},
+			want:   map[string]string{"file 2": "infile2"},
+		},
+		"simple-unmatched-paths": {
+			remote:         simple,
+			paths:          []string{"missing"},
+			allowUnmatched: true,
+			want:           map[string]string{},
+		},
+		"simple-unmatched-paths-error": {
+			remote:  simple,
+			paths:   []string{"missing"},
+			readErr: true,
+		},
 		"repo-with-dotgit-dir": {
 			remote: createRepoWithDotGitDir(t, root),
 			want:   map[string]string{"file1": "hello\n", ".git/mydir/file2": "milton\n", ".git/mydir/": "", ".git/": ""},
@@ -117,7 +140,7 @@ func TestClient_Archive(t *testing.T) {
 				}
 			}
 
-			rc, err := cli.Archive(ctx, name, gitserver.ArchiveOptions{Treeish: "HEAD", Format: "zip"})
+			rc, err := cli.Archive(ctx, name, gitserver.ArchiveOptions{Treeish: "HEAD", Format: "zip", Paths: test.paths, AllowUnmatchedPaths: test.allowUnmatched})
 			if have, want := fmt.Sprint(err), fmt.Sprint(test.err); have != want {
 				t.Errorf("archive: have err %v, want %v", have, want)
 			}
@@ -128,15 +151,29 @@ func TestClient_Archive(t *testing.T) {
 
 			defer rc.Close()
 			data, err := io.ReadAll(rc)
+			if test.readErr {
+				if err == nil {
+					t.Fatal("expected reading the archive to fail")
+				}
+				return
+			}
 			if err != nil {
 				t.Fatal(err)
 			}
+
+			got := map[string]string{}
+			if len(data) == 0 {
+				// The archive of paths which match no files is empty.
+				if !cmp.Equal(test.want, got) {
+					t.Errorf("mismatch (-want +got):\n%s", cmp.Diff(test.want, got))
+				}
+				return
+			}
 			zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
 			if err != nil {
 				t.Fatal(err)
 			}
 
-			got := map[string]string{}
 			for _, f := range zr.File {
 				r, err := f.Open()
 				if err != nil {
@@ -287,6 +324,299 @@ func TestAddrForRepo(t *testing.T) {
 	}
 }
 
+func TestAddrsForRepo(t *testing.T) {
+	addrs := []string{"gitserver-1", "gitserver-2", "gitserver-3"}
+
+	testCases := []struct {
+		name              string
+		repo              api.RepoName
+		replicationFactor int
+		wantLen           int
+	}{
+		{
+			name:              "no replicas",
+			repo:              api.RepoName("repo1"),
+			replicationFactor: 1,
+			wantLen:           1,
+		},
+		{
+			name:              "invalid replication factor",
+			repo:              api.RepoName("repo1"),
+			replicationFactor: 0,
+			wantLen:           1,
+		},
+		{
+			name:              "replicas",
+			repo:              api.RepoName("repo1"),
+			replicationFactor: 2,
+			wantLen:           2,
+		},
+		{
+			name:              "check we normalise",
+			repo:              api.RepoName("repo1.git"),
+			replicationFactor: 2,
+			wantLen:           2,
+		},
+		{
+			name:              "more replicas than gitservers",
+			repo:              api.RepoName("github.com/sourcegraph/sourcegraph.git"),
+			replicationFactor: 5,
+			wantLen:           3,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := gitserver.AddrsForRepo(tc.repo, addrs, tc.replicationFactor)
+			if len(got) != tc.wantLen {
+				t.Fatalf("Want %d addresses, got %v", tc.wantLen, got)
+			}
+			// The repo lives on the same gitserver whatever the
+			// replication factor.
+			if want := gitserver.AddrForRepo(tc.repo, addrs); got[0] != want {
+				t.Fatalf("Want %q first, got %v", want, got)
+			}
+			seen := map[string]bool{}
+			for _, addr := range got {
+				if seen[addr] {
+					t.Fatalf("Want distinct addresses, got %v", got)
+				}
+				seen[addr] = true
+			}
+			// Asking for fewer replicas returns a prefix of the replicas.
+			if tc.replicationFactor > 1 {
+				fewer := gitserver.AddrsForRepo(tc.repo, addrs, tc.wantLen-1)
+				if diff := cmp.Diff(got[:len(fewer)], fewer); diff != "" {
+					t.Fatalf("Mismatch (-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
+
+// replicaClient returns a client for three gitservers which clones each repo
+// on two of them, and the addresses of the gitservers repo1 is cloned on.
+func replicaClient() (cli *gitserver.Client, primary, replica string) {
+	addrs := []string{"gitserver-1", "gitserver-2", "gitserver-3"}
+	repoAddrs := gitserver.AddrsForRepo("repo1", addrs, 2)
+	return &gitserver.Client{
+		Addrs:             func() []string { return addrs },
+		ReplicationFactor: func() int { return 2 },
+	}, repoAddrs[0], repoAddrs[1]
+}
+
+func TestClient_ReadFailover(t *testing.T) {
+	const (
+		unreachable = 0
+		primary     = "primary"
+		replica     = "replica"
+	)
+
+	testCases := []struct {
+		name string
+		// status code of the requests by gitserver
+		responses map[string]int
+		// status code of is-repo-cloned requests to the replica
+		replicaCloned int
+		wantCloned    bool
+		wantErr       bool
+		wantRequested []string
+	}{
+		{
+			name:          "primary available",
+			responses:     map[string]int{primary: http.StatusOK},
+			wantCloned:    true,
+			wantRequested: []string{primary},
+		},
+		{
+			name:          "primary not cloned",
+			responses:     map[string]int{primary: http.StatusNotFound},
+			wantCloned:    false,
+			wantRequested: []string{primary},
+		},
+		{
+			name:          "primary unreachable",
+			responses:     map[string]int{primary: unreachable, replica: http.StatusOK},
+			replicaCloned: http.StatusOK,
+			wantCloned:    true,
+			wantRequested: []string{primary, replica, replica},
+		},
+		{
+			name:          "primary unavailable",
+			responses:     map[string]int{primary: http.StatusServiceUnavailable, replica: http.StatusOK},
+			replicaCloned: http.StatusOK,
+			wantCloned:    true,
+			wantRequested: []string{primary, replica, replica},
+		},
+		{
+			name:          "replica not cloned",
+			responses:     map[string]int{primary: unreachable},
+			replicaCloned: http.StatusNotFound,
+			wantErr:       true,
+			wantRequested: []string{primary, replica},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var requested []string
+			cli, primaryAddr, replicaAddr := replicaClient()
+			cli.HTTPClient = httpcli.DoerFunc(func(r *http.Request) (*http.Response, error) {
+				var status int
+				var ok bool
+				switch r.URL.Host {
+				case primaryAddr:
+					requested = append(requested, primary)
+					status, ok = tc.responses[primary]
+				case replicaAddr:
+					requested = append(requested, replica)
+					// The first request to the replica checks whether
+					// it has cloned the repo.
+					if len(requested) == 2 {
+						status, ok = tc.replicaCloned, true
+					} else {
+						status, ok = tc.responses[replica]
+					}
+				}
+				if !ok {
+					return nil, errors.Errorf("unexpected url: %s", r.URL.String())
+				}
+				if status == unreachable {
+					return nil, errors.New("connection refused")
+				}
+				return &http.Response{
+					StatusCode: status,
+					Body:       io.NopCloser(&bytes.Buffer{}),
+				}, nil
+			})
+
+			cloned, err := cli.IsRepoCloned(context.Background(), "repo1")
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("Want error %v, got %v", tc.wantErr, err)
+			}
+			if cloned != tc.wantCloned {
+				t.Errorf("Want cloned %v, got %v", tc.wantCloned, cloned)
+			}
+			if diff := cmp.Diff(tc.wantRequested, requested); diff != "" {
+				t.Errorf("Mismatch in requested gitservers (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestClient_RequestRepoUpdateReplicas(t *testing.T) {
+	var mu sync.Mutex
+	var requested []string
+	cli, primary, replica := replicaClient()
+	cli.HTTPClient = httpcli.DoerFunc(func(r *http.Request) (*http.Response, error) {
+		mu.Lock()
+		requested = append(requested, r.URL.String())
+		mu.Unlock()
+		switch r.URL.String() {
+		case "http://" + primary + "/repo-update":
+			return nil, errors.New("connection refused")
+		case "http://" + replica + "/repo-update":
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(bytes.NewBufferString(`{"cloned": true}`)),
+			}, nil
+		default:
+			return nil, errors.Errorf("unexpected url: %s", r.URL.String())
+		}
+	})
+
+	// The update of the replica succeeds even though the gitserver the repo
+	// lives on is unreachable.
+	info, err := cli.RequestRepoUpdate(context.Background(), "repo1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.Cloned {
+		t.Error("Want the repo to be cloned")
+	}
+
+	want := []string{"http://" + primary + "/repo-update", "http://" + replica + "/repo-update"}
+	sort.Strings(want)
+	sort.Strings(requested)
+	if diff := cmp.Diff(want, requested); diff != "" {
+		t.Fatalf("Mismatch in requested gitservers (-want +got):\n%s", diff)
+	}
+}
+
+func TestClient_CreateCommitFromPatchReplicas(t *testing.T) {
+	testCases := []struct {
+		name string
+		push *protocol.PushConfig
+		// wantReplica is the request the replica gets, given the address of
+		// the replica.
+		wantReplica func(addr string) string
+	}{
+		{
+			name: "not pushed",
+			wantReplica: func(addr string) string {
+				return "http://" + addr + "/create-commit-from-patch refs/heads/patch-1 false"
+			},
+		},
+		{
+			name: "pushed",
+			push: &protocol.PushConfig{},
+			wantReplica: func(addr string) string {
+				return "http://" + addr + "/repo-update"
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var requested []string
+			cli, primary, replica := replicaClient()
+			cli.HTTPClient = httpcli.DoerFunc(func(r *http.Request) (*http.Response, error) {
+				request := r.URL.String()
+				body := `{"cloned": true}`
+				if r.URL.Path == "/create-commit-from-patch" {
+					var req protocol.CreateCommitFromPatchRequest
+					if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+						return nil, err
+					}
+					request += fmt.Sprintf(" %s %v", req.TargetRef, req.UniqueRef)
+					body = `{"Rev": "refs/heads/patch-1"}`
+				}
+				mu.Lock()
+				requested = append(requested, request)
+				mu.Unlock()
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(bytes.NewBufferString(body)),
+				}, nil
+			})
+
+			// The gitserver the repo lives on picks a unique ref, which the
+			// replica uses.
+			rev, err := cli.CreateCommitFromPatch(context.Background(), protocol.CreateCommitFromPatchRequest{
+				Repo:      "repo1",
+				TargetRef: "refs/heads/patch",
+				UniqueRef: true,
+				Push:      tc.push,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rev != "refs/heads/patch-1" {
+				t.Errorf("Want rev refs/heads/patch-1, got %q", rev)
+			}
+
+			want := []string{
+				"http://" + primary + "/create-commit-from-patch refs/heads/patch true",
+				tc.wantReplica(replica),
+			}
+			if diff := cmp.Diff(want, requested); diff != "" {
+				t.Fatalf("Mismatch in requests (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestClient_P4Exec(t *testing.T) {
 	root, err := os.MkdirTemp("", t.Name())
 	if err != nil {
@@ -1,10 +1,14 @@
 package priority
 
+import "github.com/sourcegraph/sourcegraph/internal/search"
+
 // Cost is an approximation of the resource demand of a Sourcegraph query, or a set of queries. This is useful
 // for insight query execution strategies.
 type Cost int
 
+// Indexed and Unindexed approximate the cost of an insight query over 500 repositories, using the per repository
+// costs of search.EstimateQueryCost. Eventually this should estimate the cost of the actual query.
 const (
-	Indexed   Cost = 500
-	Unindexed Cost = 5000 // using an order of magnitude approximation at the moment. Eventually this should become something a little smarter.
+	Indexed   Cost = 500 * Cost(search.IndexedRepoCost)
+	Unindexed Cost = 500 * Cost(search.UnindexedRepoCost)
 )
@@ -0,0 +1,191 @@
+package repos
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cockroachdb/errors"
+	"github.com/inconshreveable/log15"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/conf/reposource"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/gomodules"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/gomodules/proxy"
+	"github.com/sourcegraph/sourcegraph/internal/httpcli"
+	"github.com/sourcegraph/sourcegraph/internal/jsonc"
+	"github.com/sourcegraph/sourcegraph/internal/types"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+// A GoModulesSource creates git repositories from the zips of Go modules
+// served by Go module proxies.
+type GoModulesSource struct {
+	svc    *types.ExternalService
+	config *schema.GoModulesConnection
+	client *proxy.Client
+}
+
+// NewGoModulesSource returns a new GoModulesSource from the given external
+// service.
+func NewGoModulesSource(svc *types.ExternalService, cf *httpcli.Factory) (*GoModulesSource, error) {
+	var c schema.GoModulesConnection
+	if err := jsonc.Unmarshal(svc.Config, &c); err != nil {
+		return nil, errors.Wrapf(err, "external service id=%d config error", svc.ID)
+	}
+
+	if cf == nil {
+		cf = httpcli.ExternalClientFactory
+	}
+	cli, err := cf.Doer()
+	if err != nil {
+		return nil, err
+	}
+	client, err := proxy.NewClient(&c, cli)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GoModulesSource{svc: svc, config: &c, client: client}, nil
+}
+
+// ListRepos returns a repository for each Go module configured in the
+// external service.
+func (s *GoModulesSource) ListRepos(ctx context.Context, results chan SourceResult) {
+	modules, err := GoModules(*s.config)
+	if err != nil {
+		results <- SourceResult{Source: s, Err: err}
+		return
+	}
+	for _, mod := range modules {
+		results <- SourceResult{Source: s, Repo: s.makeRepo(mod)}
+	}
+}
+
+// GetRepo returns the repository of the Go module at the given path, if at
+// least one of its configured versions exists in the proxies.
+func (s *GoModulesSource) GetRepo(ctx context.Context, modulePath string) (*types.Repo, error) {
+	mod, err := reposource.ParseGoModuleFromRepoURL(modulePath)
+	if err != nil {
+		return nil, err
+	}
+
+	dependencies, err := GoDependencies(*s.config)
+	if err != nil {
+		return nil, err
+	}
+
+	var nonExistentDependencies []reposource.GoDependency
+	hasAtLeastOneValidDependency := false
+	for _, dep := range dependencies {
+		if dep.GoModule != mod {
+			continue
+		}
+		exists, err := s.exists(ctx, dep)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			hasAtLeastOneValidDependency = true
+		} else {
+			nonExistentDependencies = append(nonExistentDependencies, dep)
+		}
+	}
+
+	if !hasAtLeastOneValidDependency {
+		return nil, &goDependencyNotFound{dependencies: nonExistentDependencies}
+	}
+
+	for _, dep := range nonExistentDependencies {
+		// Like for JVM packages, a version which was removed from the
+		// proxies doesn't reject the other versions.
+		log15.Warn("Skipping non-existing Go module", "nonExistentDependency", dep.PackageManagerSyntax())
+	}
+
+	return s.makeRepo(mod), nil
+}
+
+// exists reports whether the proxies have the given version, or any version
+// if the dependency has no version.
+func (s *GoModulesSource) exists(ctx context.Context, dep reposource.GoDependency) (bool, error) {
+	if dep.Version != "" {
+		return s.client.Exists(ctx, dep)
+	}
+	versions, err := s.client.ListVersions(ctx, dep.GoModule)
+	if proxy.IsNotFound(err) {
+		return false, nil
+	}
+	return len(versions) > 0, err
+}
+
+type goDependencyNotFound struct {
+	dependencies []reposource.GoDependency
+}
+
+func (e *goDependencyNotFound) Error() string {
+	return fmt.Sprintf("not found: Go dependency '%v'", e.dependencies)
+}
+
+func (e *goDependencyNotFound) NotFound() bool {
+	return true
+}
+
+func (s *GoModulesSource) makeRepo(mod reposource.GoModule) *types.Repo {
+	urn := s.svc.URN()
+	repoName := mod.RepoName()
+	return &types.Repo{
+		Name: repoName,
+		URI:  string(repoName),
+		ExternalRepo: api.ExternalRepoSpec{
+			ID:          string(repoName),
+			ServiceID:   extsvc.TypeGoModules,
+			ServiceType: extsvc.TypeGoModules,
+		},
+		Private: false,
+		Sources: map[string]*types.SourceInfo{
+			urn: {
+				ID:       urn,
+				CloneURL: mod.CloneURL(),
+			},
+		},
+		Metadata: &gomodules.Metadata{
+			Module: mod,
+		},
+	}
+}
+
+// ExternalServices returns a singleton slice containing the external service.
+func (s *GoModulesSource) ExternalServices() types.ExternalServices {
+	return types.ExternalServices{s.svc}
+}
+
+// GoDependencies returns the module versions configured in the given
+// connection. The version of a dependency is empty if all versions of its
+// module are mirrored.
+func GoDependencies(connection schema.GoModulesConnection) (dependencies []reposource.GoDependency, err error) {
+	for _, dep := range connection.Dependencies {
+		dependency, err := reposource.ParseGoDependencyConfig(dep)
+		if err != nil {
+			return nil, err
+		}
+		dependencies = append(dependencies, dependency)
+	}
+	return dependencies, nil
+}
+
+// GoModules returns the distinct modules configured in the given connection.
+func GoModules(connection schema.GoModulesConnection) ([]reposource.GoModule, error) {
+	dependencies, err := GoDependencies(connection)
+	if err != nil {
+		return nil, err
+	}
+	isAdded := make(map[reposource.GoModule]bool)
+	modules := []reposource.GoModule{}
+	for _, dep := range dependencies {
+		if !isAdded[dep.GoModule] {
+			modules = append(modules, dep.GoModule)
+		}
+		isAdded[dep.GoModule] = true
+	}
+	return modules, nil
+}
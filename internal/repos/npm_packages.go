@@ -0,0 +1,178 @@
+package repos
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cockroachdb/errors"
+	"github.com/inconshreveable/log15"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/conf/reposource"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/npmpackages"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/npmpackages/npm"
+	"github.com/sourcegraph/sourcegraph/internal/httpcli"
+	"github.com/sourcegraph/sourcegraph/internal/jsonc"
+	"github.com/sourcegraph/sourcegraph/internal/types"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+// A NpmPackagesSource creates git repositories from the tarballs of packages
+// published to an npm registry.
+type NpmPackagesSource struct {
+	svc    *types.ExternalService
+	config *schema.NpmPackagesConnection
+	client *npm.Client
+}
+
+// NewNpmPackagesSource returns a new NpmPackagesSource from the given external
+// service.
+func NewNpmPackagesSource(svc *types.ExternalService, cf *httpcli.Factory) (*NpmPackagesSource, error) {
+	var c schema.NpmPackagesConnection
+	if err := jsonc.Unmarshal(svc.Config, &c); err != nil {
+		return nil, errors.Wrapf(err, "external service id=%d config error", svc.ID)
+	}
+
+	if cf == nil {
+		cf = httpcli.ExternalClientFactory
+	}
+	cli, err := cf.Doer()
+	if err != nil {
+		return nil, err
+	}
+	client, err := npm.NewClient(&c, cli)
+	if err != nil {
+		return nil, err
+	}
+
+	return &NpmPackagesSource{svc: svc, config: &c, client: client}, nil
+}
+
+// ListRepos returns a repository for each npm package configured in the
+// external service.
+func (s *NpmPackagesSource) ListRepos(ctx context.Context, results chan SourceResult) {
+	packages, err := NpmPackages(*s.config)
+	if err != nil {
+		results <- SourceResult{Source: s, Err: err}
+		return
+	}
+	for _, pkg := range packages {
+		results <- SourceResult{Source: s, Repo: s.makeRepo(pkg)}
+	}
+}
+
+// GetRepo returns the repository of the npm package at the given path, if at
+// least one of its configured versions exists in the registry.
+func (s *NpmPackagesSource) GetRepo(ctx context.Context, packagePath string) (*types.Repo, error) {
+	pkg, err := reposource.ParseNpmPackageFromRepoURL(packagePath)
+	if err != nil {
+		return nil, err
+	}
+
+	dependencies, err := NpmDependencies(*s.config)
+	if err != nil {
+		return nil, err
+	}
+
+	var nonExistentDependencies []reposource.NpmDependency
+	hasAtLeastOneValidDependency := false
+	for _, dep := range dependencies {
+		if dep.NpmPackage != pkg {
+			continue
+		}
+		exists, err := s.client.Exists(ctx, dep)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			hasAtLeastOneValidDependency = true
+		} else {
+			nonExistentDependencies = append(nonExistentDependencies, dep)
+		}
+	}
+
+	if !hasAtLeastOneValidDependency {
+		return nil, &npmDependencyNotFound{dependencies: nonExistentDependencies}
+	}
+
+	for _, dep := range nonExistentDependencies {
+		// Like for JVM packages, a version which was removed from the
+		// registry doesn't reject the other versions.
+		log15.Warn("Skipping non-existing npm package", "nonExistentDependency", dep.PackageManagerSyntax())
+	}
+
+	return s.makeRepo(pkg), nil
+}
+
+type npmDependencyNotFound struct {
+	dependencies []reposource.NpmDependency
+}
+
+func (e *npmDependencyNotFound) Error() string {
+	return fmt.Sprintf("not found: npm dependency '%v'", e.dependencies)
+}
+
+func (e *npmDependencyNotFound) NotFound() bool {
+	return true
+}
+
+func (s *NpmPackagesSource) makeRepo(pkg reposource.NpmPackage) *types.Repo {
+	urn := s.svc.URN()
+	repoName := pkg.RepoName()
+	return &types.Repo{
+		Name: repoName,
+		URI:  string(repoName),
+		ExternalRepo: api.ExternalRepoSpec{
+			ID:          string(repoName),
+			ServiceID:   extsvc.TypeNpmPackages,
+			ServiceType: extsvc.TypeNpmPackages,
+		},
+		Private: false,
+		Sources: map[string]*types.SourceInfo{
+			urn: {
+				ID:       urn,
+				CloneURL: pkg.CloneURL(),
+			},
+		},
+		Metadata: &npmpackages.Metadata{
+			Package: pkg,
+		},
+	}
+}
+
+// ExternalServices returns a singleton slice containing the external service.
+func (s *NpmPackagesSource) ExternalServices() types.ExternalServices {
+	return types.ExternalServices{s.svc}
+}
+
+// NpmDependencies returns the package versions configured in the given
+// connection.
+func NpmDependencies(connection schema.NpmPackagesConnection) (dependencies []reposource.NpmDependency, err error) {
+	for _, dep := range connection.Dependencies {
+		dependency, err := reposource.ParseNpmDependency(dep)
+		if err != nil {
+			return nil, err
+		}
+		dependencies = append(dependencies, dependency)
+	}
+	return dependencies, nil
+}
+
+// NpmPackages returns the distinct packages configured in the given
+// connection.
+func NpmPackages(connection schema.NpmPackagesConnection) ([]reposource.NpmPackage, error) {
+	dependencies, err := NpmDependencies(connection)
+	if err != nil {
+		return nil, err
+	}
+	isAdded := make(map[reposource.NpmPackage]bool)
+	packages := []reposource.NpmPackage{}
+	for _, dep := range dependencies {
+		if !isAdded[dep.NpmPackage] {
+			packages = append(packages, dep.NpmPackage)
+		}
+		isAdded[dep.NpmPackage] = true
+	}
+	return packages, nil
+}
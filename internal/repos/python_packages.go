@@ -0,0 +1,178 @@
+package repos
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cockroachdb/errors"
+	"github.com/inconshreveable/log15"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/conf/reposource"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/pythonpackages"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/pythonpackages/pypi"
+	"github.com/sourcegraph/sourcegraph/internal/httpcli"
+	"github.com/sourcegraph/sourcegraph/internal/jsonc"
+	"github.com/sourcegraph/sourcegraph/internal/types"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+// A PythonPackagesSource creates git repositories from the distribution files
+// of packages published to Python package indexes.
+type PythonPackagesSource struct {
+	svc    *types.ExternalService
+	config *schema.PythonPackagesConnection
+	client *pypi.Client
+}
+
+// NewPythonPackagesSource returns a new PythonPackagesSource from the given external
+// service.
+func NewPythonPackagesSource(svc *types.ExternalService, cf *httpcli.Factory) (*PythonPackagesSource, error) {
+	var c schema.PythonPackagesConnection
+	if err := jsonc.Unmarshal(svc.Config, &c); err != nil {
+		return nil, errors.Wrapf(err, "external service id=%d config error", svc.ID)
+	}
+
+	if cf == nil {
+		cf = httpcli.ExternalClientFactory
+	}
+	cli, err := cf.Doer()
+	if err != nil {
+		return nil, err
+	}
+	client, err := pypi.NewClient(&c, cli)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PythonPackagesSource{svc: svc, config: &c, client: client}, nil
+}
+
+// ListRepos returns a repository for each Python package configured in the
+// external service.
+func (s *PythonPackagesSource) ListRepos(ctx context.Context, results chan SourceResult) {
+	packages, err := PythonPackages(*s.config)
+	if err != nil {
+		results <- SourceResult{Source: s, Err: err}
+		return
+	}
+	for _, pkg := range packages {
+		results <- SourceResult{Source: s, Repo: s.makeRepo(pkg)}
+	}
+}
+
+// GetRepo returns the repository of the Python package at the given path, if
+// at least one of its configured versions exists in the indexes.
+func (s *PythonPackagesSource) GetRepo(ctx context.Context, packagePath string) (*types.Repo, error) {
+	pkg, err := reposource.ParsePythonPackageFromRepoURL(packagePath)
+	if err != nil {
+		return nil, err
+	}
+
+	dependencies, err := PythonDependencies(*s.config)
+	if err != nil {
+		return nil, err
+	}
+
+	var nonExistentDependencies []reposource.PythonDependency
+	hasAtLeastOneValidDependency := false
+	for _, dep := range dependencies {
+		if dep.PythonPackage != pkg {
+			continue
+		}
+		exists, err := s.client.Exists(ctx, dep)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			hasAtLeastOneValidDependency = true
+		} else {
+			nonExistentDependencies = append(nonExistentDependencies, dep)
+		}
+	}
+
+	if !hasAtLeastOneValidDependency {
+		return nil, &pythonDependencyNotFound{dependencies: nonExistentDependencies}
+	}
+
+	for _, dep := range nonExistentDependencies {
+		// Like for JVM packages, a version which was removed from the
+		// indexes doesn't reject the other versions.
+		log15.Warn("Skipping non-existing Python package", "nonExistentDependency", dep.PackageManagerSyntax())
+	}
+
+	return s.makeRepo(pkg), nil
+}
+
+type pythonDependencyNotFound struct {
+	dependencies []reposource.PythonDependency
+}
+
+func (e *pythonDependencyNotFound) Error() string {
+	return fmt.Sprintf("not found: Python dependency '%v'", e.dependencies)
+}
+
+func (e *pythonDependencyNotFound) NotFound() bool {
+	return true
+}
+
+func (s *PythonPackagesSource) makeRepo(pkg reposource.PythonPackage) *types.Repo {
+	urn := s.svc.URN()
+	repoName := pkg.RepoName()
+	return &types.Repo{
+		Name: repoName,
+		URI:  string(repoName),
+		ExternalRepo: api.ExternalRepoSpec{
+			ID:          string(repoName),
+			ServiceID:   extsvc.TypePythonPackages,
+			ServiceType: extsvc.TypePythonPackages,
+		},
+		Private: false,
+		Sources: map[string]*types.SourceInfo{
+			urn: {
+				ID:       urn,
+				CloneURL: pkg.CloneURL(),
+			},
+		},
+		Metadata: &pythonpackages.Metadata{
+			Package: pkg,
+		},
+	}
+}
+
+// ExternalServices returns a singleton slice containing the external service.
+func (s *PythonPackagesSource) ExternalServices() types.ExternalServices {
+	return types.ExternalServices{s.svc}
+}
+
+// PythonDependencies returns the package versions configured in the given
+// connection.
+func PythonDependencies(connection schema.PythonPackagesConnection) (dependencies []reposource.PythonDependency, err error) {
+	for _, dep := range connection.Dependencies {
+		dependency, err := reposource.ParsePythonDependency(dep)
+		if err != nil {
+			return nil, err
+		}
+		dependencies = append(dependencies, dependency)
+	}
+	return dependencies, nil
+}
+
+// PythonPackages returns the distinct packages configured in the given
+// connection.
+func PythonPackages(connection schema.PythonPackagesConnection) ([]reposource.PythonPackage, error) {
+	dependencies, err := PythonDependencies(connection)
+	if err != nil {
+		return nil, err
+	}
+	isAdded := make(map[reposource.PythonPackage]bool)
+	packages := []reposource.PythonPackage{}
+	for _, dep := range dependencies {
+		if !isAdded[dep.PythonPackage] {
+			packages = append(packages, dep.PythonPackage)
+		}
+		isAdded[dep.PythonPackage] = true
+	}
+	return packages, nil
+}
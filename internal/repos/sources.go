@@ -60,6 +60,12 @@ func NewSource(svc *types.ExternalService, cf *httpcli.Factory) (Source, error)
 		return NewPerforceSource(svc)
 	case extsvc.KindJVMPackages:
 		return NewJVMPackagesSource(svc)
+	case extsvc.KindNpmPackages:
+		return NewNpmPackagesSource(svc, cf)
+	case extsvc.KindGoModules:
+		return NewGoModulesSource(svc, cf)
+	case extsvc.KindPythonPackages:
+		return NewPythonPackagesSource(svc, cf)
 	case extsvc.KindOther:
 		return NewOtherSource(svc, cf)
 	default:
@@ -6,7 +6,11 @@ import (
 	"sort"
 
 	"github.com/google/zoekt"
+	"github.com/inconshreveable/log15"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
 	"github.com/sourcegraph/sourcegraph/schema"
 )
 
@@ -70,6 +74,10 @@ type RepoIndexOptions struct {
 	// error is encoded in the body. If the revision is missing, an empty
 	// string should be returned rather than an error.
 	GetVersion func(branch string) (string, error)
+
+	// ListBranches returns the names of the branches of the repository. It
+	// is only called if branch globs are configured for the repository.
+	ListBranches func() ([]string, error)
 }
 
 // GetIndexOptions returns a json blob for consumption by
@@ -137,7 +145,24 @@ func getIndexOptions(
 		}
 
 		for _, rev := range c.ExperimentalFeatures.SearchIndexBranches[repoName] {
-			branches[rev] = struct{}{}
+			if !git.IsBranchGlob(rev) {
+				branches[rev] = struct{}{}
+			}
+		}
+	}
+
+	// Add all branches that match the configured branch globs. We keep track
+	// of them, since we drop them first if there are too many branches.
+	globbed := map[string]struct{}{}
+	if globs := IndexedBranchGlobs(c, repoName); len(globs) > 0 && opts.ListBranches != nil {
+		matched, err := matchBranchGlobs(globs, opts.ListBranches)
+		if err != nil {
+			return marshal(&zoektIndexOptions{Error: err.Error()})
+		}
+		for _, branch := range matched {
+			if _, ok := branches[branch]; !ok {
+				globbed[branch] = struct{}{}
+			}
 		}
 	}
 
@@ -148,6 +173,10 @@ func getIndexOptions(
 	}
 	for _, rev := range revs {
 		branches[rev] = struct{}{}
+		delete(globbed, rev)
+	}
+	for branch := range globbed {
+		branches[branch] = struct{}{}
 	}
 
 	for branch := range branches {
@@ -182,14 +211,111 @@ func getIndexOptions(
 		o.Branches = nil
 	}
 
-	// Zoekt has a limit of 64 branches
-	if len(o.Branches) > 64 {
-		o.Branches = o.Branches[:64]
+	if len(o.Branches) > MaxIndexedBranches {
+		log15.Warn("not indexing all branches of repository: too many branches", "repo", repoName, "branches", len(o.Branches), "max", MaxIndexedBranches)
+		metricIndexedBranchesTruncated.Inc()
+		o.Branches = limitBranches(o.Branches, globbed)
 	}
 
 	return marshal(o)
 }
 
+// MaxIndexedBranches is the maximum number of branches Zoekt indexes for a
+// repository.
+const MaxIndexedBranches = 64
+
+var metricIndexedBranchesTruncated = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "src_search_index_branches_truncated_total",
+	Help: "Total number of times the branches to index for a repository exceeded the maximum number of indexed branches.",
+})
+
+// limitBranches returns the first MaxIndexedBranches of branches, dropping
+// the branches in globbed first. Searches of ref globs fall back to unindexed
+// search for repositories with MaxIndexedBranches indexed branches, so they
+// still find the dropped branches.
+func limitBranches(branches []zoekt.RepositoryBranch, globbed map[string]struct{}) []zoekt.RepositoryBranch {
+	keep := make(map[string]struct{}, MaxIndexedBranches)
+	for _, wantGlobbed := range []bool{false, true} {
+		for _, b := range branches {
+			if _, ok := globbed[b.Name]; ok != wantGlobbed {
+				continue
+			}
+			if len(keep) < MaxIndexedBranches {
+				keep[b.Name] = struct{}{}
+			}
+		}
+	}
+
+	limited := branches[:0]
+	for _, b := range branches {
+		if _, ok := keep[b.Name]; ok {
+			limited = append(limited, b)
+		}
+	}
+	return limited
+}
+
+// IndexedBranchGlobs returns the glob patterns of the branch names we index
+// for repoName in addition to the default branch. They are the globs in
+// search.index.branchGlobs, which apply to all repositories, and the globs
+// in search.index.branches for repoName.
+func IndexedBranchGlobs(c *schema.SiteConfiguration, repoName string) []string {
+	if c.ExperimentalFeatures == nil {
+		return nil
+	}
+	globs := c.ExperimentalFeatures.SearchIndexBranchGlobs
+	for _, rev := range c.ExperimentalFeatures.SearchIndexBranches[repoName] {
+		if git.IsBranchGlob(rev) {
+			globs = append(globs[:len(globs):len(globs)], rev)
+		}
+	}
+	return globs
+}
+
+// HasIndexedBranchGlobs returns true if branch globs are configured to be
+// indexed for any repository.
+func HasIndexedBranchGlobs(c *schema.SiteConfiguration) bool {
+	if c.ExperimentalFeatures == nil {
+		return false
+	}
+	if len(c.ExperimentalFeatures.SearchIndexBranchGlobs) > 0 {
+		return true
+	}
+	for _, revs := range c.ExperimentalFeatures.SearchIndexBranches {
+		for _, rev := range revs {
+			if git.IsBranchGlob(rev) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchBranchGlobs returns the branches listed by listBranches which match
+// any of globs.
+func matchBranchGlobs(globs []string, listBranches func() ([]string, error)) ([]string, error) {
+	refGlobs := make([]git.RefGlob, 0, len(globs))
+	for _, g := range globs {
+		refGlobs = append(refGlobs, git.BranchRefGlob(g))
+	}
+	rg, err := git.CompileRefGlobs(refGlobs)
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := listBranches()
+	if err != nil {
+		return nil, err
+	}
+	var matched []string
+	for _, name := range names {
+		if rg.Match("refs/heads/" + name) {
+			matched = append(matched, name)
+		}
+	}
+	return matched, nil
+}
+
 func getBoolPtr(b *bool, default_ bool) bool {
 	if b == nil {
 		return default_
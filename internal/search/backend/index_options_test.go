@@ -190,6 +190,36 @@ func TestGetIndexOptions(t *testing.T) {
 				{Name: "c", Version: "!c"},
 			},
 		},
+	}, {
+		name: "conf index branch globs",
+		conf: withBranches(schema.SiteConfiguration{}, map[string][]string{"repo": {"a", "release/*"}}),
+		repo: "repo",
+		want: zoektIndexOptions{
+			RepoID:  1,
+			Symbols: true,
+			Branches: []zoekt.RepositoryBranch{
+				{Name: "HEAD", Version: "!HEAD"},
+				{Name: "a", Version: "!a"},
+				{Name: "release/1.0", Version: "!release/1.0"},
+				{Name: "release/1.1", Version: "!release/1.1"},
+			},
+		},
+	}, {
+		name: "conf index branch globs for all repos",
+		conf: schema.SiteConfiguration{
+			ExperimentalFeatures: &schema.ExperimentalFeatures{
+				SearchIndexBranchGlobs: []string{"dev-*"},
+			},
+		},
+		repo: "repo",
+		want: zoektIndexOptions{
+			RepoID:  1,
+			Symbols: true,
+			Branches: []zoekt.RepositoryBranch{
+				{Name: "HEAD", Version: "!HEAD"},
+				{Name: "dev-alice", Version: "!dev-alice"},
+			},
+		},
 	}, {
 		name:              "with search context revisions",
 		conf:              schema.SiteConfiguration{},
@@ -243,6 +273,32 @@ func TestGetIndexOptions(t *testing.T) {
 		})
 	}
 
+	{
+		// Generate case for dropping branches matching globs first when
+		// there are more than 64 branches
+		branches := []string{"dev-*"}
+		for i := 0; i < 100; i++ {
+			branches = append(branches, fmt.Sprintf("x%.2d", i))
+		}
+		want := []zoekt.RepositoryBranch{{Name: "HEAD", Version: "!HEAD"}}
+		for i := 0; i < 63; i++ {
+			want = append(want, zoekt.RepositoryBranch{
+				Name:    fmt.Sprintf("x%.2d", i),
+				Version: fmt.Sprintf("!x%.2d", i),
+			})
+		}
+		cases = append(cases, caseT{
+			name: "limit branches drops glob matches first",
+			conf: withBranches(schema.SiteConfiguration{}, map[string][]string{"repo": branches}),
+			repo: "repo",
+			want: zoektIndexOptions{
+				RepoID:   1,
+				Symbols:  true,
+				Branches: want,
+			},
+		})
+	}
+
 	getRepoIndexOptions := func(repo string) (*RepoIndexOptions, error) {
 		repoID := int32(1)
 		for _, r := range []string{"repo", "foo", "not_in_version_context", "priority", "public", "fork", "archived"} {
@@ -264,6 +320,9 @@ func TestGetIndexOptions(t *testing.T) {
 			GetVersion: func(branch string) (string, error) {
 				return "!" + branch, nil
 			},
+			ListBranches: func() ([]string, error) {
+				return []string{"main", "dev-alice", "release/1.0", "release/1.1", "releases"}, nil
+			},
 		}, nil
 	}
 
@@ -417,6 +476,43 @@ func TestGetIndexOptions_batch(t *testing.T) {
 	}
 }
 
+func TestHasIndexedBranchGlobs(t *testing.T) {
+	cases := []struct {
+		name string
+		conf schema.SiteConfiguration
+		want bool
+	}{{
+		name: "default",
+		want: false,
+	}, {
+		name: "branches",
+		conf: schema.SiteConfiguration{ExperimentalFeatures: &schema.ExperimentalFeatures{
+			SearchIndexBranches: map[string][]string{"repo": {"a"}},
+		}},
+		want: false,
+	}, {
+		name: "branch glob for repo",
+		conf: schema.SiteConfiguration{ExperimentalFeatures: &schema.ExperimentalFeatures{
+			SearchIndexBranches: map[string][]string{"repo": {"a", "release/*"}},
+		}},
+		want: true,
+	}, {
+		name: "branch glob for all repos",
+		conf: schema.SiteConfiguration{ExperimentalFeatures: &schema.ExperimentalFeatures{
+			SearchIndexBranchGlobs: []string{"release/*"},
+		}},
+		want: true,
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := HasIndexedBranchGlobs(&tc.conf); got != tc.want {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
 func parseVersionContext(name string, repoRevStrs ...string) *schema.VersionContext {
 	var repoRevs []*schema.VersionContextRevision
 	for _, repo := range repoRevStrs {
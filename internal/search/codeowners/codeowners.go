@@ -0,0 +1,163 @@
+// Package codeowners parses CODEOWNERS files and resolves the owners of files
+// in a repository at a given revision.
+package codeowners
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+)
+
+// Rule associates a CODEOWNERS path pattern with its owners.
+type Rule struct {
+	Pattern string
+	Owners  []string
+
+	re *regexp.Regexp
+}
+
+// Match returns true if the rule's pattern matches path. path is relative to
+// the root of the repository.
+func (r *Rule) Match(path string) bool {
+	return r.re.MatchString(strings.TrimPrefix(path, "/"))
+}
+
+// Ruleset is the parsed representation of a CODEOWNERS file. As with
+// gitignore files, the last matching rule takes precedence.
+type Ruleset struct {
+	Rules []*Rule
+}
+
+// Parse parses the contents of a CODEOWNERS file. Blank lines, comments and
+// GitLab style section headers (e.g. "[Docs]") are ignored.
+func Parse(data []byte) (*Ruleset, error) {
+	rs := &Ruleset{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if i := strings.Index(line, " #"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "[") || strings.HasPrefix(line, "^[") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		re, err := compilePattern(fields[0])
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %d", lineNumber)
+		}
+		rs.Rules = append(rs.Rules, &Rule{
+			Pattern: fields[0],
+			Owners:  fields[1:],
+			re:      re,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return rs, nil
+}
+
+// Match returns the owners of path, or nil if no rule matches it. A matching
+// rule without owners explicitly removes ownership from path.
+func (rs *Ruleset) Match(path string) []string {
+	if rs == nil {
+		return nil
+	}
+	for i := len(rs.Rules) - 1; i >= 0; i-- {
+		if rs.Rules[i].Match(path) {
+			return rs.Rules[i].Owners
+		}
+	}
+	return nil
+}
+
+// HasOwner returns true if owner is one of the owners of path.
+func (rs *Ruleset) HasOwner(path, owner string) bool {
+	return ContainsOwner(rs.Match(path), owner)
+}
+
+// ContainsOwner returns true if owner is in owners. Owners are compared case
+// insensitively, and a leading "@" is optional, so that "team-x" matches
+// "@org/team-x" as well as "@team-x".
+func ContainsOwner(owners []string, owner string) bool {
+	want := normalizeOwner(owner)
+	if want == "" {
+		return false
+	}
+	for _, o := range owners {
+		have := normalizeOwner(o)
+		if have == want {
+			return true
+		}
+		// Allow matching a team by its name without the organization.
+		if i := strings.LastIndexByte(have, '/'); i >= 0 && !strings.Contains(want, "/") && have[i+1:] == want {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeOwner(owner string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(owner), "@"))
+}
+
+// compilePattern converts a gitignore style CODEOWNERS pattern to a regular
+// expression matching paths relative to the repository root.
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	dirOnly := strings.HasSuffix(pattern, "/")
+	trimmed := strings.Trim(pattern, "/")
+	if trimmed == "" {
+		return nil, errors.Errorf("invalid pattern %q", pattern)
+	}
+
+	// A pattern containing a slash anywhere but at its end is relative to the
+	// root of the repository. Otherwise it matches at any depth.
+	anchored := strings.HasPrefix(pattern, "/") || strings.Contains(trimmed, "/")
+
+	var b strings.Builder
+	b.WriteString("^")
+	if !anchored {
+		b.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(trimmed); i++ {
+		switch c := trimmed[i]; c {
+		case '*':
+			if i+1 < len(trimmed) && trimmed[i+1] == '*' {
+				if i+2 < len(trimmed) && trimmed[i+2] == '/' {
+					// "**/" matches zero or more directories.
+					b.WriteString("(?:.*/)?")
+					i += 2
+				} else {
+					b.WriteString(".*")
+					i++
+				}
+				continue
+			}
+			b.WriteString("[^/]*")
+		case '?':
+			b.WriteString("[^/]")
+		case '\\':
+			if i+1 < len(trimmed) {
+				i++
+				b.WriteString(regexp.QuoteMeta(string(trimmed[i])))
+			}
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	if dirOnly {
+		// A trailing slash only matches the contents of a directory.
+		b.WriteString("/.*$")
+	} else {
+		// A pattern matching a directory matches everything beneath it.
+		b.WriteString("(?:/.*)?$")
+	}
+	return regexp.Compile(b.String())
+}
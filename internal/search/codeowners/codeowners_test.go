@@ -0,0 +1,172 @@
+package codeowners
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/search/result"
+	"github.com/sourcegraph/sourcegraph/internal/types"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+)
+
+const testFile = `
+# Default owners
+*                   @org/everyone
+
+/docs/              @org/docs @alice
+*.go                @org/backend # Go code
+/cmd/**/main.go     @bob
+vendor/
+
+[Frontend]
+client/             @org/frontend
+`
+
+func TestRulesetMatch(t *testing.T) {
+	rs, err := Parse([]byte(testFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"README.md", []string{"@org/everyone"}},
+		{"docs/index.md", []string{"@org/docs", "@alice"}},
+		{"docs", []string{"@org/everyone"}},
+		{"internal/search/search.go", []string{"@org/backend"}},
+		{"docs/examples/main.go", []string{"@org/backend"}},
+		{"cmd/frontend/main.go", []string{"@bob"}},
+		{"cmd/main.go", []string{"@bob"}},
+		{"cmd/frontend/main_test.go", []string{"@org/backend"}},
+		{"internal/vendor/lib.go", []string{}},
+		{"client/web/src/index.ts", []string{"@org/frontend"}},
+		{"/client/web/src/index.ts", []string{"@org/frontend"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.path, func(t *testing.T) {
+			got := rs.Match(tc.path)
+			if got == nil {
+				got = []string{}
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Fatalf("unexpected owners (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestContainsOwner(t *testing.T) {
+	owners := []string{"@org/Backend", "@alice", "carol@example.com"}
+
+	tests := []struct {
+		owner string
+		want  bool
+	}{
+		{"@org/backend", true},
+		{"org/backend", true},
+		{"backend", true},
+		{"@backend", true},
+		{"other/backend", false},
+		{"alice", true},
+		{"@ALICE", true},
+		{"bob", false},
+		{"carol@example.com", true},
+		{"", false},
+	}
+
+	for _, tc := range tests {
+		if got := ContainsOwner(owners, tc.owner); got != tc.want {
+			t.Errorf("ContainsOwner(%q) = %v, want %v", tc.owner, got, tc.want)
+		}
+	}
+}
+
+func TestResolverFilter(t *testing.T) {
+	git.Mocks.ReadFile = func(commit api.CommitID, name string) ([]byte, error) {
+		if commit == "c1" && name == ".github/CODEOWNERS" {
+			return []byte("*.go @org/backend\n"), nil
+		}
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	t.Cleanup(git.ResetMocks)
+
+	fileMatch := func(commit api.CommitID, path string) *result.FileMatch {
+		return &result.FileMatch{File: result.File{
+			Repo:     types.RepoName{Name: "github.com/sourcegraph/sourcegraph"},
+			CommitID: commit,
+			Path:     path,
+		}}
+	}
+
+	matches := []result.Match{
+		fileMatch("c1", "main.go"),
+		fileMatch("c1", "README.md"),
+		fileMatch("c2", "main.go"),
+		&result.RepoMatch{Name: "github.com/sourcegraph/sourcegraph"},
+	}
+
+	r := NewResolver()
+	got := r.Filter(context.Background(), matches, "backend")
+	if len(got) != 1 || got[0].(*result.FileMatch).Path != "main.go" || got[0].(*result.FileMatch).CommitID != "c1" {
+		t.Fatalf("unexpected filtered matches: %v", got)
+	}
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The matches of the caller, which other senders of a stream may hold,
+	// are left as they are.
+	if len(matches) != 4 || matches[1].(*result.FileMatch).Path != "README.md" {
+		t.Fatalf("unexpected change to the filtered matches: %v", matches)
+	}
+}
+
+func TestResolverFilterError(t *testing.T) {
+	var reads int
+	git.Mocks.ReadFile = func(commit api.CommitID, name string) ([]byte, error) {
+		reads++
+		if commit == "c1" {
+			return []byte("*.go @org/backend\n"), nil
+		}
+		return nil, errors.New("gitserver unavailable")
+	}
+	t.Cleanup(git.ResetMocks)
+
+	fileMatch := func(repo api.RepoName, commit api.CommitID, path string) *result.FileMatch {
+		return &result.FileMatch{File: result.File{
+			Repo:     types.RepoName{Name: repo},
+			CommitID: commit,
+			Path:     path,
+		}}
+	}
+
+	matches := []result.Match{
+		fileMatch("github.com/sourcegraph/broken", "c2", "a.go"),
+		fileMatch("github.com/sourcegraph/sourcegraph", "c1", "main.go"),
+		fileMatch("github.com/sourcegraph/broken", "c2", "b.go"),
+	}
+
+	// Matches in repositories whose CODEOWNERS file can be read are kept.
+	r := NewResolver()
+	got := r.Filter(context.Background(), matches, "backend")
+	if len(got) != 1 || got[0].(*result.FileMatch).Path != "main.go" {
+		t.Fatalf("unexpected filtered matches: %v", got)
+	}
+
+	// The error is reported once, and the CODEOWNERS file isn't read again.
+	if err := r.Err(); err == nil || !strings.Contains(err.Error(), "1 error occurred") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if reads != 2 {
+		t.Fatalf("got %d reads, want 2", reads)
+	}
+}
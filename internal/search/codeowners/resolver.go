@@ -0,0 +1,175 @@
+package codeowners
+
+import (
+	"context"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+	"github.com/hashicorp/go-multierror"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/search/result"
+	"github.com/sourcegraph/sourcegraph/internal/search/streaming"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+)
+
+// filePaths are the locations searched for a CODEOWNERS file, in order of
+// precedence. They mirror the locations supported by GitHub and GitLab.
+var filePaths = []string{
+	"CODEOWNERS",
+	".github/CODEOWNERS",
+	".gitlab/CODEOWNERS",
+	"docs/CODEOWNERS",
+}
+
+// maxFileSize is the maximum number of bytes read from a CODEOWNERS file.
+const maxFileSize = 3 * 1024 * 1024
+
+type cacheKey struct {
+	repo   api.RepoName
+	commit api.CommitID
+}
+
+// Resolver resolves the owners of files from the CODEOWNERS file of their
+// repository at the searched commit. Rulesets, and the errors fetching them,
+// are cached for the lifetime of the Resolver, which is intended to be a
+// single search request.
+type Resolver struct {
+	mu    sync.Mutex
+	cache map[cacheKey]*Ruleset
+	errs  map[cacheKey]error
+}
+
+func NewResolver() *Resolver {
+	return &Resolver{
+		cache: make(map[cacheKey]*Ruleset),
+		errs:  make(map[cacheKey]error),
+	}
+}
+
+// Ruleset returns the parsed CODEOWNERS file of repo at commit. If the
+// repository has no CODEOWNERS file, an empty Ruleset is returned.
+func (r *Resolver) Ruleset(ctx context.Context, repo api.RepoName, commit api.CommitID) (*Ruleset, error) {
+	key := cacheKey{repo: repo, commit: commit}
+
+	r.mu.Lock()
+	rs, ok := r.cache[key]
+	err := r.errs[key]
+	r.mu.Unlock()
+	if ok || err != nil {
+		return rs, err
+	}
+
+	rs, err = fetchRuleset(ctx, repo, commit)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		if ctx.Err() == nil {
+			r.errs[key] = err
+		}
+		return nil, err
+	}
+	r.cache[key] = rs
+	return rs, nil
+}
+
+// Err returns the errors fetching the CODEOWNERS files of the repositories
+// whose owners were resolved so far, or nil if there were none.
+func (r *Resolver) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	keys := make([]cacheKey, 0, len(r.errs))
+	for key := range r.errs {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].repo != keys[j].repo {
+			return keys[i].repo < keys[j].repo
+		}
+		return keys[i].commit < keys[j].commit
+	})
+
+	var err error
+	for _, key := range keys {
+		err = multierror.Append(err, r.errs[key])
+	}
+	return err
+}
+
+func fetchRuleset(ctx context.Context, repo api.RepoName, commit api.CommitID) (*Ruleset, error) {
+	for _, path := range filePaths {
+		data, err := git.ReadFile(ctx, repo, commit, path, maxFileSize)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading %s in %s@%s", path, repo, commit)
+		}
+		return Parse(data)
+	}
+	return &Ruleset{}, nil
+}
+
+// Owners returns the owners of the file in fm.
+func (r *Resolver) Owners(ctx context.Context, fm *result.FileMatch) ([]string, error) {
+	if fm.CommitID == "" {
+		// We cannot resolve owners without knowing the searched revision.
+		return nil, nil
+	}
+	rs, err := r.Ruleset(ctx, fm.Repo.Name, fm.CommitID)
+	if err != nil {
+		return nil, err
+	}
+	return rs.Match(fm.Path), nil
+}
+
+// Filter returns the file matches in matches whose file is owned by owner.
+// All other match types are dropped, as are file matches whose owners cannot
+// be resolved. The errors resolving owners are reported by Err.
+func (r *Resolver) Filter(ctx context.Context, matches []result.Match, owner string) []result.Match {
+	filtered := make([]result.Match, 0, len(matches))
+	for _, match := range matches {
+		fm, ok := match.(*result.FileMatch)
+		if !ok {
+			continue
+		}
+		owners, err := r.Owners(ctx, fm)
+		if err != nil {
+			continue
+		}
+		if ContainsOwner(owners, owner) {
+			filtered = append(filtered, fm)
+		}
+	}
+	return filtered
+}
+
+// Annotate populates the Owners field of the file matches in matches. File
+// matches whose owners cannot be resolved are left without owners, the errors
+// resolving them are reported by Err.
+func (r *Resolver) Annotate(ctx context.Context, matches []result.Match) {
+	for _, match := range matches {
+		fm, ok := match.(*result.FileMatch)
+		if !ok {
+			continue
+		}
+		owners, err := r.Owners(ctx, fm)
+		if err != nil {
+			continue
+		}
+		fm.Owners = owners
+	}
+}
+
+// WithAnnotation returns a child Stream of parent which populates the owners
+// of file matches before passing events on to parent.
+func WithAnnotation(ctx context.Context, parent streaming.Sender, r *Resolver) streaming.Sender {
+	return streaming.StreamFunc(func(e streaming.SearchEvent) {
+		r.Annotate(ctx, e.Results)
+		parent.Send(e)
+	})
+}
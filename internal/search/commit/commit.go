@@ -228,7 +228,7 @@ func searchCommitsInRepoStream(ctx context.Context, db dbutil.DB, op search.Comm
 	defer cancel()
 
 	// Start the commit search stream.
-	events := git.RawLogDiffSearchStream(ctx, diffParameters.Repo, diffParameters.Options)
+	events := commitSearchStream(ctx, db, &op, diffParameters)
 
 	// Ensure we drain events if we return early (limitHit or error).
 	defer func() {
@@ -13,6 +13,8 @@ import (
 	"github.com/davecgh/go-spew/spew"
 
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
 	"github.com/sourcegraph/sourcegraph/internal/database"
 	"github.com/sourcegraph/sourcegraph/internal/database/dbtesting"
 	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
@@ -22,6 +24,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/internal/search/streaming"
 	"github.com/sourcegraph/sourcegraph/internal/types"
 	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+	"github.com/sourcegraph/sourcegraph/schema"
 )
 
 func TestSearchCommitsInRepo(t *testing.T) {
@@ -108,6 +111,104 @@ func TestSearchCommitsInRepo(t *testing.T) {
 	}
 }
 
+func TestSearchCommitsInRepo_CommitIndex(t *testing.T) {
+	ctx := context.Background()
+	db := new(dbtesting.MockDB)
+	defer resetMocks()
+	defer git.ResetMocks()
+
+	conf.Mock(&conf.Unified{SiteConfiguration: schema.SiteConfiguration{
+		ExperimentalFeatures: &schema.ExperimentalFeatures{SearchCommitIndexRepositories: []string{"repo"}},
+	}})
+	defer conf.Mock(nil)
+
+	date := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	var gotArgs []string
+	git.Mocks.RawLogDiffSearch = func(opt git.RawLogDiffSearchOptions) ([]*git.LogCommitSearchResult, bool, error) {
+		gotArgs = opt.Args
+		return []*git.LogCommitSearchResult{{
+			Commit: git.Commit{ID: "c2", Author: git.Signature{Date: date.Add(time.Hour)}},
+			Diff:   &git.RawDiff{Raw: "x"},
+		}}, true, nil
+	}
+	database.Mocks.CommitIndex.Search = func(_ context.Context, opts database.CommitIndexSearchOptions) ([]*database.IndexedCommit, error) {
+		after := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+		want := database.CommitIndexSearchOptions{
+			RepoID:         1,
+			AuthorPatterns: []string{"alice"},
+			DiffPattern:    "p",
+			Since:          &after,
+			LimitOffset:    &database.LimitOffset{Limit: search.DefaultMaxSearchResults + 1},
+		}
+		if !reflect.DeepEqual(opts, want) {
+			t.Errorf("got index search options %s, want %s", spew.Sdump(opts), spew.Sdump(want))
+		}
+		return []*database.IndexedCommit{
+			{ID: "c1", AuthorDate: date, CommitterDate: date, Diff: "diff --git f f\n--- f\n+++ f\n@@ -0,0 +1 @@\n+p\n"},
+			{ID: "c0", AuthorDate: date, CommitterDate: date, Diff: "diff --git f f\n--- f\n+++ f\n@@ -0,0 +1 @@\n+q\n"},
+		}, nil
+	}
+
+	run := func(indexed bool, repo api.RepoName, rev string, queryString string) []api.CommitID {
+		t.Helper()
+		database.Mocks.CommitIndex.IndexedCommitID = func(context.Context, api.RepoID) (api.CommitID, bool, error) {
+			if indexed {
+				return "c1", true, nil
+			}
+			return "", false, nil
+		}
+		q, err := query.ParseLiteral(queryString)
+		if err != nil {
+			t.Fatal(err)
+		}
+		results, _, _, err := searchCommitsInRepo(ctx, db, search.CommitParameters{
+			RepoRevs: &search.RepositoryRevisions{
+				Repo: types.RepoName{ID: 1, Name: repo},
+				Revs: []search.RevisionSpecifier{{RevSpec: rev}},
+			},
+			PatternInfo: &search.CommitPatternInfo{Pattern: "p", IsRegExp: true, FileMatchLimit: int32(search.DefaultMaxSearchResults)},
+			Query:       q,
+			Diff:        true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []api.CommitID
+		for _, r := range results {
+			ids = append(ids, r.Commit.ID)
+		}
+		return ids
+	}
+
+	// The commits after the indexed commit are searched with git, and the
+	// rest in the index.
+	if got, want := run(true, "repo", "", "p author:alice after:2021-01-01"), []api.CommitID{"c2", "c1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if want := "c1..HEAD"; gotArgs[len(gotArgs)-1] != want {
+		t.Errorf("got git log args %v, want them to end with %q", gotArgs, want)
+	}
+
+	// The index is not used for repositories that are not indexed or not
+	// listed in the site configuration, other revisions, and dates git has to
+	// resolve.
+	for _, tc := range []struct {
+		indexed     bool
+		repo        api.RepoName
+		rev         string
+		queryString string
+	}{
+		{indexed: false, repo: "repo", rev: "", queryString: "p"},
+		{indexed: true, repo: "other", rev: "", queryString: "p"},
+		{indexed: true, repo: "repo", rev: "other", queryString: "p"},
+		{indexed: true, repo: "repo", rev: "", queryString: "p after:yesterday"},
+	} {
+		if got, want := run(tc.indexed, tc.repo, tc.rev, tc.queryString), []api.CommitID{"c2"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("%+v: got %v, want %v", tc, got, want)
+		}
+	}
+}
+
 func resetMocks() {
 	database.Mocks = database.MockStores{}
 	backend.Mocks = backend.MockServices{}
@@ -0,0 +1,203 @@
+package commit
+
+import (
+	"context"
+	"time"
+
+	"github.com/inconshreveable/log15"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/database"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
+	"github.com/sourcegraph/sourcegraph/internal/search"
+	"github.com/sourcegraph/sourcegraph/internal/search/query"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+)
+
+// maxIndexCandidates is the number of indexed commits we check when a query
+// has file filters, which we can only apply after reading the commits. `git
+// log` searches cap the number of commits at the same number in this case.
+const maxIndexCandidates = 500
+
+// commitSearchStream searches the commits of op.RepoRevs. If the repository
+// is in the commit index and the query can be answered from it, only the
+// commits that are not indexed yet are searched with `git log`.
+func commitSearchStream(ctx context.Context, db dbutil.DB, op *search.CommitParameters, diffParameters *search.DiffParameters) <-chan git.LogCommitSearchEvent {
+	if !conf.CommitIndexEnabled(op.RepoRevs.Repo.Name) {
+		return git.RawLogDiffSearchStream(ctx, diffParameters.Repo, diffParameters.Options)
+	}
+
+	indexOpts, ok, err := commitIndexSearchOptions(ctx, db, op)
+	if err == nil && ok {
+		var indexedCommit api.CommitID
+		indexedCommit, ok, err = database.CommitIndex(db).IndexedCommitID(ctx, op.RepoRevs.Repo.ID)
+		indexOpts.RepoID = op.RepoRevs.Repo.ID
+		if err == nil && ok {
+			return indexedCommitSearchStream(ctx, db, diffParameters, indexOpts, indexedCommit)
+		}
+	}
+	if err != nil {
+		log15.Warn("commit search: not using commit index", "repo", op.RepoRevs.Repo.Name, "error", err)
+	}
+	return git.RawLogDiffSearchStream(ctx, diffParameters.Repo, diffParameters.Options)
+}
+
+// indexedCommitSearchStream searches the commits after indexedCommit with
+// `git log`, and then the indexed commits.
+func indexedCommitSearchStream(ctx context.Context, db dbutil.DB, diffParameters *search.DiffParameters, indexOpts database.CommitIndexSearchOptions, indexedCommit api.CommitID) <-chan git.LogCommitSearchEvent {
+	c := make(chan git.LogCommitSearchEvent)
+	go func() {
+		defer close(c)
+
+		// The commits that are not indexed yet are the most recent ones, so
+		// we search them first.
+		opts := diffParameters.Options
+		opts.Args = append(append([]string{}, opts.Args...), string(indexedCommit)+"..HEAD")
+		complete := true
+		for event := range git.RawLogDiffSearchStream(ctx, diffParameters.Repo, opts) {
+			complete = complete && event.Complete
+			if event.Error != nil {
+				c <- event
+				return
+			}
+			if len(event.Results) > 0 {
+				c <- event
+			}
+		}
+
+		results, err := searchCommitIndex(ctx, db, diffParameters.Options, indexOpts)
+		c <- git.LogCommitSearchEvent{Results: results, Complete: complete, Error: err}
+	}()
+	return c
+}
+
+// searchCommitIndex returns the indexed commits that match opts, filtering
+// and highlighting their diffs like RawLogDiffSearch does.
+func searchCommitIndex(ctx context.Context, db dbutil.DB, opts git.RawLogDiffSearchOptions, indexOpts database.CommitIndexSearchOptions) ([]*git.LogCommitSearchResult, error) {
+	commits, err := database.CommitIndex(db).Search(ctx, indexOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	hasPathFilters := opts.Paths.ExcludePattern != "" || len(opts.Paths.IncludePatterns) > 0
+	results := make([]*git.LogCommitSearchResult, 0, len(commits))
+	for _, c := range commits {
+		res := &git.LogCommitSearchResult{Commit: indexedCommitToCommit(c)}
+		if opts.Diff || hasPathFilters {
+			var err error
+			res.Diff, res.DiffHighlights, err = git.FilterDiff(c.Diff, opts)
+			if err != nil {
+				return nil, err
+			}
+			if res.Diff == nil {
+				continue
+			}
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+func indexedCommitToCommit(c *database.IndexedCommit) git.Commit {
+	parents := make([]api.CommitID, 0, len(c.Parents))
+	for _, p := range c.Parents {
+		parents = append(parents, api.CommitID(p))
+	}
+	return git.Commit{
+		ID:        c.ID,
+		Author:    git.Signature{Name: c.AuthorName, Email: c.AuthorEmail, Date: c.AuthorDate},
+		Committer: &git.Signature{Name: c.CommitterName, Email: c.CommitterEmail, Date: c.CommitterDate},
+		Message:   git.Message(c.Message),
+		Parents:   parents,
+	}
+}
+
+// commitIndexSearchOptions returns the options to search the commit index
+// for op. It returns false if the commit index can't answer op, for example
+// because it searches other revisions than the default branch.
+func commitIndexSearchOptions(ctx context.Context, db dbutil.DB, op *search.CommitParameters) (database.CommitIndexSearchOptions, bool, error) {
+	var opts database.CommitIndexSearchOptions
+
+	// Only the default branch is indexed.
+	if revs := op.RepoRevs.Revs; len(revs) != 1 || revs[0] != (search.RevisionSpecifier{}) && revs[0] != (search.RevisionSpecifier{RevSpec: "HEAD"}) {
+		return opts, false, nil
+	}
+	// The index matches POSIX regular expressions, like `git log
+	// --extended-regexp`.
+	if !op.PatternInfo.IsRegExp {
+		return opts, false, nil
+	}
+
+	var ok bool
+	if opts.Since, ok = indexDateValue(op.Query, query.FieldAfter); !ok {
+		return opts, false, nil
+	}
+	if opts.Until, ok = indexDateValue(op.Query, query.FieldBefore); !ok {
+		return opts, false, nil
+	}
+
+	messages, minusMessages := op.Query.RegexpPatterns(query.FieldMessage)
+	authors, minusAuthors := op.Query.RegexpPatterns(query.FieldAuthor)
+	committers, minusCommitters := op.Query.RegexpPatterns(query.FieldCommitter)
+	if len(minusMessages) > 0 || len(minusAuthors) > 0 || len(minusCommitters) > 0 {
+		return opts, false, nil
+	}
+	if len(authors) > 0 {
+		var err error
+		if opts.AuthorPatterns, err = expandUsernamesToEmails(ctx, db, authors); err != nil {
+			return opts, false, err
+		}
+	}
+	if len(committers) > 0 {
+		var err error
+		if opts.CommitterPatterns, err = expandUsernamesToEmails(ctx, db, committers); err != nil {
+			return opts, false, err
+		}
+	}
+	opts.MessagePatterns = append(messages, op.ExtraMessageValues...)
+
+	if op.Diff {
+		opts.DiffPattern = op.PatternInfo.Pattern
+	}
+	opts.IsCaseSensitive = op.Query.IsCaseSensitive()
+
+	limit := int(op.PatternInfo.FileMatchLimit) + 1
+	if op.PatternInfo.ExcludePattern != "" || len(op.PatternInfo.IncludePatterns) > 0 {
+		if limit < maxIndexCandidates {
+			limit = maxIndexCandidates
+		}
+	}
+	opts.LimitOffset = &database.LimitOffset{Limit: limit}
+
+	return opts, true, nil
+}
+
+// indexDateLayouts are the before: and after: values we can resolve without
+// git. Others, like "last week", are resolved by `git log`.
+var indexDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// indexDateValue returns the date of the field in q, or nil if q has no such
+// field. It returns false if the field has a value we can't parse, or more
+// than one.
+func indexDateValue(q query.Q, field string) (*time.Time, bool) {
+	values, _ := q.StringValues(field)
+	switch len(values) {
+	case 0:
+		return nil, true
+	case 1:
+	default:
+		return nil, false
+	}
+	for _, layout := range indexDateLayouts {
+		if t, err := time.Parse(layout, values[0]); err == nil {
+			return &t, true
+		}
+	}
+	return nil, false
+}
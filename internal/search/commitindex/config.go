@@ -0,0 +1,23 @@
+package commitindex
+
+import (
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/internal/env"
+)
+
+type config struct {
+	env.BaseConfig
+
+	Interval       time.Duration
+	ReposPerRun    int
+	CommitsPerRepo int
+}
+
+var configInst = &config{}
+
+func (c *config) Load() {
+	c.Interval = c.GetInterval("SEARCH_COMMIT_INDEX_INTERVAL", "1m", "How often to update the commit index of repositories.")
+	c.ReposPerRun = c.GetInt("SEARCH_COMMIT_INDEX_REPOS_PER_RUN", "50", "The number of repositories whose commit index is updated per run, least recently updated first.")
+	c.CommitsPerRepo = c.GetInt("SEARCH_COMMIT_INDEX_COMMITS_PER_REPO", "1000", "The maximum number of commits of a repository to add to the commit index per run.")
+}
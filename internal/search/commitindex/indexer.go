@@ -0,0 +1,129 @@
+// Package commitindex maintains the commit index, which commit and diff
+// searches consult before running `git log` on gitserver.
+package commitindex
+
+import (
+	"context"
+
+	"github.com/cockroachdb/errors"
+	"github.com/inconshreveable/log15"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/database"
+	"github.com/sourcegraph/sourcegraph/internal/types"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+)
+
+// Store is the subset of *database.CommitIndexStore the Indexer uses.
+type Store interface {
+	ReposToIndex(ctx context.Context, names []api.RepoName, limit int) ([]types.RepoName, error)
+	IndexedCommitID(ctx context.Context, repoID api.RepoID) (api.CommitID, bool, error)
+	Add(ctx context.Context, repoID api.RepoID, commits []*database.IndexedCommit, indexedCommitID api.CommitID) error
+	Touch(ctx context.Context, repoID api.RepoID) error
+	Reset(ctx context.Context, repoID api.RepoID) error
+}
+
+// Indexer adds the new commits of the default branch of repositories to the
+// commit index. Each run indexes at most CommitsPerRepo of the oldest
+// un-indexed commits of each repository, so large repositories are indexed
+// over several runs.
+type Indexer struct {
+	Store          Store
+	ReposPerRun    int
+	CommitsPerRepo int
+
+	// Repos returns the names of the repositories to index. Nothing is
+	// indexed if it returns none.
+	Repos func() []api.RepoName
+}
+
+// Handle updates the index of the repositories that were indexed least
+// recently.
+func (i *Indexer) Handle(ctx context.Context) error {
+	names := i.Repos()
+	if len(names) == 0 {
+		return nil
+	}
+
+	repos, err := i.Store.ReposToIndex(ctx, names, i.ReposPerRun)
+	if err != nil {
+		return errors.Wrap(err, "listing repositories to index")
+	}
+
+	for _, repo := range repos {
+		if err := i.IndexRepo(ctx, repo); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			// Don't let one repository block indexing of the others.
+			log15.Warn("commitindex: failed to index repository", "repo", repo.Name, "error", err)
+		}
+	}
+	return nil
+}
+
+// IndexRepo adds the next batch of un-indexed commits of repo to the index.
+func (i *Indexer) IndexRepo(ctx context.Context, repo types.RepoName) error {
+	since, ok, err := i.Store.IndexedCommitID(ctx, repo.ID)
+	if err != nil {
+		return err
+	}
+	if ok {
+		reachable, err := git.IsReachableFromHEAD(ctx, repo.Name, since)
+		if err != nil {
+			return err
+		}
+		if !reachable {
+			// The default branch was force-pushed or changed. We can't tell
+			// which indexed commits are still on it, so start over.
+			if err := i.Store.Reset(ctx, repo.ID); err != nil {
+				return err
+			}
+			since = ""
+		}
+	}
+
+	until, err := git.NextCommitBoundary(ctx, repo.Name, since, i.CommitsPerRepo)
+	if err != nil {
+		return err
+	}
+	if until == "" {
+		// Nothing new. Let other repositories go first next time.
+		return i.Store.Touch(ctx, repo.ID)
+	}
+
+	diffs, err := git.CommitDiffs(ctx, repo.Name, since, until)
+	if err != nil {
+		return err
+	}
+	commits := make([]*database.IndexedCommit, 0, len(diffs))
+	for _, d := range diffs {
+		commits = append(commits, toIndexedCommit(d))
+	}
+	return i.Store.Add(ctx, repo.ID, commits, until)
+}
+
+func toIndexedCommit(d *git.CommitDiff) *database.IndexedCommit {
+	c := &database.IndexedCommit{
+		ID:          d.Commit.ID,
+		AuthorName:  d.Commit.Author.Name,
+		AuthorEmail: d.Commit.Author.Email,
+		AuthorDate:  d.Commit.Author.Date,
+		Message:     string(d.Commit.Message),
+		Parents:     make([]string, 0, len(d.Commit.Parents)),
+		Paths:       d.Paths,
+		Diff:        d.Diff,
+	}
+	if committer := d.Commit.Committer; committer != nil {
+		c.CommitterName = committer.Name
+		c.CommitterEmail = committer.Email
+		c.CommitterDate = committer.Date
+	}
+	for _, p := range d.Commit.Parents {
+		c.Parents = append(c.Parents, string(p))
+	}
+	if c.Paths == nil {
+		c.Paths = []string{}
+	}
+	return c
+}
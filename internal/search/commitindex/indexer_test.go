@@ -0,0 +1,150 @@
+package commitindex
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/database"
+	"github.com/sourcegraph/sourcegraph/internal/types"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+)
+
+type fakeStore struct {
+	indexed map[api.RepoID]api.CommitID
+	commits map[api.RepoID][]api.CommitID
+	touched []api.RepoID
+	listed  [][]api.RepoName
+}
+
+func (s *fakeStore) ReposToIndex(ctx context.Context, names []api.RepoName, limit int) ([]types.RepoName, error) {
+	s.listed = append(s.listed, names)
+	return nil, nil
+}
+
+func (s *fakeStore) IndexedCommitID(ctx context.Context, repoID api.RepoID) (api.CommitID, bool, error) {
+	commitID, ok := s.indexed[repoID]
+	return commitID, ok, nil
+}
+
+func (s *fakeStore) Add(ctx context.Context, repoID api.RepoID, commits []*database.IndexedCommit, indexedCommitID api.CommitID) error {
+	for _, c := range commits {
+		s.commits[repoID] = append(s.commits[repoID], c.ID)
+	}
+	s.indexed[repoID] = indexedCommitID
+	return nil
+}
+
+func (s *fakeStore) Touch(ctx context.Context, repoID api.RepoID) error {
+	s.touched = append(s.touched, repoID)
+	return nil
+}
+
+func (s *fakeStore) Reset(ctx context.Context, repoID api.RepoID) error {
+	delete(s.indexed, repoID)
+	delete(s.commits, repoID)
+	return nil
+}
+
+func TestIndexer_IndexRepo(t *testing.T) {
+	defer git.ResetMocks()
+
+	// The default branch is c3 -> c2 -> c1, oldest first.
+	history := []api.CommitID{"c1", "c2", "c3"}
+	git.Mocks.IsReachableFromHEAD = func(repo api.RepoName, commit api.CommitID) (bool, error) {
+		for _, c := range history {
+			if c == commit {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	position := func(commit api.CommitID) int {
+		for i, c := range history {
+			if c == commit {
+				return i + 1
+			}
+		}
+		return 0
+	}
+	git.Mocks.NextCommitBoundary = func(repo api.RepoName, since api.CommitID, maxCommits int) (api.CommitID, error) {
+		next := position(since) + maxCommits
+		if next > len(history) {
+			next = len(history)
+		}
+		if next == position(since) {
+			return "", nil
+		}
+		return history[next-1], nil
+	}
+	git.Mocks.CommitDiffs = func(repo api.RepoName, since, until api.CommitID) ([]*git.CommitDiff, error) {
+		var diffs []*git.CommitDiff
+		for i := position(until) - 1; i >= position(since); i-- {
+			diffs = append(diffs, &git.CommitDiff{
+				Commit: git.Commit{
+					ID:        history[i],
+					Author:    git.Signature{Name: "a", Date: time.Unix(int64(i), 0)},
+					Committer: &git.Signature{Name: "a", Date: time.Unix(int64(i), 0)},
+				},
+			})
+		}
+		return diffs, nil
+	}
+
+	store := &fakeStore{
+		indexed: map[api.RepoID]api.CommitID{},
+		commits: map[api.RepoID][]api.CommitID{},
+	}
+	indexer := &Indexer{Store: store, CommitsPerRepo: 2}
+	repo := types.RepoName{ID: 1, Name: "r"}
+	ctx := context.Background()
+
+	for _, want := range [][]api.CommitID{
+		{"c2", "c1"},
+		{"c2", "c1", "c3"},
+		{"c2", "c1", "c3"},
+	} {
+		if err := indexer.IndexRepo(ctx, repo); err != nil {
+			t.Fatal(err)
+		}
+		if got := store.commits[repo.ID]; !reflect.DeepEqual(got, want) {
+			t.Fatalf("got indexed commits %v, want %v", got, want)
+		}
+	}
+	if want := []api.RepoID{1}; !reflect.DeepEqual(store.touched, want) {
+		t.Errorf("got touched repos %v, want %v", store.touched, want)
+	}
+
+	// A force-push replaces the history, so the index starts over.
+	history = []api.CommitID{"d1"}
+	if err := indexer.IndexRepo(ctx, repo); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := store.commits[repo.ID], []api.CommitID{"d1"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got indexed commits %v, want %v", got, want)
+	}
+}
+
+func TestIndexer_Handle(t *testing.T) {
+	store := &fakeStore{}
+	var repos []api.RepoName
+	indexer := &Indexer{Store: store, ReposPerRun: 10, Repos: func() []api.RepoName { return repos }}
+
+	// Without repositories to index, the indexer is a no-op.
+	if err := indexer.Handle(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.listed) != 0 {
+		t.Fatalf("got %d calls to ReposToIndex, want none", len(store.listed))
+	}
+
+	repos = []api.RepoName{"a", "b"}
+	if err := indexer.Handle(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]api.RepoName{{"a", "b"}}; !reflect.DeepEqual(store.listed, want) {
+		t.Fatalf("got ReposToIndex calls %v, want %v", store.listed, want)
+	}
+}
@@ -0,0 +1,43 @@
+package commitindex
+
+import (
+	"context"
+
+	"github.com/sourcegraph/sourcegraph/cmd/worker/shared"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/database"
+	"github.com/sourcegraph/sourcegraph/internal/env"
+	"github.com/sourcegraph/sourcegraph/internal/goroutine"
+)
+
+// NewIndexingJob returns the worker job that keeps the commit index of
+// repositories up to date.
+func NewIndexingJob() shared.Job {
+	return &indexingJob{}
+}
+
+type indexingJob struct{}
+
+func (j *indexingJob) Config() []env.Config {
+	return []env.Config{configInst}
+}
+
+func (j *indexingJob) Routines(_ context.Context) ([]goroutine.BackgroundRoutine, error) {
+	db, err := shared.InitDatabase()
+	if err != nil {
+		return nil, err
+	}
+
+	indexer := &Indexer{
+		Store:          database.CommitIndex(db),
+		ReposPerRun:    configInst.ReposPerRun,
+		CommitsPerRepo: configInst.CommitsPerRepo,
+		Repos:          conf.CommitIndexRepos,
+	}
+	handler := goroutine.NewHandlerWithErrorMessage("update commit index", indexer.Handle)
+
+	return []goroutine.BackgroundRoutine{
+		// Pass a fresh context, see docs for shared.Job
+		goroutine.NewPeriodicGoroutine(context.Background(), configInst.Interval, handler),
+	}, nil
+}
@@ -0,0 +1,258 @@
+package search
+
+import (
+	"math"
+	"regexp/syntax"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/internal/lazyregexp"
+	"github.com/sourcegraph/sourcegraph/internal/search/query"
+)
+
+// QueryCost is an approximation of the work a search query causes, in units
+// of searching the default branch of one indexed repository for a selective
+// pattern.
+type QueryCost int
+
+// The cost of searching one repository, before applying the factors for
+// pattern selectivity, result count and time ranges.
+const (
+	IndexedRepoCost   QueryCost = 1
+	UnindexedRepoCost QueryCost = 10
+	CommitRepoCost    QueryCost = 10
+	DiffRepoCost      QueryCost = 20
+)
+
+var timeNow = time.Now
+
+// EstimateQueryCost estimates the cost of searching b over repoCount
+// repositories.
+func EstimateQueryCost(b query.Basic, repoCount int) QueryCost {
+	cost := float64(repoCost(b)) * float64(repoCount)
+	cost *= patternFactor(b)
+	cost *= countFactor(b)
+	return QueryCost(math.Ceil(cost))
+}
+
+// repoCost returns the cost of searching one repository for each of the
+// result types b asks for.
+func repoCost(b query.Basic) QueryCost {
+	parameters := query.ToNodes(b.Parameters)
+
+	var types []string
+	query.VisitField(parameters, query.FieldType, func(value string, _ bool, _ query.Annotation) {
+		types = append(types, value)
+	})
+	if len(types) == 0 {
+		types = []string{"file"}
+	}
+
+	var cost QueryCost
+	for _, typ := range types {
+		switch typ {
+		case "repo":
+			// Repository names are searched in the database.
+		case "commit":
+			cost += QueryCost(math.Ceil(float64(CommitRepoCost) * timeRangeFactor(b)))
+		case "diff":
+			cost += QueryCost(math.Ceil(float64(DiffRepoCost) * timeRangeFactor(b)))
+		default:
+			if isUnindexed(b) {
+				cost += UnindexedRepoCost
+			} else {
+				cost += IndexedRepoCost
+			}
+		}
+	}
+	return cost
+}
+
+// isUnindexed returns true if b searches files with searcher rather than
+// Zoekt, because it asks for it or searches revisions other than the default
+// branch. Configured branches may be indexed too, but we don't know which.
+func isUnindexed(b query.Basic) bool {
+	if b.Index() == query.No {
+		return true
+	}
+	unindexed := false
+	query.VisitField(query.ToNodes(b.Parameters), query.FieldRepo, func(value string, negated bool, _ query.Annotation) {
+		if i := strings.Index(value, "@"); i >= 0 && !negated {
+			for _, rev := range strings.Split(value[i+1:], ":") {
+				if rev != "" && rev != "HEAD" {
+					unindexed = true
+				}
+			}
+		}
+	})
+	return unindexed
+}
+
+// patternFactor estimates how much work searching for the pattern of b is,
+// compared to a pattern with a literal of at least 3 characters. Zoekt
+// can't use its trigram index to find candidate documents for shorter
+// literals or negated patterns, and structural search runs comby over every
+// candidate file.
+func patternFactor(b query.Basic) float64 {
+	if b.Pattern == nil {
+		return 1
+	}
+	factor := 1.0
+	query.VisitPattern([]query.Node{b.Pattern}, func(value string, negated bool, annotation query.Annotation) {
+		f := 1.0
+		switch {
+		case annotation.Labels.IsSet(query.Structural):
+			f = 10
+		case negated:
+			f = 4
+		case value == "":
+		case annotation.Labels.IsSet(query.Regexp):
+			if literalLength(value) < 3 {
+				f = 4
+			}
+		case len(value) < 3:
+			f = 4
+		}
+		if f > factor {
+			factor = f
+		}
+	})
+	return factor
+}
+
+// literalLength returns the length of the longest literal that every match
+// of pattern must contain.
+func literalLength(pattern string) int {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return 0
+	}
+	var walk func(re *syntax.Regexp) int
+	walk = func(re *syntax.Regexp) int {
+		switch re.Op {
+		case syntax.OpLiteral:
+			return len(re.Rune)
+		case syntax.OpCapture, syntax.OpPlus:
+			return walk(re.Sub[0])
+		case syntax.OpRepeat:
+			if re.Min == 0 {
+				return 0
+			}
+			return walk(re.Sub[0])
+		case syntax.OpConcat:
+			// Adjacent literals are usually parsed into one, so the longest
+			// one is a good enough approximation.
+			longest := 0
+			for _, sub := range re.Sub {
+				if n := walk(sub); n > longest {
+					longest = n
+				}
+			}
+			return longest
+		case syntax.OpAlternate:
+			shortest := math.MaxInt32
+			for _, sub := range re.Sub {
+				if n := walk(sub); n < shortest {
+					shortest = n
+				}
+			}
+			return shortest
+		default:
+			return 0
+		}
+	}
+	return walk(re.Simplify())
+}
+
+// countFactor estimates how much more work it is to find the number of
+// results b asks for with count:, compared to the default limit. Searches
+// stop early once they find enough results, so the factor grows
+// logarithmically.
+func countFactor(b query.Basic) float64 {
+	count, err := strconv.Atoi(b.GetCount())
+	if err != nil || count <= DefaultMaxSearchResultsStreaming {
+		return 1
+	}
+	return 1 + math.Log2(float64(count)/DefaultMaxSearchResultsStreaming)
+}
+
+// timeRangeFactor estimates which fraction of the history of a repository
+// commit and diff searches look at, based on the after: and before: values
+// of b. We assume a history of a year, which is a reasonable upper bound of
+// what such searches cover before they find enough results.
+func timeRangeFactor(b query.Basic) float64 {
+	parameters := query.ToNodes(b.Parameters)
+	after, _ := query.Q(parameters).StringValue(query.FieldAfter)
+	before, _ := query.Q(parameters).StringValue(query.FieldBefore)
+	if after == "" && before == "" {
+		return 1
+	}
+
+	now := timeNow()
+	yearAgo := now.AddDate(-1, 0, 0)
+	since, until := yearAgo, now
+	// `git log` understands more formats than we do. If we can't parse a
+	// value, assume a narrow range, since that's why people specify one.
+	if after != "" {
+		t, ok := parseTimeValue(after, now)
+		if !ok {
+			return 0.25
+		}
+		if t.After(since) {
+			since = t
+		}
+	}
+	if before != "" {
+		t, ok := parseTimeValue(before, now)
+		if !ok {
+			return 0.25
+		}
+		if t.Before(until) {
+			until = t
+		}
+	}
+
+	factor := until.Sub(since).Hours() / now.Sub(yearAgo).Hours()
+	return math.Max(0.05, math.Min(factor, 1))
+}
+
+var (
+	timeValueLayouts = []string{
+		time.RFC3339,
+		"2006-01-02T15:04:05",
+		"2006-01-02 15:04:05",
+		"2006-01-02",
+	}
+	relativeTimeValue = lazyregexp.New(`^(?:(\d+|an?|last) )?(day|week|month|year)s?(?: ago)?$`)
+)
+
+// parseTimeValue parses the common absolute and relative after: and before:
+// values, such as "2021-06-01" and "2 weeks ago".
+func parseTimeValue(value string, now time.Time) (time.Time, bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	for _, layout := range timeValueLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+
+	m := relativeTimeValue.FindStringSubmatch(value)
+	if m == nil {
+		return time.Time{}, false
+	}
+	n := 1
+	if v, err := strconv.Atoi(m[1]); err == nil {
+		n = v
+	}
+	switch m[2] {
+	case "day":
+		return now.AddDate(0, 0, -n), true
+	case "week":
+		return now.AddDate(0, 0, -7*n), true
+	case "month":
+		return now.AddDate(0, -n, 0), true
+	default:
+		return now.AddDate(-n, 0, 0), true
+	}
+}
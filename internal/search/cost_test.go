@@ -0,0 +1,53 @@
+package search
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/internal/search/query"
+)
+
+func TestEstimateQueryCost(t *testing.T) {
+	now := time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	cases := []struct {
+		query      string
+		searchType query.SearchType
+		repos      int
+		want       QueryCost
+	}{
+		{query: "foobar", searchType: query.SearchTypeLiteral, repos: 100, want: 100},
+		{query: "fo", searchType: query.SearchTypeLiteral, repos: 100, want: 400},
+		{query: "foo.*bar", searchType: query.SearchTypeRegex, repos: 100, want: 100},
+		{query: ".*", searchType: query.SearchTypeRegex, repos: 100, want: 400},
+		{query: "(ab|cd)", searchType: query.SearchTypeRegex, repos: 100, want: 400},
+		{query: "foo NOT bar", searchType: query.SearchTypeRegex, repos: 100, want: 400},
+		{query: "fmt.Println(:[x])", searchType: query.SearchTypeStructural, repos: 100, want: 1000},
+		{query: "foobar index:no", searchType: query.SearchTypeLiteral, repos: 100, want: 1000},
+		{query: "repo:foo@dev foobar", searchType: query.SearchTypeLiteral, repos: 1, want: 10},
+		{query: "repo:foo@HEAD foobar", searchType: query.SearchTypeLiteral, repos: 1, want: 1},
+		{query: "foobar count:1000", searchType: query.SearchTypeLiteral, repos: 100, want: 200},
+		{query: "foobar count:all", searchType: query.SearchTypeLiteral, repos: 100, want: 1861},
+		{query: "type:repo foo", searchType: query.SearchTypeLiteral, repos: 100, want: 0},
+		{query: "type:commit foobar", searchType: query.SearchTypeLiteral, repos: 10, want: 100},
+		{query: "type:diff foobar", searchType: query.SearchTypeLiteral, repos: 10, want: 200},
+		{query: "type:diff after:2021-09-01 foobar", searchType: query.SearchTypeLiteral, repos: 10, want: 20},
+		{query: `type:diff after:"3 months ago" foobar`, searchType: query.SearchTypeLiteral, repos: 10, want: 60},
+		{query: `type:diff after:"last thursday" foobar`, searchType: query.SearchTypeLiteral, repos: 10, want: 50},
+		{query: "type:diff before:2019-01-01 foobar", searchType: query.SearchTypeLiteral, repos: 10, want: 10},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.query, func(t *testing.T) {
+			plan, err := query.Pipeline(query.Init(tc.query, tc.searchType))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := EstimateQueryCost(plan[0], tc.repos); got != tc.want {
+				t.Errorf("got cost %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
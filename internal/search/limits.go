@@ -40,5 +40,11 @@ func SearchLimits(c *conf.Unified) schema.SearchLimits {
 	withDefault(&limits.CommitDiffWithTimeFilterMaxRepos, 10000)
 	withDefault(&limits.MaxTimeoutSeconds, 60)
 
+	if limits.QueryCost != nil {
+		queryCost := *limits.QueryCost
+		withDefault(&queryCost.MaxConcurrentExpensive, 1)
+		limits.QueryCost = &queryCost
+	}
+
 	return limits
 }
@@ -0,0 +1,179 @@
+// Package proximity evaluates NEAR/n and BEFORE/n expressions over the lines
+// of a file. Backends first search for lines matching any operand of an
+// expression, and then use a Matcher to only keep the lines which are part of
+// a match of the whole expression.
+package proximity
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+)
+
+// Pattern is a proximity expression. It matches if all Patterns match within
+// Distance lines of each other.
+type Pattern struct {
+	// Patterns are the regular expressions of the operands.
+	Patterns []string
+
+	// Distance is the maximum number of lines between the first and the last
+	// operand of a match. A distance of 0 requires all operands to match on
+	// the same line.
+	Distance int
+
+	// Ordered is true if operands must match in the order of Patterns, as
+	// for BEFORE/n.
+	Ordered bool
+
+	// CaseSensitive is true if Patterns are matched case sensitively.
+	CaseSensitive bool
+}
+
+// Regexp returns a regular expression matching any of the operands of p. It
+// is used to search for candidate lines, which are then filtered by a Matcher.
+func (p *Pattern) Regexp() string {
+	parts := make([]string, 0, len(p.Patterns))
+	for _, pattern := range p.Patterns {
+		parts = append(parts, "(?:"+pattern+")")
+	}
+	return strings.Join(parts, "|")
+}
+
+// Line is a line of a file containing candidate matches.
+type Line struct {
+	// Number is the 0-based line number.
+	Number int
+
+	// Preview is the content of the line.
+	Preview string
+}
+
+// Matcher matches a compiled Pattern against lines. It is safe for concurrent
+// use.
+type Matcher struct {
+	operands []*regexp.Regexp
+	distance int
+	ordered  bool
+}
+
+// Compile compiles p into a Matcher.
+func Compile(p *Pattern) (*Matcher, error) {
+	if len(p.Patterns) < 2 {
+		return nil, errors.New("a proximity expression requires at least two patterns")
+	}
+	m := &Matcher{distance: p.Distance, ordered: p.Ordered}
+	for _, pattern := range p.Patterns {
+		if !p.CaseSensitive {
+			pattern = "(?i:" + pattern + ")"
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		m.operands = append(m.operands, re)
+	}
+	return m, nil
+}
+
+type occurrence struct {
+	line       int // index into the lines passed to Filter
+	number     int
+	start, end int
+	operand    int
+}
+
+// Filter returns the indexes of lines which are part of a match of m, in
+// ascending order. The returned slice is empty if lines do not match m.
+func (m *Matcher) Filter(lines []Line) []int {
+	var occs []occurrence
+	for i, line := range lines {
+		for k, re := range m.operands {
+			for _, loc := range re.FindAllStringIndex(line.Preview, -1) {
+				occs = append(occs, occurrence{line: i, number: line.Number, start: loc[0], end: loc[1], operand: k})
+			}
+		}
+	}
+	sort.SliceStable(occs, func(i, j int) bool {
+		if occs[i].number != occs[j].number {
+			return occs[i].number < occs[j].number
+		}
+		return occs[i].start < occs[j].start
+	})
+
+	keep := make([]bool, len(lines))
+	if m.ordered {
+		m.matchOrdered(occs, keep)
+	} else {
+		m.matchUnordered(occs, keep)
+	}
+
+	var indexes []int
+	for i, ok := range keep {
+		if ok {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// matchUnordered slides a window of m.distance lines over occs and keeps the
+// lines of every window containing all operands.
+func (m *Matcher) matchUnordered(occs []occurrence, keep []bool) {
+	counts := make([]int, len(m.operands))
+	covered := 0
+	marked := 0
+	r := 0
+	for l := range occs {
+		for r < len(occs) && occs[r].number-occs[l].number <= m.distance {
+			if counts[occs[r].operand] == 0 {
+				covered++
+			}
+			counts[occs[r].operand]++
+			r++
+		}
+		if covered == len(m.operands) {
+			if marked < l {
+				marked = l
+			}
+			for ; marked < r; marked++ {
+				keep[occs[marked].line] = true
+			}
+		}
+		counts[occs[l].operand]--
+		if counts[occs[l].operand] == 0 {
+			covered--
+		}
+	}
+}
+
+// matchOrdered keeps the lines of the earliest chain of occurrences matching
+// all operands in order starting at each occurrence of the first operand.
+func (m *Matcher) matchOrdered(occs []occurrence, keep []bool) {
+	for i, first := range occs {
+		if first.operand != 0 {
+			continue
+		}
+		chain := []int{i}
+		prev := first
+		for k := 1; k < len(m.operands); k++ {
+			next := -1
+			for j := chain[len(chain)-1] + 1; j < len(occs) && occs[j].number-first.number <= m.distance; j++ {
+				if occs[j].operand == k && (occs[j].number > prev.number || occs[j].start >= prev.end) {
+					next = j
+					break
+				}
+			}
+			if next < 0 {
+				chain = nil
+				break
+			}
+			chain = append(chain, next)
+			prev = occs[next]
+		}
+		for _, j := range chain {
+			keep[occs[j].line] = true
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package proximity
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMatcherFilter(t *testing.T) {
+	lines := []Line{
+		{Number: 1, Preview: "func main() {"},
+		{Number: 2, Preview: "	defer cleanup()"},
+		{Number: 4, Preview: "	log.Fatal(err)"},
+		{Number: 10, Preview: "	log.Println(Err)"},
+		{Number: 20, Preview: "	err := run(); defer cleanup()"},
+	}
+
+	tests := []struct {
+		name    string
+		pattern Pattern
+		want    []int
+	}{{
+		name:    "near within distance",
+		pattern: Pattern{Patterns: []string{"defer", "log"}, Distance: 2},
+		want:    []int{1, 2},
+	}, {
+		name:    "near out of distance",
+		pattern: Pattern{Patterns: []string{"func", "log"}, Distance: 2},
+		want:    nil,
+	}, {
+		name:    "near is unordered",
+		pattern: Pattern{Patterns: []string{"log", "func"}, Distance: 3},
+		want:    []int{0, 2},
+	}, {
+		name:    "near on same line",
+		pattern: Pattern{Patterns: []string{"defer", "err"}, Distance: 0},
+		want:    []int{4},
+	}, {
+		name:    "before in order",
+		pattern: Pattern{Patterns: []string{"err", "defer"}, Distance: 0, Ordered: true},
+		want:    []int{4},
+	}, {
+		name:    "before out of order",
+		pattern: Pattern{Patterns: []string{"defer", "log"}, Distance: 5, Ordered: true},
+		want:    []int{1, 2},
+	}, {
+		name:    "before rejects reverse order",
+		pattern: Pattern{Patterns: []string{"log", "defer"}, Distance: 5, Ordered: true},
+		want:    nil,
+	}, {
+		name:    "chain of three",
+		pattern: Pattern{Patterns: []string{"func", "defer", "Fatal"}, Distance: 3, Ordered: true},
+		want:    []int{0, 1, 2},
+	}, {
+		name:    "case insensitive",
+		pattern: Pattern{Patterns: []string{"println", "ERR"}, Distance: 0},
+		want:    []int{3},
+	}, {
+		name:    "case sensitive",
+		pattern: Pattern{Patterns: []string{"println", "ERR"}, Distance: 0, CaseSensitive: true},
+		want:    nil,
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := Compile(&tc.pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, m.Filter(lines)); diff != "" {
+				t.Fatalf("unexpected lines (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPatternRegexp(t *testing.T) {
+	p := Pattern{Patterns: []string{"a|b", `c\.d`}}
+	if got, want := p.Regexp(), `(?:a|b)|(?:c\.d)`; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
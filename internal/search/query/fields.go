@@ -34,6 +34,16 @@ const (
 	FieldTimeout   = "timeout"
 	FieldCombyRule = "rule"
 	FieldSelect    = "select"
+	FieldOrder     = "order"
+	FieldFuzzy     = "fuzzy"
+)
+
+// Fields which are only generated by predicates. They are not in allFields, so
+// that they cannot be used in queries.
+const (
+	FieldRepoHasKVP   = "repohaskvp"   // repo:has()
+	FieldFileHasOwner = "filehasowner" // file:has.owner()
+	FieldRevAtTime    = "revattime"    // rev:at.time()
 )
 
 var allFields = map[string]struct{}{
@@ -69,6 +79,8 @@ var allFields = map[string]struct{}{
 	FieldCount:              empty,
 	FieldTimeout:            empty,
 	FieldCombyRule:          empty,
+	FieldOrder:              empty,
+	FieldFuzzy:              empty,
 	FieldRev:                empty,
 	"revision":              empty,
 	FieldSelect:             empty,
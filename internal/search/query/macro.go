@@ -0,0 +1,195 @@
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+)
+
+// Macros maps the names of query macros to the query fragments they expand
+// to. Macros are defined in the search.macros setting and referenced in a
+// query as @name, for example @nogen.
+type Macros map[string]string
+
+var macroReference = regexp.MustCompile(`^@([a-zA-Z_][a-zA-Z0-9_.-]*)$`)
+
+// MacroError is the error returned when a macro referenced in a query cannot
+// be expanded.
+type MacroError struct {
+	Macro string // The name of the macro, without the leading @.
+	Range Range  // The range of the reference to the macro in the query.
+	Err   error
+}
+
+func (e *MacroError) Error() string {
+	return fmt.Sprintf("cannot expand macro @%s at column %d: %s", e.Macro, e.Range.Start.Column+1, e.Err)
+}
+
+// ExpandMacros returns a step that substitutes references to macros with
+// their definition, parsed with searchType. An expanded macro behaves like a
+// parenthesized expression and is combined with the rest of the query by AND.
+// Patterns that look like a reference to a macro which is not defined are
+// left as is, so that searching for @Override still works.
+//
+// The nodes of an expanded macro take the range of the reference, so that
+// positions in the query refer to the text the user typed.
+func ExpandMacros(macros Macros, searchType SearchType) step {
+	return func(nodes []Node) ([]Node, error) {
+		if len(macros) == 0 {
+			return nodes, nil
+		}
+		e := &macroExpander{macros: macros, searchType: searchType}
+		return e.expandNodes(nodes, And)
+	}
+}
+
+type macroExpander struct {
+	macros     Macros
+	searchType SearchType
+
+	// stack contains the names of the macros being expanded, to detect
+	// macros defined in terms of themselves.
+	stack []string
+
+	// reference is the range of the reference in the query to the outermost
+	// macro being expanded.
+	reference Range
+}
+
+// rangeOf returns the range in the query of a pattern.
+func (e *macroExpander) rangeOf(pattern Pattern) Range {
+	if len(e.stack) > 0 {
+		return e.reference
+	}
+	return pattern.Annotation.Range
+}
+
+func (e *macroExpander) expandNodes(nodes []Node, kind operatorKind) ([]Node, error) {
+	expanded := make([]Node, 0, len(nodes))
+	for _, node := range nodes {
+		switch v := node.(type) {
+		case Pattern:
+			name, ok := e.lookup(v)
+			if !ok {
+				expanded = append(expanded, v)
+				continue
+			}
+			if v.Negated {
+				return nil, &MacroError{Macro: name, Range: e.rangeOf(v), Err: errors.New("macros cannot be negated")}
+			}
+			if kind.IsProximity() {
+				return nil, &MacroError{Macro: name, Range: e.rangeOf(v), Err: errors.Errorf("macros cannot be used in %s expressions", strings.ToUpper(kind.String()))}
+			}
+			macro, err := e.expandMacro(name, e.rangeOf(v))
+			if err != nil {
+				return nil, err
+			}
+			expanded = append(expanded, macro...)
+		case Operator:
+			if v.Kind == Concat {
+				concat, err := e.expandConcat(v.Operands)
+				if err != nil {
+					return nil, err
+				}
+				expanded = append(expanded, concat...)
+				continue
+			}
+			operands, err := e.expandNodes(v.Operands, v.Kind)
+			if err != nil {
+				return nil, err
+			}
+			expanded = append(expanded, v.withOperands(operands)...)
+		default:
+			expanded = append(expanded, node)
+		}
+	}
+	if kind == And {
+		return newOperator(expanded, And), nil
+	}
+	return expanded, nil
+}
+
+// expandConcat expands the operands of a concatenation. Expanded macros are
+// taken out of the concatenation and combined with it by AND, so that the
+// patterns around a reference are concatenated as if it were not there.
+func (e *macroExpander) expandConcat(operands []Node) ([]Node, error) {
+	var patterns, macros []Node
+	for _, operand := range operands {
+		expanded, err := e.expandNodes([]Node{operand}, Concat)
+		if err != nil {
+			return nil, err
+		}
+		if p, ok := operand.(Pattern); ok {
+			if _, ok := e.lookup(p); ok {
+				macros = append(macros, expanded...)
+				continue
+			}
+		}
+		patterns = append(patterns, expanded...)
+	}
+	return newOperator(append(newOperator(patterns, Concat), macros...), And), nil
+}
+
+// lookup returns the name of the macro referenced by pattern, if any.
+func (e *macroExpander) lookup(pattern Pattern) (string, bool) {
+	if pattern.Annotation.Labels.IsSet(Quoted) {
+		return "", false
+	}
+	m := macroReference.FindStringSubmatch(pattern.Value)
+	if m == nil {
+		return "", false
+	}
+	if _, ok := e.macros[m[1]]; !ok {
+		return "", false
+	}
+	return m[1], true
+}
+
+func (e *macroExpander) expandMacro(name string, rrange Range) ([]Node, error) {
+	for i, n := range e.stack {
+		if n == name {
+			cycle := append(append([]string{}, e.stack[i:]...), name)
+			return nil, &MacroError{Macro: name, Range: rrange, Err: errors.Errorf("macro is defined in terms of itself: @%s", strings.Join(cycle, " -> @"))}
+		}
+	}
+
+	nodes, err := Parse(e.macros[name], e.searchType)
+	if err != nil {
+		return nil, &MacroError{Macro: name, Range: rrange, Err: err}
+	}
+	if len(nodes) == 0 {
+		return nil, nil
+	}
+
+	if len(e.stack) == 0 {
+		e.reference = rrange
+	}
+	e.stack = append(e.stack, name)
+	nodes, err = e.expandNodes(nodes, And)
+	e.stack = e.stack[:len(e.stack)-1]
+	if err != nil {
+		return nil, err
+	}
+	return withRange(nodes, rrange), nil
+}
+
+// withRange sets the range of all nodes to rrange.
+func withRange(nodes []Node, rrange Range) []Node {
+	result := make([]Node, 0, len(nodes))
+	for _, node := range nodes {
+		switch v := node.(type) {
+		case Pattern:
+			v.Annotation.Range = rrange
+			result = append(result, v)
+		case Parameter:
+			v.Annotation.Range = rrange
+			result = append(result, v)
+		case Operator:
+			v.Operands = withRange(v.Operands, rrange)
+			result = append(result, v)
+		}
+	}
+	return result
+}
@@ -0,0 +1,117 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestExpandMacros(t *testing.T) {
+	macros := Macros{
+		"nogen":   "-file:vendor/ -file:_test.go$ -repo:archived-",
+		"errs":    "err or error",
+		"gonogen": "@nogen lang:go",
+		"a":       "x @b",
+		"b":       "@a",
+		"empty":   "",
+	}
+
+	cases := []struct {
+		input string
+		want  string
+	}{
+		{
+			input: "foo @nogen",
+			want:  `(and "foo" "-file:vendor/" "-file:_test.go$" "-repo:archived-")`,
+		},
+		{
+			input: "foo @nogen bar",
+			want:  `(and "(foo).*?(bar)" "-file:vendor/" "-file:_test.go$" "-repo:archived-")`,
+		},
+		{
+			input: "@errs lang:go",
+			want:  `(and "lang:go" (or "err" "error"))`,
+		},
+		{
+			input: "repo:foo (a or @nogen)",
+			want:  `(and "repo:foo" (or "a" (and "-file:vendor/" "-file:_test.go$" "-repo:archived-")))`,
+		},
+		{
+			input: "@gonogen foo",
+			want:  `(and "foo" "lang:go" "-file:vendor/" "-file:_test.go$" "-repo:archived-")`,
+		},
+		{
+			input: "foo @empty",
+			want:  `"foo"`,
+		},
+		{
+			input: "@undefined",
+			want:  `"@undefined"`,
+		},
+		{
+			input: `"@nogen"`,
+			want:  `"@nogen"`,
+		},
+		{
+			input: "@a",
+			want:  "cannot expand macro @a at column 1: macro is defined in terms of itself: @a -> @b -> @a",
+		},
+		{
+			input: "x   @b",
+			want:  "cannot expand macro @b at column 5: macro is defined in terms of itself: @b -> @a -> @b",
+		},
+		{
+			input: "NOT @nogen",
+			want:  "cannot expand macro @nogen at column 1: macros cannot be negated",
+		},
+		{
+			input: "foo NEAR/3 @errs",
+			want:  "cannot expand macro @errs at column 12: macros cannot be used in NEAR expressions",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.input, func(t *testing.T) {
+			var got string
+			nodes, err := Run(InitWithMacros(c.input, SearchTypeRegex, macros))
+			if err != nil {
+				got = err.Error()
+			} else {
+				got = toString(nodes)
+			}
+			if diff := cmp.Diff(c.want, got); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestExpandMacros_Range(t *testing.T) {
+	nodes, err := Run(InitWithMacros("foo   @nogen", SearchTypeRegex, Macros{"nogen": "-file:vendor/"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []Range
+	VisitParameter(nodes, func(_, _ string, _ bool, annotation Annotation) {
+		got = append(got, annotation.Range)
+	})
+	if diff := cmp.Diff([]Range{newRange(6, 12)}, got); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestStringHuman_Macros(t *testing.T) {
+	nodes, err := Run(InitWithMacros("repo:foo (a or @nogen)", SearchTypeRegex, Macros{"nogen": "-file:vendor/"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "(repo:foo and (a or -file:vendor/))"
+	if got := StringHuman(nodes); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// Parameters next to operators which can't be partitioned are preserved.
+	want = "repo:foo (a or -file:vendor/)"
+	if got := StringHuman(nodes[0].(Operator).Operands); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -29,6 +29,12 @@ func (*BaseMapper) MapNodes(mapper Mapper, nodes []Node) []Node {
 				mapped = append(mapped, result)
 			}
 		case Operator:
+			if v.Kind.IsProximity() {
+				// The operands of proximity operators are patterns. Map
+				// them, but keep the distance of the operator.
+				mapped = append(mapped, v.withOperands(mapper.MapNodes(mapper, v.Operands))...)
+				continue
+			}
 			if result := mapper.MapOperator(mapper, v.Kind, v.Operands); result != nil {
 				mapped = append(mapped, result...)
 			}
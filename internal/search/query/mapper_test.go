@@ -1,6 +1,7 @@
 package query
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -41,3 +42,26 @@ func TestMapField(t *testing.T) {
 		t.Fatal(diff)
 	}
 }
+
+func TestMapPatternProximity(t *testing.T) {
+	input := []Node{
+		Operator{
+			Kind:     Near,
+			Operands: []Node{Pattern{Value: "a"}, Pattern{Value: "b"}},
+			Distance: 3,
+		},
+	}
+	want := []Node{
+		Operator{
+			Kind:     Near,
+			Operands: []Node{Pattern{Value: "A"}, Pattern{Value: "B"}},
+			Distance: 3,
+		},
+	}
+	got := MapPattern(input, func(value string, negated bool, annotation Annotation) Node {
+		return Pattern{Value: strings.ToUpper(value), Negated: negated, Annotation: annotation}
+	})
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatal(diff)
+	}
+}
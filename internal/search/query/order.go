@@ -0,0 +1,28 @@
+package query
+
+import "strings"
+
+// ResultOrder is the order in which search results are returned, as set by
+// the order: field.
+type ResultOrder string
+
+const (
+	// OrderPath sorts results by repository name and path. It is the default.
+	OrderPath ResultOrder = "path"
+	// OrderRelevance sorts results by a relevance score computed from
+	// signals like match density and repository popularity.
+	OrderRelevance ResultOrder = "relevance"
+	// OrderRecency sorts results by the date of the last commit modifying
+	// the matched file, most recent first.
+	OrderRecency ResultOrder = "recency"
+)
+
+// ParseResultOrder returns the ResultOrder for s, or the empty ResultOrder if
+// s is not a valid order.
+func ParseResultOrder(s string) ResultOrder {
+	switch o := ResultOrder(strings.ToLower(s)); o {
+	case OrderPath, OrderRelevance, OrderRecency:
+		return o
+	}
+	return ""
+}
@@ -57,15 +57,75 @@ const (
 	Or operatorKind = iota
 	And
 	Concat
+	Near
+	Before
 )
 
+// maxProximityDistance is the largest distance, in lines, accepted by the
+// NEAR and BEFORE operators.
+const maxProximityDistance = 1000
+
+// IsProximity returns true if k is the kind of a NEAR/n or BEFORE/n operator.
+// The operands of a proximity operator are patterns which must all match within
+// Distance lines of each other.
+func (k operatorKind) IsProximity() bool {
+	return k == Near || k == Before
+}
+
+// Ordered returns true if the operands of a proximity operator must match in
+// order, as for BEFORE/n.
+func (k operatorKind) Ordered() bool {
+	return k == Before
+}
+
+func (k operatorKind) String() string {
+	switch k {
+	case Or:
+		return "or"
+	case And:
+		return "and"
+	case Concat:
+		return "concat"
+	case Near:
+		return "near"
+	case Before:
+		return "before"
+	}
+	return ""
+}
+
 // Operator is a nonterminal node of kind Kind with child nodes Operands.
 type Operator struct {
-	Kind       operatorKind
-	Operands   []Node
+	Kind     operatorKind
+	Operands []Node
+	// Distance is the number of lines within which the operands of a
+	// proximity operator must match. It is zero for other operators.
+	Distance   int
 	Annotation Annotation
 }
 
+// name returns the name of the operator, including the distance of proximity
+// operators, like "near/3".
+func (node Operator) name() string {
+	if node.Kind.IsProximity() {
+		return fmt.Sprintf("%s/%d", node.Kind, node.Distance)
+	}
+	return node.Kind.String()
+}
+
+// withOperands returns the operator with its operands replaced by operands,
+// reduced like newOperator. Unlike newOperator, it preserves the distance of
+// proximity operators.
+func (node Operator) withOperands(operands []Node) []Node {
+	if !node.Kind.IsProximity() {
+		return newOperator(operands, node.Kind)
+	}
+	if len(operands) <= 1 {
+		return operands
+	}
+	return []Node{Operator{Kind: node.Kind, Operands: operands, Distance: node.Distance}}
+}
+
 func (node Pattern) String() string {
 	if node.Negated {
 		return fmt.Sprintf("(not %s)", strconv.Quote(node.Value))
@@ -91,17 +151,7 @@ func (node Operator) String() string {
 	for _, child := range node.Operands {
 		result = append(result, child.String())
 	}
-	var kind string
-	switch node.Kind {
-	case Or:
-		kind = "or"
-	case And:
-		kind = "and"
-	case Concat:
-		kind = "concat"
-	}
-
-	return fmt.Sprintf("(%s %s)", kind, strings.Join(result, " "))
+	return fmt.Sprintf("(%s %s)", node.name(), strings.Join(result, " "))
 }
 
 type keyword string
@@ -116,6 +166,8 @@ const (
 	DQUOTE keyword = "\""
 	SLASH  keyword = "/"
 	NOT    keyword = "not"
+	NEAR   keyword = "near"
+	BEFORE keyword = "before"
 )
 
 func isSpace(buf []byte) bool {
@@ -250,6 +302,39 @@ func (p *parser) matchUnaryKeyword(keyword keyword) bool {
 	return strings.EqualFold(v, string(keyword))
 }
 
+// matchProximity returns the kind, distance and length in bytes of a proximity
+// operator like "NEAR/3" or "BEFORE/10" at the current position. Like matchKeyword, it
+// expects the operator to be preceded and followed by whitespace. The returned
+// length is zero if there is no proximity operator at the current position.
+func (p *parser) matchProximity() (kind operatorKind, distance, length int, err error) {
+	if p.pos == 0 || !isSpace(p.buf[p.pos-1:p.pos]) {
+		return 0, 0, 0, nil
+	}
+	for _, keyword := range []keyword{NEAR, BEFORE} {
+		n := len(string(keyword))
+		if len(p.buf)-p.pos <= n || p.buf[p.pos+n] != '/' || !strings.EqualFold(string(p.buf[p.pos:p.pos+n]), string(keyword)) {
+			continue
+		}
+		end := p.pos + n + 1
+		for end < len(p.buf) && '0' <= p.buf[end] && p.buf[end] <= '9' {
+			end++
+		}
+		if end == p.pos+n+1 || end >= len(p.buf) || !isSpace(p.buf[end:end+1]) {
+			continue
+		}
+		distance, err := strconv.Atoi(string(p.buf[p.pos+n+1 : end]))
+		if err != nil || distance > maxProximityDistance {
+			return 0, 0, 0, errors.Errorf("the distance of %s must be at most %d lines", strings.ToUpper(string(keyword)), maxProximityDistance)
+		}
+		kind := Near
+		if keyword == BEFORE {
+			kind = Before
+		}
+		return kind, distance, end - p.pos, nil
+	}
+	return 0, 0, 0, nil
+}
+
 // skipSpaces advances the input and places the parser position at the next
 // non-space value.
 func (p *parser) skipSpaces() error {
@@ -336,7 +421,9 @@ func ScanBalancedPattern(buf []byte) (scanned string, count int, ok bool) {
 		}
 		if lookahead("and ") ||
 			lookahead("or ") ||
-			lookahead("not ") {
+			lookahead("not ") ||
+			lookahead("near/") ||
+			lookahead("before/") {
 			// This "pattern" contains a recognized keyword, reject it.
 			return false
 		}
@@ -901,6 +988,18 @@ loop:
 		if p.done() {
 			break loop
 		}
+		kind, distance, advance, err := p.matchProximity()
+		if err != nil {
+			return nil, err
+		}
+		if advance > 0 {
+			p.pos += advance
+			nodes, err = p.parseProximity(nodes, kind, distance, label)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
 		switch {
 		case p.match(LPAREN) && !isSet(p.heuristics, allowDanglingParens):
 			if isSet(p.heuristics, parensAsPatterns) {
@@ -985,6 +1084,48 @@ loop:
 	return partitionParameters(nodes), nil
 }
 
+// parseProximity parses the right operand of a proximity operator of kind and
+// distance and combines it with the last node in nodes, its left operand. Proximity
+// operators bind tighter than any other operator, so operands must be single,
+// non-negated patterns. Consecutive operators of the same kind are chained,
+// such that "a NEAR/3 b NEAR/3 c" is parsed as (near/3 "a" "b" "c").
+func (p *parser) parseProximity(nodes []Node, kind operatorKind, distance int, label labels) ([]Node, error) {
+	keyword := fmt.Sprintf("%s/%d", strings.ToUpper(kind.String()), distance)
+	if len(nodes) == 0 {
+		return nil, errors.Errorf("%s expects a search pattern on its left", keyword)
+	}
+
+	var operands []Node
+	switch left := nodes[len(nodes)-1].(type) {
+	case Pattern:
+		if left.Negated {
+			return nil, errors.Errorf("%s does not support negated patterns", keyword)
+		}
+		operands = []Node{left}
+	case Operator:
+		if left.Kind != kind || left.Distance != distance {
+			return nil, errors.Errorf("%s cannot be combined with %s. Use parentheses and AND to combine proximity expressions", keyword, strings.ToUpper(left.name()))
+		}
+		operands = left.Operands
+	default:
+		return nil, errors.Errorf("%s expects a search pattern on its left", keyword)
+	}
+
+	if err := p.skipSpaces(); err != nil {
+		return nil, err
+	}
+	if p.done() || p.match(LPAREN) || p.match(RPAREN) || p.matchUnaryKeyword(NOT) {
+		return nil, errors.Errorf("%s expects a search pattern on its right", keyword)
+	}
+	if field, _, _ := ScanField(p.buf[p.pos:]); field != "" {
+		return nil, errors.Errorf("%s expects a search pattern on its right, not %s:", keyword, field)
+	}
+	right := p.ParsePattern(label)
+
+	operator := Operator{Kind: kind, Operands: append(operands, right), Distance: distance}
+	return append(nodes[:len(nodes)-1], operator), nil
+}
+
 // reduce takes lists of left and right nodes and reduces them if possible. For example,
 // (and a (b and c))       => (and a b c)
 // (((a and b) or c) or d) => (or (and a b) c d)
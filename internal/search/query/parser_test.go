@@ -221,12 +221,12 @@ func TestScanPredicate(t *testing.T) {
 	}).Equal(t, test(`repo:contains`))
 
 	autogold.Want("Repo with something that looks kinda like predicate", value{
-		Result:       `{"Kind":1,"Operands":[{"field":"repo","value":"nopredicate","negated":false},{"value":"(file:foo","negated":false}],"Annotation":{"labels":0,"range":{"start":{"line":0,"column":0},"end":{"line":0,"column":0}}}}`,
+		Result:       `{"Kind":1,"Operands":[{"field":"repo","value":"nopredicate","negated":false},{"value":"(file:foo","negated":false}],"Distance":0,"Annotation":{"labels":0,"range":{"start":{"line":0,"column":0},"end":{"line":0,"column":0}}}}`,
 		ResultLabels: "HeuristicDanglingParens,Regexp",
 	}).Equal(t, test(`repo:nopredicate(file:foo or file:bar)`))
 
 	autogold.Want("Pattern looks like predicate", value{
-		Result:       `{"Kind":2,"Operands":[{"value":"abc","negated":false},{"value":"contains(file:test)","negated":false}],"Annotation":{"labels":0,"range":{"start":{"line":0,"column":0},"end":{"line":0,"column":0}}}}`,
+		Result:       `{"Kind":2,"Operands":[{"value":"abc","negated":false},{"value":"contains(file:test)","negated":false}],"Distance":0,"Annotation":{"labels":0,"range":{"start":{"line":0,"column":0},"end":{"line":0,"column":0}}}}`,
 		ResultLabels: "HeuristicDanglingParens,Regexp",
 	}).Equal(t, test(`abc contains(file:test)`))
 
@@ -594,6 +594,30 @@ func TestMatchUnaryKeyword(t *testing.T) {
 	autogold.Want("(not bar)", "true").Equal(t, test("(not bar)", 1))
 }
 
+func TestParseProximity(t *testing.T) {
+	test := func(input string) string {
+		result, err := Parse(input, SearchTypeRegex)
+		if err != nil {
+			return fmt.Sprintf("ERROR: %s", err.Error())
+		}
+		return toString(result)
+	}
+
+	autogold.Want("a NEAR/3 b", `(near/3 "a" "b")`).Equal(t, test("a NEAR/3 b"))
+	autogold.Want("chained", `(near/3 "a" "b" "c")`).Equal(t, test("a near/3 b NEAR/3 c"))
+	autogold.Want("with parameter", `(and "repo:foo" (before/0 "a" "b"))`).Equal(t, test("repo:foo a BEFORE/0 b"))
+	autogold.Want("binds tighter than concat", `(concat "x" (near/2 "a" "b"))`).Equal(t, test("x a NEAR/2 b"))
+	autogold.Want("binds tighter than or", `(or (near/3 "a" "b") "c")`).Equal(t, test("a NEAR/3 b or c"))
+	autogold.Want("trailing operator is a pattern", `(concat "a" "NEAR/3")`).Equal(t, test("a NEAR/3"))
+	autogold.Want("operator requires whitespace", `(concat "aNEAR/3" "b")`).Equal(t, test("aNEAR/3 b"))
+	autogold.Want("mixed operators", "ERROR: BEFORE/3 cannot be combined with NEAR/3. Use parentheses and AND to combine proximity expressions").Equal(t, test("a NEAR/3 b BEFORE/3 c"))
+	autogold.Want("mixed distances", "ERROR: NEAR/5 cannot be combined with NEAR/3. Use parentheses and AND to combine proximity expressions").Equal(t, test("a NEAR/3 b NEAR/5 c"))
+	autogold.Want("combined with and", `(and (near/3 "a" "b") (near/5 "c" "d"))`).Equal(t, test("(a NEAR/3 b) and (c NEAR/5 d)"))
+	autogold.Want("parameter operand", "ERROR: NEAR/3 expects a search pattern on its right, not repo:").Equal(t, test("a NEAR/3 repo:foo"))
+	autogold.Want("negated operand", "ERROR: NEAR/3 does not support negated patterns").Equal(t, test("not a NEAR/3 b"))
+	autogold.Want("distance too large", "ERROR: the distance of NEAR must be at most 1000 lines").Equal(t, test("a NEAR/1001 b"))
+}
+
 func TestParseAndOrLiteral(t *testing.T) {
 	test := func(input string) string {
 		result, err := Parse(input, SearchTypeLiteral)
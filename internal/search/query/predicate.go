@@ -5,6 +5,8 @@ import (
 	"strings"
 
 	"github.com/cockroachdb/errors"
+
+	"github.com/sourcegraph/sourcegraph/internal/search/filter"
 )
 
 type Predicate interface {
@@ -31,10 +33,18 @@ var DefaultPredicateRegistry = predicateRegistry{
 		"contains.file":         func() Predicate { return &RepoContainsFilePredicate{} },
 		"contains.content":      func() Predicate { return &RepoContainsContentPredicate{} },
 		"contains.commit.after": func() Predicate { return &RepoContainsCommitAfterPredicate{} },
+		"contains.symbol":       func() Predicate { return &RepoContainsSymbolPredicate{} },
+		"has":                   func() Predicate { return &RepoHasKVPPredicate{} },
+		"has.key":               func() Predicate { return &RepoHasKeyPredicate{} },
 	},
 	FieldFile: {
 		"contains.content": func() Predicate { return &FileContainsContentPredicate{} },
 		"contains":         func() Predicate { return &FileContainsContentPredicate{} },
+		"contains.symbol":  func() Predicate { return &FileContainsSymbolPredicate{} },
+		"has.owner":        func() Predicate { return &FileHasOwnerPredicate{} },
+	},
+	FieldRev: {
+		"at.time": func() Predicate { return &RevAtTimePredicate{} },
 	},
 }
 
@@ -249,6 +259,113 @@ func (f *RepoContainsCommitAfterPredicate) Plan(parent Basic) (Plan, error) {
 	return ToPlan(Dnf(nodes))
 }
 
+/* repo:has(key:value) */
+
+// RepoHasKVPPredicate searches only inside repositories with the key/value
+// pair Key=Value in their metadata, e.g. `repo:has(team:payments)`. Without a
+// value, as in `repo:has(team)`, it searches inside repositories with the key
+// Key and any value.
+type RepoHasKVPPredicate struct {
+	Key   string
+	Value string
+}
+
+func (f *RepoHasKVPPredicate) ParseParams(params string) error {
+	i := strings.Index(params, ":")
+	if i < 0 {
+		f.Key, f.Value = strings.TrimSpace(params), ""
+	} else {
+		f.Key, f.Value = strings.TrimSpace(params[:i]), strings.TrimSpace(params[i+1:])
+		if f.Value == "" {
+			return errors.New("repo:has argument should have a non-empty value after ':'")
+		}
+	}
+	if f.Key == "" {
+		return errors.New("repo:has argument should have a non-empty key")
+	}
+	return nil
+}
+
+func (f RepoHasKVPPredicate) Field() string { return FieldRepo }
+func (f RepoHasKVPPredicate) Name() string  { return "has" }
+func (f *RepoHasKVPPredicate) Plan(parent Basic) (Plan, error) {
+	value := f.Key
+	if f.Value != "" {
+		value += ":" + f.Value
+	}
+	return planRepoHasKVP(parent, value)
+}
+
+/* repo:has.key(key) */
+
+// RepoHasKeyPredicate searches only inside repositories with the key Key in
+// their metadata, e.g. `repo:has.key(team)`.
+type RepoHasKeyPredicate struct {
+	Key string
+}
+
+func (f *RepoHasKeyPredicate) ParseParams(params string) error {
+	f.Key = strings.TrimSpace(params)
+	if f.Key == "" {
+		return errors.New("repo:has.key argument should have a non-empty key")
+	}
+	if strings.Contains(f.Key, ":") {
+		return errors.Errorf("repo:has.key argument %q should not contain ':'", f.Key)
+	}
+	return nil
+}
+
+func (f RepoHasKeyPredicate) Field() string { return FieldRepo }
+func (f RepoHasKeyPredicate) Name() string  { return "has.key" }
+func (f *RepoHasKeyPredicate) Plan(parent Basic) (Plan, error) {
+	return planRepoHasKVP(parent, f.Key)
+}
+
+// planRepoHasKVP returns the plan of a repo:has predicate, where value is the
+// parameter of repo:has(...).
+func planRepoHasKVP(parent Basic, value string) (Plan, error) {
+	nodes := make([]Node, 0, 3)
+	nodes = append(nodes, Parameter{
+		Field: FieldCount,
+		Value: "99999",
+	}, Parameter{
+		Field: FieldRepoHasKVP,
+		Value: value,
+	})
+
+	nodes = append(nodes, nonPredicateRepos(parent)...)
+	return ToPlan(Dnf(nodes))
+}
+
+/* rev:at.time(...) */
+
+// RevAtTimePredicate searches each repository at the commit its default
+// branch pointed to at a point in time, e.g. `rev:at.time(2021-01-01)`. The
+// time is any date git understands, like `2021-01-01T12:00:00Z` or `1 year
+// ago`.
+type RevAtTimePredicate struct {
+	TimeRef string
+}
+
+func (f *RevAtTimePredicate) ParseParams(params string) error {
+	params = strings.TrimSpace(params)
+	if params == "" {
+		return errors.New("rev:at.time argument should not be empty")
+	}
+	f.TimeRef = params
+	return nil
+}
+
+func (f RevAtTimePredicate) Field() string { return FieldRev }
+func (f RevAtTimePredicate) Name() string  { return "at.time" }
+
+// Plan does not run a subquery. The commit at the time depends on the
+// repository, so repositories are resolved as usual and the revattime
+// parameter resolves the commit of each one.
+func (f *RevAtTimePredicate) Plan(parent Basic) (Plan, error) {
+	return Plan{ConcatRevFilters(parent)}, nil
+}
+
 type FileContainsContentPredicate struct {
 	Pattern string
 }
@@ -284,6 +401,140 @@ func (f *FileContainsContentPredicate) Plan(parent Basic) (Plan, error) {
 	return ToPlan(Dnf(nodes))
 }
 
+/* repo:contains.symbol(...) and file:contains.symbol(...) */
+
+// ContainsSymbol holds the arguments shared by the `contains.symbol`
+// predicates: a regular expression matching symbol names, optionally
+// restricted to a symbol kind with `kind:<kind>`, e.g.
+// `contains.symbol(kind:function ^NewClient$)`.
+type ContainsSymbol struct {
+	Pattern string
+	Kind    string
+}
+
+func (c *ContainsSymbol) ParseParams(params string) error {
+	var patterns []string
+	for _, token := range strings.Fields(params) {
+		if strings.HasPrefix(strings.ToLower(token), "kind:") {
+			if c.Kind != "" {
+				return errors.New("cannot specify kind multiple times")
+			}
+			kind := strings.ToLower(token[len("kind:"):])
+			if _, err := filter.SelectPathFromString(filter.Symbol + "." + kind); err != nil || kind == "" {
+				return errors.Errorf("contains.symbol has invalid symbol kind %q", kind)
+			}
+			c.Kind = kind
+			continue
+		}
+		patterns = append(patterns, token)
+	}
+
+	c.Pattern = strings.Join(patterns, " ")
+	if c.Pattern == "" {
+		return errors.New("contains.symbol argument should contain a symbol pattern")
+	}
+	if _, err := regexp.Compile(c.Pattern); err != nil {
+		return errors.Errorf("contains.symbol argument: %w", err)
+	}
+	return nil
+}
+
+// nodes returns the nodes of a symbol search for the predicate's symbols.
+func (c *ContainsSymbol) nodes() []Node {
+	nodes := []Node{
+		Parameter{
+			Field: FieldCount,
+			Value: "99999",
+		},
+		Parameter{
+			Field: FieldType,
+			Value: "symbol",
+		},
+	}
+	if c.Kind != "" {
+		nodes = append(nodes, Parameter{
+			Field: FieldSelect,
+			Value: filter.Symbol + "." + c.Kind,
+		})
+	}
+	return append(nodes, Pattern{
+		Value:      c.Pattern,
+		Annotation: Annotation{Labels: Regexp},
+	})
+}
+
+type RepoContainsSymbolPredicate struct {
+	ContainsSymbol
+}
+
+func (f *RepoContainsSymbolPredicate) Field() string { return FieldRepo }
+func (f *RepoContainsSymbolPredicate) Name() string  { return "contains.symbol" }
+func (f *RepoContainsSymbolPredicate) Plan(parent Basic) (Plan, error) {
+	nodes := f.nodes()
+	if f.Kind == "" {
+		// Without a kind we can select repositories directly. Otherwise the
+		// symbol matches are converted to repositories during substitution.
+		nodes = append(nodes, Parameter{
+			Field: FieldSelect,
+			Value: filter.Repository,
+		})
+	}
+	nodes = append(nodes, nonPredicateRepos(parent)...)
+	return ToPlan(Dnf(nodes))
+}
+
+type FileContainsSymbolPredicate struct {
+	ContainsSymbol
+}
+
+func (f *FileContainsSymbolPredicate) Field() string { return FieldFile }
+func (f *FileContainsSymbolPredicate) Name() string  { return "contains.symbol" }
+func (f *FileContainsSymbolPredicate) Plan(parent Basic) (Plan, error) {
+	nodes := f.nodes()
+	nodes = append(nodes, nonPredicateRepos(parent)...)
+	nodes = append(nodes, nonPredicateFiles(parent)...)
+	return ToPlan(Dnf(nodes))
+}
+
+/* file:has.owner(owner) */
+
+type FileHasOwnerPredicate struct {
+	Owner string
+}
+
+func (f *FileHasOwnerPredicate) ParseParams(params string) error {
+	params = strings.TrimSpace(params)
+	if params == "" {
+		return errors.Errorf("file:has.owner argument should not be empty")
+	}
+	if strings.ContainsAny(params, " \t\n") {
+		return errors.Errorf("file:has.owner argument should be a single owner, got %q", params)
+	}
+	f.Owner = params
+	return nil
+}
+
+func (f FileHasOwnerPredicate) Field() string { return FieldFile }
+func (f FileHasOwnerPredicate) Name() string  { return "has.owner" }
+
+func (f *FileHasOwnerPredicate) Plan(parent Basic) (Plan, error) {
+	nodes := make([]Node, 0, 3)
+	nodes = append(nodes, Parameter{
+		Field: FieldCount,
+		Value: "99999",
+	}, Parameter{
+		Field: FieldType,
+		Value: "path",
+	}, Parameter{
+		Field: FieldFileHasOwner,
+		Value: f.Owner,
+	})
+
+	nodes = append(nodes, nonPredicateRepos(parent)...)
+	nodes = append(nodes, nonPredicateFiles(parent)...)
+	return ToPlan(Dnf(nodes))
+}
+
 // nonPredicateRepos returns the repo nodes in a query that aren't predicates,
 // respecting parameters that determine repo results.
 func nonPredicateRepos(q Basic) []Node {
@@ -313,3 +564,23 @@ func nonPredicateRepos(q Basic) []Node {
 	})
 	return res
 }
+
+// nonPredicateFiles returns the file nodes in a query that aren't predicates.
+// They narrow the set of files a file predicate has to consider.
+func nonPredicateFiles(q Basic) []Node {
+	var res []Node
+	VisitParameter(q.ToParseTree(), func(field, value string, negated bool, ann Annotation) {
+		if ann.Labels.IsSet(IsPredicate) {
+			return
+		}
+		if field == FieldFile {
+			res = append(res, Parameter{
+				Field:      field,
+				Value:      value,
+				Negated:    negated,
+				Annotation: ann,
+			})
+		}
+	})
+	return res
+}
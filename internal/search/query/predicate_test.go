@@ -56,6 +56,307 @@ func TestRepoContainsPredicate(t *testing.T) {
 	})
 }
 
+func TestRepoHasKVPPredicate(t *testing.T) {
+	t.Run("ParseParams", func(t *testing.T) {
+		valid := []struct {
+			params   string
+			expected *RepoHasKVPPredicate
+		}{
+			{`team:payments`, &RepoHasKVPPredicate{Key: "team", Value: "payments"}},
+			{` tier : 1 `, &RepoHasKVPPredicate{Key: "tier", Value: "1"}},
+			{`url:https://example.com`, &RepoHasKVPPredicate{Key: "url", Value: "https://example.com"}},
+			{`team`, &RepoHasKVPPredicate{Key: "team"}},
+		}
+
+		for _, tc := range valid {
+			t.Run(tc.params, func(t *testing.T) {
+				p := &RepoHasKVPPredicate{}
+				if err := p.ParseParams(tc.params); err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				if !reflect.DeepEqual(tc.expected, p) {
+					t.Fatalf("expected %#v, got %#v", tc.expected, p)
+				}
+			})
+		}
+
+		for _, params := range []string{``, ` `, `:payments`, `team:`} {
+			t.Run(params, func(t *testing.T) {
+				p := &RepoHasKVPPredicate{}
+				if err := p.ParseParams(params); err == nil {
+					t.Fatal("expected error but got none")
+				}
+			})
+		}
+	})
+
+	t.Run("Plan", func(t *testing.T) {
+		q, err := ParseLiteral(`repo:foo repo:has(team:payments) bar`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ToBasicQuery(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := &RepoHasKVPPredicate{Key: "team", Value: "payments"}
+		plan, err := p.Plan(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tree := plan.ToParseTree()
+		if kvp, _ := tree.StringValue(FieldRepoHasKVP); kvp != "team:payments" {
+			t.Fatalf("expected repohaskvp team:payments, got %q", kvp)
+		}
+		if repos, _ := tree.StringValues(FieldRepo); !reflect.DeepEqual(repos, []string{"foo"}) {
+			t.Fatalf("expected repo filters to be preserved, got %v", repos)
+		}
+	})
+
+	t.Run("Plan key only", func(t *testing.T) {
+		for _, p := range []Predicate{&RepoHasKVPPredicate{Key: "team"}, &RepoHasKeyPredicate{Key: "team"}} {
+			plan, err := p.Plan(Basic{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if kvp, _ := plan.ToParseTree().StringValue(FieldRepoHasKVP); kvp != "team" {
+				t.Fatalf("%s: expected repohaskvp team, got %q", p.Name(), kvp)
+			}
+		}
+	})
+
+	t.Run("field is internal", func(t *testing.T) {
+		q, err := ParseLiteral(`repohaskvp:team:payments`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if kvp, _ := Q(q).StringValue(FieldRepoHasKVP); kvp != "" {
+			t.Fatalf("expected repohaskvp: not to be parsed as a field, got %q", kvp)
+		}
+	})
+}
+
+func TestRepoHasKeyPredicate(t *testing.T) {
+	p := &RepoHasKeyPredicate{}
+	if err := p.ParseParams(" team "); err != nil {
+		t.Fatal(err)
+	}
+	if p.Key != "team" {
+		t.Fatalf("expected key team, got %q", p.Key)
+	}
+
+	for _, params := range []string{``, `team:payments`} {
+		t.Run(params, func(t *testing.T) {
+			if err := (&RepoHasKeyPredicate{}).ParseParams(params); err == nil {
+				t.Fatal("expected error but got none")
+			}
+		})
+	}
+}
+
+func TestContainsSymbolPredicate(t *testing.T) {
+	t.Run("ParseParams", func(t *testing.T) {
+		valid := []struct {
+			name     string
+			params   string
+			expected ContainsSymbol
+		}{
+			{`pattern`, `NewClient`, ContainsSymbol{Pattern: "NewClient"}},
+			{`kind and pattern`, `kind:function ^NewClient$`, ContainsSymbol{Pattern: "^NewClient$", Kind: "function"}},
+			{`pattern and kind`, `Client kind:Struct`, ContainsSymbol{Pattern: "Client", Kind: "struct"}},
+		}
+
+		for _, tc := range valid {
+			t.Run(tc.name, func(t *testing.T) {
+				p := &RepoContainsSymbolPredicate{}
+				if err := p.ParseParams(tc.params); err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				if !reflect.DeepEqual(tc.expected, p.ContainsSymbol) {
+					t.Fatalf("expected %#v, got %#v", tc.expected, p.ContainsSymbol)
+				}
+			})
+		}
+
+		invalid := []struct {
+			name   string
+			params string
+		}{
+			{`empty`, ``},
+			{`only kind`, `kind:function`},
+			{`unknown kind`, `kind:banana Foo`},
+			{`multiple kinds`, `kind:function kind:method Foo`},
+			{`invalid regexp`, `Foo(`},
+		}
+
+		for _, tc := range invalid {
+			t.Run(tc.name, func(t *testing.T) {
+				p := &FileContainsSymbolPredicate{}
+				if err := p.ParseParams(tc.params); err == nil {
+					t.Fatal("expected error but got none")
+				}
+			})
+		}
+	})
+
+	t.Run("Plan", func(t *testing.T) {
+		q, err := ParseLiteral(`repo:foo repo:contains.symbol(kind:function NewClient) bar`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ToBasicQuery(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		repoPlan, err := (&RepoContainsSymbolPredicate{ContainsSymbol{Pattern: "NewClient"}}).Plan(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sel, _ := repoPlan.ToParseTree().StringValue(FieldSelect); sel != "repo" {
+			t.Fatalf("expected select:repo, got %q", sel)
+		}
+
+		filePlan, err := (&FileContainsSymbolPredicate{ContainsSymbol{Pattern: "NewClient", Kind: "function"}}).Plan(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tree := filePlan.ToParseTree()
+		if typ, _ := tree.StringValue(FieldType); typ != "symbol" {
+			t.Fatalf("expected type:symbol, got %q", typ)
+		}
+		if sel, _ := tree.StringValue(FieldSelect); sel != "symbol.function" {
+			t.Fatalf("expected select:symbol.function, got %q", sel)
+		}
+		if repos, _ := tree.StringValues(FieldRepo); !reflect.DeepEqual(repos, []string{"foo"}) {
+			t.Fatalf("expected repo filters to be preserved, got %v", repos)
+		}
+	})
+}
+
+func TestFileHasOwnerPredicate(t *testing.T) {
+	t.Run("ParseParams", func(t *testing.T) {
+		valid := []struct {
+			params   string
+			expected *FileHasOwnerPredicate
+		}{
+			{`@org/team-x`, &FileHasOwnerPredicate{Owner: "@org/team-x"}},
+			{` alice `, &FileHasOwnerPredicate{Owner: "alice"}},
+		}
+
+		for _, tc := range valid {
+			t.Run(tc.params, func(t *testing.T) {
+				p := &FileHasOwnerPredicate{}
+				if err := p.ParseParams(tc.params); err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				if !reflect.DeepEqual(tc.expected, p) {
+					t.Fatalf("expected %#v, got %#v", tc.expected, p)
+				}
+			})
+		}
+
+		for _, params := range []string{``, `alice bob`} {
+			t.Run(params, func(t *testing.T) {
+				p := &FileHasOwnerPredicate{}
+				if err := p.ParseParams(params); err == nil {
+					t.Fatal("expected error but got none")
+				}
+			})
+		}
+	})
+
+	t.Run("Plan", func(t *testing.T) {
+		q, err := ParseLiteral(`repo:foo file:\.go$ file:has.owner(@alice) bar`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ToBasicQuery(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := &FileHasOwnerPredicate{Owner: "@alice"}
+		plan, err := p.Plan(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tree := plan.ToParseTree()
+		if owner, _ := tree.StringValue(FieldFileHasOwner); owner != "@alice" {
+			t.Fatalf("expected owner @alice, got %q", owner)
+		}
+		if typ, _ := tree.StringValue(FieldType); typ != "path" {
+			t.Fatalf("expected type:path, got %q", typ)
+		}
+		if repos, _ := tree.StringValues(FieldRepo); !reflect.DeepEqual(repos, []string{"foo"}) {
+			t.Fatalf("expected repo filters to be preserved, got %v", repos)
+		}
+		if files, _ := tree.StringValues(FieldFile); !reflect.DeepEqual(files, []string{`\.go$`}) {
+			t.Fatalf("expected non-predicate file filters to be preserved, got %v", files)
+		}
+	})
+
+	t.Run("field is internal", func(t *testing.T) {
+		q, err := ParseLiteral(`filehasowner:@alice`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if owner, _ := Q(q).StringValue(FieldFileHasOwner); owner != "" {
+			t.Fatalf("expected filehasowner: to be a pattern, got owner %q", owner)
+		}
+	})
+}
+
+func TestRevAtTimePredicate(t *testing.T) {
+	t.Run("ParseParams", func(t *testing.T) {
+		p := &RevAtTimePredicate{}
+		if err := p.ParseParams(` 1 year ago `); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if want := (&RevAtTimePredicate{TimeRef: "1 year ago"}); !reflect.DeepEqual(want, p) {
+			t.Fatalf("expected %#v, got %#v", want, p)
+		}
+		if err := (&RevAtTimePredicate{}).ParseParams(` `); err == nil {
+			t.Fatal("expected error but got none")
+		}
+	})
+
+	t.Run("Plan", func(t *testing.T) {
+		q, err := ParseLiteral(`repo:foo rev:at.time(2021-01-01) bar`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ToBasicQuery(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := &RevAtTimePredicate{TimeRef: "2021-01-01"}
+		plan, err := p.Plan(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tree := plan.ToParseTree()
+		if at, _ := tree.StringValue(FieldRevAtTime); at != "2021-01-01" {
+			t.Fatalf("expected revattime 2021-01-01, got %q", at)
+		}
+		if repos, _ := tree.StringValues(FieldRepo); !reflect.DeepEqual(repos, []string{"foo"}) {
+			t.Fatalf("expected repo filters to be preserved, got %v", repos)
+		}
+		if rev, _ := tree.StringValue(FieldRev); rev != "" {
+			t.Fatalf("expected rev: to be removed, got %q", rev)
+		}
+	})
+
+	t.Run("field is internal", func(t *testing.T) {
+		q, err := ParseLiteral(`revattime:2021-01-01`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if at, _ := Q(q).StringValue(FieldRevAtTime); at != "" {
+			t.Fatalf("expected revattime: not to be parsed as a field, got %q", at)
+		}
+	})
+}
+
 func TestParseAsPredicate(t *testing.T) {
 	tests := []struct {
 		input  string
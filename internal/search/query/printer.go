@@ -30,6 +30,8 @@ func stringHumanPattern(nodes []Node) string {
 				separator = " or "
 			case And:
 				separator = " and "
+			case Near, Before:
+				separator = " " + strings.ToUpper(n.name()) + " "
 			}
 			result = append(result, "("+strings.Join(nested, separator)+")")
 		}
@@ -72,8 +74,16 @@ func StringHuman(nodes []Node) string {
 	if err != nil {
 		// We couldn't partition at this level in the tree, so recurse on operators until we can.
 		var v []string
+		// separate is true if the previous node was a parameter or pattern,
+		// which we separate from the next node by whitespace.
+		separate := false
 		for _, node := range nodes {
-			if term, ok := node.(Operator); ok {
+			switch term := node.(type) {
+			case Operator:
+				if separate {
+					v = append(v, " ")
+					separate = false
+				}
 				var s []string
 				for _, operand := range term.Operands {
 					s = append(s, StringHuman([]Node{operand}))
@@ -83,6 +93,18 @@ func StringHuman(nodes []Node) string {
 				} else if term.Kind == And {
 					v = append(v, "("+strings.Join(s, " and ")+")")
 				}
+			case Parameter, Pattern:
+				// Parameters and patterns may appear next to operators we
+				// can't partition in expanded macros, as in "repo:foo (a or @m)".
+				if len(v) > 0 {
+					v = append(v, " ")
+				}
+				if p, ok := term.(Parameter); ok {
+					v = append(v, stringHumanParameters([]Parameter{p}))
+				} else {
+					v = append(v, stringHumanPattern([]Node{term}))
+				}
+				separate = true
 			}
 		}
 		return strings.Join(v, "")
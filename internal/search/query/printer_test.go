@@ -28,3 +28,11 @@ func TestStringHuman(t *testing.T) {
 	autogold.Want("13", "repo:foo ((not b) and (not c) and a)").Equal(t, test("repo:foo a -content:b -content:c"))
 	autogold.Want("14", "-repo:modspeed -file:pogspeed ((not Phoenicians) and Arizonan)").Equal(t, test("-repo:modspeed -file:pogspeed Arizonan -content:Phoenicians"))
 }
+
+func TestStringHuman_UnpartitionedParameters(t *testing.T) {
+	or := Operator{Kind: Or, Operands: []Node{Pattern{Value: "a"}, Parameter{Field: "file", Value: "x"}}}
+	repo := Parameter{Field: "repo", Value: "foo"}
+
+	autogold.Want("parameter before operator", "repo:foo (a or file:x)").Equal(t, StringHuman([]Node{repo, or}))
+	autogold.Want("parameter after operator", "(a or file:x) repo:foo").Equal(t, StringHuman([]Node{or, repo}))
+}
@@ -66,10 +66,16 @@ func For(searchType SearchType) step {
 // Init creates a step from an input string and search type. It parses the
 // initial input string.
 func Init(in string, searchType SearchType) step {
+	return InitWithMacros(in, searchType, nil)
+}
+
+// InitWithMacros is like Init, but expands references to macros after parsing
+// the input string, before any other processing.
+func InitWithMacros(in string, searchType SearchType, macros Macros) step {
 	parser := func([]Node) ([]Node, error) {
 		return Parse(in, searchType)
 	}
-	return sequence(parser, For(searchType))
+	return sequence(parser, ExpandMacros(macros, searchType), For(searchType))
 }
 
 // InitLiteral is Init where SearchType is Literal.
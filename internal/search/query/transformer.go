@@ -327,7 +327,7 @@ func Hoist(nodes []Node) ([]Node, error) {
 	}
 
 	expression, ok := nodes[0].(Operator)
-	if !ok || expression.Kind == Concat {
+	if !ok || expression.Kind == Concat || expression.Kind.IsProximity() {
 		return nil, errors.Errorf("heuristic requires top-level and- or or-expression")
 	}
 
@@ -405,6 +405,12 @@ func distribute(prefixes [][]Node, nodes []Node) [][]Node {
 				prefixes = result
 			case And, Concat:
 				prefixes = distribute(prefixes, v.Operands)
+			default:
+				if v.Kind.IsProximity() {
+					// Proximity operators are evaluated as a single
+					// pattern, so they are leaves of the DNF.
+					prefixes = product(prefixes, []Node{v})
+				}
 			}
 		case Parameter, Pattern:
 			prefixes = product(prefixes, []Node{v})
@@ -454,7 +460,7 @@ func substituteOrForRegexp(nodes []Node) []Node {
 					newNode = newOperator(append(newNode, rest...), Or)
 				}
 			} else {
-				newNode = append(newNode, newOperator(substituteOrForRegexp(v.Operands), v.Kind)...)
+				newNode = append(newNode, v.withOperands(substituteOrForRegexp(v.Operands))...)
 			}
 		case Parameter, Pattern:
 			newNode = append(newNode, node)
@@ -549,7 +555,7 @@ func substituteConcat(callback func([]Pattern) Pattern) func(nodes []Node) []Nod
 						newNode = append(newNode, callback(ps))
 					}
 				} else {
-					newNode = append(newNode, newOperator(substituteNodes(v.Operands), v.Kind)...)
+					newNode = append(newNode, v.withOperands(substituteNodes(v.Operands))...)
 				}
 			}
 		}
@@ -652,13 +658,28 @@ func FuzzifyRegexPatterns(nodes []Node) []Node {
 }
 
 // concatRevFilters removes rev: filters from parameters and attaches their value as @rev to the repo: filters.
+// A rev:at.time(...) predicate is replaced by a revattime: parameter instead.
 // Invariant: Guaranteed to succeed on a validat Basic query.
 func ConcatRevFilters(b Basic) Basic {
 	var revision string
-	nodes := MapField(ToNodes(b.Parameters), FieldRev, func(value string, _ bool) Node {
+	var atTime bool
+	nodes := MapParameter(ToNodes(b.Parameters), func(field, value string, negated bool, annotation Annotation) Node {
+		if field != FieldRev {
+			return Parameter{Field: field, Value: value, Negated: negated, Annotation: annotation}
+		}
+		if annotation.Labels.IsSet(IsPredicate) {
+			atTime = true
+			var predicate RevAtTimePredicate
+			_, params := ParseAsPredicate(value)
+			_ = predicate.ParseParams(params)
+			return Parameter{Field: FieldRevAtTime, Value: predicate.TimeRef}
+		}
 		revision = value
 		return nil // remove this node
 	})
+	if atTime {
+		return Basic{Parameters: toParameters(nodes), Pattern: b.Pattern}
+	}
 	if revision == "" {
 		return b
 	}
@@ -902,6 +902,14 @@ func TestConcatRevFilters(t *testing.T) {
 			input: "repo:foo file:bas qux AND (rev:a or rev:b)",
 			want:  `("repo:foo@a" "file:bas" "qux") OR ("repo:foo@b" "file:bas" "qux")`,
 		},
+		{
+			input: "repo:foo rev:at.time(2021-01-01) bar",
+			want:  `("repo:foo" "revattime:2021-01-01" "bar")`,
+		},
+		{
+			input: "rev:at.time( 1 year ago ) bar",
+			want:  `("revattime:1 year ago" "bar")`,
+		},
 	}
 	for _, c := range cases {
 		t.Run(c.input, func(t *testing.T) {
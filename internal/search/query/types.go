@@ -197,6 +197,10 @@ func (b Basic) IsCaseSensitive() bool {
 	return Q(ToNodes(b.Parameters)).IsCaseSensitive()
 }
 
+func (b Basic) IsFuzzy() bool {
+	return Q(ToNodes(b.Parameters)).IsFuzzy()
+}
+
 func (b Basic) Index() YesNoOnly {
 	v := Q(ToNodes(b.Parameters)).yesNoOnlyValue(FieldIndex)
 	if v == nil {
@@ -205,6 +209,10 @@ func (b Basic) Index() YesNoOnly {
 	return *v
 }
 
+func (b Basic) Order() ResultOrder {
+	return Q(ToNodes(b.Parameters)).Order()
+}
+
 // A query is a tree of Nodes. We choose the type name Q so that external uses like query.Q do not stutter.
 type Q []Node
 
@@ -290,6 +298,16 @@ func (q Q) Count() *int {
 	return count
 }
 
+// Order returns the order of results requested by the order: field. It
+// defaults to OrderPath.
+func (q Q) Order() ResultOrder {
+	v, _ := q.StringValue(FieldOrder)
+	if o := ParseResultOrder(v); o != "" {
+		return o
+	}
+	return OrderPath
+}
+
 func (q Q) Archived() *YesNoOnly {
 	return q.yesNoOnlyValue(FieldArchived)
 }
@@ -326,6 +344,12 @@ func (q Q) IsCaseSensitive() bool {
 	return q.BoolValue("case")
 }
 
+// IsFuzzy returns whether symbol names should be matched fuzzily, as set by
+// fuzzy:yes.
+func (q Q) IsFuzzy() bool {
+	return q.BoolValue(FieldFuzzy)
+}
+
 func (q Q) Repositories() (repos []string, negatedRepos []string) {
 	VisitField(q, FieldRepo, func(value string, negated bool, _ Annotation) {
 		if negated {
@@ -398,6 +422,9 @@ func (q Q) valueToTypedValue(field, value string, label labels) []*Value {
 
 	case
 		FieldRepoHasCommitAfter,
+		FieldRepoHasKVP,
+		FieldFileHasOwner,
+		FieldRevAtTime,
 		FieldBefore, "until",
 		FieldAfter, "since":
 		return []*Value{{String: &value}}
@@ -412,7 +439,9 @@ func (q Q) valueToTypedValue(field, value string, label labels) []*Value {
 		FieldIndex,
 		FieldCount,
 		FieldTimeout,
-		FieldCombyRule:
+		FieldCombyRule,
+		FieldOrder,
+		FieldFuzzy:
 		return []*Value{{String: &value}}
 	}
 	return []*Value{{String: &value}}
@@ -141,7 +141,7 @@ func processTopLevel(nodes []Node) ([]Node, error) {
 			return nodes, nil
 		} else if term.Kind == And {
 			return term.Operands, nil
-		} else if term.Kind == Concat {
+		} else if term.Kind == Concat || term.Kind.IsProximity() {
 			return nodes, nil
 		} else {
 			return nil, &UnsupportedError{Msg: "cannot evaluate: unable to partition pure search pattern"}
@@ -275,6 +275,13 @@ func validateField(field, value string, negated bool, seen map[string]struct{})
 		return err
 	}
 
+	isValidOrder := func() error {
+		if ParseResultOrder(value) == "" {
+			return errors.Errorf("invalid value %q for field %q. Valid values are: relevance, path, recency", value, field)
+		}
+		return nil
+	}
+
 	satisfies := func(fns ...func() error) error {
 		for _, fn := range fns {
 			if err := fn(); err != nil {
@@ -316,8 +323,13 @@ func validateField(field, value string, negated bool, seen map[string]struct{})
 		FieldRepoHasFile:
 		return satisfies(isValidRegexp)
 	case
-		FieldRepoHasCommitAfter:
+		FieldRepoHasCommitAfter,
+		FieldFileHasOwner,
+		FieldRevAtTime:
 		return satisfies(isSingular, isNotNegated)
+	case
+		FieldRepoHasKVP:
+		return satisfies(isNotNegated)
 	case
 		FieldBefore,
 		FieldAfter:
@@ -347,6 +359,12 @@ func validateField(field, value string, negated bool, seen map[string]struct{})
 	case
 		FieldSelect:
 		return satisfies(isSingular, isNotNegated, isValidSelect)
+	case
+		FieldOrder:
+		return satisfies(isSingular, isNotNegated, isValidOrder)
+	case
+		FieldFuzzy:
+		return satisfies(isSingular, isBoolean, isNotNegated)
 	default:
 		return isUnrecognizedField()
 	}
@@ -379,6 +397,22 @@ func validateRepoRevPair(nodes []Node) error {
 		return errors.New("invalid syntax. You specified both @ and rev: for a" +
 			" repo: filter and I don't know how to interpret this. Remove either @ or rev: and try again")
 	}
+	atTimeSpecified := exists(nodes, func(node Node) bool {
+		n, ok := node.(Parameter)
+		return ok && n.Field == FieldRev && n.Annotation.Labels.IsSet(IsPredicate)
+	})
+	if atTimeSpecified {
+		revWithoutAtTime := exists(nodes, func(node Node) bool {
+			n, ok := node.(Parameter)
+			return ok && n.Field == FieldRev && !n.Annotation.Labels.IsSet(IsPredicate)
+		})
+		if revWithoutAtTime {
+			return errors.New("invalid syntax. The query contains both `rev:` and `rev:at.time()`, and only one revision can be searched. Remove either filter and try again")
+		}
+		// rev:at.time(...) applies to the default branch of every
+		// repository, so it does not need a repo: filter.
+		return nil
+	}
 	if !seenRepo && revSpecified {
 		return errors.New("invalid syntax. The query contains `rev:` without `repo:`. Add a `repo:` filter and try again")
 	}
@@ -407,6 +441,23 @@ func validateCommitParameters(nodes []Node) error {
 	return nil
 }
 
+// Fuzzy matching is only supported for symbol names.
+func validateFuzzy(nodes []Node) error {
+	if !Q(nodes).IsFuzzy() {
+		return nil
+	}
+	typeSymbolExists := false
+	VisitField(nodes, FieldType, func(value string, _ bool, _ Annotation) {
+		if value == "symbol" {
+			typeSymbolExists = true
+		}
+	})
+	if !typeSymbolExists {
+		return errors.New("your query contains fuzzy:yes, which requires type:symbol in the query")
+	}
+	return nil
+}
+
 func validateTypeStructural(nodes []Node) error {
 	seenStructural := false
 	seenType := false
@@ -536,6 +587,7 @@ func validate(nodes []Node) error {
 		validateRepoRevPair,
 		validateRepoHasFile,
 		validateCommitParameters,
+		validateFuzzy,
 		validateTypeStructural,
 	)
 }
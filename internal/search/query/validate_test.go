@@ -17,6 +17,22 @@ func TestAndOrQuery_Validation(t *testing.T) {
 			input: "index:foo",
 			want:  `invalid value "foo" for field "index". Valid values are: yes, only, no`,
 		},
+		{
+			input: "order:stars",
+			want:  `invalid value "stars" for field "order". Valid values are: relevance, path, recency`,
+		},
+		{
+			input: "order:path order:recency",
+			want:  `field "order" may not be used more than once`,
+		},
+		{
+			input: "fuzzy:yes nsm",
+			want:  `your query contains fuzzy:yes, which requires type:symbol in the query`,
+		},
+		{
+			input: "fuzzy:maybe type:symbol nsm",
+			want:  `invalid boolean "maybe"`,
+		},
 		{
 			input: "case:yes case:no",
 			want:  `field "case" may not be used more than once`,
@@ -79,6 +95,18 @@ func TestAndOrQuery_Validation(t *testing.T) {
 			input: `repo:'' rev:bedge`,
 			want:  "invalid syntax. The query contains `rev:` without `repo:`. Add a `repo:` filter and try again",
 		},
+		{
+			input: "repo:foo@a rev:at.time(2021-01-01)",
+			want:  "invalid syntax. You specified both @ and rev: for a repo: filter and I don't know how to interpret this. Remove either @ or rev: and try again",
+		},
+		{
+			input: "repo:foo rev:main rev:at.time(2021-01-01)",
+			want:  "invalid syntax. The query contains both `rev:` and `rev:at.time()`, and only one revision can be searched. Remove either filter and try again",
+		},
+		{
+			input: "rev:at.time() foo",
+			want:  "invalid predicate value: rev:at.time argument should not be empty",
+		},
 		{
 			input: "repo:foo author:rob@saucegraph.com",
 			want:  `your query contains the field 'author', which requires type:commit or type:diff in the query`,
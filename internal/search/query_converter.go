@@ -10,7 +10,9 @@ import (
 	"github.com/go-enry/go-enry/v2"
 	"github.com/sourcegraph/sourcegraph/internal/conf"
 	"github.com/sourcegraph/sourcegraph/internal/search/filter"
+	"github.com/sourcegraph/sourcegraph/internal/search/proximity"
 	"github.com/sourcegraph/sourcegraph/internal/search/query"
+	"github.com/sourcegraph/sourcegraph/internal/search/symbol/fuzzy"
 
 	zoekt "github.com/google/zoekt/query"
 )
@@ -115,7 +117,11 @@ func ToTextPatternInfo(q query.Basic, p Protocol, transform query.BasicPass) *Te
 
 	var pattern string
 	if p, ok := q.Pattern.(query.Pattern); ok {
-		if q.IsLiteral() {
+		if q.IsFuzzy() {
+			// Fuzzy patterns are matched character by character.
+			pattern = p.Value
+			isRegexp = false
+		} else if q.IsLiteral() {
 			// Escape regexp meta characters if this pattern should be treated literally.
 			pattern = regexp.QuoteMeta(p.Value)
 		} else {
@@ -123,6 +129,13 @@ func ToTextPatternInfo(q query.Basic, p Protocol, transform query.BasicPass) *Te
 		}
 	}
 
+	var prox *proximity.Pattern
+	if o, ok := q.Pattern.(query.Operator); ok && o.Kind.IsProximity() {
+		prox = toProximityPattern(q, o)
+		pattern = prox.Regexp()
+		isRegexp = true
+	}
+
 	if q.Pattern == nil {
 		// For compatibility: A nil pattern implies isRegexp is set to
 		// true. This has no effect on search logic.
@@ -153,9 +166,31 @@ func ToTextPatternInfo(q query.Basic, p Protocol, transform query.BasicPass) *Te
 		CombyRule:                    q.FindValue(query.FieldCombyRule),
 		Index:                        q.Index(),
 		Select:                       selector,
+		Proximity:                    prox,
+		IsFuzzy:                      q.IsFuzzy(),
 	}
 }
 
+// toProximityPattern converts a NEAR/n or BEFORE/n operator to a
+// proximity.Pattern. The parser guarantees that its operands are patterns.
+func toProximityPattern(q query.Basic, o query.Operator) *proximity.Pattern {
+	p := &proximity.Pattern{
+		Distance:      o.Distance,
+		Ordered:       o.Kind.Ordered(),
+		CaseSensitive: q.IsCaseSensitive(),
+	}
+	for _, operand := range o.Operands {
+		if pattern, ok := operand.(query.Pattern); ok {
+			value := pattern.Value
+			if pattern.Annotation.Labels.IsSet(query.Literal) {
+				value = regexp.QuoteMeta(value)
+			}
+			p.Patterns = append(p.Patterns, value)
+		}
+	}
+	return p
+}
+
 func TimeoutDuration(b query.Basic) time.Duration {
 	d := DefaultTimeout
 	maxTimeout := time.Duration(SearchLimits(conf.Get()).MaxTimeoutSeconds) * time.Second
@@ -215,7 +250,26 @@ func QueryToZoektQuery(p *TextPatternInfo, forSymbols bool) (zoekt.Q, error) {
 
 	var q zoekt.Q
 	var err error
-	if p.IsRegExp {
+	if p.Proximity != nil {
+		// Zoekt cannot evaluate proximity, so we search for files matching
+		// all operands and filter their line matches afterwards.
+		var operands []zoekt.Q
+		for _, pattern := range p.Proximity.Patterns {
+			operand, err := parseRe(pattern, false, true, p.IsCaseSensitive)
+			if err != nil {
+				return nil, err
+			}
+			operands = append(operands, operand)
+		}
+		q = zoekt.NewAnd(operands...)
+	} else if p.IsFuzzy {
+		// Zoekt can't score fuzzy matches, but it can find the symbols
+		// containing the characters of the pattern in order.
+		q, err = parseRe(fuzzy.Regexp(p.Pattern), false, true, p.IsCaseSensitive)
+		if err != nil {
+			return nil, err
+		}
+	} else if p.IsRegExp {
 		fileNameOnly := p.PatternMatchesPath && !p.PatternMatchesContent
 		contentOnly := !p.PatternMatchesPath && p.PatternMatchesContent
 		q, err = parseRe(p.Pattern, fileNameOnly, contentOnly, p.IsCaseSensitive)
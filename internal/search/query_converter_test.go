@@ -44,6 +44,15 @@ func TestQueryToZoektQuery(t *testing.T) {
 			},
 			Query: "sym:foo case:no",
 		},
+		{
+			Name: "symbol fuzzy",
+			Type: SymbolRequest,
+			Pattern: &TextPatternInfo{
+				IsFuzzy: true,
+				Pattern: "f b",
+			},
+			Query: "sym:f.*?b case:no",
+		},
 		{
 			Name: "regex",
 			Type: TextRequest,
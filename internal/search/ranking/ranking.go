@@ -0,0 +1,199 @@
+// Package ranking orders search results by relevance signals.
+package ranking
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-enry/go-enry/v2"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/search/query"
+	"github.com/sourcegraph/sourcegraph/internal/search/result"
+)
+
+// Signals are the relevance signals of a file match.
+type Signals struct {
+	// MatchCount is the number of matches in the file.
+	MatchCount int
+
+	// PathDepth is the number of directories containing the file.
+	PathDepth int
+
+	IsTest      bool
+	IsVendor    bool
+	IsGenerated bool
+
+	// RepoStars is the star count of the repository on its code host.
+	RepoStars int
+
+	// LastCommit is the date of the last commit modifying the file. It is
+	// zero if unknown.
+	LastCommit time.Time
+}
+
+// StaticSignals returns the signals of fm which can be computed without I/O.
+func StaticSignals(fm *result.FileMatch) Signals {
+	return Signals{
+		MatchCount:  fm.ResultCount(),
+		PathDepth:   strings.Count(strings.Trim(fm.Path, "/"), "/"),
+		IsTest:      enry.IsTest(fm.Path),
+		IsVendor:    enry.IsVendor(fm.Path),
+		IsGenerated: enry.IsGenerated(fm.Path, nil),
+	}
+}
+
+// Scorer computes the relevance score of a file match from its signals. Matches
+// with higher scores are ranked first.
+type Scorer interface {
+	Score(Signals) float64
+}
+
+// ScorerFunc is an adapter to use an ordinary function as a Scorer.
+type ScorerFunc func(Signals) float64
+
+func (f ScorerFunc) Score(s Signals) float64 { return f(s) }
+
+// DefaultScorer is the Scorer used unless another one is configured. Its
+// weights are chosen so that a vendored, generated or test file only ranks
+// above a regular file if it has considerably more matches.
+type DefaultScorer struct {
+	// Now returns the current time. It defaults to time.Now.
+	Now func() time.Time
+}
+
+const (
+	vendorPenalty    = 3
+	generatedPenalty = 2
+	testPenalty      = 0.5
+	depthPenalty     = 0.1
+
+	// recencyHalfLife is the age at which a file's recency boost is halved.
+	recencyHalfLife = 90 * 24 * time.Hour
+)
+
+func (d DefaultScorer) Score(s Signals) float64 {
+	score := math.Log1p(float64(s.MatchCount))
+	score += 0.5 * math.Log10(1+float64(s.RepoStars))
+	score -= depthPenalty * float64(s.PathDepth)
+	if s.IsVendor {
+		score -= vendorPenalty
+	}
+	if s.IsGenerated {
+		score -= generatedPenalty
+	}
+	if s.IsTest {
+		score -= testPenalty
+	}
+	if !s.LastCommit.IsZero() {
+		now := time.Now
+		if d.Now != nil {
+			now = d.Now
+		}
+		age := now().Sub(s.LastCommit)
+		if age < 0 {
+			age = 0
+		}
+		score += math.Pow(0.5, float64(age)/float64(recencyHalfLife))
+	}
+	return score
+}
+
+// Ranker sorts search results according to a query.ResultOrder.
+type Ranker struct {
+	Source Source
+	Scorer Scorer
+}
+
+// NewRanker returns a Ranker loading signals from src and scoring matches with
+// the DefaultScorer.
+func NewRanker(src Source) *Ranker {
+	return &Ranker{Source: src, Scorer: DefaultScorer{}}
+}
+
+// Rank sorts matches in place by order. File matches are ranked before all
+// other matches, which are sorted as for query.OrderPath. Failing to load a
+// signal does not fail ranking, the signal is left at its zero value instead.
+func (r *Ranker) Rank(ctx context.Context, matches []result.Match, order query.ResultOrder) {
+	if order != query.OrderRelevance && order != query.OrderRecency {
+		sort.SliceStable(matches, func(i, j int) bool { return matches[i].Key().Less(matches[j].Key()) })
+		return
+	}
+
+	signals := make(map[result.Key]*Signals, len(matches))
+	var files []*result.FileMatch
+	for _, m := range matches {
+		if fm, ok := m.(*result.FileMatch); ok {
+			s := StaticSignals(fm)
+			signals[fm.Key()] = &s
+			files = append(files, fm)
+		}
+	}
+	r.loadSignals(ctx, files, signals, order)
+
+	scores := make(map[result.Key]float64, len(signals))
+	for key, s := range signals {
+		scores[key] = r.Scorer.Score(*s)
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		left, right := matches[i].Key(), matches[j].Key()
+		ls, lok := signals[left]
+		rs, rok := signals[right]
+		if lok != rok {
+			return lok
+		}
+		if !lok {
+			return left.Less(right)
+		}
+		if order == query.OrderRecency && !ls.LastCommit.Equal(rs.LastCommit) {
+			return ls.LastCommit.After(rs.LastCommit)
+		}
+		if scores[left] != scores[right] {
+			return scores[left] > scores[right]
+		}
+		return left.Less(right)
+	})
+}
+
+// loadSignals populates the signals of files which require I/O.
+func (r *Ranker) loadSignals(ctx context.Context, files []*result.FileMatch, signals map[result.Key]*Signals, order query.ResultOrder) {
+	if r.Source == nil || len(files) == 0 {
+		return
+	}
+
+	ids := make([]api.RepoID, 0, len(files))
+	seen := make(map[api.RepoID]struct{}, len(files))
+	for _, fm := range files {
+		if _, ok := seen[fm.Repo.ID]; !ok {
+			seen[fm.Repo.ID] = struct{}{}
+			ids = append(ids, fm.Repo.ID)
+		}
+	}
+	if stars, err := r.Source.RepoStars(ctx, ids); err == nil {
+		for _, fm := range files {
+			signals[fm.Key()].RepoStars = stars[fm.Repo.ID]
+		}
+	}
+
+	// Looking up the last commit of a file is expensive, so when ranking by
+	// relevance we only do it for the files we are most likely to rank first.
+	// Ranking by recency needs the date of every file.
+	lookups := make([]*result.FileMatch, len(files))
+	copy(lookups, files)
+	if order == query.OrderRelevance {
+		sort.SliceStable(lookups, func(i, j int) bool {
+			return r.Scorer.Score(*signals[lookups[i].Key()]) > r.Scorer.Score(*signals[lookups[j].Key()])
+		})
+		if len(lookups) > maxLastCommitLookups {
+			lookups = lookups[:maxLastCommitLookups]
+		}
+	}
+	dates := lastCommits(ctx, r.Source, lookups)
+	for i, fm := range lookups {
+		signals[fm.Key()].LastCommit = dates[i]
+	}
+}
@@ -0,0 +1,164 @@
+package ranking
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/search/query"
+	"github.com/sourcegraph/sourcegraph/internal/search/result"
+	"github.com/sourcegraph/sourcegraph/internal/types"
+)
+
+type fakeSource struct {
+	stars       map[api.RepoID]int
+	lastCommits map[string]time.Time
+}
+
+func (s *fakeSource) RepoStars(_ context.Context, _ []api.RepoID) (map[api.RepoID]int, error) {
+	return s.stars, nil
+}
+
+func (s *fakeSource) LastCommit(_ context.Context, repo api.RepoName, _ api.CommitID, path string) (time.Time, error) {
+	return s.lastCommits[string(repo)+"/"+path], nil
+}
+
+func fileMatch(repoID api.RepoID, repo, path string, matches int) *result.FileMatch {
+	fm := &result.FileMatch{File: result.File{
+		Repo:     types.RepoName{ID: repoID, Name: api.RepoName(repo)},
+		CommitID: "deadbeef",
+		Path:     path,
+	}}
+	for i := 0; i < matches; i++ {
+		fm.LineMatches = append(fm.LineMatches, &result.LineMatch{
+			LineNumber:       int32(i),
+			OffsetAndLengths: [][2]int32{{0, 1}},
+		})
+	}
+	return fm
+}
+
+func paths(matches []result.Match) []string {
+	var out []string
+	for _, m := range matches {
+		switch v := m.(type) {
+		case *result.FileMatch:
+			out = append(out, string(v.Repo.Name)+"/"+v.Path)
+		case *result.RepoMatch:
+			out = append(out, string(v.Name))
+		}
+	}
+	return out
+}
+
+func TestRank(t *testing.T) {
+	now := time.Date(2021, 8, 1, 0, 0, 0, 0, time.UTC)
+	src := &fakeSource{
+		stars: map[api.RepoID]int{1: 10, 2: 10000},
+		lastCommits: map[string]time.Time{
+			"a/vendor/github.com/pkg/errors/errors.go": now.Add(-1000 * 24 * time.Hour),
+			"a/internal/errors/errors.go":              now.Add(-10 * 24 * time.Hour),
+			"a/internal/errors/errors_test.go":         now.Add(-1 * 24 * time.Hour),
+			"b/errors.go":                              now.Add(-365 * 24 * time.Hour),
+		},
+	}
+	ranker := &Ranker{Source: src, Scorer: DefaultScorer{Now: func() time.Time { return now }}}
+
+	newMatches := func() []result.Match {
+		return []result.Match{
+			&result.RepoMatch{ID: 1, Name: "a"},
+			fileMatch(1, "a", "vendor/github.com/pkg/errors/errors.go", 5),
+			fileMatch(1, "a", "internal/errors/errors.go", 3),
+			fileMatch(1, "a", "internal/errors/errors_test.go", 3),
+			fileMatch(2, "b", "errors.go", 1),
+		}
+	}
+
+	tests := []struct {
+		order query.ResultOrder
+		want  []string
+	}{{
+		order: query.OrderRelevance,
+		want: []string{
+			"b/errors.go",
+			"a/internal/errors/errors.go",
+			"a/internal/errors/errors_test.go",
+			"a/vendor/github.com/pkg/errors/errors.go",
+			"a",
+		},
+	}, {
+		order: query.OrderRecency,
+		want: []string{
+			"a/internal/errors/errors_test.go",
+			"a/internal/errors/errors.go",
+			"b/errors.go",
+			"a/vendor/github.com/pkg/errors/errors.go",
+			"a",
+		},
+	}, {
+		order: query.OrderPath,
+		want: []string{
+			"a",
+			"a/internal/errors/errors.go",
+			"a/internal/errors/errors_test.go",
+			"a/vendor/github.com/pkg/errors/errors.go",
+			"b/errors.go",
+		},
+	}}
+
+	for _, tc := range tests {
+		t.Run(string(tc.order), func(t *testing.T) {
+			matches := newMatches()
+			ranker.Rank(context.Background(), matches, tc.order)
+			if diff := cmp.Diff(tc.want, paths(matches)); diff != "" {
+				t.Fatalf("unexpected order (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRankRecencyLooksUpAllFiles(t *testing.T) {
+	now := time.Date(2021, 8, 1, 0, 0, 0, 0, time.UTC)
+	src := &fakeSource{lastCommits: map[string]time.Time{}}
+
+	var matches []result.Match
+	for i := 0; i <= maxLastCommitLookups; i++ {
+		path := fmt.Sprintf("file%03d.go", i)
+		src.lastCommits["a/"+path] = now.Add(-time.Duration(maxLastCommitLookups-i) * time.Hour)
+		matches = append(matches, fileMatch(1, "a", path, 1))
+	}
+
+	ranker := &Ranker{Source: src, Scorer: DefaultScorer{Now: func() time.Time { return now }}}
+	ranker.Rank(context.Background(), matches, query.OrderRecency)
+
+	want := fmt.Sprintf("a/file%03d.go", maxLastCommitLookups)
+	if got := paths(matches)[0]; got != want {
+		t.Fatalf("got %q ranked first, want %q", got, want)
+	}
+}
+
+func TestStaticSignals(t *testing.T) {
+	tests := []struct {
+		path string
+		want Signals
+	}{
+		{"main.go", Signals{MatchCount: 1}},
+		{"cmd/frontend/main.go", Signals{MatchCount: 1, PathDepth: 2}},
+		{"vendor/github.com/pkg/errors/errors.go", Signals{MatchCount: 1, PathDepth: 4, IsVendor: true, IsGenerated: true}},
+		{"internal/search/search_test.go", Signals{MatchCount: 1, PathDepth: 2, IsTest: true}},
+		{"web/package-lock.json", Signals{MatchCount: 1, PathDepth: 1, IsGenerated: true}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.path, func(t *testing.T) {
+			got := StaticSignals(fileMatch(1, "a", tc.path, 1))
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Fatalf("unexpected signals (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
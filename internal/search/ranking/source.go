@@ -0,0 +1,99 @@
+package ranking
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/database"
+	"github.com/sourcegraph/sourcegraph/internal/database/dbutil"
+	"github.com/sourcegraph/sourcegraph/internal/search/result"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+)
+
+const (
+	// maxLastCommitLookups is the maximum number of files for which we look
+	// up the last commit when ranking a result set by relevance.
+	maxLastCommitLookups = 200
+
+	// lastCommitConcurrency is the number of concurrent last commit lookups.
+	lastCommitConcurrency = 8
+)
+
+// Source loads the signals of file matches which require I/O.
+type Source interface {
+	// RepoStars returns the star count of the repositories in ids.
+	RepoStars(ctx context.Context, ids []api.RepoID) (map[api.RepoID]int, error)
+
+	// LastCommit returns the date of the last commit modifying path in repo
+	// at commit.
+	LastCommit(ctx context.Context, repo api.RepoName, commit api.CommitID, path string) (time.Time, error)
+}
+
+// NewSource returns a Source loading repository metadata from db and commits
+// from gitserver.
+func NewSource(db dbutil.DB) Source {
+	return &source{db: db}
+}
+
+type source struct {
+	db dbutil.DB
+}
+
+func (s *source) RepoStars(ctx context.Context, ids []api.RepoID) (map[api.RepoID]int, error) {
+	repos, err := database.Repos(s.db).Metadata(ctx, ids...)
+	if err != nil {
+		return nil, err
+	}
+	stars := make(map[api.RepoID]int, len(repos))
+	for _, r := range repos {
+		stars[r.ID] = r.Stars
+	}
+	return stars, nil
+}
+
+func (s *source) LastCommit(ctx context.Context, repo api.RepoName, commit api.CommitID, path string) (time.Time, error) {
+	commits, err := git.Commits(ctx, repo, git.CommitsOptions{
+		Range:            string(commit),
+		N:                1,
+		Path:             path,
+		NoEnsureRevision: true,
+	})
+	if err != nil || len(commits) == 0 {
+		return time.Time{}, err
+	}
+	if c := commits[0]; c.Committer != nil {
+		return c.Committer.Date, nil
+	}
+	return commits[0].Author.Date, nil
+}
+
+// lastCommits returns the date of the last commit of each file in files.
+// Dates which cannot be loaded are zero.
+func lastCommits(ctx context.Context, src Source, files []*result.FileMatch) []time.Time {
+	dates := make([]time.Time, len(files))
+	sem := make(chan struct{}, lastCommitConcurrency)
+	var wg sync.WaitGroup
+	for i, fm := range files {
+		if fm.CommitID == "" {
+			continue
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, fm *result.FileMatch) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if date, err := src.LastCommit(ctx, fm.Repo.Name, fm.CommitID, fm.Path); err == nil {
+				dates[i] = date
+			}
+		}(i, fm)
+	}
+	wg.Wait()
+	return dates
+}
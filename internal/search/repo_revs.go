@@ -110,6 +110,8 @@ func (r *RepositoryRevisions) Equal(other *RepositoryRevisions) bool {
 // - 'foo@*bar' refers to the 'foo' repo and all refs matching the glob 'bar/*',
 //   because git interprets the ref glob 'bar' as being 'bar/*' (see `man git-log`
 //   section on the --glob flag)
+// - 'foo@release/*' refers to the 'foo' repo and all branches matching the glob
+//   'release/*', i.e. it is the same as 'foo@*refs/heads/release/*'
 func ParseRepositoryRevisions(repoAndOptionalRev string) (string, []RevisionSpecifier) {
 	i := strings.Index(repoAndOptionalRev, "@")
 	if i == -1 {
@@ -140,6 +142,8 @@ func parseRev(spec string) RevisionSpecifier {
 		return RevisionSpecifier{ExcludeRefGlob: spec[2:]}
 	} else if strings.HasPrefix(spec, "*") {
 		return RevisionSpecifier{RefGlob: spec[1:]}
+	} else if git.IsBranchGlob(spec) {
+		return RevisionSpecifier{RefGlob: git.BranchRefGlob(spec).Include}
 	}
 	return RevisionSpecifier{RevSpec: spec}
 }
@@ -125,7 +125,7 @@ func (r *Resolver) Resolve(ctx context.Context, op search.RepoOptions) (Resolved
 
 	var searchableRepos []types.RepoName
 
-	if envvar.SourcegraphDotComMode() && len(includePatterns) == 0 && !query.HasTypeRepo(op.Query) && searchcontexts.IsGlobalSearchContext(searchContext) {
+	if envvar.SourcegraphDotComMode() && len(includePatterns) == 0 && len(op.HasKVPs) == 0 && !query.HasTypeRepo(op.Query) && searchcontexts.IsGlobalSearchContext(searchContext) {
 		start := time.Now()
 		searchableRepos, err = searchableRepositories(ctx, r.SearchableReposFunc, excludePatterns)
 		if err != nil {
@@ -163,6 +163,10 @@ func (r *Resolver) Resolve(ctx context.Context, op search.RepoOptions) (Resolved
 			OnlyPrivate:  op.Visibility == query.Private,
 		}
 
+		for _, kvp := range op.HasKVPs {
+			options.KVPFilters = append(options.KVPFilters, database.RepoKVPFilter{Key: kvp.Key, Value: kvp.Value})
+		}
+
 		if searchContext.ID != 0 {
 			options.SearchContextID = searchContext.ID
 		} else if searchContext.NamespaceUserID != 0 {
@@ -311,6 +315,13 @@ func (r *Resolver) Resolve(ctx context.Context, op search.RepoOptions) (Resolved
 		tr.LazyPrintf("repohascommitafter removed %d repos in %s", before-len(repoRevs), time.Since(start))
 	}
 
+	if op.RevAtTime != "" && err == nil {
+		start := time.Now()
+		before := len(repoRevs)
+		repoRevs, err = resolveRevAtTime(ctx, repoRevs, op.RevAtTime)
+		tr.LazyPrintf("revattime removed %d repos in %s", before-len(repoRevs), time.Since(start))
+	}
+
 	return Resolved{
 		RepoRevs:        repoRevs,
 		RepoSet:         repoSet,
@@ -604,6 +615,48 @@ func filterRepoHasCommitAfter(ctx context.Context, revisions []*search.Repositor
 	return pass, err
 }
 
+// resolveRevAtTime replaces the revisions of each repository with the commit
+// its default branch pointed to at the given time. Repositories without a
+// commit before the time are removed.
+func resolveRevAtTime(ctx context.Context, revisions []*search.RepositoryRevisions, at string) ([]*search.RepositoryRevisions, error) {
+	var (
+		mut  sync.Mutex
+		pass []*search.RepositoryRevisions
+		run  = parallel.NewRun(128)
+	)
+
+	for _, revs := range revisions {
+		run.Acquire()
+
+		revs := revs
+		goroutine.Go(func() {
+			defer run.Release()
+
+			commit, err := git.CommitBefore(ctx, revs.GitserverRepo(), at, "HEAD")
+			if err != nil {
+				if errors.HasType(err, &gitserver.RevisionNotFoundError{}) || vcs.IsRepoNotExist(err) {
+					return
+				}
+				run.Error(err)
+				return
+			}
+			if commit == "" {
+				return
+			}
+
+			mut.Lock()
+			pass = append(pass, &search.RepositoryRevisions{
+				Repo: revs.Repo,
+				Revs: []search.RevisionSpecifier{{RevSpec: string(commit)}},
+			})
+			mut.Unlock()
+		})
+	}
+
+	err := run.Wait()
+	return pass, err
+}
+
 func optimizeRepoPatternWithHeuristics(repoPattern string) string {
 	if envvar.SourcegraphDotComMode() && (strings.HasPrefix(repoPattern, "github.com") || strings.HasPrefix(repoPattern, `github\.com`)) {
 		repoPattern = "^" + repoPattern
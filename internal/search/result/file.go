@@ -46,6 +46,10 @@ type FileMatch struct {
 	LineMatches []*LineMatch
 	Symbols     []*SymbolMatch `json:"-"`
 
+	// Owners are the owners of the file according to the CODEOWNERS file of
+	// the repository. It is only populated for `select:file.owners`.
+	Owners []string `json:"-"`
+
 	LimitHit bool
 }
 
@@ -79,6 +83,8 @@ func (fm *FileMatch) Select(selectPath filter.SelectPath) Match {
 		if len(selectPath) > 1 && selectPath[1] == "directory" {
 			fm.Path = path.Clean(path.Dir(fm.Path)) + "/" // Add trailing slash for clarity.
 		}
+		// For select:file.owners the owners are populated after selection, since
+		// resolving them requires reading CODEOWNERS files from gitserver.
 		return fm
 	case filter.Symbol:
 		if len(fm.Symbols) > 0 {
@@ -26,6 +26,13 @@ type Symbol struct {
 	Signature  string
 	Pattern    string
 
+	// EndLine and EndCharacter are the end of the definition of the symbol,
+	// e.g. the closing brace of a function. EndLine is 1-based like Line and
+	// EndCharacter is 0-based. They are zero if the parser of the symbol
+	// doesn't record them, which is the case for ctags. See DefinitionRange.
+	EndLine      int
+	EndCharacter int
+
 	FileLimited bool
 }
 
@@ -195,6 +202,19 @@ func (s Symbol) Range() lsp.Range {
 	}
 }
 
+// DefinitionRange returns the range of the lines of the definition of the
+// symbol, e.g. a function including its body. It returns false if the parser
+// of the symbol doesn't record where definitions end.
+func (s Symbol) DefinitionRange() (lsp.Range, bool) {
+	if s.EndLine == 0 {
+		return lsp.Range{}, false
+	}
+	return lsp.Range{
+		Start: lsp.Position{Line: s.Line - 1, Character: 0},
+		End:   lsp.Position{Line: s.EndLine - 1, Character: s.EndCharacter},
+	}, true
+}
+
 // Symbols is the result of a search on the symbols service.
 type Symbols = []Symbol
 
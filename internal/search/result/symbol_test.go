@@ -22,6 +22,24 @@ func TestSymbolRange(t *testing.T) {
 	})
 }
 
+func TestSymbolDefinitionRange(t *testing.T) {
+	if _, ok := (Symbol{Line: 1, Name: "foo"}).DefinitionRange(); ok {
+		t.Fatal("expected no definition range without an end")
+	}
+
+	want := lsp.Range{
+		Start: lsp.Position{Line: 2, Character: 0},
+		End:   lsp.Position{Line: 4, Character: 1},
+	}
+	got, ok := Symbol{Line: 3, Name: "foo", EndLine: 5, EndCharacter: 1}.DefinitionRange()
+	if !ok {
+		t.Fatal("expected definition range")
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
 func TestSymbolURL(t *testing.T) {
 	repoA := types.RepoName{Name: "repo/A", ID: 1}
 	fileAA := File{Repo: repoA, Path: "A"}
@@ -73,6 +73,7 @@ func Search(
 			IsNegated:                    p.IsNegated,
 			PatternMatchesContent:        p.PatternMatchesContent,
 			PatternMatchesPath:           p.PatternMatchesPath,
+			Proximity:                    p.Proximity,
 		},
 		Indexed:          indexed,
 		FetchTimeout:     fetchTimeout.String(),
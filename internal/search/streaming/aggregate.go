@@ -0,0 +1,154 @@
+package streaming
+
+import (
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/sourcegraph/sourcegraph/internal/search/result"
+)
+
+// AggregateMode is the dimension by which an Aggregate groups results.
+type AggregateMode string
+
+const (
+	AggregateByRepo    AggregateMode = "repo"
+	AggregateByPath    AggregateMode = "path"
+	AggregateByAuthor  AggregateMode = "author"
+	AggregateByCapture AggregateMode = "capture"
+)
+
+// ParseAggregateMode returns the AggregateMode for s. It is case insensitive.
+func ParseAggregateMode(s string) (AggregateMode, error) {
+	switch mode := AggregateMode(strings.ToLower(s)); mode {
+	case AggregateByRepo, AggregateByPath, AggregateByAuthor, AggregateByCapture:
+		return mode, nil
+	}
+	return "", errors.Errorf("unsupported aggregation %q, expected one of repo, path, author or capture", s)
+}
+
+// AggregateGroup is the number of matches in one group of an Aggregate.
+type AggregateGroup struct {
+	// Label identifies the group, e.g. a repository name or a captured value.
+	Label string
+
+	// Count is the number of matches in the group.
+	Count int
+}
+
+// Aggregate counts matches grouped by an AggregateMode. Unlike
+// SearchFilters, which only tracks the most relevant filters, an Aggregate
+// counts every match it is updated with so it yields an exact histogram over
+// the full result set.
+type Aggregate struct {
+	Mode AggregateMode
+
+	capture *regexp.Regexp
+	groups  map[string]int
+}
+
+// NewAggregate returns an Aggregate grouping by mode. pattern is the regular
+// expression used by AggregateByCapture and must be empty for other modes.
+// Values are grouped by the first capture group of pattern, or by the whole
+// match if pattern has no capture groups.
+func NewAggregate(mode AggregateMode, pattern string) (*Aggregate, error) {
+	a := &Aggregate{Mode: mode, groups: make(map[string]int)}
+	if mode != AggregateByCapture {
+		if pattern != "" {
+			return nil, errors.Errorf("a pattern is only supported when aggregating by capture, not by %s", mode)
+		}
+		return a, nil
+	}
+	if pattern == "" {
+		return nil, errors.New("aggregating by capture requires a pattern")
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, errors.Errorf("invalid aggregation pattern: %w", err)
+	}
+	a.capture = re
+	return a, nil
+}
+
+// Update adds the results of event to the aggregate.
+func (a *Aggregate) Update(event SearchEvent) {
+	for _, match := range event.Results {
+		switch a.Mode {
+		case AggregateByRepo:
+			a.groups[string(match.RepoName().Name)] += match.ResultCount()
+
+		case AggregateByPath:
+			if fm, ok := match.(*result.FileMatch); ok {
+				a.groups[path.Join(string(fm.Repo.Name), fm.Path)] += fm.ResultCount()
+			}
+
+		case AggregateByAuthor:
+			if cm, ok := match.(*result.CommitMatch); ok {
+				a.groups[cm.Commit.Author.Name] += cm.ResultCount()
+			}
+
+		case AggregateByCapture:
+			for _, value := range matchValues(match) {
+				a.addCaptures(value)
+			}
+		}
+	}
+}
+
+// matchValues returns the strings of match that a capture pattern is run
+// against.
+func matchValues(match result.Match) []string {
+	switch v := match.(type) {
+	case *result.FileMatch:
+		values := make([]string, 0, len(v.LineMatches)+len(v.Symbols))
+		for _, lm := range v.LineMatches {
+			values = append(values, lm.Preview)
+		}
+		for _, sm := range v.Symbols {
+			values = append(values, sm.Symbol.Name)
+		}
+		if len(values) == 0 {
+			values = append(values, v.Path)
+		}
+		return values
+	case *result.CommitMatch:
+		return []string{v.Body.Value}
+	case *result.RepoMatch:
+		return []string{string(v.Name)}
+	}
+	return nil
+}
+
+func (a *Aggregate) addCaptures(value string) {
+	group := 0
+	if a.capture.NumSubexp() > 0 {
+		group = 1
+	}
+	for _, m := range a.capture.FindAllStringSubmatchIndex(value, -1) {
+		start, end := m[2*group], m[2*group+1]
+		if start < 0 {
+			// The capture group did not participate in the match.
+			continue
+		}
+		a.groups[value[start:end]]++
+	}
+}
+
+// Compute returns the groups ordered by descending count. Groups with equal
+// counts are ordered alphabetically.
+func (a *Aggregate) Compute() []AggregateGroup {
+	groups := make([]AggregateGroup, 0, len(a.groups))
+	for label, count := range a.groups {
+		groups = append(groups, AggregateGroup{Label: label, Count: count})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Count != groups[j].Count {
+			return groups[i].Count > groups[j].Count
+		}
+		return groups[i].Label < groups[j].Label
+	})
+	return groups
+}
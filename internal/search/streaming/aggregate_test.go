@@ -0,0 +1,104 @@
+package streaming
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/search/result"
+	"github.com/sourcegraph/sourcegraph/internal/types"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+)
+
+func TestAggregate(t *testing.T) {
+	fileMatch := func(repo, path string, lines ...string) *result.FileMatch {
+		fm := &result.FileMatch{File: result.File{
+			Repo: types.RepoName{Name: api.RepoName("github.com/" + repo)},
+			Path: path,
+		}}
+		for _, line := range lines {
+			fm.LineMatches = append(fm.LineMatches, &result.LineMatch{
+				Preview:          line,
+				OffsetAndLengths: [][2]int32{{0, 1}},
+			})
+		}
+		return fm
+	}
+	commitMatch := func(repo, author string) *result.CommitMatch {
+		return &result.CommitMatch{
+			Repo:   types.RepoName{Name: api.RepoName("github.com/" + repo)},
+			Commit: git.Commit{Author: git.Signature{Name: author}},
+		}
+	}
+
+	event := SearchEvent{Results: []result.Match{
+		fileMatch("a", "main.go", `log.Printf("x")`, `fmt.Printf("y")`),
+		fileMatch("a", "util.go", `fmt.Println("z")`),
+		fileMatch("b", "main.go", `fmt.Printf("x")`),
+		commitMatch("b", "alice"),
+		commitMatch("c", "bob"),
+		commitMatch("c", "alice"),
+	}}
+
+	tests := []struct {
+		mode    AggregateMode
+		pattern string
+		want    []AggregateGroup
+	}{{
+		mode: AggregateByRepo,
+		want: []AggregateGroup{
+			{Label: "github.com/a", Count: 3},
+			{Label: "github.com/b", Count: 2},
+			{Label: "github.com/c", Count: 2},
+		},
+	}, {
+		mode: AggregateByPath,
+		want: []AggregateGroup{
+			{Label: "github.com/a/main.go", Count: 2},
+			{Label: "github.com/a/util.go", Count: 1},
+			{Label: "github.com/b/main.go", Count: 1},
+		},
+	}, {
+		mode: AggregateByAuthor,
+		want: []AggregateGroup{
+			{Label: "alice", Count: 2},
+			{Label: "bob", Count: 1},
+		},
+	}, {
+		mode:    AggregateByCapture,
+		pattern: `(\w+)\.Print`,
+		want: []AggregateGroup{
+			{Label: "fmt", Count: 3},
+			{Label: "log", Count: 1},
+		},
+	}}
+
+	for _, tc := range tests {
+		t.Run(string(tc.mode), func(t *testing.T) {
+			a, err := NewAggregate(tc.mode, tc.pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+			a.Update(event)
+			if diff := cmp.Diff(tc.want, a.Compute()); diff != "" {
+				t.Fatalf("unexpected groups (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNewAggregateErrors(t *testing.T) {
+	if _, err := ParseAggregateMode("lang"); err == nil {
+		t.Fatal("expected error for unsupported mode")
+	}
+	if _, err := NewAggregate(AggregateByCapture, ""); err == nil {
+		t.Fatal("expected error for capture without pattern")
+	}
+	if _, err := NewAggregate(AggregateByCapture, "("); err == nil {
+		t.Fatal("expected error for invalid pattern")
+	}
+	if _, err := NewAggregate(AggregateByRepo, "foo"); err == nil {
+		t.Fatal("expected error for pattern with repo mode")
+	}
+}
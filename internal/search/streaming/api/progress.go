@@ -42,6 +42,10 @@ type ProgressStats struct {
 
 	LimitHit bool
 
+	// Queued is true if the search waits for other expensive searches to
+	// finish.
+	Queued bool
+
 	// SuggestedLimit is what to suggest to the user for count if needed.
 	SuggestedLimit int
 
@@ -185,6 +189,19 @@ func excludedArchiveHandler(resultsResolver ProgressStats) (Skipped, bool) {
 	}, true
 }
 
+func queuedHandler(resultsResolver ProgressStats) (Skipped, bool) {
+	if !resultsResolver.Queued {
+		return Skipped{}, false
+	}
+
+	return Skipped{
+		Reason:   Queued,
+		Title:    "queued",
+		Message:  "This search is expensive, so it waits for your other expensive searches to finish before it starts.",
+		Severity: SeverityInfo,
+	}, true
+}
+
 // TODO implement all skipped reasons
 var skippedHandlers = []func(stats ProgressStats) (Skipped, bool){
 	repositoryMissingHandler,
@@ -196,6 +213,7 @@ var skippedHandlers = []func(stats ProgressStats) (Skipped, bool){
 	excludedForkHandler,
 	excludedArchiveHandler,
 	displayLimitHandler,
+	queuedHandler,
 }
 
 func number(i int) string {
@@ -50,6 +50,9 @@ func TestSearchProgress(t *testing.T) {
 		"traced": {
 			Trace: "abcd",
 		},
+		"queued": {
+			Queued: true,
+		},
 	}
 
 	for name, c := range cases {
@@ -80,6 +80,9 @@ const (
 	// ExcludedArchive is when we did not search a repository because it is
 	// archived.
 	ExcludedArchive SkippedReason = "excluded-archive"
+	// Queued is when we did not start searching yet because the search is
+	// waiting for other expensive searches of the user to finish.
+	Queued SkippedReason = "queued"
 )
 
 // SkippedSeverity is an enum for Skipped.Severity.
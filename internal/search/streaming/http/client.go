@@ -27,12 +27,13 @@ func NewRequest(baseURL string, query string) (*http.Request, error) {
 
 // FrontendStreamDecoder decodes streaming events from the frontend service
 type FrontendStreamDecoder struct {
-	OnProgress func(*api.Progress)
-	OnMatches  func([]EventMatch)
-	OnFilters  func([]*EventFilter)
-	OnAlert    func(*EventAlert)
-	OnError    func(*EventError)
-	OnUnknown  func(event, data []byte)
+	OnProgress  func(*api.Progress)
+	OnMatches   func([]EventMatch)
+	OnFilters   func([]*EventFilter)
+	OnAggregate func([]*EventAggregate)
+	OnAlert     func(*EventAlert)
+	OnError     func(*EventError)
+	OnUnknown   func(event, data []byte)
 }
 
 func (rr FrontendStreamDecoder) ReadAll(r io.Reader) error {
@@ -73,6 +74,15 @@ func (rr FrontendStreamDecoder) ReadAll(r io.Reader) error {
 				return errors.Errorf("failed to decode filters payload: %w", err)
 			}
 			rr.OnFilters(d)
+		} else if bytes.Equal(event, []byte("aggregate")) {
+			if rr.OnAggregate == nil {
+				continue
+			}
+			var d []*EventAggregate
+			if err := json.Unmarshal(data, &d); err != nil {
+				return errors.Errorf("failed to decode aggregate payload: %w", err)
+			}
+			rr.OnAggregate(d)
 		} else if bytes.Equal(event, []byte("alert")) {
 			if rr.OnAlert == nil {
 				continue
@@ -47,6 +47,7 @@ type EventPathMatch struct {
 	RepoLastFetched *time.Time `json:"repoLastFetched,omitempty"`
 	Branches        []string   `json:"branches,omitempty"`
 	Version         string     `json:"version,omitempty"`
+	Owners          []string   `json:"owners,omitempty"`
 }
 
 func (e *EventPathMatch) eventMatch() {}
@@ -156,6 +157,15 @@ type EventFilter struct {
 	Kind     string `json:"kind"`
 }
 
+// EventAggregate is the number of matches in one group of an aggregation
+// requested with the aggregate URL parameter. It is sent once the search has
+// completed successfully, ordered by descending count. It is not sent if
+// results are missing, for example because the count: limit was hit.
+type EventAggregate struct {
+	Label string `json:"label"`
+	Count int    `json:"count"`
+}
+
 // EventAlert is GQL.SearchAlert. It replaces when sent to match existing
 // behaviour.
 type EventAlert struct {
@@ -0,0 +1,276 @@
+// Package fuzzy scores symbol names against fuzzy patterns, like the "go to
+// symbol" of an IDE. The symbols service ranks the symbols of a repository
+// with it, and the frontend merges the results of many repositories by the
+// same scores.
+package fuzzy
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/sourcegraph/sourcegraph/internal/search/result"
+)
+
+// MaxCandidates is the maximum number of symbols we score for a fuzzy search.
+// Symbols are scored in Go, so queries matching more symbols only rank the
+// first candidates.
+const MaxCandidates = 10000
+
+// Scores of fuzzy matches. Matching the start of a word counts most, so that
+// "nsm" matches "NewSymbolMatch" better than "newsummary".
+const (
+	scoreMatch            = 1
+	scoreWordStart        = 8
+	scoreConsecutive      = 4
+	scoreExactCase        = 1
+	scoreFullMatch        = 50
+	scoreFullMatchCase    = 25
+	scoreExported         = 5
+	penaltyUnmatched      = 1 // per 4 unmatched characters
+	penaltyPathComponents = 2 // per directory
+)
+
+// Score returns the score of name for the fuzzy pattern, or false if name
+// doesn't contain the characters of pattern in order.
+func Score(pattern, name string, caseSensitive bool) (int, bool) {
+	p, n := []rune(pattern), []rune(name)
+	if len(p) == 0 {
+		return 0, true
+	}
+	if len(p) > len(n) {
+		return 0, false
+	}
+
+	// best[j] is the best score of matching the pattern so far with its last
+	// character at n[j], or -1 if that isn't possible.
+	best := make([]int, len(n))
+	prev := make([]int, len(n))
+	for j := range prev {
+		prev[j] = -1
+	}
+	for i, pc := range p {
+		// bestBefore is the best score of matching p[:i] ending before j-1.
+		bestBefore := -1
+		for j, nc := range n {
+			best[j] = -1
+			if j >= 2 && prev[j-2] > bestBefore {
+				bestBefore = prev[j-2]
+			}
+			if !runeEqual(pc, nc, caseSensitive) {
+				continue
+			}
+
+			score := scoreMatch
+			if isWordStart(n, j) {
+				score += scoreWordStart
+			}
+			if pc == nc {
+				score += scoreExactCase
+			}
+
+			if i == 0 {
+				best[j] = score
+				continue
+			}
+			from := bestBefore
+			if j >= 1 && prev[j-1] >= 0 && prev[j-1]+scoreConsecutive > from {
+				from = prev[j-1] + scoreConsecutive
+			}
+			if from >= 0 {
+				best[j] = from + score
+			}
+		}
+		best, prev = prev, best
+	}
+
+	score := -1
+	for _, s := range prev {
+		if s > score {
+			score = s
+		}
+	}
+	if score < 0 {
+		return 0, false
+	}
+
+	if len(p) == len(n) {
+		score += scoreFullMatch
+		if pattern == name {
+			score += scoreFullMatchCase
+		}
+	}
+	score -= (len(n) - len(p)) / 4 * penaltyUnmatched
+	return score, true
+}
+
+func runeEqual(a, b rune, caseSensitive bool) bool {
+	if caseSensitive {
+		return a == b
+	}
+	return a == b || unicode.ToLower(a) == unicode.ToLower(b)
+}
+
+// isWordStart returns whether name[i] starts a word of a camelCase,
+// snake_case or kebab-case identifier.
+func isWordStart(name []rune, i int) bool {
+	if i == 0 {
+		return true
+	}
+	prev, cur := name[i-1], name[i]
+	switch {
+	case prev == '_' || prev == '-' || prev == '.' || prev == '$':
+		return true
+	case unicode.IsUpper(cur) && !unicode.IsUpper(prev):
+		return true
+	case unicode.IsDigit(cur) && !unicode.IsDigit(prev):
+		return true
+	case unicode.IsUpper(prev) && unicode.IsUpper(cur) && i+1 < len(name) && unicode.IsLower(name[i+1]):
+		// The "S" in "HTTPServer".
+		return true
+	}
+	return false
+}
+
+// isExported returns whether the symbol with the given name is likely visible
+// outside of its file or package.
+func isExported(name, language string) bool {
+	r, _ := utf8.DecodeRuneInString(name)
+	if strings.EqualFold(language, "go") {
+		return unicode.IsUpper(r)
+	}
+	// Most languages mark private symbols with a leading underscore by
+	// convention.
+	return r != '_'
+}
+
+// SymbolScore returns the score of symbol for the fuzzy pattern, or false if
+// its name doesn't match. Unlike Score, it prefers exported symbols and
+// shallow paths.
+func SymbolScore(pattern string, symbol *result.Symbol, caseSensitive bool) (int, bool) {
+	score, ok := Score(pattern, symbol.Name, caseSensitive)
+	if !ok {
+		return 0, false
+	}
+	if isExported(symbol.Name, symbol.Language) {
+		score += scoreExported
+	}
+	score -= strings.Count(symbol.Path, "/") * penaltyPathComponents
+	return score, true
+}
+
+// Less orders symbols with equal scores deterministically, preferring short
+// paths.
+func Less(a, b *result.Symbol) bool {
+	if len(a.Path) != len(b.Path) {
+		return len(a.Path) < len(b.Path)
+	}
+	if a.Path != b.Path {
+		return a.Path < b.Path
+	}
+	return a.Line < b.Line
+}
+
+// Rank returns the symbols matching the fuzzy pattern, best first.
+func Rank(symbols []result.Symbol, pattern string, caseSensitive bool) []result.Symbol {
+	type scored struct {
+		symbol result.Symbol
+		score  int
+	}
+	matches := make([]scored, 0, len(symbols))
+	for i := range symbols {
+		score, ok := SymbolScore(pattern, &symbols[i], caseSensitive)
+		if !ok {
+			continue
+		}
+		matches = append(matches, scored{symbol: symbols[i], score: score})
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		return Less(&a.symbol, &b.symbol)
+	})
+
+	ranked := make([]result.Symbol, 0, len(matches))
+	for _, m := range matches {
+		ranked = append(ranked, m.symbol)
+	}
+	return ranked
+}
+
+// Regexp returns a regular expression matching the names which contain the
+// characters of pattern other than whitespace in order. Zoekt uses it to find
+// candidates for a fuzzy search in indexed repositories.
+func Regexp(pattern string) string {
+	var b strings.Builder
+	for _, r := range pattern {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString(".*?")
+		}
+		b.WriteString(regexp.QuoteMeta(string(r)))
+	}
+	return b.String()
+}
+
+// RankMatches merges the symbols of the file matches of many repositories by
+// their scores for the fuzzy pattern and returns file matches with the best
+// limit symbols, ordered by their best symbol. It also returns whether it
+// dropped symbols.
+func RankMatches(matches []result.Match, pattern string, caseSensitive bool, limit int) ([]result.Match, bool) {
+	type scored struct {
+		file   *result.FileMatch
+		symbol *result.SymbolMatch
+		score  int
+	}
+	var symbols []scored
+	for _, m := range matches {
+		fm, ok := m.(*result.FileMatch)
+		if !ok {
+			continue
+		}
+		for _, sm := range fm.Symbols {
+			score, ok := SymbolScore(pattern, &sm.Symbol, caseSensitive)
+			if !ok {
+				continue
+			}
+			symbols = append(symbols, scored{file: fm, symbol: sm, score: score})
+		}
+	}
+
+	sort.SliceStable(symbols, func(i, j int) bool {
+		a, b := symbols[i], symbols[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if a.file.Repo.Name != b.file.Repo.Name {
+			return a.file.Repo.Name < b.file.Repo.Name
+		}
+		return Less(&a.symbol.Symbol, &b.symbol.Symbol)
+	})
+
+	limitHit := len(symbols) > limit
+	if limitHit {
+		symbols = symbols[:limit]
+	}
+
+	var ranked []result.Match
+	files := map[*result.FileMatch]*result.FileMatch{}
+	for _, s := range symbols {
+		fm, ok := files[s.file]
+		if !ok {
+			fm = &result.FileMatch{File: s.file.File}
+			files[s.file] = fm
+			ranked = append(ranked, fm)
+		}
+		fm.Symbols = append(fm.Symbols, s.symbol)
+	}
+	return ranked, limitHit
+}
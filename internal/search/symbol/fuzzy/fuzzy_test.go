@@ -0,0 +1,126 @@
+package fuzzy
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/search/result"
+	"github.com/sourcegraph/sourcegraph/internal/types"
+)
+
+func TestScore(t *testing.T) {
+	for _, test := range []struct {
+		pattern, name string
+		caseSensitive bool
+		wantOk        bool
+	}{
+		{pattern: "nsm", name: "NewSymbolMatch", wantOk: true},
+		{pattern: "NSM", name: "NewSymbolMatch", caseSensitive: true, wantOk: true},
+		{pattern: "nsm", name: "NewSymbolMatch", caseSensitive: true, wantOk: false},
+		{pattern: "gdf", name: "get_db_file", wantOk: true},
+		{pattern: "fdg", name: "get_db_file", wantOk: false},
+		{pattern: "toolong", name: "tool", wantOk: false},
+		{pattern: "", name: "anything", wantOk: true},
+	} {
+		_, ok := Score(test.pattern, test.name, test.caseSensitive)
+		if ok != test.wantOk {
+			t.Errorf("Score(%q, %q, %t) matched %t, want %t", test.pattern, test.name, test.caseSensitive, ok, test.wantOk)
+		}
+	}
+
+	// Each pair is ordered from the better to the worse match.
+	for _, test := range []struct {
+		pattern       string
+		better, worse string
+	}{
+		// Word starts beat matches in the middle of words.
+		{"nsm", "NewSymbolMatch", "newsummary"},
+		{"gdf", "get_db_file", "gadfly"},
+		{"srv", "HTTPServer", "observer"},
+		// Consecutive characters beat scattered ones.
+		{"fil", "filter", "fxixl"},
+		// Full matches beat prefixes, and exact case beats other cases.
+		{"server", "server", "serverName"},
+		{"Server", "Server", "server"},
+		// Shorter names beat longer ones.
+		{"parse", "parseFile", "parseFileWithAllTheOptions"},
+	} {
+		better, ok1 := Score(test.pattern, test.better, false)
+		worse, ok2 := Score(test.pattern, test.worse, false)
+		if !ok1 || !ok2 {
+			t.Errorf("expected %q to match %q and %q", test.pattern, test.better, test.worse)
+			continue
+		}
+		if better <= worse {
+			t.Errorf("pattern %q: expected %q (score %d) to score higher than %q (score %d)", test.pattern, test.better, better, test.worse, worse)
+		}
+	}
+}
+
+func TestRank(t *testing.T) {
+	symbols := []result.Symbol{
+		{Name: "newServer", Path: "a/b/c/server.go", Language: "Go"},
+		{Name: "NewServer", Path: "a/b/c/server.go", Language: "Go"},
+		{Name: "NewServer", Path: "server.go", Language: "Go"},
+		{Name: "unrelated", Path: "server.go", Language: "Go"},
+		{Name: "NextServe", Path: "a/serve.go", Language: "Go"},
+	}
+	var got []string
+	for _, symbol := range Rank(symbols, "NewServer", false) {
+		got = append(got, symbol.Path+":"+symbol.Name)
+	}
+	want := []string{
+		// Exact case, exported and shallow.
+		"server.go:NewServer",
+		"a/b/c/server.go:NewServer",
+		"a/b/c/server.go:newServer",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestRankMatches(t *testing.T) {
+	fileMatch := func(repo, path string, names ...string) *result.FileMatch {
+		fm := &result.FileMatch{File: result.File{Repo: types.RepoName{Name: api.RepoName(repo)}, Path: path}}
+		for _, name := range names {
+			fm.Symbols = append(fm.Symbols, &result.SymbolMatch{
+				Symbol: result.Symbol{Name: name, Path: path, Language: "Go"},
+				File:   &fm.File,
+			})
+		}
+		return fm
+	}
+	matches := []result.Match{
+		// Each repository is ranked on its own by the symbols service.
+		fileMatch("a", "x/y/server.go", "NewServer", "newServer"),
+		fileMatch("b", "server.go", "NewServer", "unrelated"),
+		fileMatch("b", "serve.go", "NextServe"),
+	}
+
+	ranked, limitHit := RankMatches(matches, "NewServer", false, 2)
+	if !limitHit {
+		t.Error("expected limit hit")
+	}
+	var got []string
+	for _, m := range ranked {
+		fm := m.(*result.FileMatch)
+		for _, sm := range fm.Symbols {
+			got = append(got, string(fm.Repo.Name)+"/"+fm.Path+":"+sm.Symbol.Name)
+		}
+	}
+	want := []string{
+		"b/server.go:NewServer",
+		"a/x/y/server.go:NewServer",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestRegexp(t *testing.T) {
+	if got, want := Regexp("a. b"), `a.*?\..*?b`; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"regexp"
 	"regexp/syntax"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/cockroachdb/errors"
@@ -23,6 +24,7 @@ import (
 	searchrepos "github.com/sourcegraph/sourcegraph/internal/search/repos"
 	"github.com/sourcegraph/sourcegraph/internal/search/result"
 	"github.com/sourcegraph/sourcegraph/internal/search/streaming"
+	"github.com/sourcegraph/sourcegraph/internal/search/symbol/fuzzy"
 	zoektutil "github.com/sourcegraph/sourcegraph/internal/search/zoekt"
 	"github.com/sourcegraph/sourcegraph/internal/trace"
 	"github.com/sourcegraph/sourcegraph/internal/trace/ot"
@@ -54,6 +56,43 @@ func Search(ctx context.Context, args *search.TextParameters, limit int, stream
 		tr.Finish()
 	}()
 
+	if args.PatternInfo.IsFuzzy {
+		return searchFuzzy(ctx, tr, args, limit, stream)
+	}
+	return searchSymbols(ctx, tr, args, limit, limit, stream)
+}
+
+// searchFuzzy searches for the symbols matching the fuzzy pattern of args.
+// Zoekt and the symbols service find the candidates in each repository, and we
+// merge them by their scores. We can only send the results once all
+// repositories are searched.
+func searchFuzzy(ctx context.Context, tr *trace.Trace, args *search.TextParameters, limit int, stream streaming.Sender) error {
+	// Zoekt doesn't rank candidates like we do, so we ask it for as many as
+	// the symbols service scores.
+	argsCopy := *args
+	patternInfo := *args.PatternInfo
+	patternInfo.FileMatchLimit = fuzzy.MaxCandidates
+	argsCopy.PatternInfo = &patternInfo
+
+	matches, stats, err := streaming.CollectStream(func(s streaming.Sender) error {
+		return searchSymbols(ctx, tr, &argsCopy, fuzzy.MaxCandidates, limit, s)
+	})
+
+	// The symbols service ignores whitespace in fuzzy patterns.
+	pattern := strings.Join(strings.Fields(args.PatternInfo.Pattern), "")
+	matches, limitHit := fuzzy.RankMatches(matches, pattern, args.PatternInfo.IsCaseSensitive, limit)
+	stats.IsLimitHit = stats.IsLimitHit || limitHit
+	tr.LazyPrintf("ranked %d file matches, limit hit: %t", len(matches), limitHit)
+	stream.Send(streaming.SearchEvent{
+		Results: matches,
+		Stats:   stats,
+	})
+	return err
+}
+
+// searchSymbols sends the symbols matching args. It stops after limit results
+// and asks the symbols service for at most repoLimit results per repository.
+func searchSymbols(ctx context.Context, tr *trace.Trace, args *search.TextParameters, limit, repoLimit int, stream streaming.Sender) error {
 	ctx, stream, cancel := streaming.WithLimit(ctx, stream, limit)
 	defer cancel()
 
@@ -92,8 +131,8 @@ func Search(ctx context.Context, args *search.TextParameters, limit int, stream
 		goroutine.Go(func() {
 			defer run.Release()
 
-			matches, err := searchInRepo(ctx, repoRevs, args.PatternInfo, limit)
-			stats, err := searchrepos.HandleRepoSearchResult(repoRevs, len(matches) > limit, false, err)
+			matches, err := searchInRepo(ctx, repoRevs, args.PatternInfo, repoLimit)
+			stats, err := searchrepos.HandleRepoSearchResult(repoRevs, len(matches) > repoLimit, false, err)
 			stream.Send(streaming.SearchEvent{
 				Results: matches,
 				Stats:   stats,
@@ -141,6 +180,7 @@ func searchInRepo(ctx context.Context, repoRevs *search.RepositoryRevisions, pat
 		Query:           patternInfo.Pattern,
 		IsCaseSensitive: patternInfo.IsCaseSensitive,
 		IsRegExp:        patternInfo.IsRegExp,
+		IsFuzzy:         patternInfo.IsFuzzy,
 		IncludePatterns: patternInfo.IncludePatterns,
 		ExcludePattern:  patternInfo.ExcludePattern,
 		// Ask for limit + 1 so we can detect whether there are more results than the limit.
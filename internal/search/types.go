@@ -11,6 +11,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/internal/endpoint"
 	"github.com/sourcegraph/sourcegraph/internal/search/backend"
 	"github.com/sourcegraph/sourcegraph/internal/search/filter"
+	"github.com/sourcegraph/sourcegraph/internal/search/proximity"
 	"github.com/sourcegraph/sourcegraph/internal/search/query"
 	"github.com/sourcegraph/sourcegraph/internal/search/result"
 	"github.com/sourcegraph/sourcegraph/internal/types"
@@ -72,6 +73,11 @@ type SymbolsParameters struct {
 	// when finding matches.
 	IsCaseSensitive bool
 
+	// IsFuzzy if true matches symbol names which contain the characters of
+	// Query in order, ranking the best matches first. It takes precedence
+	// over IsRegExp.
+	IsFuzzy bool
+
 	// IncludePatterns is a list of regexes that symbol's file paths
 	// need to match to get included in the result
 	//
@@ -143,6 +149,10 @@ type ZoektParameters struct {
 	FileMatchLimit int32
 	Select         filter.SelectPath
 
+	// Proximity is set if Query searches for the candidate lines of a
+	// proximity expression. Results must be filtered by it.
+	Proximity *proximity.Pattern
+
 	Zoekt *backend.Zoekt
 }
 
@@ -220,6 +230,15 @@ type TextPatternInfo struct {
 	Index           query.YesNoOnly
 	Select          filter.SelectPath
 
+	// Proximity is set for NEAR/n and BEFORE/n expressions. Pattern is then
+	// the union of its operands, matching candidate lines which backends
+	// filter with Proximity.
+	Proximity *proximity.Pattern
+
+	// IsFuzzy is set for symbol searches with fuzzy:yes. Pattern is then
+	// the unescaped pattern, which symbol names contain as a subsequence.
+	IsFuzzy bool
+
 	// We do not support IsMultiline
 	// IsMultiline     bool
 	IncludePatterns []string
@@ -254,6 +273,12 @@ func (p *TextPatternInfo) String() string {
 	if p.IsCaseSensitive {
 		args = append(args, "case")
 	}
+	if p.Proximity != nil {
+		args = append(args, fmt.Sprintf("proximity:%d", p.Proximity.Distance))
+	}
+	if p.IsFuzzy {
+		args = append(args, "fuzzy")
+	}
 	if !p.PatternMatchesContent {
 		args = append(args, "nocontent")
 	}
@@ -288,6 +313,13 @@ func (p *TextPatternInfo) String() string {
 	return fmt.Sprintf("TextPatternInfo{%s}", strings.Join(args, ","))
 }
 
+// RepoKVPFilter matches repositories with the key/value pair Key=Value in
+// their metadata. A nil Value matches any value of Key.
+type RepoKVPFilter struct {
+	Key   string
+	Value *string
+}
+
 type RepoOptions struct {
 	RepoFilters        []string
 	MinusRepoFilters   []string
@@ -300,6 +332,8 @@ type RepoOptions struct {
 	NoArchived         bool
 	OnlyArchived       bool
 	CommitAfter        string
+	RevAtTime          string
+	HasKVPs            []RepoKVPFilter
 	Visibility         query.RepoVisibility
 	Ranked             bool // Return results ordered by rank
 	Limit              int
@@ -331,6 +365,16 @@ func (op *RepoOptions) String() string {
 	if op.CommitAfter != "" {
 		_, _ = fmt.Fprintf(&b, " CommitAfter=%q", op.CommitAfter)
 	}
+	if op.RevAtTime != "" {
+		_, _ = fmt.Fprintf(&b, " RevAtTime=%q", op.RevAtTime)
+	}
+	for _, kvp := range op.HasKVPs {
+		if kvp.Value != nil {
+			_, _ = fmt.Fprintf(&b, " HasKVP=%q", kvp.Key+":"+*kvp.Value)
+		} else {
+			_, _ = fmt.Fprintf(&b, " HasKVP=%q", kvp.Key)
+		}
+	}
 
 	if op.NoForks {
 		b.WriteString(" NoForks")
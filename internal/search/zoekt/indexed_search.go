@@ -15,14 +15,17 @@ import (
 
 	"github.com/sourcegraph/sourcegraph/internal/actor"
 	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
 	"github.com/sourcegraph/sourcegraph/internal/search"
 	"github.com/sourcegraph/sourcegraph/internal/search/backend"
 	"github.com/sourcegraph/sourcegraph/internal/search/filter"
+	"github.com/sourcegraph/sourcegraph/internal/search/proximity"
 	"github.com/sourcegraph/sourcegraph/internal/search/query"
 	"github.com/sourcegraph/sourcegraph/internal/search/result"
 	"github.com/sourcegraph/sourcegraph/internal/search/streaming"
 	"github.com/sourcegraph/sourcegraph/internal/trace"
 	"github.com/sourcegraph/sourcegraph/internal/types"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
 )
 
 // indexedRepoRevs creates both the Sourcegraph and Zoekt representation of a
@@ -46,8 +49,9 @@ var headBranch = []string{"HEAD"}
 
 // add will add reporev and repo to the list of repository and branches to
 // search if reporev's refs are a subset of repo's branches. It will return
-// the revision specifiers it can't add.
-func (rb *IndexedRepoRevs) add(reporev *search.RepositoryRevisions, repo *zoekt.Repository) []search.RevisionSpecifier {
+// the revision specifiers it can't add. Ref globs are only added if they
+// are in branchGlobs, the branch globs indexed for repo.
+func (rb *IndexedRepoRevs) add(reporev *search.RepositoryRevisions, repo *zoekt.Repository, branchGlobs []string) []search.RevisionSpecifier {
 	// A repo should only appear once in revs. However, in case this
 	// invariant is broken we will treat later revs as if it isn't
 	// indexed.
@@ -56,12 +60,20 @@ func (rb *IndexedRepoRevs) add(reporev *search.RepositoryRevisions, repo *zoekt.
 	}
 
 	if !reporev.OnlyExplicit() {
-		// Contains a RefGlob or ExcludeRefGlob so we can't do indexed
-		// search on it.
-		//
-		// TODO we could only process the explicit revs and return the non
-		// explicit ones as unindexed.
-		return reporev.Revs
+		// Contains a RefGlob or ExcludeRefGlob. We can only search it in
+		// the index if all branches the globs match are indexed.
+		revs, ok := expandIndexedRefGlobs(reporev.Revs, repo, branchGlobs)
+		if !ok {
+			return reporev.Revs
+		}
+		if len(revs) == 0 {
+			// The globs match no indexed branches, so there is nothing to
+			// search.
+			return nil
+		}
+		expanded := reporev.Copy()
+		expanded.Revs = revs
+		reporev = expanded
 	}
 
 	if len(reporev.Revs) == 1 && repo.Branches[0].Name == "HEAD" && (reporev.Revs[0].RevSpec == "" || reporev.Revs[0].RevSpec == "HEAD") {
@@ -108,6 +120,11 @@ func (rb *IndexedRepoRevs) add(reporev *search.RepositoryRevisions, repo *zoekt.
 
 	// We found indexed branches! Track them.
 	if len(indexed) > 0 {
+		if len(unindexed) > 0 {
+			// Maintain the invariant between repoBranches and Revs.
+			reporev = reporev.Copy()
+			reporev.Revs = indexed
+		}
 		rb.repoRevs[string(reporev.Repo.Name)] = reporev
 		rb.repoBranches[string(reporev.Repo.Name)] = branches
 	}
@@ -115,6 +132,62 @@ func (rb *IndexedRepoRevs) add(reporev *search.RepositoryRevisions, repo *zoekt.
 	return unindexed
 }
 
+// expandIndexedRefGlobs replaces the ref globs in revs with the branches of
+// repo they match. It returns false if an include glob isn't one of
+// branchGlobs or repo has the maximum number of indexed branches, since the
+// glob may match branches that aren't indexed.
+func expandIndexedRefGlobs(revs []search.RevisionSpecifier, repo *zoekt.Repository, branchGlobs []string) ([]search.RevisionSpecifier, bool) {
+	if len(repo.Branches) >= backend.MaxIndexedBranches {
+		return nil, false
+	}
+
+	indexedGlobs := make(map[string]struct{}, len(branchGlobs))
+	for _, g := range branchGlobs {
+		indexedGlobs[git.BranchRefGlob(g).Include] = struct{}{}
+	}
+
+	var (
+		globs    []git.RefGlob
+		expanded []search.RevisionSpecifier
+		explicit = map[string]struct{}{}
+	)
+	for _, rev := range revs {
+		switch {
+		case rev.RefGlob != "":
+			include := rev.RefGlob
+			if !strings.HasPrefix(include, "refs/") {
+				include = "refs/" + include
+			}
+			if _, ok := indexedGlobs[include]; !ok {
+				return nil, false
+			}
+			globs = append(globs, git.RefGlob{Include: include})
+		case rev.ExcludeRefGlob != "":
+			globs = append(globs, git.RefGlob{Exclude: rev.ExcludeRefGlob})
+		default:
+			expanded = append(expanded, rev)
+			explicit[rev.RevSpec] = struct{}{}
+		}
+	}
+
+	rg, err := git.CompileRefGlobs(globs)
+	if err != nil {
+		return nil, false
+	}
+	for _, branch := range repo.Branches {
+		if branch.Name == "HEAD" {
+			continue
+		}
+		if _, ok := explicit[branch.Name]; ok {
+			continue
+		}
+		if rg.Match("refs/heads/" + branch.Name) {
+			expanded = append(expanded, search.RevisionSpecifier{RevSpec: branch.Name})
+		}
+	}
+	return expanded, true
+}
+
 // getRepoInputRev returns the repo and inputRev associated with file.
 func (rb *IndexedRepoRevs) getRepoInputRev(file *zoekt.FileMatch) (repo types.RepoName, inputRevs []string) {
 	repoRev := rb.repoRevs[file.Repository]
@@ -169,8 +242,13 @@ func (s *IndexedUniverseSearchRequest) Search(ctx context.Context, c streaming.S
 		return nil
 	}
 
+	c, selector, err := withProximityFilter(s.Args, c)
+	if err != nil {
+		return err
+	}
+
 	q := zoektGlobalQuery(s.Args.Query, s.RepoOptions, s.UserPrivateRepos)
-	return doZoektSearchGlobal(ctx, q, s.Args.Typ, s.Args.Zoekt.Client, s.Args.FileMatchLimit, s.Args.Select, c)
+	return doZoektSearchGlobal(ctx, q, s.Args.Typ, s.Args.Zoekt.Client, s.Args.FileMatchLimit, selector, c)
 }
 
 // IndexedRepos for a request over the indexed universe cannot answer which
@@ -204,6 +282,7 @@ func NewIndexedUniverseSearchRequest(ctx context.Context, args *search.TextParam
 			Typ:            typ,
 			FileMatchLimit: args.PatternInfo.FileMatchLimit,
 			Select:         args.PatternInfo.Select,
+			Proximity:      args.PatternInfo.Proximity,
 			Zoekt:          args.Zoekt,
 		},
 	}, nil
@@ -271,7 +350,62 @@ func (s *IndexedSubsetSearchRequest) Search(ctx context.Context, c streaming.Sen
 		since = s.since
 	}
 
-	return zoektSearch(ctx, s.RepoRevs, s.Args.Query, s.Args.Typ, s.Args.Zoekt.Client, s.Args.FileMatchLimit, s.Args.Select, since, c)
+	c, selector, err := withProximityFilter(s.Args, c)
+	if err != nil {
+		return err
+	}
+
+	return zoektSearch(ctx, s.RepoRevs, s.Args.Query, s.Args.Typ, s.Args.Zoekt.Client, s.Args.FileMatchLimit, selector, since, c)
+}
+
+// withProximityFilter returns a child stream of c which filters file matches
+// by the proximity expression of args, if any. It also returns the selector to
+// search with: Zoekt cannot evaluate proximity expressions when only listing
+// repositories, so we search for file matches and select repositories from
+// the filtered file matches.
+func withProximityFilter(args *search.ZoektParameters, c streaming.Sender) (streaming.Sender, filter.SelectPath, error) {
+	if args.Proximity == nil || args.Typ == search.SymbolRequest {
+		return c, args.Select, nil
+	}
+	m, err := proximity.Compile(args.Proximity)
+	if err != nil {
+		return nil, nil, err
+	}
+	selector := args.Select
+	if selector.Root() == filter.Repository {
+		c = streaming.WithSelect(c, selector)
+		selector = nil
+	}
+	return streaming.StreamFunc(func(event streaming.SearchEvent) {
+		matches := event.Results[:0]
+		for _, match := range event.Results {
+			fm, ok := match.(*result.FileMatch)
+			if !ok {
+				continue
+			}
+			fm.LineMatches = filterProximity(m, fm.LineMatches)
+			if len(fm.LineMatches) > 0 {
+				matches = append(matches, fm)
+			}
+		}
+		event.Results = matches
+		c.Send(event)
+	}), selector, nil
+}
+
+// filterProximity returns the line matches of lines which are part of a match
+// of m.
+func filterProximity(m *proximity.Matcher, lines []*result.LineMatch) []*result.LineMatch {
+	candidates := make([]proximity.Line, 0, len(lines))
+	for _, l := range lines {
+		candidates = append(candidates, proximity.Line{Number: int(l.LineNumber), Preview: l.Preview})
+	}
+	indexes := m.Filter(candidates)
+	filtered := make([]*result.LineMatch, 0, len(indexes))
+	for _, i := range indexes {
+		filtered = append(filtered, lines[i])
+	}
+	return filtered
 }
 
 const maxUnindexedRepoRevSearchesPerQuery = 200
@@ -312,8 +446,10 @@ func NewIndexedSubsetSearchRequest(ctx context.Context, args *search.TextParamet
 		}, nil
 	}
 
-	// Fallback to Unindexed if the query contains ref-globs
-	if query.ContainsRefGlobs(args.Query) {
+	// Fallback to Unindexed if the query contains ref-globs and no branch
+	// globs are indexed.
+	siteConfig := conf.Get().SiteConfiguration
+	if query.ContainsRefGlobs(args.Query) && !backend.HasIndexedBranchGlobs(&siteConfig) {
 		if args.PatternInfo.Index == query.Only {
 			return nil, errors.Errorf("invalid index:%q (revsions with glob pattern cannot be resolved for indexed searches)", args.PatternInfo.Index)
 		}
@@ -359,8 +495,14 @@ func NewIndexedSubsetSearchRequest(ctx context.Context, args *search.TextParamet
 
 	tr.LogFields(log.Int("all_indexed_set.size", len(indexedSet)))
 
+	// Ref globs can only be searched in the index if they are configured to
+	// be indexed, otherwise they may match branches which aren't indexed.
+	branchGlobs := func(repo string) []string {
+		return backend.IndexedBranchGlobs(&siteConfig, repo)
+	}
+
 	// Split based on indexed vs unindexed
-	indexed, searcherRepos := zoektIndexedRepos(indexedSet, args.Repos, filter)
+	indexed, searcherRepos := zoektIndexedRepos(indexedSet, args.Repos, filter, branchGlobs)
 
 	tr.LogFields(
 		log.Int("indexed.size", len(indexed.repoRevs)),
@@ -383,6 +525,7 @@ func NewIndexedSubsetSearchRequest(ctx context.Context, args *search.TextParamet
 			Typ:            typ,
 			FileMatchLimit: args.PatternInfo.FileMatchLimit,
 			Select:         args.PatternInfo.Select,
+			Proximity:      args.PatternInfo.Proximity,
 			Zoekt:          args.Zoekt,
 		},
 
@@ -732,8 +875,9 @@ func contextWithoutDeadline(cOld context.Context) (context.Context, context.Canc
 
 // zoektIndexedRepos splits the revs into two parts: (1) the repository
 // revisions in indexedSet (indexed) and (2) the repositories that are
-// unindexed.
-func zoektIndexedRepos(indexedSet map[string]*zoekt.Repository, revs []*search.RepositoryRevisions, filter func(*zoekt.Repository) bool) (indexed *IndexedRepoRevs, unindexed []*search.RepositoryRevisions) {
+// unindexed. branchGlobs returns the branch globs indexed for a repository,
+// see backend.IndexedBranchGlobs. If it is nil, ref globs are unindexed.
+func zoektIndexedRepos(indexedSet map[string]*zoekt.Repository, revs []*search.RepositoryRevisions, filter func(*zoekt.Repository) bool, branchGlobs func(repo string) []string) (indexed *IndexedRepoRevs, unindexed []*search.RepositoryRevisions) {
 	// PERF: If len(revs) is large, we expect to be doing an indexed
 	// search. So set indexed to the max size it can be to avoid growing.
 	indexed = &IndexedRepoRevs{
@@ -749,7 +893,11 @@ func zoektIndexedRepos(indexedSet map[string]*zoekt.Repository, revs []*search.R
 			continue
 		}
 
-		unindexedRevs := indexed.add(reporev, repo)
+		var globs []string
+		if branchGlobs != nil {
+			globs = branchGlobs(string(reporev.Repo.Name))
+		}
+		unindexedRevs := indexed.add(reporev, repo, globs)
 		if len(unindexedRevs) > 0 {
 			copy := reporev.Copy()
 			copy.Revs = unindexedRevs
@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/md5"
 	"encoding/binary"
+	"fmt"
 	"testing"
 	"time"
 
@@ -15,6 +16,8 @@ import (
 	"github.com/sourcegraph/sourcegraph/internal/api"
 	"github.com/sourcegraph/sourcegraph/internal/search"
 	searchbackend "github.com/sourcegraph/sourcegraph/internal/search/backend"
+	"github.com/sourcegraph/sourcegraph/internal/search/filter"
+	"github.com/sourcegraph/sourcegraph/internal/search/proximity"
 	"github.com/sourcegraph/sourcegraph/internal/search/query"
 	"github.com/sourcegraph/sourcegraph/internal/search/result"
 	"github.com/sourcegraph/sourcegraph/internal/search/streaming"
@@ -393,7 +396,7 @@ func TestZoektIndexedRepos(t *testing.T) {
 
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
-			indexed, unindexed := zoektIndexedRepos(zoektRepos, tc.repos, nil)
+			indexed, unindexed := zoektIndexedRepos(zoektRepos, tc.repos, nil, nil)
 
 			if diff := cmp.Diff(repoRevsSliceToMap(tc.indexed), indexed.repoRevs); diff != "" {
 				t.Error("unexpected indexed:", diff)
@@ -534,7 +537,7 @@ func TestZoektIndexedRepos_single(t *testing.T) {
 	}
 
 	for _, tt := range cases {
-		indexed, unindexed := zoektIndexedRepos(zoektRepos, []*search.RepositoryRevisions{repoRev(tt.rev)}, nil)
+		indexed, unindexed := zoektIndexedRepos(zoektRepos, []*search.RepositoryRevisions{repoRev(tt.rev)}, nil, nil)
 		got := ret{
 			Indexed:   indexed.repoRevs,
 			Unindexed: unindexed,
@@ -549,6 +552,113 @@ func TestZoektIndexedRepos_single(t *testing.T) {
 	}
 }
 
+func TestZoektIndexedRepos_refGlobs(t *testing.T) {
+	repoRev := func(revs ...search.RevisionSpecifier) *search.RepositoryRevisions {
+		return &search.RepositoryRevisions{
+			Repo: types.RepoName{ID: api.RepoID(0), Name: "test/repo"},
+			Revs: revs,
+		}
+	}
+	zoektRepos := map[string]*zoekt.Repository{
+		"test/repo": {
+			Name: "test/repo",
+			Branches: []zoekt.RepositoryBranch{
+				{Name: "HEAD", Version: "df3f4e499698e48152b39cd655d8901eaf583fa5"},
+				{Name: "release/1.0", Version: "8ec975423738fe7851676083ebf660a062ed1578"},
+				{Name: "release/1.1", Version: "a6ffbd5e0d7ac0d1c25a3ea7bd57e1d6a8ad3a5c"},
+			},
+		},
+	}
+	branchGlobs := func(repo string) []string {
+		return []string{"release/*"}
+	}
+
+	type ret struct {
+		Indexed   map[string]*search.RepositoryRevisions
+		Unindexed []*search.RepositoryRevisions
+	}
+
+	cases := []struct {
+		name          string
+		rev           *search.RepositoryRevisions
+		wantIndexed   []*search.RepositoryRevisions
+		wantUnindexed []*search.RepositoryRevisions
+	}{{
+		name:          "indexed glob",
+		rev:           repoRev(search.RevisionSpecifier{RefGlob: "refs/heads/release/*"}),
+		wantIndexed:   []*search.RepositoryRevisions{repoRev(search.RevisionSpecifier{RevSpec: "release/1.0"}, search.RevisionSpecifier{RevSpec: "release/1.1"})},
+		wantUnindexed: []*search.RepositoryRevisions{},
+	}, {
+		name: "indexed glob with exclude",
+		rev: repoRev(
+			search.RevisionSpecifier{RefGlob: "heads/release/*"},
+			search.RevisionSpecifier{ExcludeRefGlob: "refs/heads/release/1.0"},
+		),
+		wantIndexed:   []*search.RepositoryRevisions{repoRev(search.RevisionSpecifier{RevSpec: "release/1.1"})},
+		wantUnindexed: []*search.RepositoryRevisions{},
+	}, {
+		name: "indexed glob and explicit revs",
+		rev: repoRev(
+			search.RevisionSpecifier{RevSpec: "HEAD"},
+			search.RevisionSpecifier{RevSpec: "HEAD~1"},
+			search.RevisionSpecifier{RefGlob: "refs/heads/release/*"},
+		),
+		wantIndexed: []*search.RepositoryRevisions{repoRev(
+			search.RevisionSpecifier{RevSpec: "HEAD"},
+			search.RevisionSpecifier{RevSpec: "release/1.0"},
+			search.RevisionSpecifier{RevSpec: "release/1.1"},
+		)},
+		wantUnindexed: []*search.RepositoryRevisions{repoRev(search.RevisionSpecifier{RevSpec: "HEAD~1"})},
+	}, {
+		name:          "unindexed glob",
+		rev:           repoRev(search.RevisionSpecifier{RefGlob: "refs/heads/feature/*"}),
+		wantIndexed:   []*search.RepositoryRevisions{},
+		wantUnindexed: []*search.RepositoryRevisions{repoRev(search.RevisionSpecifier{RefGlob: "refs/heads/feature/*"})},
+	}}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			indexed, unindexed := zoektIndexedRepos(zoektRepos, []*search.RepositoryRevisions{tt.rev}, nil, branchGlobs)
+			got := ret{
+				Indexed:   indexed.repoRevs,
+				Unindexed: unindexed,
+			}
+			want := ret{
+				Indexed:   repoRevsSliceToMap(tt.wantIndexed),
+				Unindexed: tt.wantUnindexed,
+			}
+			if !cmp.Equal(want, got) {
+				t.Errorf("mismatch (-want +got):\n%s", cmp.Diff(want, got))
+			}
+
+			// Results on a branch must map back to the branch name.
+			if r, ok := indexed.repoRevs["test/repo"]; ok {
+				for i, b := range indexed.repoBranches["test/repo"] {
+					if rev := r.Revs[i].RevSpec; b != rev {
+						t.Errorf("branch %q mapped to rev %q", b, rev)
+					}
+				}
+			}
+		})
+	}
+
+	// Without configured branch globs ref globs are not indexed.
+	rev := repoRev(search.RevisionSpecifier{RefGlob: "refs/heads/release/*"})
+	if _, unindexed := zoektIndexedRepos(zoektRepos, []*search.RepositoryRevisions{rev}, nil, nil); len(unindexed) != 1 {
+		t.Errorf("got %d unindexed repos, want 1", len(unindexed))
+	}
+
+	// If a repository has the maximum number of indexed branches, some
+	// branches matching the glob may not be indexed.
+	full := &zoekt.Repository{Name: "test/repo"}
+	for i := 0; i < searchbackend.MaxIndexedBranches; i++ {
+		full.Branches = append(full.Branches, zoekt.RepositoryBranch{Name: fmt.Sprintf("release/%d", i)})
+	}
+	if _, unindexed := zoektIndexedRepos(map[string]*zoekt.Repository{"test/repo": full}, []*search.RepositoryRevisions{rev}, nil, branchGlobs); len(unindexed) != 1 {
+		t.Errorf("got %d unindexed repos for repository with maximum number of branches, want 1", len(unindexed))
+	}
+}
+
 func TestZoektFileMatchToSymbolResults(t *testing.T) {
 	symbolInfo := func(sym string) *zoekt.Symbol {
 		return &zoekt.Symbol{
@@ -721,3 +831,55 @@ func matchesToFileMatches(matches []result.Match) ([]*result.FileMatch, error) {
 	}
 	return fms, nil
 }
+
+func TestWithProximityFilter(t *testing.T) {
+	fileMatch := func(repo api.RepoName, lines ...string) *result.FileMatch {
+		fm := &result.FileMatch{File: result.File{Repo: types.RepoName{Name: repo}, Path: "a.go"}}
+		for i, l := range lines {
+			fm.LineMatches = append(fm.LineMatches, &result.LineMatch{Preview: l, LineNumber: int32(i)})
+		}
+		return fm
+	}
+
+	for _, tc := range []struct {
+		selector     filter.SelectPath
+		wantSelector filter.SelectPath
+		want         []result.Match
+	}{
+		{
+			selector:     filter.SelectPath{filter.Content},
+			wantSelector: filter.SelectPath{filter.Content},
+			want:         []result.Match{fileMatch("a", "foo", "bar")},
+		},
+		{
+			selector:     filter.SelectPath{filter.Repository},
+			wantSelector: nil,
+			want:         []result.Match{&result.RepoMatch{Name: "a"}},
+		},
+	} {
+		t.Run(tc.selector.Root(), func(t *testing.T) {
+			args := &search.ZoektParameters{
+				Select:    tc.selector,
+				Proximity: &proximity.Pattern{Patterns: []string{"foo", "bar"}, Distance: 1},
+			}
+			var got []result.Match
+			c, selector, err := withProximityFilter(args, streaming.StreamFunc(func(event streaming.SearchEvent) {
+				got = append(got, event.Results...)
+			}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.wantSelector, selector); diff != "" {
+				t.Fatalf("selector mismatch (-want +got):\n%s", diff)
+			}
+
+			c.Send(streaming.SearchEvent{Results: []result.Match{
+				fileMatch("a", "foo", "bar"),
+				fileMatch("b", "foo"),
+			}})
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Fatalf("results mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -55,8 +55,9 @@ const maxFileSize = 1 << 20 // 1MB; match https://sourcegraph.com/search?q=repo:
 type Store struct {
 	// FetchTar returns an io.ReadCloser to a tar archive of a repository at the specified Git
 	// remote URL and commit ID. If the error implements "BadRequest() bool", it will be used to
-	// determine if the error is a bad request (eg invalid repo).
-	FetchTar func(ctx context.Context, repo api.RepoName, commit api.CommitID) (io.ReadCloser, error)
+	// determine if the error is a bad request (eg invalid repo). If paths is non-empty, the
+	// archive only needs to contain the files matching these git pathspecs.
+	FetchTar func(ctx context.Context, repo api.RepoName, commit api.CommitID, paths []string) (io.ReadCloser, error)
 
 	// FilterTar returns a FilterFunc that filters out files we don't want to write to disk
 	FilterTar func(ctx context.Context, repo api.RepoName, commit api.CommitID) (FilterFunc, error)
@@ -109,7 +110,9 @@ func (s *Store) Start() {
 
 // PrepareZip returns the path to a local zip archive of repo at commit.
 // It will first consult the local cache, otherwise will fetch from the network.
-func (s *Store) PrepareZip(ctx context.Context, repo api.RepoName, commit api.CommitID) (path string, err error) {
+// If paths is non-empty, the archive only contains the files matching these git
+// pathspecs. Archives of different paths are cached separately.
+func (s *Store) PrepareZip(ctx context.Context, repo api.RepoName, commit api.CommitID, paths []string) (path string, err error) {
 	span, ctx := ot.StartSpanFromContext(ctx, "Store.prepareZip")
 	ext.Component.Set(span, "store")
 	defer func() {
@@ -130,10 +133,7 @@ func (s *Store) PrepareZip(ctx context.Context, repo api.RepoName, commit api.Co
 	}
 
 	largeFilePatterns := conf.Get().SearchLargeFiles
-
-	// key is a sha256 hash since we want to use it for the disk name
-	h := sha256.Sum256([]byte(fmt.Sprintf("%q %q %q", repo, commit, largeFilePatterns)))
-	key := hex.EncodeToString(h[:])
+	key := cacheKey(repo, commit, paths, largeFilePatterns)
 	span.LogKV("key", key)
 
 	// Our fetch can take a long time, and the frontend aggressively cancels
@@ -149,7 +149,7 @@ func (s *Store) PrepareZip(ctx context.Context, repo api.RepoName, commit api.Co
 		// since we're just going to close it again immediately.
 		bgctx := opentracing.ContextWithSpan(context.Background(), opentracing.SpanFromContext(ctx))
 		f, err := s.cache.Open(bgctx, key, func(ctx context.Context) (io.ReadCloser, error) {
-			return s.fetch(ctx, repo, commit, largeFilePatterns)
+			return s.fetch(ctx, repo, commit, paths, largeFilePatterns, nil)
 		})
 		var path string
 		if f != nil {
@@ -176,10 +176,126 @@ func (s *Store) PrepareZip(ctx context.Context, repo api.RepoName, commit api.Co
 	}
 }
 
+// StreamFunc is called with the name and contents of each file of an archive
+// while it is fetched. The contents of files we don't search, e.g. binary or
+// large files, are nil. Files above the size limit which we search anyway,
+// see search.largeFiles, are not passed to it, since we would have to hold
+// them in memory. It is called from a single goroutine, never after StreamZip
+// returns, and may retain content.
+type StreamFunc func(name string, content []byte) error
+
+// StreamZip is like PrepareZip, but if the archive of repo at commit is not
+// cached it calls onFile for each file while the archive is fetched from the
+// network and written to the cache. This lets callers search the archive
+// before it is fully fetched.
+//
+// Unlike PrepareZip, the fetch stops when ctx is done, in which case the
+// archive is not cached. StreamZip then returns without waiting for the
+// fetch, but onFile is not called anymore. If onFile returns an error, the
+// fetch stops and StreamZip returns that error.
+//
+// streamed is false if the archive was already cached or fetched by another
+// request. In that case onFile is not called and callers should read the
+// archive at path instead. Otherwise callers should search the LargeFiles of
+// the archive at path, which are not passed to onFile.
+func (s *Store) StreamZip(ctx context.Context, repo api.RepoName, commit api.CommitID, paths []string, onFile StreamFunc) (path string, streamed bool, err error) {
+	span, ctx := ot.StartSpanFromContext(ctx, "Store.streamZip")
+	ext.Component.Set(span, "store")
+	defer func() {
+		if err != nil {
+			ext.Error.Set(span, true)
+			span.SetTag("err", err.Error())
+		}
+		span.SetTag("streamed", streamed)
+		span.Finish()
+	}()
+
+	// Ensure we have initialized
+	s.Start()
+
+	if len(commit) != 40 {
+		return "", false, errors.Errorf("commit must be resolved (repo=%q, commit=%q)", repo, commit)
+	}
+
+	largeFilePatterns := conf.Get().SearchLargeFiles
+	key := cacheKey(repo, commit, paths, largeFilePatterns)
+	span.LogKV("key", key)
+
+	// The cache fetches in the background, so that a fetch can outlive the
+	// request which started it, and Open returns as soon as ctx is done.
+	// mu guards the state shared with the fetch, and returned stops calls
+	// to onFile once we return.
+	var (
+		mu       sync.Mutex
+		fetched  bool
+		returned bool
+	)
+	guardedOnFile := func(name string, content []byte) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if returned {
+			return context.Canceled
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return onFile(name, content)
+	}
+	defer func() {
+		mu.Lock()
+		returned = true
+		mu.Unlock()
+	}()
+
+	f, err := s.cache.Open(ctx, key, func(fetchCtx context.Context) (io.ReadCloser, error) {
+		// Nobody waits for the files we stream once the request is done, so
+		// we stop fetching.
+		fetchCtx, cancel := context.WithCancel(fetchCtx)
+		go func() {
+			select {
+			case <-ctx.Done():
+				cancel()
+			case <-fetchCtx.Done():
+			}
+		}()
+		mu.Lock()
+		fetched = true
+		mu.Unlock()
+		return s.fetch(fetchCtx, repo, commit, paths, largeFilePatterns, guardedOnFile)
+	})
+	if err != nil {
+		return "", false, err
+	}
+	f.File.Close()
+
+	mu.Lock()
+	streamed = fetched
+	mu.Unlock()
+	if streamed {
+		streamedFetches.Inc()
+	}
+	return f.Path, streamed, nil
+}
+
+// cacheKey returns the key of the archive of the paths of repo at commit in
+// the cache.
+func cacheKey(repo api.RepoName, commit api.CommitID, paths, largeFilePatterns []string) string {
+	key := fmt.Sprintf("%q %q %q", repo, commit, largeFilePatterns)
+	if len(paths) > 0 {
+		// Archives of all paths keep the key they had before we could fetch
+		// archives of some paths.
+		key += fmt.Sprintf(" %q", paths)
+	}
+	// key is a sha256 hash since we want to use it for the disk name
+	h := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(h[:])
+}
+
 // fetch fetches an archive from the network and stores it on disk. It does
 // not populate the in-memory cache. You should probably be calling
-// prepareZip.
-func (s *Store) fetch(ctx context.Context, repo api.RepoName, commit api.CommitID, largeFilePatterns []string) (rc io.ReadCloser, err error) {
+// prepareZip. If onFile is non-nil, it is called for each file written to the
+// archive.
+func (s *Store) fetch(ctx context.Context, repo api.RepoName, commit api.CommitID, paths, largeFilePatterns []string, onFile StreamFunc) (rc io.ReadCloser, err error) {
 	fetchQueueSize.Inc()
 	ctx, releaseFetchLimiter, err := s.fetchLimiter.Acquire(ctx) // Acquire concurrent fetches semaphore
 	if err != nil {
@@ -196,6 +312,7 @@ func (s *Store) fetch(ctx context.Context, repo api.RepoName, commit api.CommitI
 	ext.Component.Set(span, "store")
 	span.SetTag("repo", repo)
 	span.SetTag("commit", commit)
+	span.SetTag("paths", paths)
 
 	// Done is called when the returned reader is closed, or if this function
 	// returns an error. It should always be called once.
@@ -222,7 +339,7 @@ func (s *Store) fetch(ctx context.Context, repo api.RepoName, commit api.CommitI
 		}
 	}()
 
-	r, err := s.FetchTar(ctx, repo, commit)
+	r, err := s.FetchTar(ctx, repo, commit, paths)
 	if err != nil {
 		return nil, err
 	}
@@ -247,7 +364,7 @@ func (s *Store) fetch(ctx context.Context, repo api.RepoName, commit api.CommitI
 		defer r.Close()
 		tr := tar.NewReader(r)
 		zw := zip.NewWriter(pw)
-		err := copySearchable(tr, zw, largeFilePatterns, filter)
+		err := copySearchable(tr, zw, largeFilePatterns, filter, onFile)
 		if err1 := zw.Close(); err == nil {
 			err = err1
 		}
@@ -261,7 +378,13 @@ func (s *Store) fetch(ctx context.Context, repo api.RepoName, commit api.CommitI
 
 // copySearchable copies searchable files from tr to zw. A searchable file is
 // any file that is under size limit, non-binary, and not matching the filter.
-func copySearchable(tr *tar.Reader, zw *zip.Writer, largeFilePatterns []string, filter FilterFunc) error {
+// If onFile is non-nil, it is called for each file copied to zw.
+func copySearchable(tr *tar.Reader, zw *zip.Writer, largeFilePatterns []string, filter FilterFunc, onFile StreamFunc) error {
+	streaming := onFile != nil
+	if !streaming {
+		onFile = func(string, []byte) error { return nil }
+	}
+
 	// 32*1024 is the same size used by io.Copy
 	buf := make([]byte, 32*1024)
 	for {
@@ -302,23 +425,39 @@ func copySearchable(tr *tar.Reader, zw *zip.Writer, largeFilePatterns []string,
 		n, err := tr.Read(buf)
 		switch err {
 		case io.EOF:
-			if n == 0 {
-				continue
-			}
 		case nil:
 		default:
 			return err
 		}
 
-		// We do not search the content of large files unless they are
-		// allowed.
-		if hdr.Size > maxFileSize && !ignoreSizeMax(hdr.Name, largeFilePatterns) {
+		// We do not search the content of empty files, of large files unless
+		// they are allowed, and of binary files. Heuristic: Assume file is
+		// binary if first 256 bytes contain a 0x00. Best effort, so ignore
+		// err. We only search names of binary files.
+		if n == 0 ||
+			(hdr.Size > maxFileSize && !ignoreSizeMax(hdr.Name, largeFilePatterns)) ||
+			bytes.IndexByte(buf[:n], 0x00) >= 0 {
+			if err := onFile(hdr.Name, nil); err != nil {
+				return err
+			}
 			continue
 		}
 
-		// Heuristic: Assume file is binary if first 256 bytes contain a
-		// 0x00. Best effort, so ignore err. We only search names of binary files.
-		if n > 0 && bytes.IndexByte(buf[:n], 0x00) >= 0 {
+		if streaming && hdr.Size <= maxFileSize {
+			// Read the whole file, so that onFile can search it while we
+			// copy the rest of the archive. Larger files are only copied,
+			// callers search them in the cached archive.
+			content := make([]byte, hdr.Size)
+			copy(content, buf[:n])
+			if _, err := io.ReadFull(tr, content[n:]); err != nil {
+				return err
+			}
+			if _, err := w.Write(content); err != nil {
+				return err
+			}
+			if err := onFile(hdr.Name, content); err != nil {
+				return err
+			}
 			continue
 		}
 
@@ -335,7 +474,6 @@ func copySearchable(tr *tar.Reader, zw *zip.Writer, largeFilePatterns []string,
 		if err != nil {
 			return err
 		}
-
 	}
 }
 
@@ -410,6 +548,10 @@ var (
 		Name: "searcher_store_fetch_failed",
 		Help: "The total number of archive fetches that failed.",
 	})
+	streamedFetches = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "searcher_store_streamed_fetches",
+		Help: "The total number of archives searched while they were fetched.",
+	})
 )
 
 // temporaryError wraps an error but adds the Temporary method. It does not
@@ -2,15 +2,19 @@ package store
 
 import (
 	"archive/tar"
+	"archive/zip"
 	"bytes"
 	"context"
 	"io"
 	"os"
+	"sort"
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/cockroachdb/errors"
+	"github.com/google/go-cmp/cmp"
 
 	"github.com/sourcegraph/sourcegraph/internal/api"
 	"github.com/sourcegraph/sourcegraph/internal/errcode"
@@ -27,7 +31,7 @@ func TestPrepareZip(t *testing.T) {
 	var gotRepo api.RepoName
 	var gotCommit api.CommitID
 	var fetchZipCalled int64
-	s.FetchTar = func(ctx context.Context, repo api.RepoName, commit api.CommitID) (io.ReadCloser, error) {
+	s.FetchTar = func(ctx context.Context, repo api.RepoName, commit api.CommitID, paths []string) (io.ReadCloser, error) {
 		<-returnFetch
 		atomic.AddInt64(&fetchZipCalled, 1)
 		gotRepo = repo
@@ -41,7 +45,7 @@ func TestPrepareZip(t *testing.T) {
 	for i := 0; i < 10; i++ {
 		go func() {
 			<-startPrepareZip
-			_, err := s.PrepareZip(context.Background(), wantRepo, wantCommit)
+			_, err := s.PrepareZip(context.Background(), wantRepo, wantCommit, nil)
 			prepareZipErr <- err
 		}()
 	}
@@ -75,7 +79,7 @@ func TestPrepareZip(t *testing.T) {
 	if !onDisk {
 		t.Fatal("timed out waiting for items to appear in cache at", s.Path)
 	}
-	_, err := s.PrepareZip(context.Background(), wantRepo, wantCommit)
+	_, err := s.PrepareZip(context.Background(), wantRepo, wantCommit, nil)
 	if err != nil {
 		t.Fatal("expected PrepareZip to succeed:", err)
 	}
@@ -85,19 +89,51 @@ func TestPrepareZip_fetchTarFail(t *testing.T) {
 	fetchErr := errors.New("test")
 	s, cleanup := tmpStore(t)
 	defer cleanup()
-	s.FetchTar = func(ctx context.Context, repo api.RepoName, commit api.CommitID) (io.ReadCloser, error) {
+	s.FetchTar = func(ctx context.Context, repo api.RepoName, commit api.CommitID, paths []string) (io.ReadCloser, error) {
 		return nil, fetchErr
 	}
-	_, err := s.PrepareZip(context.Background(), "foo", "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef")
+	_, err := s.PrepareZip(context.Background(), "foo", "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef", nil)
 	if !errors.Is(err, fetchErr) {
 		t.Fatalf("expected PrepareZip to fail with %v, failed with %v", fetchErr, err)
 	}
 }
 
+func TestPrepareZip_paths(t *testing.T) {
+	s, cleanup := tmpStore(t)
+	defer cleanup()
+
+	var gotPaths [][]string
+	s.FetchTar = func(ctx context.Context, repo api.RepoName, commit api.CommitID, paths []string) (io.ReadCloser, error) {
+		gotPaths = append(gotPaths, paths)
+		return emptyTar(t), nil
+	}
+
+	// Archives of different paths are cached separately.
+	all, err := s.PrepareZip(context.Background(), "foo", "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs, err := s.PrepareZip(context.Background(), "foo", "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef", []string{":(top)docs/*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if all == docs {
+		t.Errorf("expected archives of different paths to be cached separately, both are at %s", all)
+	}
+	if _, err := s.PrepareZip(context.Background(), "foo", "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef", []string{":(top)docs/*"}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][]string{nil, {":(top)docs/*"}}
+	if diff := cmp.Diff(want, gotPaths); diff != "" {
+		t.Errorf("unexpected paths passed to FetchTar (-want +got):\n%s", diff)
+	}
+}
+
 func TestPrepareZip_errHeader(t *testing.T) {
 	s, cleanup := tmpStore(t)
 	defer cleanup()
-	s.FetchTar = func(ctx context.Context, repo api.RepoName, commit api.CommitID) (io.ReadCloser, error) {
+	s.FetchTar = func(ctx context.Context, repo api.RepoName, commit api.CommitID, paths []string) (io.ReadCloser, error) {
 		buf := new(bytes.Buffer)
 		w := tar.NewWriter(buf)
 		w.Flush()
@@ -108,7 +144,7 @@ func TestPrepareZip_errHeader(t *testing.T) {
 		}
 		return io.NopCloser(bytes.NewReader(buf.Bytes())), nil
 	}
-	_, err := s.PrepareZip(context.Background(), "foo", "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef")
+	_, err := s.PrepareZip(context.Background(), "foo", "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef", nil)
 	if have, want := errors.Cause(err).Error(), tar.ErrHeader.Error(); have != want {
 		t.Fatalf("expected PrepareZip to fail with tar.ErrHeader, failed with %v", err)
 	}
@@ -117,6 +153,215 @@ func TestPrepareZip_errHeader(t *testing.T) {
 	}
 }
 
+func TestStreamZip(t *testing.T) {
+	s, cleanup := tmpStore(t)
+	defer cleanup()
+
+	var fetchTarCalled int64
+	s.FetchTar = func(ctx context.Context, repo api.RepoName, commit api.CommitID, paths []string) (io.ReadCloser, error) {
+		atomic.AddInt64(&fetchTarCalled, 1)
+		return tarOf(t, map[string]string{
+			"a.txt":   "hello\n",
+			"b.bin":   "\x00binary",
+			"c/d.txt": "world\n",
+		}), nil
+	}
+
+	streamed := map[string]string{}
+	onFile := func(name string, content []byte) error {
+		streamed[name] = string(content)
+		return nil
+	}
+	path, ok, err := s.StreamZip(context.Background(), "foo", "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef", nil, onFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal("expected the archive to be streamed")
+	}
+	want := map[string]string{
+		"a.txt":   "hello\n",
+		"b.bin":   "",
+		"c/d.txt": "world\n",
+	}
+	if diff := cmp.Diff(want, streamed); diff != "" {
+		t.Errorf("unexpected streamed files (-want +got):\n%s", diff)
+	}
+
+	zf, err := s.ZipCache.Get(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zf.Close()
+	if len(zf.Files) != 3 {
+		t.Errorf("expected the cached archive to contain 3 files, got %v", zf.Files)
+	}
+
+	// The archive is cached now, so it is not streamed again.
+	streamed = map[string]string{}
+	_, ok, err = s.StreamZip(context.Background(), "foo", "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef", nil, onFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok || len(streamed) != 0 {
+		t.Errorf("expected the cached archive not to be streamed, streamed %v", streamed)
+	}
+	if n := atomic.LoadInt64(&fetchTarCalled); n != 1 {
+		t.Errorf("expected FetchTar to be called once, called %d times", n)
+	}
+}
+
+func TestStreamZip_stop(t *testing.T) {
+	s, cleanup := tmpStore(t)
+	defer cleanup()
+	s.FetchTar = func(ctx context.Context, repo api.RepoName, commit api.CommitID, paths []string) (io.ReadCloser, error) {
+		return tarOf(t, map[string]string{"a.txt": "a", "b.txt": "b"}), nil
+	}
+
+	stopErr := errors.New("stop")
+	_, _, err := s.StreamZip(context.Background(), "foo", "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef", nil, func(string, []byte) error {
+		return stopErr
+	})
+	if !errors.Is(err, stopErr) {
+		t.Fatalf("expected StreamZip to fail with %v, failed with %v", stopErr, err)
+	}
+
+	// The partial archive is not cached.
+	files, err := os.ReadDir(s.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		if strings.HasSuffix(f.Name(), ".zip") {
+			t.Errorf("expected no archive to be cached, found %s", f.Name())
+		}
+	}
+}
+
+func TestStreamZip_cancel(t *testing.T) {
+	s, cleanup := tmpStore(t)
+	defer cleanup()
+
+	// The archive has a file, then blocks until release is closed.
+	release := make(chan struct{})
+	closed := make(chan struct{})
+	s.FetchTar = func(ctx context.Context, repo api.RepoName, commit api.CommitID, paths []string) (io.ReadCloser, error) {
+		pr, pw := io.Pipe()
+		go func() {
+			w := tar.NewWriter(pw)
+			write := func(name string) error {
+				if err := w.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: 1}); err != nil {
+					return err
+				}
+				_, err := w.Write([]byte("x"))
+				return err
+			}
+			err := write("a.txt")
+			if err == nil {
+				// Flush a.txt, so that it is read before we block.
+				err = w.Flush()
+			}
+			<-release
+			for _, name := range []string{"b.txt", "c.txt"} {
+				if err == nil {
+					err = write(name)
+				}
+			}
+			if err == nil {
+				err = w.Close()
+			}
+			pw.CloseWithError(err)
+		}()
+		return closeFunc{Reader: pr, close: func() error {
+			close(closed)
+			return pr.Close()
+		}}, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// onFile is not synchronized with the test on purpose, so that the race
+	// detector catches calls after StreamZip returns.
+	var names []string
+	var returned int32
+	onFile := func(name string, _ []byte) error {
+		if atomic.LoadInt32(&returned) != 0 {
+			t.Errorf("onFile(%q) called after StreamZip returned", name)
+		}
+		names = append(names, name)
+		// Cancel while the fetch is in progress.
+		cancel()
+		return nil
+	}
+	_, _, err := s.StreamZip(ctx, "foo", "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef", nil, onFile)
+	atomic.StoreInt32(&returned, 1)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected StreamZip to fail with %v, failed with %v", context.Canceled, err)
+	}
+	if diff := cmp.Diff([]string{"a.txt"}, names); diff != "" {
+		t.Errorf("unexpected streamed files (-want +got):\n%s", diff)
+	}
+
+	// Let the fetch continue and wait until it stops reading the archive.
+	close(release)
+	select {
+	case <-closed:
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for the fetch to stop")
+	}
+	if diff := cmp.Diff([]string{"a.txt"}, names); diff != "" {
+		t.Errorf("unexpected streamed files (-want +got):\n%s", diff)
+	}
+}
+
+type closeFunc struct {
+	io.Reader
+	close func() error
+}
+
+func (c closeFunc) Close() error { return c.close() }
+
+func TestCopySearchable_streamLargeFiles(t *testing.T) {
+	large := strings.Repeat("a", maxFileSize+1)
+	tr := tar.NewReader(tarOf(t, map[string]string{
+		"large.txt":   large,
+		"skipped.txt": large,
+		"small.txt":   "hello\n",
+	}))
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	streamed := map[string]string{}
+	onFile := func(name string, content []byte) error {
+		streamed[name] = string(content)
+		return nil
+	}
+	filter := func(*tar.Header) bool { return false }
+	if err := copySearchable(tr, zw, []string{"large.txt"}, filter, onFile); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Large files are not read into memory, but copied to the archive.
+	want := map[string]string{
+		"skipped.txt": "",
+		"small.txt":   "hello\n",
+	}
+	if diff := cmp.Diff(want, streamed); diff != "" {
+		t.Errorf("unexpected streamed files (-want +got):\n%s", diff)
+	}
+	zf, err := MockZipFile(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := zf.LargeFiles()
+	if len(files) != 1 || files[0].Name != "large.txt" || string(zf.DataFor(files[0])) != large {
+		t.Errorf("expected large.txt to be the only large file in the archive, got %v", files)
+	}
+}
+
 func TestIngoreSizeMax(t *testing.T) {
 	patterns := []string{
 		"foo",
@@ -158,6 +403,29 @@ func tmpStore(t *testing.T) (*Store, func()) {
 	}, func() { os.RemoveAll(d) }
 }
 
+func tarOf(t *testing.T, files map[string]string) io.ReadCloser {
+	buf := new(bytes.Buffer)
+	w := tar.NewWriter(buf)
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		body := files[name]
+		if err := w.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(body))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return io.NopCloser(bytes.NewReader(buf.Bytes()))
+}
+
 func emptyTar(t *testing.T) io.ReadCloser {
 	buf := new(bytes.Buffer)
 	w := tar.NewWriter(buf)
@@ -211,6 +211,18 @@ func (f *ZipFile) DataFor(s *SrcFile) []byte {
 	return f.Data[s.Off : s.Off+int64(s.Len)]
 }
 
+// LargeFiles returns the files in f above the size limit, which StreamZip
+// does not pass to its StreamFunc.
+func (f *ZipFile) LargeFiles() []*SrcFile {
+	var large []*SrcFile
+	for i := range f.Files {
+		if f.Files[i].Len > maxFileSize {
+			large = append(large, &f.Files[i])
+		}
+	}
+	return large
+}
+
 func (f *SrcFile) String() string {
 	return fmt.Sprintf("<%s: %d+%d bytes>", f.Name, f.Off, f.Len)
 }
@@ -18,7 +18,9 @@ import (
 	"github.com/sourcegraph/sourcegraph/internal/api"
 )
 
-func FetchTarFromGithub(ctx context.Context, repo api.RepoName, commit api.CommitID) (io.ReadCloser, error) {
+// FetchTarFromGithub fetches the archive of repo at commit from GitHub. It
+// ignores paths and always returns the archive of all paths.
+func FetchTarFromGithub(ctx context.Context, repo api.RepoName, commit api.CommitID, paths []string) (io.ReadCloser, error) {
 	// key is a sha256 hash since we want to use it for the disk name
 	h := sha256.Sum256([]byte(string(repo) + " " + string(commit)))
 	key := hex.EncodeToString(h[:])
@@ -58,7 +58,7 @@ func TempZipFromFiles(files map[string]string) (path string, cleanup func(), err
 	ctx := context.Background()
 	repo := api.RepoName("foo")
 	var commit api.CommitID = "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef"
-	path, err = s.PrepareZip(ctx, repo, commit)
+	path, err = s.PrepareZip(ctx, repo, commit, nil)
 	if err != nil {
 		return "", cleanup, err
 	}
@@ -66,6 +66,17 @@ func (e *ExternalService) RedactConfigSecrets() (string, error) {
 		newCfg, err = redactField(e.Config, []string{"url"})
 	case *schema.JVMPackagesConnection:
 		newCfg, err = e.Config, nil
+	case *schema.NpmPackagesConnection:
+		// Credentials are optional for public registries
+		var fields [][]string
+		if cfg.Credentials != "" {
+			fields = append(fields, []string{"credentials"})
+		}
+		newCfg, err = redactField(e.Config, fields...)
+	case *schema.GoModulesConnection:
+		newCfg, err = e.Config, nil
+	case *schema.PythonPackagesConnection:
+		newCfg, err = e.Config, nil
 	default:
 		// return an error here, it's safer to fail than to incorrectly return unsafe data.
 		err = errors.Errorf("RedactExternalServiceConfig: kind %q not implemented", e.Kind)
@@ -147,6 +158,17 @@ func (e *ExternalService) UnredactConfig(old *ExternalService) error {
 		unredacted, err = unredactField(old.Config, e.Config, &cfg, jsonStringField{[]string{"url"}, &cfg.Url})
 	case *schema.JVMPackagesConnection:
 		unredacted, err = e.Config, nil
+	case *schema.NpmPackagesConnection:
+		// Credentials are optional for public registries
+		var fields []jsonStringField
+		if cfg.Credentials != "" {
+			fields = append(fields, jsonStringField{[]string{"credentials"}, &cfg.Credentials})
+		}
+		unredacted, err = unredactField(old.Config, e.Config, &cfg, fields...)
+	case *schema.GoModulesConnection:
+		unredacted, err = e.Config, nil
+	case *schema.PythonPackagesConnection:
+		unredacted, err = e.Config, nil
 	default:
 		// return an error here, it's safer to fail than to incorrectly return unsafe data.
 		err = errors.Errorf("UnRedactExternalServiceConfig: kind %q not implemented", e.Kind)
@@ -51,6 +51,10 @@ func TestRoundTripRedactExternalServiceConfig(t *testing.T) {
 		P4Passwd: someSecret,
 		P4User:   "admin",
 	}
+	npmPackagesConfig := schema.NpmPackagesConnection{
+		Credentials: someSecret,
+		Registry:    "https://registry.npmjs.org",
+	}
 	otherConfig := schema.OtherExternalServiceConnection{
 		Url:                   someSecret,
 		RepositoryPathPattern: "foo",
@@ -121,6 +125,12 @@ func TestRoundTripRedactExternalServiceConfig(t *testing.T) {
 			editField:   &perforceConfig.P4User,
 			secretField: &perforceConfig.P4Passwd,
 		},
+		{
+			kind:        extsvc.KindNpmPackages,
+			config:      &npmPackagesConfig,
+			editField:   &npmPackagesConfig.Registry,
+			secretField: &npmPackagesConfig.Credentials,
+		},
 		{
 			kind:        extsvc.KindOther,
 			config:      &otherConfig,
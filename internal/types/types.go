@@ -460,6 +460,20 @@ type GitserverRepo struct {
 	UpdatedAt   time.Time
 }
 
+// GitserverRepoReplica represents the data a gitserver which holds a replica
+// of a repo knows about it. Replicas exist when repos are cloned on more than
+// one gitserver.
+type GitserverRepoReplica struct {
+	RepoID api.RepoID
+	// The hostname of the gitserver holding the replica
+	ShardID     string
+	CloneStatus CloneStatus
+	// The last error that occurred or empty if the last action was successful
+	LastError   string
+	LastFetched time.Time
+	UpdatedAt   time.Time
+}
+
 // ExternalService is a connection to an external service.
 type ExternalService struct {
 	ID              int64
@@ -0,0 +1,217 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/cockroachdb/errors"
+	"github.com/sourcegraph/go-diff/diff"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+	"github.com/sourcegraph/sourcegraph/internal/trace/ot"
+)
+
+// CommitDiff is a commit with the diff of the changes it made, as returned by
+// CommitDiffs.
+type CommitDiff struct {
+	Commit Commit
+
+	// Diff is the --unified=0 diff of the commit to its first parent, without
+	// a/ and b/ prefixes.
+	Diff string
+
+	// Paths are the sorted paths the commit touched.
+	Paths []string
+}
+
+// IsReachableFromHEAD reports whether commit is HEAD or one of its ancestors.
+// It returns false if commit does not exist in repo, for example because it
+// was removed by a force-push.
+func IsReachableFromHEAD(ctx context.Context, repo api.RepoName, commit api.CommitID) (bool, error) {
+	if Mocks.IsReachableFromHEAD != nil {
+		return Mocks.IsReachableFromHEAD(repo, commit)
+	}
+
+	span, ctx := ot.StartSpanFromContext(ctx, "Git: IsReachableFromHEAD")
+	span.SetTag("Commit", commit)
+	defer span.Finish()
+
+	if err := checkSpecArgSafety(string(commit)); err != nil {
+		return false, err
+	}
+
+	// List a commit reachable from commit but not from HEAD. There is none
+	// if commit is reachable from HEAD.
+	args := []string{"rev-list", "--max-count=1", "HEAD.." + string(commit)}
+	cmd := gitserver.DefaultClient.Command("git", args...)
+	cmd.Repo = repo
+	out, err := cmd.CombinedOutput(ctx)
+	if err != nil {
+		if bytes.Contains(out, []byte("bad revision")) || bytes.Contains(out, []byte("unknown revision")) {
+			return false, nil
+		}
+		return false, errors.WithMessage(err, fmt.Sprintf("git command %v failed (output: %q)", args, out))
+	}
+	return len(bytes.TrimSpace(out)) == 0, nil
+}
+
+// NextCommitBoundary returns the commit up to which the next at most
+// maxCommits commits of since..HEAD can be processed, oldest first, such that
+// all their ancestors are processed before them. If since is empty, all
+// commits reachable from HEAD are considered. It returns an empty commit ID
+// if there are no commits in since..HEAD.
+func NextCommitBoundary(ctx context.Context, repo api.RepoName, since api.CommitID, maxCommits int) (_ api.CommitID, err error) {
+	if Mocks.NextCommitBoundary != nil {
+		return Mocks.NextCommitBoundary(repo, since, maxCommits)
+	}
+
+	span, ctx := ot.StartSpanFromContext(ctx, "Git: NextCommitBoundary")
+	span.SetTag("Since", since)
+	defer span.Finish()
+
+	revRange, err := headRange(since)
+	if err != nil {
+		return "", err
+	}
+
+	// With --reverse --topo-order, no commit is listed before its parents, so
+	// everything reachable from the maxCommits'th commit is listed before it.
+	// (--max-count would limit the commits before reversing them.)
+	cmd := gitserver.DefaultClient.Command("git", "rev-list", "--reverse", "--topo-order", revRange)
+	cmd.Repo = repo
+
+	// Don't wait for all of the output when we only need its start.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	rc, err := gitserver.StdoutReader(ctx, cmd)
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	var last api.CommitID
+	scanner := bufio.NewScanner(rc)
+	for n := 0; n < maxCommits && scanner.Scan(); n++ {
+		last = api.CommitID(scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return last, nil
+}
+
+// CommitDiffs returns the non-merge commits in since..until with their diffs.
+// If since is empty, it returns all non-merge commits reachable from until.
+func CommitDiffs(ctx context.Context, repo api.RepoName, since, until api.CommitID) (_ []*CommitDiff, err error) {
+	if Mocks.CommitDiffs != nil {
+		return Mocks.CommitDiffs(repo, since, until)
+	}
+
+	span, ctx := ot.StartSpanFromContext(ctx, "Git: CommitDiffs")
+	span.SetTag("Since", since)
+	span.SetTag("Until", until)
+	defer span.Finish()
+
+	if err := checkSpecArgSafety(string(until)); err != nil {
+		return nil, err
+	}
+	revRange := string(until)
+	if since != "" {
+		if err := checkSpecArgSafety(string(since)); err != nil {
+			return nil, err
+		}
+		revRange = string(since) + ".." + revRange
+	}
+
+	args := []string{"log", "--no-merges", "-z", "--no-prefix", "--patch", "--unified=0", "--no-color", logFormatWithoutRefs, revRange, "--"}
+	cmd := gitserver.DefaultClient.Command("git", args...)
+	cmd.Repo = repo
+	data, err := cmd.Output(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, fmt.Sprintf("git command %v failed", args))
+	}
+	return parseCommitDiffs(data)
+}
+
+// parseCommitDiffs parses the output of `git log -z --patch` with
+// logFormatWithoutRefs.
+func parseCommitDiffs(data []byte) ([]*CommitDiff, error) {
+	var commits []*CommitDiff
+	for len(data) > 0 {
+		var commit *Commit
+		var err error
+		commit, _, data, err = parseCommitFromLog(data)
+		if err != nil {
+			return nil, err
+		}
+
+		var rawDiff []byte
+		if len(data) >= 1 && data[0] == '\x00' {
+			// No diff patch.
+			data = data[1:]
+		} else if len(data) >= 1 && data[0] == '\n' {
+			data = data[1:]
+			if patchEnd := bytes.Index(data, []byte("\n\x00")); patchEnd != -1 {
+				rawDiff = data[:patchEnd+1]
+				data = data[patchEnd+2:]
+			} else {
+				rawDiff = data
+				data = nil
+			}
+		}
+
+		paths, err := diffPaths(rawDiff)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing diff of commit %s", commit.ID)
+		}
+		commits = append(commits, &CommitDiff{
+			Commit: *commit,
+			Diff:   string(rawDiff),
+			Paths:  paths,
+		})
+	}
+	return commits, nil
+}
+
+// diffPaths returns the sorted paths of the files in rawDiff.
+func diffPaths(rawDiff []byte) ([]string, error) {
+	seen := map[string]struct{}{}
+	dr := diff.NewMultiFileDiffReader(bytes.NewReader(rawDiff))
+	for {
+		fileDiff, err := dr.ReadFile()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		for _, name := range []string{fileDiff.OrigName, fileDiff.NewName} {
+			if name != "" && name != "/dev/null" {
+				seen[name] = struct{}{}
+			}
+		}
+	}
+
+	paths := make([]string, 0, len(seen))
+	for path := range seen {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// headRange returns the revision range of commits reachable from HEAD but not
+// from since.
+func headRange(since api.CommitID) (string, error) {
+	if since == "" {
+		return "HEAD", nil
+	}
+	if err := checkSpecArgSafety(string(since)); err != nil {
+		return "", err
+	}
+	return string(since) + "..HEAD", nil
+}
@@ -0,0 +1,111 @@
+package git
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+)
+
+func TestRepository_CommitDiffs(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	const env = "GIT_COMMITTER_NAME=a GIT_COMMITTER_EMAIL=a@a.com GIT_AUTHOR_NAME=a GIT_AUTHOR_EMAIL=a@a.com "
+	repo := MakeGitRepository(t,
+		"echo hello > f",
+		"git add f",
+		env+"GIT_COMMITTER_DATE=2006-01-02T15:04:05Z git commit -m one",
+		"echo world >> f && echo x > g",
+		"git add f g",
+		env+"GIT_COMMITTER_DATE=2007-01-02T15:04:05Z git commit -m two",
+		env+"GIT_COMMITTER_DATE=2008-01-02T15:04:05Z git commit --allow-empty -m three",
+	)
+
+	first, err := NextCommitBoundary(ctx, repo, "", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	all, err := NextCommitBoundary(ctx, repo, "", 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	head, err := ResolveRevision(ctx, repo, "HEAD", ResolveRevisionOptions{NoEnsureRevision: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if all != head {
+		t.Errorf("got boundary %s for all commits, want HEAD %s", all, head)
+	}
+	if none, err := NextCommitBoundary(ctx, repo, head, 100); err != nil || none != "" {
+		t.Errorf("got boundary %q, %v after HEAD, want none", none, err)
+	}
+
+	diffs, err := CommitDiffs(ctx, repo, first, all)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var messages []string
+	for _, d := range diffs {
+		messages = append(messages, string(d.Commit.Message))
+	}
+	if want := []string{"three", "two"}; !reflect.DeepEqual(messages, want) {
+		t.Fatalf("got commits %q, want %q", messages, want)
+	}
+	if diffs[0].Diff != "" || len(diffs[0].Paths) != 0 {
+		t.Errorf("got diff %q and paths %q for empty commit, want none", diffs[0].Diff, diffs[0].Paths)
+	}
+	if want := []string{"f", "g"}; !reflect.DeepEqual(diffs[1].Paths, want) {
+		t.Errorf("got paths %q, want %q", diffs[1].Paths, want)
+	}
+	wantDiff := "diff --git f f\nindex ce01362..94954ab 100644\n--- f\n+++ f\n@@ -1,0 +2 @@ hello\n+world\ndiff --git g g\nnew file mode 100644\nindex 0000000..587be6b\n--- /dev/null\n+++ g\n@@ -0,0 +1 @@\n+x\n"
+	if diffs[1].Diff != wantDiff {
+		t.Errorf("got diff %q, want %q", diffs[1].Diff, wantDiff)
+	}
+
+	for commit, want := range map[api.CommitID]bool{
+		first:            true,
+		head:             true,
+		"deadbeefdeadbe": false,
+	} {
+		got, err := IsReachableFromHEAD(ctx, repo, commit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("IsReachableFromHEAD(%s): got %v, want %v", commit, got, want)
+		}
+	}
+}
+
+func TestFilterDiff(t *testing.T) {
+	rawDiff := "diff --git f f\n--- f\n+++ f\n@@ -1,0 +2 @@\n+world\ndiff --git g g\n--- /dev/null\n+++ g\n@@ -0,0 +1 @@\n+x\n"
+
+	diff, highlights, err := FilterDiff(rawDiff, RawLogDiffSearchOptions{
+		Query:             TextSearchOptions{Pattern: "WORLD"},
+		OnlyMatchingHunks: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff == nil || !strings.Contains(diff.Raw, "+world") || strings.Contains(diff.Raw, "+x") {
+		t.Fatalf("got diff %+v, want only the hunk of f", diff)
+	}
+	if len(highlights) != 1 || highlights[0].Character != 1 || highlights[0].Length != len("world") {
+		t.Errorf("got highlights %+v, want one of world", highlights)
+	}
+
+	diff, _, err = FilterDiff(rawDiff, RawLogDiffSearchOptions{
+		Query:             TextSearchOptions{Pattern: "world"},
+		Paths:             PathOptions{IncludePatterns: []string{"^g$"}, IsRegExp: true},
+		OnlyMatchingHunks: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff != nil {
+		t.Errorf("got diff %q, want none", diff.Raw)
+	}
+}
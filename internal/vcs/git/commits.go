@@ -191,6 +191,33 @@ func HasCommitAfter(ctx context.Context, repo api.RepoName, date string, revspec
 	return n > 0, err
 }
 
+// CommitBefore returns the commit that revspec pointed to at the given date,
+// which is the latest commit committed before date on the first-parent
+// history of revspec. It returns an empty commit ID if there is no such
+// commit, for example because the repository did not exist yet.
+func CommitBefore(ctx context.Context, repo api.RepoName, date string, revspec string) (api.CommitID, error) {
+	span, ctx := ot.StartSpanFromContext(ctx, "Git: CommitBefore")
+	span.SetTag("Date", date)
+	span.SetTag("RevSpec", revspec)
+	defer span.Finish()
+
+	if revspec == "" {
+		revspec = "HEAD"
+	}
+	if err := checkSpecArgSafety(revspec); err != nil {
+		return "", err
+	}
+
+	args := []string{"rev-list", "--max-count=1", "--first-parent", "--before=" + date, revspec}
+	cmd := gitserver.DefaultClient.Command("git", args...)
+	cmd.Repo = repo
+	out, err := cmd.CombinedOutput(ctx)
+	if err != nil {
+		return "", errors.WithMessage(err, fmt.Sprintf("git command %v failed (output: %q)", args, out))
+	}
+	return api.CommitID(bytes.TrimSpace(out)), nil
+}
+
 func isBadObjectErr(output, obj string) bool {
 	return output == "fatal: bad object "+obj
 }
@@ -160,6 +160,50 @@ func TestRepository_HasCommitAfter(t *testing.T) {
 	}
 }
 
+func TestRepository_CommitBefore(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	commitDates := []string{
+		"2006-01-02T15:04:05Z",
+		"2007-01-02T15:04:05Z",
+		"2008-01-02T15:04:05Z",
+	}
+	gitCommands := make([]string, len(commitDates))
+	for i, date := range commitDates {
+		gitCommands[i] = fmt.Sprintf("GIT_COMMITTER_NAME=a GIT_COMMITTER_EMAIL=a@a.com GIT_COMMITTER_DATE=%s git commit --allow-empty -m foo --author='a <a@a.com>'", date)
+	}
+	repo := MakeGitRepository(t, gitCommands...)
+
+	testCases := []struct {
+		before  string
+		revspec string
+		want    string // committer date of the commit, or "" for none
+	}{
+		{before: "2007-06-01T00:00:00Z", revspec: "", want: "2007-01-02T15:04:05Z"},
+		{before: "2009-01-01T00:00:00Z", revspec: "HEAD", want: "2008-01-02T15:04:05Z"},
+		{before: "2007-01-02T15:04:05Z", revspec: "master", want: "2007-01-02T15:04:05Z"},
+		{before: "2005-01-01T00:00:00Z", revspec: "HEAD", want: ""},
+	}
+	for _, tc := range testCases {
+		id, err := CommitBefore(ctx, repo, tc.before, tc.revspec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got string
+		if id != "" {
+			commit, err := GetCommit(ctx, repo, id, ResolveRevisionOptions{NoEnsureRevision: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = commit.Committer.Date.Format(time.RFC3339)
+		}
+		if got != tc.want {
+			t.Errorf("CommitBefore(%q, %q): got commit %q, want %q", tc.before, tc.revspec, got, tc.want)
+		}
+	}
+}
+
 func TestRepository_FirstEverCommit(t *testing.T) {
 	t.Parallel()
 	ctx := context.Background()
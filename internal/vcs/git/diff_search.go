@@ -335,19 +335,9 @@ func rawShowSearch(ctx context.Context, repo api.RepoName, opt RawLogDiffSearchO
 	// Even though we've already searched using the query, we need to
 	// search the returned diff again to filter to only matching hunks
 	// and to highlight matches.
-	var query *regexp.Regexp
-	if pattern := opt.Query.Pattern; pattern != "" {
-		if !opt.Query.IsRegExp {
-			pattern = regexp.QuoteMeta(pattern)
-		}
-		if !opt.Query.IsCaseSensitive {
-			pattern = "(?i:" + pattern + ")"
-		}
-		var err error
-		query, err = regexp.Compile(pattern)
-		if err != nil {
-			return nil, false, err
-		}
+	query, err := compileQuery(opt.Query)
+	if err != nil {
+		return nil, false, err
 	}
 
 	pathMatcher, err := compilePathMatcher(opt.Paths)
@@ -466,6 +456,41 @@ func rawShowSearch(ctx context.Context, repo api.RepoName, opt RawLogDiffSearchO
 	return results, complete, nil
 }
 
+// compileQuery compiles the pattern of opt, or returns nil if it is empty.
+func compileQuery(opt TextSearchOptions) (*regexp.Regexp, error) {
+	pattern := opt.Pattern
+	if pattern == "" {
+		return nil, nil
+	}
+	if !opt.IsRegExp {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if !opt.IsCaseSensitive {
+		pattern = "(?i:" + pattern + ")"
+	}
+	return regexp.Compile(pattern)
+}
+
+// FilterDiff filters and highlights rawDiff like RawLogDiffSearch does for
+// the diff of each commit it finds, for diffs that were read from elsewhere,
+// like the commit index. It returns a nil diff if no part of rawDiff matches
+// the query and paths of opt.
+func FilterDiff(rawDiff string, opt RawLogDiffSearchOptions) (*RawDiff, []Highlight, error) {
+	query, err := compileQuery(opt.Query)
+	if err != nil {
+		return nil, nil, err
+	}
+	pathMatcher, err := compilePathMatcher(opt.Paths)
+	if err != nil {
+		return nil, nil, err
+	}
+	filtered, highlights, err := filterAndHighlightDiff([]byte(rawDiff), query, opt.OnlyMatchingHunks, pathMatcher)
+	if err != nil || filtered == nil {
+		return nil, nil, err
+	}
+	return &RawDiff{Raw: string(filtered)}, highlights, nil
+}
+
 func logDiffCommonArgs(opt RawLogDiffSearchOptions) []string {
 	var args []string
 	if opt.Query.Pattern != "" && opt.Diff {
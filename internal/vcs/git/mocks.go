@@ -26,6 +26,10 @@ var Mocks, emptyMocks struct {
 	Commits          func(repo api.RepoName, opt CommitsOptions) ([]*Commit, error)
 	MergeBase        func(repo api.RepoName, a, b api.CommitID) (api.CommitID, error)
 	GetDefaultBranch func(repo api.RepoName) (refName string, commit api.CommitID, err error)
+
+	IsReachableFromHEAD func(repo api.RepoName, commit api.CommitID) (bool, error)
+	NextCommitBoundary  func(repo api.RepoName, since api.CommitID, maxCommits int) (api.CommitID, error)
+	CommitDiffs         func(repo api.RepoName, since, until api.CommitID) ([]*CommitDiff, error)
 }
 
 // ResetMocks clears the mock functions set on Mocks (so that subsequent tests don't inadvertently
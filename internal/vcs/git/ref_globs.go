@@ -96,3 +96,17 @@ func (gs RefGlobs) Match(ref string) bool {
 	}
 	return match
 }
+
+// IsBranchGlob reports whether name is a glob pattern of branch names, such
+// as "release/*", rather than a revspec. Ref names can't contain glob
+// characters (see `man git-check-ref-format`), but some revspecs, such as
+// "HEAD^{/fix.*}", can.
+func IsBranchGlob(name string) bool {
+	return strings.ContainsAny(name, "?*[") && !strings.ContainsAny(name, "^~:@{\\")
+}
+
+// BranchRefGlob returns the RefGlob that includes the branches matching the
+// branch glob pattern.
+func BranchRefGlob(pattern string) RefGlob {
+	return RefGlob{Include: "refs/heads/" + pattern}
+}
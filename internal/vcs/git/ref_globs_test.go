@@ -56,3 +56,17 @@ func TestRefGlobs(t *testing.T) {
 		})
 	}
 }
+
+func TestIsBranchGlob(t *testing.T) {
+	for name, want := range map[string]bool{
+		"release/*":     true,
+		"v3.[0-9]":      true,
+		"main":          false,
+		"HEAD":          false,
+		"HEAD^{/fix.*}": false,
+	} {
+		if got := IsBranchGlob(name); got != want {
+			t.Errorf("IsBranchGlob(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
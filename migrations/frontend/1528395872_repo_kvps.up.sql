@@ -0,0 +1,10 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS repo_kvps (
+    repo_id integer NOT NULL REFERENCES repo(id) ON DELETE CASCADE DEFERRABLE,
+    key text NOT NULL,
+    value text,
+    PRIMARY KEY (repo_id, key)
+);
+
+COMMIT;
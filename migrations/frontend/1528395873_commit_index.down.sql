@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS commit_index_metadata;
+DROP TABLE IF EXISTS commit_index;
+
+COMMIT;
@@ -0,0 +1,31 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS commit_index (
+    repo_id integer NOT NULL REFERENCES repo(id) ON DELETE CASCADE DEFERRABLE,
+    commit_id text NOT NULL,
+    author_name text NOT NULL,
+    author_email text NOT NULL,
+    author_date timestamp with time zone NOT NULL,
+    committer_name text NOT NULL,
+    committer_email text NOT NULL,
+    committer_date timestamp with time zone NOT NULL,
+    message text NOT NULL,
+    parents text[] NOT NULL DEFAULT '{}'::text[],
+    paths text[] NOT NULL DEFAULT '{}'::text[],
+    diff text NOT NULL,
+    PRIMARY KEY (repo_id, commit_id)
+);
+
+CREATE INDEX IF NOT EXISTS commit_index_repo_id_committer_date ON commit_index (repo_id, committer_date DESC);
+CREATE INDEX IF NOT EXISTS commit_index_message_trgm ON commit_index USING gin (message gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS commit_index_diff_trgm ON commit_index USING gin (diff gin_trgm_ops);
+
+CREATE TABLE IF NOT EXISTS commit_index_metadata (
+    repo_id integer PRIMARY KEY REFERENCES repo(id) ON DELETE CASCADE DEFERRABLE,
+    indexed_commit text NOT NULL,
+    last_indexed_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+COMMENT ON COLUMN commit_index_metadata.indexed_commit IS 'All non-merge commits reachable from this commit are in commit_index.';
+
+COMMIT;
@@ -0,0 +1,5 @@
+BEGIN;
+
+DROP TABLE IF EXISTS search_export_jobs;
+
+COMMIT;
@@ -0,0 +1,30 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS search_export_jobs (
+    id                serial PRIMARY KEY,
+    user_id           integer NOT NULL REFERENCES users(id) ON DELETE CASCADE DEFERRABLE,
+    query             text NOT NULL,
+    format            text NOT NULL,
+    download_secret   text NOT NULL,
+    result_count      integer NOT NULL DEFAULT 0,
+    cancel            boolean NOT NULL DEFAULT FALSE,
+    created_at        timestamp with time zone NOT NULL DEFAULT now(),
+    state             text NOT NULL DEFAULT 'queued',
+    failure_message   text,
+    started_at        timestamp with time zone,
+    finished_at       timestamp with time zone,
+    process_after     timestamp with time zone,
+    num_resets        integer NOT NULL DEFAULT 0,
+    num_failures      integer NOT NULL DEFAULT 0,
+    last_heartbeat_at timestamp with time zone,
+    execution_logs    json[],
+    worker_hostname   text NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS search_export_jobs_state ON search_export_jobs (state);
+CREATE INDEX IF NOT EXISTS search_export_jobs_user_id ON search_export_jobs (user_id);
+
+COMMENT ON COLUMN search_export_jobs.download_secret IS 'Key used to sign the download URLs of the exported results.';
+COMMENT ON COLUMN search_export_jobs.result_count IS 'Number of results written to the export so far.';
+
+COMMIT;
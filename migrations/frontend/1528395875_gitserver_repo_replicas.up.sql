@@ -0,0 +1,17 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS gitserver_repo_replicas (
+    repo_id      integer NOT NULL REFERENCES repo(id) ON DELETE CASCADE,
+    shard_id     text NOT NULL,
+    clone_status text NOT NULL DEFAULT 'not_cloned',
+    last_error   text,
+    last_fetched timestamp with time zone NOT NULL DEFAULT now(),
+    updated_at   timestamp with time zone NOT NULL DEFAULT now(),
+    PRIMARY KEY (repo_id, shard_id)
+);
+
+CREATE INDEX IF NOT EXISTS gitserver_repo_replicas_shard_id_idx ON gitserver_repo_replicas (shard_id);
+
+COMMENT ON TABLE gitserver_repo_replicas IS 'The state of the replicas of repos cloned on more than one gitserver. The gitserver a repo lives on is tracked in gitserver_repos.';
+
+COMMIT;
@@ -0,0 +1,11 @@
+BEGIN;
+
+DELETE FROM search_export_jobs WHERE user_id IS NULL;
+
+COMMENT ON COLUMN search_export_jobs.user_id IS NULL;
+
+ALTER TABLE search_export_jobs DROP CONSTRAINT IF EXISTS search_export_jobs_user_id_fkey;
+ALTER TABLE search_export_jobs ADD CONSTRAINT search_export_jobs_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE DEFERRABLE;
+ALTER TABLE search_export_jobs ALTER COLUMN user_id SET NOT NULL;
+
+COMMIT;
@@ -0,0 +1,11 @@
+BEGIN;
+
+-- Deleting a user must not delete their export jobs right away, since the
+-- janitor has to delete their exported results from blob storage first.
+ALTER TABLE search_export_jobs ALTER COLUMN user_id DROP NOT NULL;
+ALTER TABLE search_export_jobs DROP CONSTRAINT IF EXISTS search_export_jobs_user_id_fkey;
+ALTER TABLE search_export_jobs ADD CONSTRAINT search_export_jobs_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL DEFERRABLE;
+
+COMMENT ON COLUMN search_export_jobs.user_id IS 'The user who created the export. NULL once the user is deleted, until the janitor deletes the job and its exported results.';
+
+COMMIT;
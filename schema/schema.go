@@ -550,15 +550,29 @@ type ExperimentalFeatures struct {
 	EnablePostSignupFlow bool `json:"enablePostSignupFlow,omitempty"`
 	// EventLogging description: Enables user event logging inside of the Sourcegraph instance. This will allow admins to have greater visibility of user activity, such as frequently viewed pages, frequent searches, and more. These event logs (and any specific user actions) are only stored locally, and never leave this Sourcegraph instance.
 	EventLogging string `json:"eventLogging,omitempty"`
+	// GitserverReplicationFactor description: The number of gitservers each repository is cloned on. Reads of a repository are retried on the other gitservers it is cloned on if the gitserver it lives on is unavailable. Values larger than the number of gitservers clone each repository on all of them.
+	GitserverReplicationFactor int `json:"gitserverReplicationFactor,omitempty"`
+	// GoModules description: Allow adding Go module proxy code host connections
+	GoModules string `json:"goModules,omitempty"`
 	// JvmPackages description: Allow adding JVM packages code host connections
 	JvmPackages string `json:"jvmPackages,omitempty"`
+	// NpmPackages description: Allow adding npm packages code host connections
+	NpmPackages string `json:"npmPackages,omitempty"`
+	// PartialClone description: JSON array of configuration that maps from Git clone URL domain/path to a blob size limit. Matching repositories are cloned as partial clones without the blobs larger than the limit, which are fetched on demand. Requires Git 2.27 or later on gitserver and a code host which supports partial clones.
+	PartialClone []*PartialCloneMapping `json:"partialClone,omitempty"`
 	// Perforce description: Allow adding Perforce code host connections
 	Perforce string `json:"perforce,omitempty"`
+	// PythonPackages description: Allow adding Python package index code host connections
+	PythonPackages string `json:"pythonPackages,omitempty"`
 	// Ranking description: Experimental search result ranking options.
 	Ranking *Ranking `json:"ranking,omitempty"`
 	// RateLimitAnonymous description: Configures the hourly rate limits for anonymous calls to the GraphQL API. Setting limit to 0 disables the limiter. This is only relevant if unauthenticated calls to the API are permitted.
 	RateLimitAnonymous int `json:"rateLimitAnonymous,omitempty"`
-	// SearchIndexBranches description: A map from repository name to a list of extra revs (branch, ref, tag, commit sha, etc) to index for a repository. We always index the default branch ("HEAD") and revisions in version contexts. This allows specifying additional revisions. Sourcegraph can index up to 64 branches per repository.
+	// SearchCommitIndexRepositories description: The names of the repositories whose commits on the default branch are indexed by the search-commit-indexer job of the worker service. Commit and diff searches of indexed repositories look up indexed commits in the index instead of running `git log`. Indexing stores the diffs of all commits in the database, so only list repositories with frequent commit and diff searches. By default, no repository is indexed.
+	SearchCommitIndexRepositories []string `json:"search.commitIndex.repositories,omitempty"`
+	// SearchIndexBranchGlobs description: A list of glob patterns of branch names, such as "release/*", to index for every repository. Searches of revisions matching one of these globs, such as "rev:release/*", use the index.
+	SearchIndexBranchGlobs []string `json:"search.index.branchGlobs,omitempty"`
+	// SearchIndexBranches description: A map from repository name to a list of extra revs (branch, ref, tag, commit sha, etc) to index for a repository. A rev may be a glob pattern of branch names, such as "release/*", in which case all matching branches are indexed. We always index the default branch ("HEAD") and revisions in version contexts. This allows specifying additional revisions. Sourcegraph can index up to 64 branches per repository.
 	SearchIndexBranches map[string][]string `json:"search.index.branches,omitempty"`
 	// SearchMultipleRevisionsPerRepository description: DEPRECATED. Always on. Will be removed in 3.19.
 	SearchMultipleRevisionsPerRepository *bool `json:"searchMultipleRevisionsPerRepository,omitempty"`
@@ -814,6 +828,24 @@ type GitoliteConnection struct {
 	Prefix string `json:"prefix"`
 }
 
+// GoModulesConnection description: Configuration for a connection to Go module proxies
+type GoModulesConnection struct {
+	// Dependencies description: An array of "module@version" strings specifying which Go modules to mirror on Sourcegraph. A module path without a version mirrors all versions listed by the proxy.
+	Dependencies []string `json:"dependencies,omitempty"`
+	// RateLimit description: Rate limit applied when making background API requests to the Go module proxies.
+	RateLimit *GoRateLimit `json:"rateLimit,omitempty"`
+	// Urls description: The list of Go module proxy URLs to fetch modules from. 404 and 410 responses fall back to the next URL in the list, like the GOPROXY environment variable.
+	Urls []string `json:"urls"`
+}
+
+// GoRateLimit description: Rate limit applied when making background API requests to the Go module proxies.
+type GoRateLimit struct {
+	// Enabled description: true if rate limiting is enabled.
+	Enabled bool `json:"enabled"`
+	// RequestsPerHour description: Requests per hour permitted. This is an average, calculated per second. Internally, the burst limit is set to 100, which implies that for a requests per hour limit as low as 1, users will continue to be able to send a maximum of 100 requests immediately, provided that the complexity cost of each request is 1.
+	RequestsPerHour float64 `json:"requestsPerHour"`
+}
+
 // HTTPHeaderAuthProvider description: Configures the HTTP header authentication provider (which authenticates users by consulting an HTTP request header set by an authentication proxy such as https://github.com/bitly/oauth2_proxy).
 type HTTPHeaderAuthProvider struct {
 	// EmailHeader description: The name (case-insensitive) of an HTTP header whose value is taken to be the email of the client requesting the page. Set this value when using an HTTP proxy that authenticates requests, and you don't want the extra configurability of the other authentication methods.
@@ -1057,6 +1089,26 @@ type NotifierWebhook struct {
 	Url         string `json:"url"`
 	Username    string `json:"username,omitempty"`
 }
+
+// NpmPackagesConnection description: Configuration for a connection to an npm packages registry.
+type NpmPackagesConnection struct {
+	// Credentials description: Access token for logging into the npm registry. It is sent as a bearer token in the Authorization header of each request.
+	Credentials string `json:"credentials,omitempty"`
+	// Dependencies description: An array of "package@version" strings specifying which npm packages to mirror on Sourcegraph. Scoped packages are written "@scope/package@version".
+	Dependencies []string `json:"dependencies,omitempty"`
+	// RateLimit description: Rate limit applied when making background API requests to the npm registry.
+	RateLimit *NpmRateLimit `json:"rateLimit,omitempty"`
+	// Registry description: The URL at which the npm registry can be found.
+	Registry string `json:"registry"`
+}
+
+// NpmRateLimit description: Rate limit applied when making background API requests to the npm registry.
+type NpmRateLimit struct {
+	// Enabled description: true if rate limiting is enabled.
+	Enabled bool `json:"enabled"`
+	// RequestsPerHour description: Requests per hour permitted. This is an average, calculated per second. Internally, the burst limit is set to 100, which implies that for a requests per hour limit as low as 1, users will continue to be able to send a maximum of 100 requests immediately, provided that the complexity cost of each request is 1.
+	RequestsPerHour float64 `json:"requestsPerHour"`
+}
 type OAuthIdentity struct {
 	Type string `json:"type"`
 }
@@ -1137,6 +1189,14 @@ type ParentSourcegraph struct {
 	Url string `json:"url,omitempty"`
 }
 
+// PartialCloneMapping description: Mapping from Git clone URL domain/path to the blob size limit of partial clones. The `domainPath` field contains the Git clone URL domain/path part. The `blobLimit` field contains the size limit in bytes, optionally with a "k", "m" or "g" suffix.
+type PartialCloneMapping struct {
+	// BlobLimit description: Blobs larger than this size in bytes are fetched on demand, as in `git clone --filter=blob:limit=<n>`.
+	BlobLimit string `json:"blobLimit"`
+	// DomainPath description: Git clone URL domain/path
+	DomainPath string `json:"domainPath"`
+}
+
 // PerforceAuthorization description: If non-null, enforces Perforce depot permissions.
 type PerforceAuthorization struct {
 }
@@ -1200,6 +1260,36 @@ type PhabricatorConnection struct {
 	// Url description: URL of a Phabricator instance, such as https://phabricator.example.com
 	Url string `json:"url,omitempty"`
 }
+
+// PythonPackagesConnection description: Configuration for a connection to Python simple repository APIs compatible with PyPI
+type PythonPackagesConnection struct {
+	// Dependencies description: An array of "package==version" strings specifying which Python packages to mirror on Sourcegraph.
+	Dependencies []string `json:"dependencies,omitempty"`
+	// RateLimit description: Rate limit applied when making background API requests to the Python package indexes.
+	RateLimit *PythonRateLimit `json:"rateLimit,omitempty"`
+	// Urls description: The list of PEP 503 simple index URLs to fetch packages from. A 404 response falls back to the next URL in the list.
+	Urls []string `json:"urls"`
+}
+
+// PythonRateLimit description: Rate limit applied when making background API requests to the Python package indexes.
+type PythonRateLimit struct {
+	// Enabled description: true if rate limiting is enabled.
+	Enabled bool `json:"enabled"`
+	// RequestsPerHour description: Requests per hour permitted. This is an average, calculated per second. Internally, the burst limit is set to 100, which implies that for a requests per hour limit as low as 1, users will continue to be able to send a maximum of 100 requests immediately, provided that the complexity cost of each request is 1.
+	RequestsPerHour float64 `json:"requestsPerHour"`
+}
+
+// QueryCostLimits description: Limits on the estimated cost of searches. The cost of a search is estimated from the number of repositories it searches, whether they are indexed, the pattern, the result count and the time range of commit and diff searches. Searching the default branch of one indexed repository for a literal costs 1. Any value less than or equal to zero means unlimited.
+type QueryCostLimits struct {
+	// Confirm description: Searches that cost more only run if the user confirms them.
+	Confirm int `json:"confirm,omitempty"`
+	// MaxConcurrentExpensive description: The maximum number of expensive searches a user can run at a time. Defaults to 1.
+	MaxConcurrentExpensive int `json:"maxConcurrentExpensive,omitempty"`
+	// Queue description: Searches that cost more are expensive. A user can run at most maxConcurrentExpensive expensive searches at a time, further expensive searches wait until one finishes.
+	Queue int `json:"queue,omitempty"`
+	// Reject description: Searches that cost more are rejected, unless the user is a site admin, in which case they must be confirmed.
+	Reject int `json:"reject,omitempty"`
+}
 type QuickLink struct {
 	// Description description: A description for this quick link
 	Description string `json:"description,omitempty"`
@@ -1285,6 +1375,8 @@ type SearchLimits struct {
 	MaxRepos int `json:"maxRepos,omitempty"`
 	// MaxTimeoutSeconds description: The maximum value for "timeout:" that search will respect. "timeout:" values larger than maxTimeoutSeconds are capped at maxTimeoutSeconds. Note: You need to ensure your load balancer / reverse proxy in front of Sourcegraph won't timeout the request for larger values. Note: Too many large rearch requests may harm Soucregraph for other users. Defaults to 1 minute.
 	MaxTimeoutSeconds int `json:"maxTimeoutSeconds,omitempty"`
+	// QueryCost description: Limits on the estimated cost of searches. The cost of a search is estimated from the number of repositories it searches, whether they are indexed, the pattern, the result count and the time range of commit and diff searches. Searching the default branch of one indexed repository for a literal costs 1. Any value less than or equal to zero means unlimited.
+	QueryCost *QueryCostLimits `json:"queryCost,omitempty"`
 }
 type SearchSavedQueries struct {
 	// Description description: Description of this saved query
@@ -1374,6 +1466,8 @@ type Settings struct {
 	SearchIncludeArchived *bool `json:"search.includeArchived,omitempty"`
 	// SearchIncludeForks description: Whether searches should include searching forked repositories.
 	SearchIncludeForks *bool `json:"search.includeForks,omitempty"`
+	// SearchMacros description: Named query fragments that can be referenced in a search query as `@name`. A reference is expanded to its query fragment before the query is run, as if it were a parenthesized expression. Macros may reference other macros.
+	SearchMacros map[string]string `json:"search.macros,omitempty"`
 	// SearchMigrateParser description: REMOVED. Previously, a flag to enable and/or-expressions in queries as an aid transition to new language features in versions <= 3.24.0.
 	SearchMigrateParser *bool `json:"search.migrateParser,omitempty"`
 	// SearchRepositoryGroups description: Named groups of repositories that can be referenced in a search query using the `repogroup:` operator. The list can contain string literals (to include single repositories) and JSON objects with a "regex" field (to include all repositories matching the regular expression). Retrieving repogroups via the GQL interface will currently exclude repositories matched by regex patterns. #14208.
@@ -1584,6 +1678,8 @@ type SiteConfiguration struct {
 	SearchLargeFiles []string `json:"search.largeFiles,omitempty"`
 	// SearchLimits description: Limits that search applies for number of repositories searched and timeouts.
 	SearchLimits *SearchLimits `json:"search.limits,omitempty"`
+	// SearchSymbolsParsers description: A map from language name (as in `lang:` filters, e.g. "go") to the parser the symbols service uses for files of that language. "ctags" (the default for all languages) runs universal-ctags. "go" parses Go files in-process with the Go standard library, which records the scope, signature and full range of each symbol. It can only be selected for Go. Changing this causes repositories to be re-parsed on their next symbol search.
+	SearchSymbolsParsers map[string]string `json:"search.symbols.parsers,omitempty"`
 	// UpdateChannel description: The channel on which to automatically check for Sourcegraph updates.
 	UpdateChannel string `json:"update.channel,omitempty"`
 	// UseJaeger description: DEPRECATED. Use `"observability.tracing": { "sampling": "all" }`, instead. Enables Jaeger tracing.